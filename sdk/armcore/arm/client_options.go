@@ -0,0 +1,76 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package arm contains the options shared by the clients of Azure Resource Manager services.
+package arm
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// Cloud is the configuration of Azure Resource Manager in an Azure cloud.
+type Cloud struct {
+	// Endpoint is the Azure Resource Manager endpoint, such as "https://management.azure.com".
+	Endpoint string
+
+	// Audience is the resource the tokens for Endpoint are requested for, such as "https://management.core.windows.net/".
+	// Leave this empty to request tokens for Endpoint.
+	Audience string
+}
+
+var (
+	// AzurePublicCloud is the configuration of the Azure public cloud.
+	AzurePublicCloud = Cloud{Endpoint: "https://management.azure.com", Audience: "https://management.core.windows.net/"}
+
+	// AzureChina is the configuration of Azure China.
+	AzureChina = Cloud{Endpoint: "https://management.chinacloudapi.cn", Audience: "https://management.core.chinacloudapi.cn/"}
+
+	// AzureGovernment is the configuration of Azure Government.
+	AzureGovernment = Cloud{Endpoint: "https://management.usgovcloudapi.net", Audience: "https://management.core.usgovcloudapi.net/"}
+)
+
+// ClientOptions configures the cloud and the pipeline of an Azure Resource Manager client.
+type ClientOptions struct {
+	azcore.PipelineOptions
+
+	// Cloud is the cloud the client sends requests to, one of the clouds above or the configuration of an
+	// Azure Stack instance. Leave this as its zero value to use AzurePublicCloud.
+	Cloud Cloud
+}
+
+// cloud returns the cloud of the options, AzurePublicCloud by default.
+func (o *ClientOptions) cloud() Cloud {
+	if o == nil || o.Cloud == (Cloud{}) {
+		return AzurePublicCloud
+	}
+	return o.Cloud
+}
+
+// Endpoint returns the Azure Resource Manager endpoint of the cloud.
+func (o *ClientOptions) Endpoint() string {
+	return o.cloud().Endpoint
+}
+
+// Scope returns the ".default" scope of the cloud's audience, such as "https://management.core.windows.net//.default".
+func (o *ClientOptions) Scope() string {
+	c := o.cloud()
+	audience := c.Audience
+	if audience == "" {
+		audience = c.Endpoint
+	}
+	return audience + "/.default"
+}
+
+// NewPipeline creates a pipeline from the options whose requests are authorized with tokens from cred for the
+// cloud's audience. The authentication policy runs after the PerRetryPolicies. Pass nil to accept the default values.
+func NewPipeline(cred azcore.TokenCredential, o *ClientOptions) azcore.Pipeline {
+	var po azcore.PipelineOptions
+	if o != nil {
+		po = o.PipelineOptions
+	}
+	auth := azcore.NewBearerTokenPolicy(cred, azcore.AuthenticationPolicyOptions{Options: azcore.TokenRequestOptions{Scopes: []string{o.Scope()}}})
+	po.PerRetryPolicies = append(append([]azcore.Policy{}, po.PerRetryPolicies...), auth)
+	return azcore.NewDefaultPipeline(&po)
+}
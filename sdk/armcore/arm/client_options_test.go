@@ -0,0 +1,73 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package arm
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/mock"
+)
+
+// scopeCredential returns tokens whose value is the requested scope
+type scopeCredential struct{}
+
+func (scopeCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: opts.Scopes[0], ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func (c scopeCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
+	return azcore.NewBearerTokenPolicy(c, options)
+}
+
+func TestClientOptionsCloud(t *testing.T) {
+	for _, test := range []struct {
+		o        *ClientOptions
+		endpoint string
+		scope    string
+	}{
+		{nil, "https://management.azure.com", "https://management.core.windows.net//.default"},
+		{&ClientOptions{}, "https://management.azure.com", "https://management.core.windows.net//.default"},
+		{&ClientOptions{Cloud: AzureGovernment}, "https://management.usgovcloudapi.net", "https://management.core.usgovcloudapi.net//.default"},
+		{&ClientOptions{Cloud: Cloud{Endpoint: "https://management.local.azurestack.external"}}, "https://management.local.azurestack.external", "https://management.local.azurestack.external/.default"},
+	} {
+		if e := test.o.Endpoint(); e != test.endpoint {
+			t.Fatalf("expected endpoint %s, got %s", test.endpoint, e)
+		}
+		if s := test.o.Scope(); s != test.scope {
+			t.Fatalf("expected scope %s, got %s", test.scope, s)
+		}
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.SetResponse(mock.WithStatusCode(http.StatusOK))
+	o := &ClientOptions{Cloud: AzureChina}
+	o.HTTPClient = transport
+	pl := NewPipeline(scopeCredential{}, o)
+	u, err := url.Parse(o.Endpoint() + "/subscriptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pl.Do(context.Background(), azcore.NewRequest(http.MethodGet, *u)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	requests := transport.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	if auth := requests[0].Header.Get(azcore.HeaderAuthorization); auth != "Bearer https://management.core.chinacloudapi.cn//.default" {
+		t.Fatalf("unexpected Authorization header %q", auth)
+	}
+	if len(o.PerRetryPolicies) != 0 {
+		t.Fatal("expected the options to be left unchanged")
+	}
+}
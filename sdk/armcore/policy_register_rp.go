@@ -68,6 +68,9 @@ func DefaultRegistrationOptions() RegistrationOptions {
 // NewRPRegistrationPolicy creates a policy object configured using the specified pipeline
 // and options. Pass nil to accept the default options; this is the same as passing the result
 // from a call to DefaultRegistrationOptions().
+// When a request fails with a 409 MissingSubscriptionRegistration error the policy registers the resource
+// provider named in the error with the request's subscription, polls the provider until its registration
+// state is Registered and then sends the original request again, up to Attempts times.
 func NewRPRegistrationPolicy(cred azcore.Credential, o *RegistrationOptions) azcore.Policy {
 	if o == nil {
 		def := DefaultRegistrationOptions()
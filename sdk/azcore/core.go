@@ -50,10 +50,19 @@ type transportPolicy struct {
 }
 
 func (tp transportPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
-	resp, err := tp.trans.Do(ctx, req.Request)
+	sent := req.Request
+	var secrets redactionOpValues
+	if req.OperationValue(&secrets) {
+		sent = secrets.restore(req.Request)
+	}
+	resp, err := tp.trans.Do(ctx, sent)
 	if err != nil {
 		return nil, err
 	}
+	if sent != req.Request {
+		// the policies that log the response see the redacted request
+		resp.Request = req.Request
+	}
 	return &Response{Response: resp}, nil
 }
 
@@ -37,17 +37,34 @@ func (cf credentialFunc) AuthenticationPolicy(options AuthenticationPolicyOption
 type TokenCredential interface {
 	Credential
 	// GetToken requests an access token for the specified set of scopes.
-	GetToken(ctx context.Context, options TokenRequestOptions) (*AccessToken, error)
+	// Requesting the token stops when ctx is done.
+	GetToken(ctx context.Context, options TokenRequestOptions) (AccessToken, error)
 }
 
 // AccessToken represents an Azure service bearer access token with expiry information.
 type AccessToken struct {
 	Token     string
 	ExpiresOn time.Time
+	// RefreshOn is when the service recommends refreshing the token, or the zero time when it made no recommendation.
+	// Callers should refresh the token after this time rather than waiting until shortly before ExpiresOn.
+	RefreshOn time.Time
 }
 
 // TokenRequestOptions contain specific parameter that may be used by credentials types when attempting to get a token.
 type TokenRequestOptions struct {
 	// Scopes contains the list of permission scopes required for the token.
 	Scopes []string
+
+	// Claims are additional claims required in the token, such as those in the claims challenge a resource
+	// returns in a WWW-Authenticate header. This is the decoded JSON, not the base64 value of the challenge.
+	Claims string
+
+	// EnableCAE requests a token that supports Continuous Access Evaluation. Such tokens can be revoked before
+	// they expire, so the client must be able to handle the claims challenges resources return for them.
+	EnableCAE bool
+
+	// TenantID is the tenant the token is requested from, for applications that access resources in several tenants.
+	// Leave this empty to use the tenant of the credential. Credentials that support it only request tokens from
+	// tenants they're configured to allow.
+	TenantID string
 }
@@ -0,0 +1,165 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// rfc3339Format formats times with 7 fractional digits, as many Azure services do
+	rfc3339Format = "2006-01-02T15:04:05.0000000Z07:00"
+
+	// rfc3339NoTimezone is the format of times some services send without a time zone, which are in UTC
+	rfc3339NoTimezone = "2006-01-02T15:04:05.999999999"
+)
+
+var jsonNull = []byte("null")
+
+// TimeRFC1123 is a time.Time encoded in RFC 1123 format in UTC, such as "Mon, 02 Jan 2006 15:04:05 GMT",
+// the format of HTTP headers like Last-Modified.
+type TimeRFC1123 time.Time
+
+// FormatRFC1123 returns t in RFC 1123 format in UTC.
+func FormatRFC1123(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// ParseRFC1123 parses an RFC 1123 time, with the GMT time zone or another named one.
+func ParseRFC1123(s string) (time.Time, error) {
+	t, err := time.Parse(http.TimeFormat, s)
+	if err != nil {
+		t, err = time.Parse(time.RFC1123, s)
+	}
+	return t, err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, used by XML, for TimeRFC1123.
+func (t TimeRFC1123) MarshalText() ([]byte, error) {
+	return []byte(FormatRFC1123(time.Time(t))), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, used by XML, for TimeRFC1123.
+func (t *TimeRFC1123) UnmarshalText(data []byte) error {
+	p, err := ParseRFC1123(string(data))
+	if err != nil {
+		return err
+	}
+	*t = TimeRFC1123(p)
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for TimeRFC1123.
+func (t TimeRFC1123) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(FormatRFC1123(time.Time(t)))), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TimeRFC1123. A null leaves t unchanged.
+func (t *TimeRFC1123) UnmarshalJSON(data []byte) error {
+	s, err := unquoteJSONTime(data)
+	if err != nil || s == nil {
+		return err
+	}
+	return t.UnmarshalText([]byte(*s))
+}
+
+// TimeRFC3339 is a time.Time encoded in RFC 3339 format with 7 fractional digits, such as
+// "2006-01-02T15:04:05.0000000Z". Times without a time zone are decoded as UTC.
+type TimeRFC3339 time.Time
+
+// FormatRFC3339 returns t in RFC 3339 format with 7 fractional digits.
+func FormatRFC3339(t time.Time) string {
+	return t.Format(rfc3339Format)
+}
+
+// ParseRFC3339 parses an RFC 3339 time with any number of fractional digits. A time without a time zone is in UTC.
+func ParseRFC3339(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		if utc, utcErr := time.Parse(rfc3339NoTimezone, s); utcErr == nil {
+			return utc, nil
+		}
+	}
+	return t, err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, used by XML, for TimeRFC3339.
+func (t TimeRFC3339) MarshalText() ([]byte, error) {
+	return []byte(FormatRFC3339(time.Time(t))), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, used by XML, for TimeRFC3339.
+func (t *TimeRFC3339) UnmarshalText(data []byte) error {
+	p, err := ParseRFC3339(string(data))
+	if err != nil {
+		return err
+	}
+	*t = TimeRFC3339(p)
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for TimeRFC3339.
+func (t TimeRFC3339) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(FormatRFC3339(time.Time(t)))), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TimeRFC3339. A null leaves t unchanged.
+func (t *TimeRFC3339) UnmarshalJSON(data []byte) error {
+	s, err := unquoteJSONTime(data)
+	if err != nil || s == nil {
+		return err
+	}
+	return t.UnmarshalText([]byte(*s))
+}
+
+// TimeUnix is a time.Time encoded as the number of seconds since January 1, 1970 UTC, a JSON number.
+type TimeUnix time.Time
+
+// MarshalText implements the encoding.TextMarshaler interface, used by XML, for TimeUnix.
+func (t TimeUnix) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatInt(time.Time(t).Unix(), 10)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, used by XML, for TimeUnix.
+func (t *TimeUnix) UnmarshalText(data []byte) error {
+	sec, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	*t = TimeUnix(time.Unix(sec, 0).UTC())
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for TimeUnix.
+func (t TimeUnix) MarshalJSON() ([]byte, error) {
+	return t.MarshalText()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TimeUnix. The number may be quoted, as some
+// services send it as a string. A null leaves t unchanged.
+func (t *TimeUnix) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		return nil
+	}
+	return t.UnmarshalText([]byte(strings.Trim(string(data), `"`)))
+}
+
+// unquoteJSONTime returns the string in data, a JSON string, or nil when data is null.
+func unquoteJSONTime(data []byte) (*string, error) {
+	if bytes.Equal(data, jsonNull) {
+		return nil, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
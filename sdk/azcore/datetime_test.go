@@ -0,0 +1,75 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+type testTimes struct {
+	XMLName  xml.Name     `xml:"Times" json:"-"`
+	RFC1123  TimeRFC1123  `xml:"RFC1123" json:"rfc1123"`
+	RFC3339  TimeRFC3339  `xml:"RFC3339" json:"rfc3339"`
+	Unix     TimeUnix     `xml:"Unix" json:"unix"`
+	Optional *TimeRFC3339 `xml:"Optional,omitempty" json:"optional,omitempty"`
+}
+
+var testTime = time.Date(2021, time.March, 4, 5, 6, 7, 123456700, time.UTC)
+
+func TestTimesJSON(t *testing.T) {
+	v := testTimes{RFC1123: TimeRFC1123(testTime), RFC3339: TimeRFC3339(testTime), Unix: TimeUnix(testTime)}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"rfc1123":"Thu, 04 Mar 2021 05:06:07 GMT","rfc3339":"2021-03-04T05:06:07.1234567Z","unix":1614834367}`
+	if string(b) != expected {
+		t.Fatalf("unexpected JSON: %s", b)
+	}
+	var u testTimes
+	if err = json.Unmarshal([]byte(`{"rfc1123":"Thu, 04 Mar 2021 05:06:07 GMT","rfc3339":"2021-03-04T05:06:07.1234567","unix":"1614834367","optional":null}`), &u); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !time.Time(u.RFC3339).Equal(testTime) || !time.Time(u.RFC1123).Equal(testTime.Truncate(time.Second)) || !time.Time(u.Unix).Equal(testTime.Truncate(time.Second)) || u.Optional != nil {
+		t.Fatalf("unexpected times: %+v", u)
+	}
+	if err = json.Unmarshal([]byte(`{"rfc3339":"yesterday"}`), &u); err == nil {
+		t.Fatal("expected an error for an invalid time")
+	}
+}
+
+func TestTimesXML(t *testing.T) {
+	v := testTimes{RFC1123: TimeRFC1123(testTime), RFC3339: TimeRFC3339(testTime), Unix: TimeUnix(testTime)}
+	b, err := xml.Marshal(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "<Times><RFC1123>Thu, 04 Mar 2021 05:06:07 GMT</RFC1123><RFC3339>2021-03-04T05:06:07.1234567Z</RFC3339><Unix>1614834367</Unix></Times>"
+	if string(b) != expected {
+		t.Fatalf("unexpected XML: %s", b)
+	}
+	var u testTimes
+	if err = xml.Unmarshal(b, &u); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !time.Time(u.RFC3339).Equal(testTime) || !time.Time(u.RFC1123).Equal(testTime.Truncate(time.Second)) {
+		t.Fatalf("unexpected times: %+v", u)
+	}
+}
+
+func TestParseTimes(t *testing.T) {
+	for _, s := range []string{"2021-03-04T05:06:07.1234567Z", "2021-03-04T06:06:07.1234567+01:00", "2021-03-04T05:06:07.1234567"} {
+		if p, err := ParseRFC3339(s); err != nil || !p.Equal(testTime) {
+			t.Fatalf("unexpected result for %s: %v, %v", s, p, err)
+		}
+	}
+	if p, err := ParseRFC1123("Thu, 04 Mar 2021 05:06:07 UTC"); err != nil || !p.Equal(testTime.Truncate(time.Second)) {
+		t.Fatalf("unexpected result: %v, %v", p, err)
+	}
+}
@@ -6,8 +6,13 @@
 package azcore
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	sdkruntime "github.com/Azure/azure-sdk-for-go/sdk/internal/runtime"
 )
@@ -15,6 +20,10 @@ import (
 var (
 	// ErrNoMorePolicies is returned from Request.Next() if there are no more policies in the pipeline.
 	ErrNoMorePolicies = errors.New("no more policies")
+
+	// ErrNonSeekableBody is returned when a request's body must be sent again but can't be rewound, because it
+	// was set with SetStreamBody or assigned without SetBody.
+	ErrNonSeekableBody = errors.New("the request body isn't seekable so it can't be sent again")
 )
 
 var (
@@ -33,3 +42,115 @@ type HTTPResponse interface {
 
 // ensure our internal ResponseError type implements HTTPResponse
 var _ HTTPResponse = (*sdkruntime.ResponseError)(nil)
+
+// ResponseError is the error returned for a response the service reported as failed. Use errors.As() to
+// inspect it and switch on ErrorCode to handle particular failures:
+//
+//	var respErr *azcore.ResponseError
+//	if errors.As(err, &respErr) && respErr.ErrorCode == "ResourceNotFound" {
+//		...
+//	}
+type ResponseError struct {
+	// ErrorCode is the service's error code, from the x-ms-error-code header or the error.code field of
+	// the response's JSON body. It's empty when the service didn't return one.
+	ErrorCode string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// ClientRequestID is the request's x-ms-client-request-id header, which identifies the request
+	// in the service's logs, for example when opening a support case.
+	ClientRequestID string
+
+	resp *http.Response
+	body []byte
+}
+
+// NewResponseError creates a *ResponseError from a failed response, reading the response's body if the body
+// download policy didn't. The body remains readable from the error's RawResponse.
+func NewResponseError(resp *Response) error {
+	body := resp.payload()
+	if body == nil && resp.Body != nil {
+		// the body wasn't downloaded, read it so it can be included in the error
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			b = nil
+		}
+		body = b
+		resp.Body = &nopClosingBytesReader{s: body}
+	}
+	var clientRequestID string
+	if resp.Request != nil {
+		clientRequestID = resp.Request.Header.Get(xMsClientRequestID)
+	}
+	return &ResponseError{
+		ErrorCode:       responseErrorCode(resp.Header, body),
+		StatusCode:      resp.StatusCode,
+		ClientRequestID: clientRequestID,
+		resp:            resp.Response,
+		body:            body,
+	}
+}
+
+// responseErrorCode returns the error code in the x-ms-error-code header, or else in the body's
+// error.code or code field.
+func responseErrorCode(header http.Header, body []byte) string {
+	if code := header.Get(HeaderXmsErrorCode); code != "" {
+		return code
+	}
+	if len(body) == 0 {
+		return ""
+	}
+	var payload struct {
+		Code  string `json:"code"`
+		Error *struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), &payload); err != nil {
+		return ""
+	}
+	if payload.Error != nil && payload.Error.Code != "" {
+		return payload.Error.Code
+	}
+	return payload.Code
+}
+
+// Error implements the error interface for type ResponseError. The message contains the request's method
+// and URL, without its query, the response's status and error code, the client request ID and the response's body.
+func (e *ResponseError) Error() string {
+	const separator = "--------------------------------------------------------------------------------"
+	msg := &strings.Builder{}
+	if req := e.resp.Request; req != nil && req.URL != nil {
+		u := *req.URL
+		u.RawQuery, u.User = "", nil
+		fmt.Fprintf(msg, "%s %s\n", req.Method, u.String())
+	}
+	fmt.Fprintln(msg, separator)
+	fmt.Fprintf(msg, "RESPONSE %d: %s\n", e.StatusCode, e.resp.Status)
+	code := e.ErrorCode
+	if code == "" {
+		code = "UNAVAILABLE"
+	}
+	fmt.Fprintf(msg, "ERROR CODE: %s\n", code)
+	if e.ClientRequestID != "" {
+		fmt.Fprintf(msg, "CLIENT REQUEST ID: %s\n", e.ClientRequestID)
+	}
+	fmt.Fprintln(msg, separator)
+	if len(e.body) == 0 {
+		fmt.Fprintln(msg, "Response contained no body")
+	} else {
+		fmt.Fprintln(msg, string(e.body))
+	}
+	fmt.Fprint(msg, separator)
+	return msg.String()
+}
+
+// RawResponse returns the HTTP response associated with this error.
+func (e *ResponseError) RawResponse() *http.Response {
+	return e.resp
+}
+
+// ensure ResponseError implements HTTPResponse
+var _ HTTPResponse = (*ResponseError)(nil)
@@ -0,0 +1,87 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestResponseErrorCode(t *testing.T) {
+	cases := []struct {
+		header http.Header
+		body   string
+		code   string
+	}{
+		{header: http.Header{http.CanonicalHeaderKey(HeaderXmsErrorCode): {"HeaderCode"}}, body: `{"error":{"code":"BodyCode"}}`, code: "HeaderCode"},
+		{body: `{"error":{"code":"BodyCode","message":"not found"}}`, code: "BodyCode"},
+		{body: "\xef\xbb\xbf" + `{"code":"TopLevelCode"}`, code: "TopLevelCode"},
+		{body: `<Error><Code>XMLCode</Code></Error>`},
+		{},
+	}
+	for _, c := range cases {
+		if code := responseErrorCode(c.header, []byte(c.body)); code != c.code {
+			t.Fatalf("expected %q for %q, got %q", c.code, c.body, code)
+		}
+	}
+}
+
+func TestNewResponseError(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusNotFound), mock.WithBody([]byte(`{"error":{"code":"ResourceNotFound"}}`)))
+	pl := NewPipeline(srv)
+	u := srv.URL()
+	srvURL := u.String()
+	u.RawQuery = "sig=secret"
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, u))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = fmt.Errorf("wrapped: %w", NewResponseError(resp))
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		t.Fatal("expected a *ResponseError")
+	}
+	if respErr.ErrorCode != "ResourceNotFound" || respErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected error code %q or status code %d", respErr.ErrorCode, respErr.StatusCode)
+	}
+	if respErr.RawResponse() != resp.Response {
+		t.Fatal("expected the raw response")
+	}
+	msg := respErr.Error()
+	if !strings.Contains(msg, "GET "+srvURL) || strings.Contains(msg, "secret") || !strings.Contains(msg, "ERROR CODE: ResourceNotFound") {
+		t.Fatalf("unexpected message: %s", msg)
+	}
+}
+
+func TestNewResponseErrorSkippedDownload(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusConflict), mock.WithBody([]byte(`{"code":"Conflict"}`)))
+	pl := NewPipeline(srv)
+	req := NewRequest(http.MethodPut, srv.URL())
+	req.SkipBodyDownload()
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var respErr *ResponseError
+	if !errors.As(NewResponseError(resp), &respErr) || respErr.ErrorCode != "Conflict" {
+		t.Fatalf("expected the error code from the body, got %v", respErr)
+	}
+	body, err := ioutil.ReadAll(respErr.RawResponse().Body)
+	if err != nil || string(body) != `{"code":"Conflict"}` {
+		t.Fatalf("expected the body to remain readable, got %q, %v", body, err)
+	}
+}
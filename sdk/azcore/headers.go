@@ -7,24 +7,32 @@ package azcore
 
 // Constants ensuring that header names are correctly spelled and consistently cased.
 const (
-	HeaderAuthorization      = "Authorization"
-	HeaderCacheControl       = "Cache-Control"
-	HeaderContentEncoding    = "Content-Encoding"
-	HeaderContentDisposition = "Content-Disposition"
-	HeaderContentLanguage    = "Content-Language"
-	HeaderContentLength      = "Content-Length"
-	HeaderContentMD5         = "Content-MD5"
-	HeaderContentType        = "Content-Type"
-	HeaderDate               = "Date"
-	HeaderIfMatch            = "If-Match"
-	HeaderIfModifiedSince    = "If-Modified-Since"
-	HeaderIfNoneMatch        = "If-None-Match"
-	HeaderIfUnmodifiedSince  = "If-Unmodified-Since"
-	HeaderMetadata           = "Metadata"
-	HeaderRange              = "Range"
-	HeaderRetryAfter         = "Retry-After"
-	HeaderURLEncoded         = "application/x-www-form-urlencoded"
-	HeaderUserAgent          = "User-Agent"
-	HeaderXmsDate            = "x-ms-date"
-	HeaderXmsVersion         = "x-ms-version"
+	HeaderAuthorization             = "Authorization"
+	HeaderAzureAsync                = "Azure-AsyncOperation"
+	HeaderCacheControl              = "Cache-Control"
+	HeaderContentEncoding           = "Content-Encoding"
+	HeaderContentDisposition        = "Content-Disposition"
+	HeaderContentLanguage           = "Content-Language"
+	HeaderContentLength             = "Content-Length"
+	HeaderContentMD5                = "Content-MD5"
+	HeaderContentRange              = "Content-Range"
+	HeaderContentType               = "Content-Type"
+	HeaderDate                      = "Date"
+	HeaderIfMatch                   = "If-Match"
+	HeaderIfModifiedSince           = "If-Modified-Since"
+	HeaderIfNoneMatch               = "If-None-Match"
+	HeaderIfUnmodifiedSince         = "If-Unmodified-Since"
+	HeaderLocation                  = "Location"
+	HeaderMetadata                  = "Metadata"
+	HeaderRange                     = "Range"
+	HeaderRetryAfter                = "Retry-After"
+	HeaderRetryAfterMS              = "retry-after-ms"
+	HeaderURLEncoded                = "application/x-www-form-urlencoded"
+	HeaderUserAgent                 = "User-Agent"
+	HeaderWWWAuthenticate           = "WWW-Authenticate"
+	HeaderXmsAuthorizationAuxiliary = "x-ms-authorization-auxiliary"
+	HeaderXmsDate                   = "x-ms-date"
+	HeaderXmsErrorCode              = "x-ms-error-code"
+	HeaderXmsRetryAfterMS           = "x-ms-retry-after-ms"
+	HeaderXmsVersion                = "x-ms-version"
 )
@@ -0,0 +1,113 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HTTPRange is a range of bytes of a resource, sent in a request's Range header.
+type HTTPRange struct {
+	// Offset is the position of the range's first byte.
+	Offset int64
+
+	// Count is the number of bytes in the range. Zero means the range continues to the end of the resource.
+	Count int64
+}
+
+// Format returns the value of a Range header for the range, such as "bytes=0-499" or "bytes=500-" when Count
+// is zero. It returns an empty string for the whole resource, when Offset and Count are both zero.
+func (r HTTPRange) Format() string {
+	if r.Offset == 0 && r.Count == 0 {
+		return ""
+	}
+	if r.Count == 0 {
+		return fmt.Sprintf("bytes=%d-", r.Offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", r.Offset, r.Offset+r.Count-1)
+}
+
+// ParseHTTPRange parses the value of a Range header with a single range, such as "bytes=0-499" or "bytes=500-".
+// Suffix ranges, such as "bytes=-500", and multiple ranges aren't supported.
+func ParseHTTPRange(s string) (HTTPRange, error) {
+	spec := strings.TrimPrefix(strings.TrimSpace(s), "bytes=")
+	if len(spec) == len(s) || strings.Contains(spec, ",") {
+		return HTTPRange{}, fmt.Errorf("unsupported range %q", s)
+	}
+	first, last, err := parseByteRange(spec)
+	if err != nil {
+		return HTTPRange{}, fmt.Errorf("invalid range %q: %w", s, err)
+	}
+	if last < 0 {
+		return HTTPRange{Offset: first}, nil
+	}
+	return HTTPRange{Offset: first, Count: last - first + 1}, nil
+}
+
+// ContentRange is the range of bytes of a resource in a response, sent in its Content-Range header.
+type ContentRange struct {
+	// Offset is the position of the range's first byte.
+	Offset int64
+
+	// Count is the number of bytes in the range. It's zero when the requested range couldn't be satisfied.
+	Count int64
+
+	// Size is the size of the whole resource, or -1 when the service didn't send it.
+	Size int64
+}
+
+// ParseContentRange parses the value of a Content-Range header, such as "bytes 0-499/1234", "bytes 0-499/*" when
+// the resource's size is unknown, or "bytes */1234" when the requested range couldn't be satisfied.
+func ParseContentRange(s string) (ContentRange, error) {
+	spec := strings.TrimSpace(s)
+	if !strings.HasPrefix(spec, "bytes ") {
+		return ContentRange{}, fmt.Errorf("unsupported content range %q", s)
+	}
+	spec = strings.TrimSpace(spec[len("bytes "):])
+	slash := strings.IndexByte(spec, '/')
+	if slash < 0 {
+		return ContentRange{}, fmt.Errorf("invalid content range %q", s)
+	}
+	cr := ContentRange{Size: -1}
+	if size := spec[slash+1:]; size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || n < 0 {
+			return ContentRange{}, fmt.Errorf("invalid content range %q: bad size", s)
+		}
+		cr.Size = n
+	}
+	if byteRange := spec[:slash]; byteRange != "*" {
+		first, last, err := parseByteRange(byteRange)
+		if err != nil || last < 0 {
+			return ContentRange{}, fmt.Errorf("invalid content range %q: bad byte range", s)
+		}
+		cr.Offset, cr.Count = first, last-first+1
+	}
+	return cr, nil
+}
+
+// parseByteRange parses "first-last" or "first-", returning -1 for a missing last position.
+func parseByteRange(s string) (first, last int64, err error) {
+	dash := strings.IndexByte(s, '-')
+	if dash <= 0 {
+		return 0, 0, errors.New("missing first byte position")
+	}
+	first, err = strconv.ParseInt(s[:dash], 10, 64)
+	if err != nil || first < 0 {
+		return 0, 0, errors.New("bad first byte position")
+	}
+	if s[dash+1:] == "" {
+		return first, -1, nil
+	}
+	last, err = strconv.ParseInt(s[dash+1:], 10, 64)
+	if err != nil || last < first {
+		return 0, 0, errors.New("bad last byte position")
+	}
+	return first, last, nil
+}
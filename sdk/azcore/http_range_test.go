@@ -0,0 +1,77 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestHTTPRangeFormat(t *testing.T) {
+	for r, expected := range map[HTTPRange]string{
+		{}:                      "",
+		{Offset: 500}:           "bytes=500-",
+		{Count: 500}:            "bytes=0-499",
+		{Offset: 10, Count: 1}:  "bytes=10-10",
+		{Offset: 10, Count: 20}: "bytes=10-29",
+	} {
+		if v := r.Format(); v != expected {
+			t.Fatalf("expected %q for %+v, got %q", expected, r, v)
+		}
+	}
+}
+
+func TestParseHTTPRange(t *testing.T) {
+	for s, expected := range map[string]HTTPRange{
+		"bytes=0-499": {Count: 500},
+		"bytes=500-":  {Offset: 500},
+		"bytes=10-10": {Offset: 10, Count: 1},
+	} {
+		r, err := ParseHTTPRange(s)
+		if err != nil || r != expected {
+			t.Fatalf("expected %+v for %q, got %+v, %v", expected, s, r, err)
+		}
+		if r.Format() != s {
+			t.Fatalf("expected %q to round trip, got %q", s, r.Format())
+		}
+	}
+	for _, s := range []string{"", "0-499", "bytes=-500", "bytes=0-1,5-9", "bytes=9-5", "bytes=a-b", "items=0-1"} {
+		if _, err := ParseHTTPRange(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	for s, expected := range map[string]ContentRange{
+		"bytes 0-499/1234": {Count: 500, Size: 1234},
+		"bytes 500-999/*":  {Offset: 500, Count: 500, Size: -1},
+		"bytes */1234":     {Size: 1234},
+	} {
+		if cr, err := ParseContentRange(s); err != nil || cr != expected {
+			t.Fatalf("expected %+v for %q, got %+v, %v", expected, s, cr, err)
+		}
+	}
+	for _, s := range []string{"", "bytes 0-499", "bytes 0-/1234", "bytes 5-1/10", "bytes 0-1/x", "items 0-1/2"} {
+		if _, err := ParseContentRange(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestRequestSetRange(t *testing.T) {
+	u, _ := url.Parse("https://contoso.com")
+	req := NewRequest(http.MethodGet, *u)
+	req.SetRange(HTTPRange{Offset: 100})
+	if h := req.Header.Get(HeaderRange); h != "bytes=100-" {
+		t.Fatalf("unexpected Range header %q", h)
+	}
+	req.SetRange(HTTPRange{})
+	if _, ok := req.Header[HeaderRange]; ok {
+		t.Fatal("expected the Range header to be removed")
+	}
+}
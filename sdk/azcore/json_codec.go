@@ -0,0 +1,55 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// JSONCodec encodes and decodes JSON payloads, with the semantics of encoding/json.
+// Implementations must be safe for concurrent use.
+type JSONCodec interface {
+	// Marshal returns the JSON encoding of v, like json.Marshal.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes the JSON data into the value pointed to by v, like json.Unmarshal.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// stdJSONCodec is the JSONCodec of encoding/json
+type stdJSONCodec struct{}
+
+func (stdJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// jsonCodecHolder wraps the codec so that atomic.Value always stores the same type
+type jsonCodecHolder struct {
+	codec JSONCodec
+}
+
+// jsonCodec holds the codec set with SetJSONCodec.
+var jsonCodec atomic.Value
+
+// SetJSONCodec replaces encoding/json as the codec of Request.MarshalAsJSON and Response.UnmarshalAsJSON in every
+// pipeline, for example with a faster implementation for workloads that decode large responses. Pass nil to
+// restore encoding/json. Set it before sending requests; requests being marshaled may use either codec.
+func SetJSONCodec(c JSONCodec) {
+	jsonCodec.Store(jsonCodecHolder{codec: c})
+}
+
+// getJSONCodec returns the codec set with SetJSONCodec, or encoding/json's when there's none.
+func getJSONCodec() JSONCodec {
+	if h, ok := jsonCodec.Load().(jsonCodecHolder); ok && h.codec != nil {
+		return h.codec
+	}
+	return stdJSONCodec{}
+}
@@ -0,0 +1,60 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// countingJSONCodec counts its calls and delegates to encoding/json
+type countingJSONCodec struct {
+	marshals, unmarshals int32
+}
+
+func (c *countingJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt32(&c.marshals, 1)
+	return json.Marshal(v)
+}
+
+func (c *countingJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt32(&c.unmarshals, 1)
+	return json.Unmarshal(data, v)
+}
+
+func TestSetJSONCodec(t *testing.T) {
+	codec := &countingJSONCodec{}
+	SetJSONCodec(codec)
+	defer SetJSONCodec(nil)
+	u, _ := url.Parse("https://contoso.com")
+	if err := NewRequest(http.MethodPut, *u).MarshalAsJSON(testJSON{SomeInt: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithBody([]byte(`{"SomeInt":1,"SomeString":"s"}`)))
+	resp, err := NewPipeline(srv).Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var tj testJSON
+	if err = resp.UnmarshalAsJSON(&tj); err != nil || tj.SomeString != "s" {
+		t.Fatalf("unexpected result %+v, %v", tj, err)
+	}
+	if codec.marshals != 1 || codec.unmarshals != 1 {
+		t.Fatalf("expected the codec to be used, got %d marshals and %d unmarshals", codec.marshals, codec.unmarshals)
+	}
+	SetJSONCodec(nil)
+	if _, ok := getJSONCodec().(stdJSONCodec); !ok {
+		t.Fatal("expected encoding/json to be restored")
+	}
+}
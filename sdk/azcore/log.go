@@ -26,8 +26,45 @@ const (
 
 	// LogSlowResponse entries contain information for responses that take longer than the specified threshold.
 	LogSlowResponse LogClassification = "SlowResponse"
+
+	// LogAuthentication entries contain information about the tokens acquired by the authentication policies
+	// and the challenges services responded with. Tokens themselves are never logged.
+	LogAuthentication LogClassification = "Authentication"
 )
 
+// LogLevel is the severity of log entries. Lower levels are more severe.
+type LogLevel int
+
+const (
+	// LogLevelError entries report failures.
+	LogLevelError LogLevel = iota
+
+	// LogLevelWarning entries report unexpected conditions that didn't cause a failure, such as slow responses.
+	LogLevelWarning
+
+	// LogLevelInformational entries report the SDK's normal operation, such as requests and responses.
+	LogLevelInformational
+
+	// LogLevelVerbose entries contain details that are mostly useful when diagnosing a problem.
+	LogLevelVerbose
+)
+
+// logLevels are the levels of the classifications that aren't informational
+var logLevels = map[LogClassification]LogLevel{
+	LogError:        LogLevelError,
+	LogSlowResponse: LogLevelWarning,
+	LogRetryPolicy:  LogLevelVerbose,
+}
+
+// Level returns the level of entries with the classification, so that listeners can map them to the levels of
+// their logger. Classifications defined outside azcore are informational.
+func (cls LogClassification) Level() LogLevel {
+	if level, ok := logLevels[cls]; ok {
+		return level
+	}
+	return LogLevelInformational
+}
+
 // Listener is the function signature invoked when writing log entries.
 // A Listener is required to perform its own synchronization if it's
 // expected to be called from multiple Go routines.
@@ -37,6 +74,14 @@ type Listener func(LogClassification, string)
 type Logger struct {
 	cls []LogClassification
 	lst Listener
+	// lvl is the least severe level that's written, offset so the zero value writes every level
+	lvl LogLevel
+}
+
+// SetLevel is used to control the least severe level of the entries written to the log, for example
+// LogLevelWarning writes only warnings and errors. By default all levels are written.
+func (l *Logger) SetLevel(level LogLevel) {
+	l.lvl = LogLevelVerbose - level
 }
 
 // SetClassifications is used to control which classifications are written to
@@ -51,7 +96,7 @@ func (l *Logger) SetListener(lst Listener) {
 }
 
 // Should returns true if the specified log classification should be written to the log.
-// By default all log classifications will be logged.  Call SetClassification() to limit
+// By default all log classifications will be logged.  Call SetClassification() or SetLevel() to limit
 // the log classifications for logging.
 // If no listener has been set this will return false.
 // Calling this method is useful when the message to log is computationally expensive
@@ -60,6 +105,9 @@ func (l *Logger) Should(cls LogClassification) bool {
 	if l.lst == nil {
 		return false
 	}
+	if cls.Level() > LogLevelVerbose-l.lvl {
+		return false
+	}
 	if l.cls == nil || len(l.cls) == 0 {
 		return true
 	}
@@ -83,6 +131,7 @@ func (l *Logger) Write(cls LogClassification, message string) {
 // for testing purposes
 func (l *Logger) resetClassifications() {
 	l.cls = nil
+	l.lvl = 0
 }
 
 var log Logger
@@ -48,3 +48,23 @@ func TestLoggingClassification(t *testing.T) {
 		t.Fatalf("unexpected log entry: %s", log[LogError])
 	}
 }
+
+func TestLoggingLevel(t *testing.T) {
+	log := map[LogClassification]string{}
+	Log().SetListener(func(cls LogClassification, msg string) {
+		log[cls] = msg
+	})
+	Log().SetLevel(LogLevelWarning)
+	defer Log().resetClassifications()
+	Log().Write(LogRequest, "this shouldn't be in the log")
+	Log().Write(LogRetryPolicy, "this shouldn't be in the log")
+	Log().Write(LogClassification("Custom"), "this shouldn't be in the log")
+	Log().Write(LogSlowResponse, "slow")
+	Log().Write(LogError, "error")
+	if len(log) != 2 || log[LogSlowResponse] != "slow" || log[LogError] != "error" {
+		t.Fatalf("unexpected log entries: %v", log)
+	}
+	if LogAuthentication.Level() != LogLevelInformational || LogRetryPolicy.Level() != LogLevelVerbose {
+		t.Fatal("unexpected classification levels")
+	}
+}
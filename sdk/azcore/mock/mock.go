@@ -0,0 +1,246 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package mock provides a fake transport for testing clients and credentials built on an azcore.Pipeline.
+// The transport responds to requests with scripted responses and errors, without a network, and records
+// the requests it receives so tests can assert on them.
+package mock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrNoResponse is returned by Transport.Do when the response queue is empty and no static response was set.
+var ErrNoResponse = errors.New("the mock transport has no response for the request")
+
+// Transport is a fake azcore.Transport. Responses are taken from the front of the response queue;
+// a static response set with SetResponse or SetError is returned once the queue is empty.
+// It's safe for concurrent use.
+type Transport struct {
+	// mu protects the following fields
+	mu sync.Mutex
+
+	// static is the response returned when the queue is empty, if not nil
+	static *response
+
+	// resp is the queue of responses, each response is taken from the front
+	resp []response
+
+	// requests are the requests received so far
+	requests []*http.Request
+}
+
+// NewTransport creates a new Transport with an empty response queue.
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// Do implements the azcore.Transport interface on Transport. It records a copy of req, whose body can
+// be read by the test, then returns the next response or error, or ErrNoResponse when there's none.
+// The request fails with ctx's error when ctx is done before a slow response is returned.
+func (t *Transport) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.requests = append(t.requests, recorded)
+	var r response
+	switch {
+	case len(t.resp) > 0:
+		r = t.resp[0]
+		t.resp = t.resp[1:]
+	case t.static != nil:
+		r = *t.static
+	default:
+		t.mu.Unlock()
+		return nil, ErrNoResponse
+	}
+	t.mu.Unlock()
+	if r.delay > 0 {
+		select {
+		case <-time.After(r.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.httpResponse(req), nil
+}
+
+// Requests returns the requests received so far, in the order they were received.
+func (t *Transport) Requests() []*http.Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*http.Request(nil), t.requests...)
+}
+
+// AppendError appends the error to the end of the response queue, such as a network error returned
+// by the request instead of a response.
+func (t *Transport) AppendError(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resp = append(t.resp, response{err: err})
+}
+
+// RepeatError appends the error n number of times to the end of the response queue.
+func (t *Transport) RepeatError(n int, err error) {
+	for i := 0; i < n; i++ {
+		t.AppendError(err)
+	}
+}
+
+// SetError indicates the same error should be returned once the response queue is empty.
+func (t *Transport) SetError(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.static = &response{err: err}
+}
+
+// AppendResponse appends the response to the end of the response queue.
+// If no options are provided the default response is an http.StatusOK.
+func (t *Transport) AppendResponse(opts ...ResponseOption) {
+	r := newResponse(opts)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resp = append(t.resp, r)
+}
+
+// RepeatResponse appends the response n number of times to the end of the response queue.
+// If no options are provided the default response is an http.StatusOK.
+func (t *Transport) RepeatResponse(n int, opts ...ResponseOption) {
+	for i := 0; i < n; i++ {
+		t.AppendResponse(opts...)
+	}
+}
+
+// SetResponse indicates the same response should be returned once the response queue is empty.
+// If no options are provided the default response is an http.StatusOK.
+func (t *Transport) SetResponse(opts ...ResponseOption) {
+	r := newResponse(opts)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.static = &r
+}
+
+// ResponseOption is an abstraction for configuring a mock HTTP response.
+type ResponseOption interface {
+	apply(r *response)
+}
+
+type fnRespOpt func(*response)
+
+func (fn fnRespOpt) apply(r *response) {
+	fn(r)
+}
+
+type response struct {
+	code    int
+	body    []byte
+	headers http.Header
+	err     error
+	rerr    error
+	delay   time.Duration
+}
+
+func newResponse(opts []ResponseOption) response {
+	r := response{code: http.StatusOK, headers: http.Header{}}
+	for _, o := range opts {
+		o.apply(&r)
+	}
+	return r
+}
+
+// httpResponse returns a new *http.Response for req from the scripted response.
+func (r response) httpResponse(req *http.Request) *http.Response {
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.code, http.StatusText(r.code)),
+		StatusCode:    r.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.headers.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+	if r.rerr != nil {
+		resp.Body = &readFailer{err: r.rerr}
+	}
+	return resp
+}
+
+// WithStatusCode sets the HTTP response's status code to the specified value.
+func WithStatusCode(c int) ResponseOption {
+	return fnRespOpt(func(r *response) {
+		r.code = c
+	})
+}
+
+// WithBody sets the HTTP response's body to the specified value.
+func WithBody(b []byte) ResponseOption {
+	return fnRespOpt(func(r *response) {
+		r.body = b
+	})
+}
+
+// WithHeader adds the specified header and value to the HTTP response.
+func WithHeader(k, v string) ResponseOption {
+	return fnRespOpt(func(r *response) {
+		r.headers.Add(k, v)
+	})
+}
+
+// WithSlowResponse waits for the specified duration, or until the request's context is done,
+// before returning the HTTP response.
+func WithSlowResponse(d time.Duration) ResponseOption {
+	return fnRespOpt(func(r *response) {
+		r.delay = d
+	})
+}
+
+// WithBodyReadError returns a response whose body fails with err when it's read, such as a connection
+// reset while the body is downloaded.
+func WithBodyReadError(err error) ResponseOption {
+	return fnRespOpt(func(r *response) {
+		r.rerr = err
+	})
+}
+
+type readFailer struct {
+	err error
+}
+
+func (r *readFailer) Close() error {
+	return nil
+}
+
+func (r *readFailer) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// recordRequest returns a copy of req whose body holds the bytes of req's body, which is consumed.
+func recordRequest(req *http.Request) (*http.Request, error) {
+	recorded := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return recorded, nil
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	recorded.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return recorded, nil
+}
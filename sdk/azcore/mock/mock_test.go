@@ -0,0 +1,105 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package mock_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/mock"
+)
+
+func newRequest(t *testing.T, method string) *azcore.Request {
+	u, err := url.Parse("https://contoso.com/path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return azcore.NewRequest(method, *u)
+}
+
+func TestTransportScriptedResponses(t *testing.T) {
+	errReset := errors.New("connection reset by peer")
+	transport := mock.NewTransport()
+	transport.AppendError(errReset)
+	transport.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	transport.AppendResponse(mock.WithStatusCode(http.StatusCreated), mock.WithHeader("x-ms-request-id", "id"), mock.WithBody([]byte("content")))
+	retry := azcore.DefaultRetryOptions()
+	retry.RetryDelay = time.Millisecond
+	pl := azcore.NewPipeline(transport, azcore.NewRetryPolicy(&retry))
+	req := newRequest(t, http.MethodPut)
+	if err := req.SetBody(azcore.NopCloser(strings.NewReader("body"))); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("x-ms-request-id") != "id" {
+		t.Fatalf("unexpected response: %v", resp.Response)
+	}
+	if b, _ := ioutil.ReadAll(resp.Body); string(b) != "content" {
+		t.Fatalf("unexpected body %q", b)
+	}
+	requests := transport.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	for _, r := range requests {
+		if b, _ := ioutil.ReadAll(r.Body); r.Method != http.MethodPut || string(b) != "body" {
+			t.Fatalf("unexpected request %s with body %q", r.Method, b)
+		}
+	}
+	if _, err = pl.Do(context.Background(), newRequest(t, http.MethodGet)); !errors.Is(err, mock.ErrNoResponse) {
+		t.Fatalf("expected ErrNoResponse, got %v", err)
+	}
+}
+
+func TestTransportStaticResponse(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.AppendResponse(mock.WithStatusCode(http.StatusAccepted))
+	transport.SetResponse(mock.WithStatusCode(http.StatusNoContent))
+	for _, expected := range []int{http.StatusAccepted, http.StatusNoContent, http.StatusNoContent} {
+		resp, err := transport.Do(context.Background(), newRequest(t, http.MethodGet).Request)
+		if err != nil || resp.StatusCode != expected {
+			t.Fatalf("expected status %d, got %v, %v", expected, resp, err)
+		}
+	}
+	errStatic := errors.New("static")
+	transport.SetError(errStatic)
+	if _, err := transport.Do(context.Background(), newRequest(t, http.MethodGet).Request); err != errStatic {
+		t.Fatalf("expected the static error, got %v", err)
+	}
+}
+
+func TestTransportBodyReadError(t *testing.T) {
+	errRead := errors.New("read failure")
+	transport := mock.NewTransport()
+	transport.SetResponse(mock.WithBodyReadError(errRead))
+	resp, err := transport.Do(context.Background(), newRequest(t, http.MethodGet).Request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = ioutil.ReadAll(resp.Body); err != errRead {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+}
+
+func TestTransportSlowResponse(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.SetResponse(mock.WithSlowResponse(time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := transport.Do(ctx, newRequest(t, http.MethodGet).Request); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context's error, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// nullables are the sentinel values returned by NullValue, by type
+var nullables sync.Map
+
+// NullValue returns a sentinel value of ptr's pointer type, such as NullValue((*string)(nil)).(*string). Assign it
+// to a pointer field of a model to send the field as an explicit JSON null, for example to remove a property in a
+// JSON merge-patch operation, where a nil pointer leaves the property unchanged. Request.MarshalAsJSON encodes it as
+// null wherever it appears in v, including in nested structs, maps and slices. It panics when ptr isn't a pointer.
+func NullValue(ptr interface{}) interface{} {
+	t := reflect.TypeOf(ptr)
+	if t == nil || t.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("NullValue requires a pointer type, got %T", ptr))
+	}
+	if v, ok := nullables.Load(t); ok {
+		return v
+	}
+	v, _ := nullables.LoadOrStore(t, reflect.New(t.Elem()).Interface())
+	return v
+}
+
+// IsNullValue returns true when v is a sentinel value returned by NullValue.
+func IsNullValue(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return false
+	}
+	n, ok := nullables.Load(t)
+	return ok && n == v
+}
+
+// marshalJSON returns the JSON encoding of v with the fields set to a NullValue encoded as null.
+func marshalJSON(v interface{}) ([]byte, error) {
+	b, err := getJSONCodec().Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var paths [][]string
+	findNullValues(reflect.ValueOf(v), nil, &paths)
+	if len(paths) == 0 {
+		return b, nil
+	}
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err = d.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		setJSONNull(doc, path)
+	}
+	return json.Marshal(doc)
+}
+
+// findNullValues appends the JSON paths of the NullValue sentinels in v to paths. Slice indexes are formatted
+// as decimal strings.
+func findNullValues(v reflect.Value, path []string, paths *[][]string) {
+	if !v.IsValid() {
+		return
+	}
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Ptr && isNullValue(v) {
+			*paths = append(*paths, append([]string(nil), path...))
+			return
+		}
+		if encodesItself(v) {
+			return
+		}
+		v = v.Elem()
+	}
+	if encodesItself(v) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := jsonFieldName(f)
+			if !ok {
+				continue
+			}
+			if f.Anonymous && name == "" {
+				// the fields of an embedded struct are promoted
+				findNullValues(v.Field(i), path, paths)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			findNullValues(v.Field(i), append(path, name), paths)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			findNullValues(iter.Value(), append(path, iter.Key().String()), paths)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			findNullValues(v.Index(i), append(path, fmt.Sprint(i)), paths)
+		}
+	}
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isNullValue returns true when v, a non-nil pointer, is a sentinel value returned by NullValue.
+// Unlike IsNullValue it works for values of unexported fields, such as the fields of embedded unexported structs.
+func isNullValue(v reflect.Value) bool {
+	n, ok := nullables.Load(v.Type())
+	return ok && reflect.ValueOf(n).Pointer() == v.Pointer()
+}
+
+// encodesItself returns true when v's type controls its own JSON encoding, such as time.Time.
+func encodesItself(v reflect.Value) bool {
+	t := v.Type()
+	if v.CanAddr() {
+		t = reflect.PtrTo(t)
+	}
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// jsonFieldName returns the name of f in its json tag, empty when there's none,
+// and false when f isn't encoded.
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" || (f.PkgPath != "" && !f.Anonymous) {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if f.Anonymous && name == "" {
+		t := f.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			// embedded non-struct types are encoded under their type name
+			return f.Name, f.PkgPath == ""
+		}
+	}
+	return name, true
+}
+
+// setJSONNull sets the value at path in doc, a decoded JSON document, to null.
+func setJSONNull(doc interface{}, path []string) {
+	for i, key := range path {
+		last := i == len(path)-1
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			if last {
+				node[key] = nil
+				return
+			}
+			doc = node[key]
+		case []interface{}:
+			var idx int
+			if _, err := fmt.Sscan(key, &idx); err != nil || idx >= len(node) {
+				return
+			}
+			if last {
+				node[idx] = nil
+				return
+			}
+			doc = node[idx]
+		default:
+			return
+		}
+	}
+}
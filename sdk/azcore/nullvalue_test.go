@@ -0,0 +1,75 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testPatchTags struct {
+	Tags map[string]*string `json:"tags,omitempty"`
+}
+
+type testPatch struct {
+	testPatchTags
+	Name     *string      `json:"name,omitempty"`
+	Count    *int32       `json:"count,omitempty"`
+	Expires  *time.Time   `json:"expires,omitempty"`
+	Children []*testPatch `json:"children,omitempty"`
+	Ignored  *string      `json:"-"`
+}
+
+func TestNullValue(t *testing.T) {
+	s := NullValue((*string)(nil)).(*string)
+	if s == nil || s != NullValue((*string)(nil)) || !IsNullValue(s) {
+		t.Fatal("expected the same non-nil sentinel for a type")
+	}
+	if IsNullValue(new(string)) || IsNullValue(nil) || IsNullValue("") {
+		t.Fatal("unexpected sentinel")
+	}
+	if IsNullValue(NullValue((*int32)(nil))) == IsNullValue((*int32)(nil)) {
+		t.Fatal("expected the sentinels of other types to differ")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a non-pointer type")
+		}
+	}()
+	NullValue("")
+}
+
+func TestRequestMarshalAsJSONNullValue(t *testing.T) {
+	name := "name"
+	nullString := NullValue((*string)(nil)).(*string)
+	v := testPatch{
+		testPatchTags: testPatchTags{Tags: map[string]*string{"keep": &name, "remove": nullString}},
+		Count:         NullValue((*int32)(nil)).(*int32),
+		Expires:       NullValue((*time.Time)(nil)).(*time.Time),
+		Children:      []*testPatch{{Name: nullString}, {Name: &name}},
+		Ignored:       nullString,
+	}
+	u, _ := url.Parse("https://contoso.com")
+	req := NewRequest(http.MethodPatch, *u)
+	if err := req.MarshalAsJSON(v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := ioutil.ReadAll(req.Body)
+	expected := `{"children":[{"name":null},{"name":"name"}],"count":null,"expires":null,"tags":{"keep":"name","remove":null}}`
+	if string(b) != expected {
+		t.Fatalf("unexpected JSON: %s", b)
+	}
+	req = NewRequest(http.MethodPatch, *u)
+	if err := req.MarshalAsJSON(testPatch{Name: &name}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, _ = ioutil.ReadAll(req.Body); string(b) != `{"name":"name"}` {
+		t.Fatalf("unexpected JSON: %s", b)
+	}
+}
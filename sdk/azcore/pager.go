@@ -0,0 +1,93 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"net/http"
+)
+
+// Pager iterates over the pages of a paginated operation's results:
+//
+//	for pager.NextPage(ctx) {
+//		// process the current page
+//	}
+//	if err := pager.Err(); err != nil {
+//		// handle the error
+//	}
+type Pager interface {
+	// NextPage fetches the next page of results. It returns false when there are no more pages or
+	// fetching one failed, in which case Err returns the error.
+	NextPage(ctx context.Context) bool
+
+	// More returns true when there is another page to fetch.
+	More() bool
+
+	// PageResponse returns the response of the current page, or nil before the first page was fetched.
+	PageResponse() *Response
+
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+}
+
+// PagingHandler contains the operation-specific functions a Pager calls to fetch each page.
+type PagingHandler struct {
+	// Fetcher sends the request for a page. nextLink is empty for the first page, otherwise it's the value
+	// Advancer returned for the previous page. Responses with a status code of 400 or above are returned
+	// from Err as a *ResponseError.
+	Fetcher func(ctx context.Context, nextLink string) (*Response, error)
+
+	// Advancer is called with the response of each page, it typically unmarshals the page so the client's pager
+	// can return it and returns the link to the following page, or an empty string when it's the last page.
+	Advancer func(resp *Response) (nextLink string, err error)
+}
+
+// NewPager creates a Pager for the operation whose pages are fetched by handler.
+// Clients typically embed it in a pager returning their operation's page type.
+func NewPager(handler PagingHandler) Pager {
+	return &pager{handler: handler, more: true}
+}
+
+type pager struct {
+	handler  PagingHandler
+	nextLink string
+	current  *Response
+	more     bool
+	err      error
+}
+
+func (p *pager) NextPage(ctx context.Context) bool {
+	if !p.more {
+		return false
+	}
+	resp, err := p.handler.Fetcher(ctx, p.nextLink)
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		err = NewResponseError(resp)
+	}
+	if err == nil {
+		p.nextLink, err = p.handler.Advancer(resp)
+	}
+	if err != nil {
+		p.err = err
+		p.more = false
+		return false
+	}
+	p.current = resp
+	p.more = p.nextLink != ""
+	return true
+}
+
+func (p *pager) More() bool {
+	return p.more
+}
+
+func (p *pager) PageResponse() *Response {
+	return p.current
+}
+
+func (p *pager) Err() error {
+	return p.err
+}
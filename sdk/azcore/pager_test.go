@@ -0,0 +1,94 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+type testPage struct {
+	Values   []int  `json:"values"`
+	NextLink string `json:"nextLink"`
+}
+
+func newTestPager(srv *mock.Server, page *testPage) Pager {
+	pl := NewPipeline(srv)
+	return NewPager(PagingHandler{
+		Fetcher: func(ctx context.Context, nextLink string) (*Response, error) {
+			u := srv.URL()
+			if nextLink != "" {
+				u.Path = nextLink
+			}
+			return pl.Do(ctx, NewRequest(http.MethodGet, u))
+		},
+		Advancer: func(resp *Response) (string, error) {
+			*page = testPage{}
+			if err := resp.UnmarshalAsJSON(page); err != nil {
+				return "", err
+			}
+			return page.NextLink, nil
+		},
+	})
+}
+
+func TestPager(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(`{"values":[1,2],"nextLink":"/page2"}`)))
+	srv.AppendResponse(mock.WithBody([]byte(`{"values":[3]}`)))
+	var page testPage
+	pager := newTestPager(srv, &page)
+	if !pager.More() || pager.PageResponse() != nil {
+		t.Fatal("expected a page to fetch")
+	}
+	var values []int
+	for pager.NextPage(context.Background()) {
+		if pager.PageResponse() == nil {
+			t.Fatal("missing page response")
+		}
+		values = append(values, page.Values...)
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 3 || values[2] != 3 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+	if pager.More() || pager.NextPage(context.Background()) {
+		t.Fatal("expected no more pages")
+	}
+	if srv.Requests() != 2 {
+		t.Fatalf("expected 2 requests, got %d", srv.Requests())
+	}
+}
+
+func TestPagerResponseError(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(`{"values":[1],"nextLink":"/page2"}`)))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusForbidden), mock.WithBody([]byte(`{"error":{"code":"AuthorizationFailed"}}`)))
+	var page testPage
+	pager := newTestPager(srv, &page)
+	pages := 0
+	for pager.NextPage(context.Background()) {
+		pages++
+	}
+	if pages != 1 {
+		t.Fatalf("expected 1 page, got %d", pages)
+	}
+	var respErr *ResponseError
+	if !errors.As(pager.Err(), &respErr) || respErr.ErrorCode != "AuthorizationFailed" {
+		t.Fatalf("expected a *ResponseError, got %v", pager.Err())
+	}
+	if pager.More() {
+		t.Fatal("expected no more pages after an error")
+	}
+}
@@ -0,0 +1,219 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"fmt"
+)
+
+// PipelineOptions configures the pipeline created by NewDefaultPipeline.
+type PipelineOptions struct {
+	// HTTPClient sets the transport for making HTTP requests.
+	// Leave this as nil to use the default HTTP transport.
+	HTTPClient Transport
+
+	// Telemetry configures the built-in telemetry policy behavior.
+	Telemetry TelemetryOptions
+
+	// RequestID configures the built-in unique request ID policy behavior.
+	RequestID RequestIDOptions
+
+	// APIVersion configures the built-in API version policy behavior, see NewAPIVersionPolicy.
+	// Leave APIVersion.Version empty to send the client's API version.
+	APIVersion APIVersionOptions
+
+	// Retry configures the built-in retry policy behavior.
+	// Leave this as nil to accept the values returned by DefaultRetryOptions().
+	Retry *RetryOptions
+
+	// Logging configures the built-in request logging policy behavior.
+	Logging RequestLogOptions
+
+	// Tracer records each attempt of a request as a span, see NewTracingPolicy.
+	// Leave this as nil to record nothing.
+	Tracer Tracer
+
+	// Metrics reports the metrics of each request to a callback, see NewMetricsPolicy.
+	// Leave Metrics.Callback as nil to report nothing.
+	Metrics MetricsOptions
+
+	// PerCallPolicies are run once per request, after the built-in telemetry, request ID, API version, custom
+	// headers and metrics policies and before the retry policy.
+	PerCallPolicies []Policy
+
+	// PerRetryPolicies are run for each attempt of a request, after the built-in retry policy and before
+	// the request logging policy. Authentication policies belong here, so that each attempt is authorized.
+	PerRetryPolicies []Policy
+
+	// Placements insert, replace or remove policies relative to the named policies of the pipeline once it's
+	// assembled, in order. A placement relative to a built-in policy that isn't in the pipeline, such as
+	// PolicyNameTracing when there's no Tracer, is skipped. A placement relative to any other name that isn't in
+	// the pipeline or with an unknown Position is an error: it's logged at LogError and every request sent through
+	// the pipeline fails with it.
+	Placements []PolicyPlacement
+}
+
+// PolicyName identifies a policy of a pipeline created by NewDefaultPipeline, so that PipelineOptions.Placements
+// can refer to it. The built-in policies have the names below; name other policies with NamedPolicy.
+type PolicyName string
+
+const (
+	// PolicyNameTelemetry is the name of the built-in telemetry policy.
+	PolicyNameTelemetry PolicyName = "Telemetry"
+	// PolicyNameRequestID is the name of the built-in unique request ID policy.
+	PolicyNameRequestID PolicyName = "RequestID"
+	// PolicyNameAPIVersion is the name of the built-in API version policy.
+	PolicyNameAPIVersion PolicyName = "APIVersion"
+	// PolicyNameCustomHeaders is the name of the built-in custom headers policy.
+	PolicyNameCustomHeaders PolicyName = "CustomHeaders"
+	// PolicyNameMetrics is the name of the built-in metrics policy, present when there's a Metrics.Callback.
+	PolicyNameMetrics PolicyName = "Metrics"
+	// PolicyNameRetry is the name of the built-in retry policy.
+	PolicyNameRetry PolicyName = "Retry"
+	// PolicyNameAuthentication is the name of the bearer token policies in PerRetryPolicies.
+	PolicyNameAuthentication PolicyName = "Authentication"
+	// PolicyNameTracing is the name of the built-in tracing policy, present when there's a Tracer.
+	PolicyNameTracing PolicyName = "Tracing"
+	// PolicyNameLogging is the name of the built-in request logging policy.
+	PolicyNameLogging PolicyName = "Logging"
+)
+
+// PolicyPosition is where PolicyPlacement puts a policy relative to a named policy.
+type PolicyPosition int
+
+const (
+	// PolicyBefore inserts the policy before the named policy, so it runs first.
+	PolicyBefore PolicyPosition = iota
+	// PolicyAfter inserts the policy after the named policy.
+	PolicyAfter
+	// PolicyReplace replaces the named policy, or removes it when the placement's Policy is nil.
+	PolicyReplace
+)
+
+// PolicyPlacement places a policy relative to a named policy of a pipeline created by NewDefaultPipeline.
+type PolicyPlacement struct {
+	// Name is the name of the policy that Policy is placed relative to. The first policy with the name is used.
+	Name PolicyName
+
+	// Position is where Policy is placed relative to the named policy.
+	Position PolicyPosition
+
+	// Policy is the policy that's placed. Wrap it with NamedPolicy so later placements can refer to it.
+	Policy Policy
+}
+
+type namedPolicy struct {
+	name   PolicyName
+	policy Policy
+}
+
+// NamedPolicy names p, so that PipelineOptions.Placements can place policies relative to it when it's one of the
+// PerCallPolicies, PerRetryPolicies or a placed policy. NewDefaultPipeline adds p itself to the pipeline.
+func NamedPolicy(name PolicyName, p Policy) Policy {
+	return &namedPolicy{name: name, policy: p}
+}
+
+func (p *namedPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	return p.policy.Do(ctx, req)
+}
+
+// newNamedPolicy returns p with its name, unwrapping a policy named with NamedPolicy.
+func newNamedPolicy(p Policy) namedPolicy {
+	switch tp := p.(type) {
+	case *namedPolicy:
+		return *tp
+	case *bearerTokenPolicy, *challengePolicy:
+		return namedPolicy{name: PolicyNameAuthentication, policy: p}
+	}
+	return namedPolicy{policy: p}
+}
+
+// optionalPolicyNames are the names of the built-in policies that are only in some pipelines.
+var optionalPolicyNames = map[PolicyName]bool{
+	PolicyNameMetrics:        true,
+	PolicyNameAuthentication: true,
+	PolicyNameTracing:        true,
+}
+
+// placePolicies returns policies with the placements applied, in order, skipping those relative to an optional
+// built-in policy that isn't in the pipeline.
+func placePolicies(policies []namedPolicy, placements []PolicyPlacement) ([]namedPolicy, error) {
+	for _, pp := range placements {
+		if pp.Position != PolicyBefore && pp.Position != PolicyAfter && pp.Position != PolicyReplace {
+			return nil, fmt.Errorf("unknown policy position %d for a placement relative to %q", pp.Position, pp.Name)
+		}
+		i := -1
+		for j, np := range policies {
+			if np.name == pp.Name {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			if optionalPolicyNames[pp.Name] {
+				continue
+			}
+			return nil, fmt.Errorf("no policy named %q to place a policy relative to", pp.Name)
+		}
+		placed := []namedPolicy{}
+		if pp.Policy != nil {
+			placed = append(placed, newNamedPolicy(pp.Policy))
+		}
+		switch pp.Position {
+		case PolicyBefore:
+			policies = append(policies[:i], append(placed, policies[i:]...)...)
+		case PolicyAfter:
+			policies = append(policies[:i+1], append(placed, policies[i+1:]...)...)
+		case PolicyReplace:
+			policies = append(policies[:i], append(placed, policies[i+1:]...)...)
+		}
+	}
+	return policies, nil
+}
+
+// NewDefaultPipeline creates a Pipeline with the built-in policies in their standard order: telemetry, unique
+// request ID, API version, custom headers, metrics when there's a Metrics.Callback, the PerCallPolicies, retry, the
+// PerRetryPolicies, tracing when there's a Tracer and request logging, then applies the Placements. When a placement
+// is invalid, the error is logged at LogError and the returned Pipeline fails every request with it.
+// Pass nil to accept the default values.
+func NewDefaultPipeline(o *PipelineOptions) Pipeline {
+	if o == nil {
+		o = &PipelineOptions{}
+	}
+	named := []namedPolicy{
+		{name: PolicyNameTelemetry, policy: NewTelemetryPolicy(o.Telemetry)},
+		{name: PolicyNameRequestID, policy: NewRequestIDPolicy(o.RequestID)},
+		{name: PolicyNameAPIVersion, policy: NewAPIVersionPolicy(o.APIVersion)},
+		{name: PolicyNameCustomHeaders, policy: NewCustomHeadersPolicy()},
+	}
+	if o.Metrics.Callback != nil {
+		named = append(named, namedPolicy{name: PolicyNameMetrics, policy: NewMetricsPolicy(o.Metrics)})
+	}
+	for _, p := range o.PerCallPolicies {
+		named = append(named, newNamedPolicy(p))
+	}
+	named = append(named, namedPolicy{name: PolicyNameRetry, policy: NewRetryPolicy(o.Retry)})
+	for _, p := range o.PerRetryPolicies {
+		named = append(named, newNamedPolicy(p))
+	}
+	if o.Tracer != nil {
+		named = append(named, namedPolicy{name: PolicyNameTracing, policy: NewTracingPolicy(o.Tracer)})
+	}
+	named = append(named, namedPolicy{name: PolicyNameLogging, policy: NewRequestLogPolicy(o.Logging)})
+	named, err := placePolicies(named, o.Placements)
+	if err != nil {
+		Log().Write(LogError, fmt.Sprintf("NewDefaultPipeline: %v", err))
+		return NewPipeline(o.HTTPClient, PolicyFunc(func(context.Context, *Request) (*Response, error) {
+			return nil, err
+		}))
+	}
+	policies := make([]Policy, len(named))
+	for i, np := range named {
+		policies[i] = np.policy
+	}
+	return NewPipeline(o.HTTPClient, policies...)
+}
@@ -0,0 +1,176 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestNewDefaultPipelinePolicyOrder(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusInternalServerError))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	perCall, perRetry := 0, 0
+	pl := NewDefaultPipeline(&PipelineOptions{
+		HTTPClient: srv,
+		Retry:      testRetryOptions(),
+		PerCallPolicies: []Policy{PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			perCall++
+			if req.Header.Get(HeaderUserAgent) == "" || req.Header.Get(xMsClientRequestID) == "" {
+				t.Fatal("expected the telemetry and request ID policies to run before the per-call policies")
+			}
+			return req.Next(ctx)
+		})},
+		PerRetryPolicies: []Policy{PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			perRetry++
+			return req.Next(ctx)
+		})},
+	})
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+	if perCall != 1 || perRetry != 2 {
+		t.Fatalf("expected 1 per-call and 2 per-retry invocations, got %d and %d", perCall, perRetry)
+	}
+}
+
+func TestNewDefaultPipelineNilOptions(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	pl := NewDefaultPipeline(nil)
+	req := NewRequest(http.MethodGet, srv.URL())
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Request.Header.Get(HeaderUserAgent) == "" {
+		t.Fatal("missing User-Agent header")
+	}
+}
+
+// recordingPolicy appends its name to order each time it runs
+func recordingPolicy(name string, order *[]string) Policy {
+	return PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		*order = append(*order, name)
+		return req.Next(ctx)
+	})
+}
+
+func TestNewDefaultPipelinePlacements(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	var order []string
+	pl := NewDefaultPipeline(&PipelineOptions{
+		HTTPClient:       srv,
+		PerCallPolicies:  []Policy{NamedPolicy("custom", recordingPolicy("custom", &order))},
+		PerRetryPolicies: []Policy{NewBearerTokenPolicy(newFakeTokenCredential(time.Hour), AuthenticationPolicyOptions{})},
+		Placements: []PolicyPlacement{
+			{Name: PolicyNameRetry, Position: PolicyBefore, Policy: recordingPolicy("before retry", &order)},
+			{Name: PolicyNameAuthentication, Position: PolicyAfter, Policy: PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+				if req.Header.Get(HeaderAuthorization) == "" {
+					t.Fatal("expected the policy to run after authentication")
+				}
+				order = append(order, "after auth")
+				return req.Next(ctx)
+			})},
+			{Name: PolicyNameLogging, Position: PolicyReplace, Policy: recordingPolicy("logging", &order)},
+			{Name: "custom", Position: PolicyAfter, Policy: recordingPolicy("after custom", &order)},
+			{Name: PolicyNameTelemetry, Position: PolicyReplace},
+		},
+	})
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ua := resp.Request.Header.Get(HeaderUserAgent); strings.Contains(ua, platformInfo) {
+		t.Fatalf("expected the telemetry policy to be removed, got User-Agent %q", ua)
+	}
+	if s := strings.Join(order, ", "); s != "custom, after custom, before retry, after auth, logging" {
+		t.Fatalf("unexpected order: %s", s)
+	}
+}
+
+func TestNewDefaultPipelinePlacementNoAnchor(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	var order []string
+	pl := NewDefaultPipeline(&PipelineOptions{
+		HTTPClient: srv,
+		Placements: []PolicyPlacement{
+			{Name: PolicyNameTracing, Position: PolicyAfter, Policy: recordingPolicy("after tracing", &order)},
+			{Name: PolicyNameMetrics, Position: PolicyReplace},
+			{Name: PolicyNameLogging, Position: PolicyBefore, Policy: recordingPolicy("before logging", &order)},
+		},
+	})
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := strings.Join(order, ", "); s != "before logging" {
+		t.Fatalf("expected the placements relative to absent built-in policies to be skipped, got %s", s)
+	}
+}
+
+func TestNewDefaultPipelineInvalidPlacement(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		placement PolicyPlacement
+		err       string
+	}{
+		{
+			name:      "unknown name",
+			placement: PolicyPlacement{Name: "Retyr", Position: PolicyBefore, Policy: PolicyFunc(nil)},
+			err:       `no policy named "Retyr"`,
+		},
+		{
+			name:      "unknown position",
+			placement: PolicyPlacement{Name: PolicyNameRetry, Position: PolicyPosition(42), Policy: PolicyFunc(nil)},
+			err:       "unknown policy position 42",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var logged []string
+			Log().SetListener(func(cls LogClassification, msg string) {
+				if cls == LogError {
+					logged = append(logged, msg)
+				}
+			})
+			defer Log().SetListener(nil)
+			sent := false
+			pl := NewDefaultPipeline(&PipelineOptions{
+				HTTPClient: TransportFunc(func(context.Context, *http.Request) (*http.Response, error) {
+					sent = true
+					return nil, nil
+				}),
+				Placements: []PolicyPlacement{test.placement},
+			})
+			if len(logged) != 1 || !strings.Contains(logged[0], test.err) {
+				t.Fatalf("expected the invalid placement to be logged, got %v", logged)
+			}
+			_, err := pl.Do(context.Background(), NewRequest(http.MethodGet, url.URL{Scheme: "https", Host: "localhost"}))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected the request to fail with %q, got %v", test.err, err)
+			}
+			if sent {
+				t.Fatal("expected the request not to be sent")
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"fmt"
+)
+
+// APIVersionLocation is where a service reads the API version of a request.
+type APIVersionLocation int
+
+const (
+	// APIVersionLocationQueryParam sends the API version as a query parameter, the default.
+	APIVersionLocationQueryParam APIVersionLocation = iota
+	// APIVersionLocationHeader sends the API version as a header.
+	APIVersionLocationHeader
+)
+
+// APIVersionOptions configures the API version policy's behavior.
+type APIVersionOptions struct {
+	// Version overrides the API version the client sends, for example to target a preview version of the service
+	// before a client for it is released. Leave this empty to send the client's API version.
+	Version string
+
+	// Location is where the API version is sent. The default is the query string.
+	Location APIVersionLocation
+
+	// Name is the name of the query parameter or header containing the API version.
+	// The default is "api-version" for a query parameter and "x-ms-version" for a header.
+	Name string
+}
+
+// used as a context key for adding/retrieving the API version
+type ctxWithAPIVersionKey struct{}
+
+// WithAPIVersion adds the specified API version to the parent context. The API version policy sends it instead of
+// the client's API version and APIVersionOptions.Version for the requests sent with the context.
+func WithAPIVersion(parent context.Context, version string) context.Context {
+	return context.WithValue(parent, ctxWithAPIVersionKey{}, version)
+}
+
+// NewAPIVersionPolicy creates a policy object that replaces the API version of each request with the version added
+// to the context by WithAPIVersion or else with o.Version. Requests are sent unchanged when neither is set.
+func NewAPIVersionPolicy(o APIVersionOptions) Policy {
+	name := o.Name
+	if name == "" {
+		name = "api-version"
+		if o.Location == APIVersionLocationHeader {
+			name = "x-ms-version"
+		}
+	}
+	return PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		version := o.Version
+		if v, ok := ctx.Value(ctxWithAPIVersionKey{}).(string); ok && v != "" {
+			version = v
+		}
+		if version == "" {
+			return req.Next(ctx)
+		}
+		switch o.Location {
+		case APIVersionLocationQueryParam:
+			q := req.URL.Query()
+			q.Set(name, version)
+			req.URL.RawQuery = q.Encode()
+		case APIVersionLocationHeader:
+			req.Request.Header.Set(name, version)
+		default:
+			return nil, fmt.Errorf("unknown API version location %d", o.Location)
+		}
+		return req.Next(ctx)
+	})
+}
@@ -0,0 +1,65 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestAPIVersionPolicyQueryParam(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	pl := NewDefaultPipeline(&PipelineOptions{HTTPClient: srv, APIVersion: APIVersionOptions{Version: "2021-01-01-preview"}})
+	for _, test := range []struct {
+		ctx      context.Context
+		expected string
+	}{
+		{context.Background(), "2021-01-01-preview"},
+		{WithAPIVersion(context.Background(), "2022-02-02"), "2022-02-02"},
+	} {
+		u := srv.URL()
+		u.RawQuery = "api-version=2020-01-01&comp=list"
+		resp, err := pl.Do(test.ctx, NewRequest(http.MethodGet, u))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		q := resp.Request.URL.Query()
+		if v := q.Get("api-version"); v != test.expected {
+			t.Fatalf("expected API version %s, got %s", test.expected, v)
+		}
+		if q.Get("comp") != "list" {
+			t.Fatalf("expected the other query parameters to be kept, got %v", q)
+		}
+	}
+}
+
+func TestAPIVersionPolicyHeader(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	pl := NewPipeline(srv, NewAPIVersionPolicy(APIVersionOptions{Location: APIVersionLocationHeader}))
+	req := NewRequest(http.MethodGet, srv.URL())
+	req.Header.Set("x-ms-version", "2019-12-12")
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get("x-ms-version"); v != "2019-12-12" {
+		t.Fatalf("expected the client's version to be kept, got %s", v)
+	}
+	resp, err = pl.Do(WithAPIVersion(context.Background(), "2020-10-02"), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get("x-ms-version"); v != "2020-10-02" {
+		t.Fatalf("unexpected version %s", v)
+	}
+}
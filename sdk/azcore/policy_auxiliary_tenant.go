@@ -0,0 +1,61 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"strings"
+)
+
+// AuxiliaryTenantOptions configures the auxiliary tenant authorization policy's behavior.
+type AuxiliaryTenantOptions struct {
+	// Options contains the TokenRequestOptions, such as the scopes, of the tokens requested for each auxiliary
+	// tenant. Its TenantID is ignored.
+	Options TokenRequestOptions
+
+	// TenantIDs are the auxiliary tenants a token is requested from. Azure Resource Manager accepts up to 3.
+	TenantIDs []string
+}
+
+type auxiliaryTenantPolicy struct {
+	// tenants cache and refresh the token of each auxiliary tenant
+	tenants []*bearerTokenPolicy
+}
+
+// NewAuxiliaryTenantPolicy creates a policy that sets the x-ms-authorization-auxiliary header of each request to
+// bearer tokens from cred for each of the auxiliary tenants, as required by cross-tenant Azure Resource Manager
+// operations such as moving resources between tenants. Add it alongside the credential's authentication policy,
+// which authorizes the request in the primary tenant. The tokens are cached and refreshed like those of the bearer
+// token policy. Requests whose URL doesn't use the HTTPS protocol scheme fail with ErrHTTPSRequired.
+func NewAuxiliaryTenantPolicy(cred TokenCredential, o AuxiliaryTenantOptions) Policy {
+	p := &auxiliaryTenantPolicy{}
+	for _, tenantID := range o.TenantIDs {
+		options := o.Options
+		options.TenantID = tenantID
+		p.tenants = append(p.tenants, &bearerTokenPolicy{cred: cred, options: options})
+	}
+	return p
+}
+
+func (p *auxiliaryTenantPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	if len(p.tenants) == 0 {
+		return req.Next(ctx)
+	}
+	if req.URL.Scheme != "https" {
+		// HTTPS must be used, otherwise the tokens are at the risk of being exposed
+		return nil, ErrHTTPSRequired
+	}
+	headers := make([]string, 0, len(p.tenants))
+	for _, tenant := range p.tenants {
+		header, err := tenant.authorizationHeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	req.Request.Header.Set(HeaderXmsAuthorizationAuxiliary, strings.Join(headers, ", "))
+	return req.Next(ctx)
+}
@@ -0,0 +1,73 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// tenantTokenCredential returns a token named after the tenant of each request, counting the requests per tenant
+type tenantTokenCredential struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *tenantTokenCredential) GetToken(ctx context.Context, opts TokenRequestOptions) (AccessToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[opts.TenantID]++
+	return AccessToken{Token: "token-" + opts.TenantID, ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func (c *tenantTokenCredential) AuthenticationPolicy(options AuthenticationPolicyOptions) Policy {
+	return NewBearerTokenPolicy(c, options)
+}
+
+func TestAuxiliaryTenantPolicy(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	cred := &tenantTokenCredential{calls: map[string]int{}}
+	pl := NewPipeline(srv,
+		NewBearerTokenPolicy(cred, AuthenticationPolicyOptions{Options: TokenRequestOptions{Scopes: []string{"scope"}}}),
+		NewAuxiliaryTenantPolicy(cred, AuxiliaryTenantOptions{Options: TokenRequestOptions{Scopes: []string{"scope"}}, TenantIDs: []string{"a", "b"}}))
+	for i := 0; i < 2; i++ {
+		resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if h := resp.Request.Header.Get(HeaderAuthorization); h != "Bearer token-" {
+			t.Fatalf("unexpected Authorization header: %s", h)
+		}
+		if h := resp.Request.Header.Get(HeaderXmsAuthorizationAuxiliary); h != "Bearer token-a, Bearer token-b" {
+			t.Fatalf("unexpected %s header: %s", HeaderXmsAuthorizationAuxiliary, h)
+		}
+	}
+	if cred.calls["a"] != 1 || cred.calls["b"] != 1 {
+		t.Fatalf("expected the tokens to be cached, got %v", cred.calls)
+	}
+}
+
+func TestAuxiliaryTenantPolicyHTTPSRequired(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	cred := &tenantTokenCredential{calls: map[string]int{}}
+	pl := NewPipeline(srv, NewAuxiliaryTenantPolicy(cred, AuxiliaryTenantOptions{TenantIDs: []string{"a"}}))
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); !errors.Is(err, ErrHTTPSRequired) {
+		t.Fatalf("expected ErrHTTPSRequired, got %v", err)
+	}
+	if len(cred.calls) != 0 || srv.Requests() != 0 {
+		t.Fatal("expected no token to be requested or sent")
+	}
+}
@@ -0,0 +1,149 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bearerTokenPrefix = "Bearer "
+
+	// bearerTokenRefreshWindow is how long before a token expires that it's refreshed. Requests keep using
+	// the old token while another request refreshes it.
+	bearerTokenRefreshWindow = 2 * time.Minute
+)
+
+// ErrHTTPSRequired is returned by the bearer token policy for requests whose URL doesn't use the HTTPS protocol scheme,
+// because sending a token over HTTP would expose it.
+var ErrHTTPSRequired = errors.New("token credentials require a URL using the HTTPS protocol scheme")
+
+type bearerTokenPolicy struct {
+	// mu protects the following shared state
+	mu sync.Mutex
+
+	// renewing is non-nil while the token is being refreshed and is closed when the refresh finishes
+	renewing chan struct{}
+
+	// header contains the authorization header value
+	header string
+
+	// expiresOn is when the token will expire
+	expiresOn time.Time
+
+	// refreshOn is when the service recommends refreshing the token, zero when it made no recommendation
+	refreshOn time.Time
+
+	// the following fields are read-only
+	cred    TokenCredential
+	options TokenRequestOptions
+}
+
+// NewBearerTokenPolicy creates a policy that sets the Authorization header of each request to a bearer token
+// from cred for the scopes in opts. The token is cached by the policy and refreshed shortly before it expires,
+// or after the time the service recommended refreshing it, by a single request while the others keep using it.
+// Requests whose URL doesn't use the HTTPS protocol scheme fail with ErrHTTPSRequired.
+func NewBearerTokenPolicy(cred TokenCredential, opts AuthenticationPolicyOptions) Policy {
+	return &bearerTokenPolicy{
+		cred:    cred,
+		options: opts.Options,
+	}
+}
+
+func (b *bearerTokenPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	if req.URL.Scheme != "https" {
+		// HTTPS must be used, otherwise the tokens are at the risk of being exposed
+		return nil, ErrHTTPSRequired
+	}
+	if err := b.authorize(ctx, req); err != nil {
+		return nil, err
+	}
+	return req.Next(ctx)
+}
+
+// authorize sets the request's Authorization header to the policy's token, refreshing the token when it's expiring.
+func (b *bearerTokenPolicy) authorize(ctx context.Context, req *Request) error {
+	header, err := b.authorizationHeader(ctx)
+	if err != nil {
+		return err
+	}
+	req.Request.Header.Set(HeaderXmsDate, time.Now().UTC().Format(http.TimeFormat))
+	req.Request.Header.Set(HeaderAuthorization, header)
+	return nil
+}
+
+// setToken replaces the policy's token with one acquired outside the policy, for example in response to a challenge.
+func (b *bearerTokenPolicy) setToken(tk AccessToken) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.header = bearerTokenPrefix + tk.Token
+	b.expiresOn = tk.ExpiresOn
+	b.refreshOn = tk.RefreshOn
+}
+
+// authorizationHeader returns the authorization header value, refreshing the token when it's expiring.
+// Waiting for another go routine to refresh the token stops when ctx is done.
+func (b *bearerTokenPolicy) authorizationHeader(ctx context.Context) (string, error) {
+	for {
+		now := time.Now()
+		b.mu.Lock()
+		if !b.expiresOn.IsZero() && b.expiresOn.After(now) {
+			expiring := b.expiresOn.Add(-bearerTokenRefreshWindow).Before(now) || (!b.refreshOn.IsZero() && b.refreshOn.Before(now))
+			if !expiring || b.renewing != nil {
+				// the token is not expiring yet, or it's within the refresh window and
+				// another go routine is refreshing it, so use the existing token
+				header := b.header
+				b.mu.Unlock()
+				return header, nil
+			}
+		}
+		if b.renewing == nil {
+			// another go routine isn't refreshing the token so this one will
+			b.renewing = make(chan struct{})
+			b.mu.Unlock()
+			return b.refresh(ctx)
+		}
+		// the token was never obtained or has expired, so wait for another go routine to refresh it
+		renewing := b.renewing
+		b.mu.Unlock()
+		select {
+		case <-renewing:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// refresh gets a new token and signals any go routines waiting for it. Waiters try again when it fails.
+func (b *bearerTokenPolicy) refresh(ctx context.Context) (string, error) {
+	tk, err := b.cred.GetToken(ctx, b.options)
+	if Log().Should(LogAuthentication) {
+		if err != nil {
+			Log().Write(LogAuthentication, fmt.Sprintf("BearerTokenPolicy => failed to acquire a token for %s: %v", strings.Join(b.options.Scopes, ", "), err))
+		} else {
+			Log().Write(LogAuthentication, fmt.Sprintf("BearerTokenPolicy => acquired a token for %s, expires on %s", strings.Join(b.options.Scopes, ", "), tk.ExpiresOn.UTC().Format(time.RFC3339)))
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.header = bearerTokenPrefix + tk.Token
+		b.expiresOn = tk.ExpiresOn
+		b.refreshOn = tk.RefreshOn
+	}
+	close(b.renewing)
+	b.renewing = nil
+	if err != nil {
+		return "", err
+	}
+	return b.header, nil
+}
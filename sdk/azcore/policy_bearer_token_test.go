@@ -0,0 +1,173 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// fakeTokenCredential counts its calls and returns tokens from its getToken func
+type fakeTokenCredential struct {
+	calls    int32
+	getToken func() (AccessToken, error)
+}
+
+func (f *fakeTokenCredential) GetToken(ctx context.Context, opts TokenRequestOptions) (AccessToken, error) {
+	atomic.AddInt32(&f.calls, 1)
+	return f.getToken()
+}
+
+func (f *fakeTokenCredential) AuthenticationPolicy(options AuthenticationPolicyOptions) Policy {
+	return NewBearerTokenPolicy(f, options)
+}
+
+func newFakeTokenCredential(lifetime time.Duration) *fakeTokenCredential {
+	return &fakeTokenCredential{getToken: func() (AccessToken, error) {
+		return AccessToken{Token: "token", ExpiresOn: time.Now().Add(lifetime)}, nil
+	}}
+}
+
+func TestBearerTokenPolicySuccess(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	cred := newFakeTokenCredential(time.Hour)
+	pl := NewPipeline(srv, NewBearerTokenPolicy(cred, AuthenticationPolicyOptions{Options: TokenRequestOptions{Scopes: []string{"scope"}}}))
+	for i := 0; i < 3; i++ {
+		resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if h := resp.Request.Header.Get(HeaderAuthorization); h != "Bearer token" {
+			t.Fatalf("unexpected Authorization header: %s", h)
+		}
+		if resp.Request.Header.Get(HeaderXmsDate) == "" {
+			t.Fatal("missing x-ms-date header")
+		}
+	}
+	if cred.calls != 1 {
+		t.Fatalf("expected the token to be cached, got %d calls", cred.calls)
+	}
+}
+
+func TestBearerTokenPolicyHTTPSRequired(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	cred := newFakeTokenCredential(time.Hour)
+	pl := NewPipeline(srv, NewBearerTokenPolicy(cred, AuthenticationPolicyOptions{}))
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); !errors.Is(err, ErrHTTPSRequired) {
+		t.Fatalf("expected ErrHTTPSRequired, got %v", err)
+	}
+	if cred.calls != 0 || srv.Requests() != 0 {
+		t.Fatal("expected no token to be requested or sent")
+	}
+}
+
+func TestBearerTokenPolicyRefreshesExpiringToken(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	// the token expires within the refresh window, so each request refreshes it
+	cred := newFakeTokenCredential(bearerTokenRefreshWindow / 2)
+	pl := NewPipeline(srv, NewBearerTokenPolicy(cred, AuthenticationPolicyOptions{}))
+	for i := 0; i < 2; i++ {
+		if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if cred.calls != 2 {
+		t.Fatalf("expected the expiring token to be refreshed, got %d calls", cred.calls)
+	}
+}
+
+func TestBearerTokenPolicyConcurrentRequests(t *testing.T) {
+	// the mock server isn't safe for concurrent requests
+	transport := TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	release := make(chan struct{})
+	cred := &fakeTokenCredential{getToken: func() (AccessToken, error) {
+		<-release
+		return AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}}
+	pl := NewPipeline(transport, NewBearerTokenPolicy(cred, AuthenticationPolicyOptions{}))
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pl.Do(context.Background(), NewRequest(http.MethodGet, url.URL{Scheme: "https", Host: "localhost"}))
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if cred.calls != 1 {
+		t.Fatalf("expected a single token request, got %d", cred.calls)
+	}
+}
+
+func TestBearerTokenPolicyCredentialError(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	credErr := errors.New("no token")
+	cred := &fakeTokenCredential{getToken: func() (AccessToken, error) {
+		return AccessToken{}, credErr
+	}}
+	pl := NewPipeline(srv, NewBearerTokenPolicy(cred, AuthenticationPolicyOptions{}))
+	for i := 0; i < 2; i++ {
+		if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); !errors.Is(err, credErr) {
+			t.Fatalf("expected the credential's error, got %v", err)
+		}
+	}
+	if cred.calls != 2 || srv.Requests() != 0 {
+		t.Fatalf("expected each request to try to get a token and none to be sent, got %d calls and %d requests", cred.calls, srv.Requests())
+	}
+}
+
+func TestBearerTokenPolicyLogging(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	const secret = "secret-token"
+	cred := &fakeTokenCredential{getToken: func() (AccessToken, error) {
+		return AccessToken{Token: secret, ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}}
+	var entries []string
+	Log().SetListener(func(cls LogClassification, msg string) {
+		if cls == LogAuthentication {
+			entries = append(entries, msg)
+		}
+	})
+	defer Log().SetListener(nil)
+	pl := NewPipeline(srv, NewBearerTokenPolicy(cred, AuthenticationPolicyOptions{Options: TokenRequestOptions{Scopes: []string{"scope"}}}))
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || !strings.Contains(entries[0], "acquired a token for scope") || strings.Contains(entries[0], secret) {
+		t.Fatalf("unexpected log entries: %v", entries)
+	}
+}
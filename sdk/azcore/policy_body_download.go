@@ -15,14 +15,26 @@ import (
 // newBodyDownloadPolicy creates a policy object that downloads the response's body to a []byte.
 func newBodyDownloadPolicy() Policy {
 	return PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		var upload uploadProgressOpValues
+		if req.OperationValue(&upload); upload.pr != nil && req.Body != nil {
+			// wrap the body of this try only, the retry policy rewinds the body so progress starts again from zero
+			httpReq := *req.Request
+			if body, ok := httpReq.Body.(ReadSeekCloser); ok {
+				httpReq.Body = NewRequestBodyProgress(body, upload.pr)
+			} else {
+				httpReq.Body = NewResponseBodyProgress(httpReq.Body, upload.pr)
+			}
+			req.Request = &httpReq
+		}
 		resp, err := req.Next(ctx)
 		if err != nil {
 			return resp, err
 		}
-		var opValues bodyDownloadPolicyOpValues
-		if req.OperationValue(&opValues); !opValues.skip && resp.Body != nil {
-			// Either bodyDownloadPolicyOpValues was not specified (so skip is false)
-			// or it was specified and skip is false: don't skip downloading the body
+		var download downloadProgressOpValues
+		if req.OperationValue(&download); download.pr != nil && resp.Body != nil {
+			resp.Body = NewResponseBodyProgress(resp.Body, download.pr)
+		}
+		if req.bodyDownloadEnabled(ctx, resp) && resp.Body != nil {
 			var b []byte
 			b, err = ioutil.ReadAll(resp.Body)
 			resp.Body.Close()
@@ -35,11 +47,40 @@ func newBodyDownloadPolicy() Policy {
 	})
 }
 
+// SkipBodyDownloadOptions configures which response bodies are downloaded for requests sent with
+// a context returned by WithSkipBodyDownload.
+type SkipBodyDownloadOptions struct {
+	// DownloadErrors downloads the bodies of error responses, those with a status code of 400 or higher,
+	// so that they can be parsed. The bodies of other responses are left streaming.
+	DownloadErrors bool
+}
+
+// used as a context key for adding/retrieving SkipBodyDownloadOptions
+type ctxWithSkipBodyDownloadKey struct{}
+
+// WithSkipBodyDownload returns a context that disables automatic downloading of the response bodies of the requests
+// sent with it, like Request.SkipBodyDownload, so that an operation returning a large payload streams it to the
+// caller instead of buffering it in memory. The caller must read and close the body, or drain it. Use this to skip
+// downloading bodies at the API-call level.
+func WithSkipBodyDownload(parent context.Context, o SkipBodyDownloadOptions) context.Context {
+	return context.WithValue(parent, ctxWithSkipBodyDownloadKey{}, o)
+}
+
 // bodyDownloadPolicyOpValues is the struct containing the per-operation values
 type bodyDownloadPolicyOpValues struct {
 	skip bool
 }
 
+// uploadProgressOpValues is the struct containing the per-operation values set by Request.SetUploadProgress
+type uploadProgressOpValues struct {
+	pr ProgressReceiver
+}
+
+// downloadProgressOpValues is the struct containing the per-operation values set by Request.SetDownloadProgress
+type downloadProgressOpValues struct {
+	pr ProgressReceiver
+}
+
 // nopClosingBytesReader is an io.ReadCloser around a byte slice.
 // It also provides direct access to the byte slice.
 type nopClosingBytesReader struct {
@@ -7,6 +7,7 @@ package azcore
 
 import (
 	"context"
+	"io/ioutil"
 	"net/http"
 	"testing"
 
@@ -50,6 +51,36 @@ func TestSkipBodyDownload(t *testing.T) {
 	}
 }
 
+func TestWithSkipBodyDownload(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable), mock.WithBody([]byte("retried")))
+	srv.AppendResponse(mock.WithBody([]byte("streamed")))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNotFound), mock.WithBody([]byte("error")))
+	pl := NewPipeline(srv, NewRetryPolicy(testRetryOptions()))
+	ctx := WithSkipBodyDownload(context.Background(), SkipBodyDownloadOptions{DownloadErrors: true})
+	resp, err := pl.Do(ctx, NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.payload()) > 0 {
+		t.Fatalf("unexpected download: %s", resp.payload())
+	}
+	// the try's timeout is cancelled when the streamed body is closed
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(b) != "streamed" {
+		t.Fatalf("unexpected body %q, %v", b, err)
+	}
+	resp, err = pl.Do(ctx, NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.payload()) != "error" {
+		t.Fatalf("expected the error response to be downloaded, got %q", resp.payload())
+	}
+}
+
 func TestDownloadBodyFail(t *testing.T) {
 	const message = "downloaded"
 	srv, close := mock.NewServer()
@@ -0,0 +1,182 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// challengeParams matches the auth-params of a challenge, such as resource="https://vault.azure.net".
+var challengeParams = regexp.MustCompile(`([a-zA-Z_]+)\s*=\s*(?:"([^"]*)"|([^\s,]*))`)
+
+type challengePolicy struct {
+	cred TokenCredential
+
+	// mu protects bearer
+	mu sync.Mutex
+	// bearer caches the token for the scopes and tenant in its options, it's nil until they're known
+	bearer *bearerTokenPolicy
+}
+
+// NewChallengeBearerTokenPolicy creates a bearer token policy that also responds to the authentication challenges
+// in the WWW-Authenticate header of 401 responses, by acquiring a token with the challenge's parameters and sending
+// the request again, once.
+//   - A discovery challenge, such as Key Vault's, names the resource and the tenant that tokens must be requested
+//     for, which are used from then on. When opts contains no scopes, requests are sent without a token until the
+//     service challenges them. The resource must be the request's host or one of its parent domains.
+//   - A Continuous Access Evaluation challenge, whose error is insufficient_claims, contains claims the token must
+//     satisfy, for example after the user's session was revoked.
+//
+// The policy must follow the retry policy, which makes the request's body replayable.
+func NewChallengeBearerTokenPolicy(cred TokenCredential, opts AuthenticationPolicyOptions) Policy {
+	p := &challengePolicy{cred: cred}
+	if len(opts.Options.Scopes) > 0 {
+		p.bearer = &bearerTokenPolicy{cred: cred, options: opts.Options}
+	}
+	return p
+}
+
+func (p *challengePolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	if req.URL.Scheme != "https" {
+		// HTTPS must be used, otherwise the tokens are at the risk of being exposed
+		return nil, ErrHTTPSRequired
+	}
+	p.mu.Lock()
+	bearer := p.bearer
+	p.mu.Unlock()
+	if bearer != nil {
+		if err := bearer.authorize(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := req.Next(ctx)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := parseBearerChallenge(resp.Header.Get(HeaderWWWAuthenticate))
+	if challenge == nil {
+		return resp, nil
+	}
+	if Log().Should(LogAuthentication) {
+		Log().Write(LogAuthentication, fmt.Sprintf("ChallengeBearerTokenPolicy => %s responded with a challenge: error=%q, resource=%q, scope=%q",
+			req.URL.Host, challenge["error"], challenge["resource"]+challenge["resource_id"], challenge["scope"]))
+	}
+	var options TokenRequestOptions
+	if bearer != nil {
+		options = bearer.options
+	}
+	if challenge["error"] == "insufficient_claims" {
+		claims, err := decodeChallengeClaims(challenge["claims"])
+		if bearer == nil || err != nil {
+			return resp, nil
+		}
+		options.Claims = claims
+		options.EnableCAE = true
+	} else {
+		scope, ok := challengeScope(challenge, req.URL)
+		if !ok {
+			return resp, nil
+		}
+		options = TokenRequestOptions{Scopes: []string{scope}, TenantID: challengeTenant(challenge), EnableCAE: options.EnableCAE}
+		bearer = &bearerTokenPolicy{cred: p.cred, options: options}
+		p.mu.Lock()
+		p.bearer = bearer
+		p.mu.Unlock()
+	}
+	tk, err := p.cred.GetToken(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	bearer.setToken(tk)
+	resp.Drain()
+	if err = req.RewindBody(); err != nil {
+		return nil, err
+	}
+	if err = bearer.authorize(ctx, req); err != nil {
+		return nil, err
+	}
+	return req.Next(ctx)
+}
+
+// parseBearerChallenge returns the auth-params of the Bearer challenge in a WWW-Authenticate header,
+// or nil when there isn't one.
+func parseBearerChallenge(header string) map[string]string {
+	i := strings.Index(strings.ToLower(header), "bearer")
+	if i < 0 {
+		return nil
+	}
+	params := map[string]string{}
+	for _, m := range challengeParams.FindAllStringSubmatch(header[i+len("bearer"):], -1) {
+		key := strings.ToLower(m[1])
+		if _, ok := params[key]; ok {
+			// the parameter belongs to a following challenge
+			break
+		}
+		params[key] = m[2] + m[3]
+	}
+	return params
+}
+
+// decodeChallengeClaims decodes the base64 claims of a Continuous Access Evaluation challenge.
+func decodeChallengeClaims(claims string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(claims)
+	if err != nil {
+		b, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(claims, "="))
+	}
+	return string(b), err
+}
+
+// challengeScope returns the scope of the resource named by a discovery challenge. It returns false when the
+// challenge names no resource, or one that isn't the request's host or a parent domain of it, so that a token
+// isn't sent to a service it wasn't intended for.
+func challengeScope(challenge map[string]string, endpoint *url.URL) (string, bool) {
+	scope := challenge["scope"]
+	if scope == "" {
+		resource := challenge["resource"]
+		if resource == "" {
+			resource = challenge["resource_id"]
+		}
+		if resource == "" {
+			return "", false
+		}
+		scope = strings.TrimSuffix(resource, "/") + "/.default"
+	}
+	u, err := url.Parse(scope)
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+	host, resourceHost := strings.ToLower(endpoint.Hostname()), strings.ToLower(u.Hostname())
+	if host != resourceHost && !strings.HasSuffix(host, "."+resourceHost) {
+		return "", false
+	}
+	return scope, true
+}
+
+// challengeTenant returns the tenant in the authorization URI of a discovery challenge, such as
+// https://login.microsoftonline.com/{tenant}, or an empty string when it doesn't name one.
+func challengeTenant(challenge map[string]string) string {
+	authorization := challenge["authorization"]
+	if authorization == "" {
+		authorization = challenge["authorization_uri"]
+	}
+	u, err := url.Parse(authorization)
+	if err != nil {
+		return ""
+	}
+	tenant := strings.Split(strings.Trim(u.Path, "/"), "/")[0]
+	switch strings.ToLower(tenant) {
+	case "common", "organizations", "consumers":
+		return ""
+	}
+	return tenant
+}
@@ -0,0 +1,168 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// recordingCredential records the options of each token request
+type recordingCredential struct {
+	requests []TokenRequestOptions
+}
+
+func (c *recordingCredential) GetToken(ctx context.Context, opts TokenRequestOptions) (AccessToken, error) {
+	c.requests = append(c.requests, opts)
+	return AccessToken{Token: "token" + string(rune('0'+len(c.requests))), ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func (c *recordingCredential) AuthenticationPolicy(options AuthenticationPolicyOptions) Policy {
+	return NewChallengeBearerTokenPolicy(c, options)
+}
+
+// newChallengeTestServer returns a mock server for requests to its https URL. The mock server's TLS
+// variant can't queue responses, so requests are sent to it over HTTP.
+func newChallengeTestServer() (*mock.Server, Transport, url.URL, func()) {
+	srv, close := mock.NewServer()
+	transport := TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		req.URL.Scheme = "http"
+		return srv.Do(ctx, req)
+	})
+	u := srv.URL()
+	u.Scheme = "https"
+	return srv, transport, u, close
+}
+
+func TestChallengePolicyDiscovery(t *testing.T) {
+	srv, transport, srvURL, close := newChallengeTestServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized),
+		mock.WithHeader(HeaderWWWAuthenticate, `Bearer authorization="https://login.microsoftonline.com/tenant-id", resource="https://`+srvURL.Hostname()+`"`))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	cred := &recordingCredential{}
+	pl := NewPipeline(transport, cred.AuthenticationPolicy(AuthenticationPolicyOptions{}))
+	for i := 0; i < 2; i++ {
+		resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srvURL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status code: %d", resp.StatusCode)
+		}
+		if h := resp.Request.Header.Get(HeaderAuthorization); h != "Bearer token1" {
+			t.Fatalf("unexpected Authorization header: %s", h)
+		}
+	}
+	if len(cred.requests) != 1 {
+		t.Fatalf("expected the discovered token to be cached, got %d token requests", len(cred.requests))
+	}
+	if opts := cred.requests[0]; opts.TenantID != "tenant-id" || len(opts.Scopes) != 1 || opts.Scopes[0] != "https://"+srvURL.Hostname()+"/.default" {
+		t.Fatalf("unexpected token request options: %+v", opts)
+	}
+	if srv.Requests() != 3 {
+		t.Fatalf("expected 3 requests, got %d", srv.Requests())
+	}
+}
+
+func TestChallengePolicyDiscoveryOtherResource(t *testing.T) {
+	srv, transport, srvURL, close := newChallengeTestServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized),
+		mock.WithHeader(HeaderWWWAuthenticate, `Bearer authorization="https://login.microsoftonline.com/tenant-id", resource="https://vault.azure.net"`))
+	cred := &recordingCredential{}
+	pl := NewPipeline(transport, cred.AuthenticationPolicy(AuthenticationPolicyOptions{}))
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srvURL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || len(cred.requests) != 0 {
+		t.Fatal("expected no token for a resource other than the request's host")
+	}
+}
+
+func TestChallengePolicyInsufficientClaims(t *testing.T) {
+	srv, transport, srvURL, close := newChallengeTestServer()
+	defer close()
+	const claims = `{"access_token":{"nbf":{"essential":true,"value":"1600000000"}}}`
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized),
+		mock.WithHeader(HeaderWWWAuthenticate, `Bearer realm="", authorization_uri="https://login.microsoftonline.com/common/oauth2/authorize", error="insufficient_claims", claims="`+base64.StdEncoding.EncodeToString([]byte(claims))+`"`))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	cred := &recordingCredential{}
+	var bodies []string
+	recordBody := PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, string(b))
+		if err = req.RewindBody(); err != nil {
+			return nil, err
+		}
+		return req.Next(ctx)
+	})
+	pl := NewPipeline(transport, NewRetryPolicy(testRetryOptions()), cred.AuthenticationPolicy(AuthenticationPolicyOptions{Options: TokenRequestOptions{Scopes: []string{"scope"}}}), recordBody)
+	req := NewRequest(http.MethodPost, srvURL)
+	if err := req.SetBody(NopCloser(strings.NewReader("payload"))); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+	if len(cred.requests) != 2 || cred.requests[1].Claims != claims || !cred.requests[1].EnableCAE || cred.requests[1].Scopes[0] != "scope" {
+		t.Fatalf("expected a token request with the challenge's claims, got %+v", cred.requests)
+	}
+	if h := resp.Request.Header.Get(HeaderAuthorization); h != "Bearer token2" {
+		t.Fatalf("unexpected Authorization header: %s", h)
+	}
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "payload" {
+		t.Fatalf("expected the body to be sent again, got %q", bodies)
+	}
+}
+
+func TestChallengePolicyReplaysOnce(t *testing.T) {
+	srv, transport, srvURL, close := newChallengeTestServer()
+	defer close()
+	for i := 0; i < 2; i++ {
+		srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized),
+			mock.WithHeader(HeaderWWWAuthenticate, `Bearer error="insufficient_claims", claims="e30="`))
+	}
+	cred := &recordingCredential{}
+	pl := NewPipeline(transport, cred.AuthenticationPolicy(AuthenticationPolicyOptions{Options: TokenRequestOptions{Scopes: []string{"scope"}}}))
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srvURL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || srv.Requests() != 2 {
+		t.Fatalf("expected the request to be sent again only once, got %d requests", srv.Requests())
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	params := parseBearerChallenge(`Bearer authorization="https://login.microsoftonline.com/tid", resource=https://vault.azure.net, PoP nonce="n", resource="other"`)
+	if params["authorization"] != "https://login.microsoftonline.com/tid" || params["resource"] != "https://vault.azure.net" {
+		t.Fatalf("unexpected parameters: %v", params)
+	}
+	if params := parseBearerChallenge(`Basic realm="x"`); params != nil {
+		t.Fatalf("expected no Bearer challenge, got %v", params)
+	}
+	if tenant := challengeTenant(map[string]string{"authorization_uri": "https://login.microsoftonline.com/common/oauth2/authorize"}); tenant != "" {
+		t.Fatalf("expected no tenant, got %s", tenant)
+	}
+}
@@ -0,0 +1,162 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CircuitBreakerOptions configures the circuit breaker policy's behavior.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is how many consecutive requests to an endpoint must fail before requests to it fail fast.
+	// The default is 5.
+	FailureThreshold int
+
+	// Cooldown is how long requests to a failing endpoint fail fast before a single request is sent to find out
+	// whether it has recovered. The default is 30 seconds.
+	Cooldown time.Duration
+
+	// StatusCodes are the response status codes counted as failures, in addition to requests that couldn't be sent.
+	// The default is 408, 500, 502, 503 and 504.
+	StatusCodes []int
+}
+
+func (o CircuitBreakerOptions) defaults() CircuitBreakerOptions {
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = 5
+	}
+	if o.Cooldown <= 0 {
+		o.Cooldown = 30 * time.Second
+	}
+	if o.StatusCodes == nil {
+		o.StatusCodes = []int{
+			http.StatusRequestTimeout,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	return o
+}
+
+// CircuitOpenError is returned by the circuit breaker policy without sending a request when the endpoint
+// failed several consecutive requests. Requests to the endpoint fail fast until RetryAfter.
+type CircuitOpenError struct {
+	// Endpoint is the scheme and host of the failing endpoint, such as "https://contoso.blob.core.windows.net".
+	Endpoint string
+	// RetryAfter is when a request will be sent to the endpoint again.
+	RetryAfter time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s failed several consecutive requests, no requests will be sent to it until %s", e.Endpoint, e.RetryAfter.Format(time.RFC3339))
+}
+
+// IsNotRetriable returns true indicating that this is a terminal error.
+func (e *CircuitOpenError) IsNotRetriable() bool {
+	return true
+}
+
+// CircuitBreaker counts the consecutive failed requests to an endpoint. Once there are FailureThreshold of them
+// the circuit opens and requests to the endpoint mustn't be sent for Cooldown. Then a single request is let through,
+// closing the circuit if it succeeds and opening it again if it fails. It's safe for concurrent use.
+// NewCircuitBreakerPolicy uses one per endpoint; use it directly to fail fast when sending requests without a pipeline
+// or when failures are detected from the results of operations rather than from responses.
+type CircuitBreaker struct {
+	options CircuitBreakerOptions
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool // a request is testing whether the endpoint has recovered
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker with the FailureThreshold and Cooldown of o.
+// Its StatusCodes are only used by NewCircuitBreakerPolicy.
+func NewCircuitBreaker(o CircuitBreakerOptions) *CircuitBreaker {
+	return &CircuitBreaker{options: o.defaults()}
+}
+
+// Allow returns true when a request may be sent to the endpoint, or false and when the next request will be
+// allowed when the circuit is open. Each request that's allowed must be followed by a call to Done.
+func (b *CircuitBreaker) Allow() (bool, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.options.FailureThreshold {
+		return true, time.Time{}
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false, b.openUntil
+	}
+	b.probing = true
+	return true, time.Time{}
+}
+
+// Done records whether a request that was allowed failed. Failures of requests whose ctx is done aren't counted.
+func (b *CircuitBreaker) Done(ctx context.Context, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if failed && ctx.Err() != nil {
+		// the request was cancelled by the caller, which says nothing about the endpoint
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.options.FailureThreshold {
+		b.openUntil = time.Now().Add(b.options.Cooldown)
+	}
+}
+
+type circuitBreakerPolicy struct {
+	options CircuitBreakerOptions
+
+	// mu protects breakers
+	mu sync.Mutex
+	// breakers are the circuit breakers of the endpoints requests were sent to, by endpoint
+	breakers map[string]*CircuitBreaker
+}
+
+// NewCircuitBreakerPolicy creates a policy that counts the consecutive failed requests to each endpoint, its scheme
+// and host, with a CircuitBreaker. Once there are FailureThreshold of them the circuit opens and requests to the
+// endpoint fail fast with a *CircuitOpenError for Cooldown. Then a single request is let through, closing the circuit
+// if it succeeds and opening it again if it fails. Requests cancelled by their context aren't counted. Add the policy
+// to PipelineOptions.PerRetryPolicies so each try is counted, the retry policy doesn't retry a *CircuitOpenError.
+func NewCircuitBreakerPolicy(o CircuitBreakerOptions) Policy {
+	return &circuitBreakerPolicy{options: o.defaults(), breakers: map[string]*CircuitBreaker{}}
+}
+
+func (p *circuitBreakerPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	endpoint := strings.ToLower(req.URL.Scheme + "://" + req.URL.Host)
+	b := p.breaker(endpoint)
+	if ok, retryAfter := b.Allow(); !ok {
+		return nil, &CircuitOpenError{Endpoint: endpoint, RetryAfter: retryAfter}
+	}
+	resp, err := req.Next(ctx)
+	b.Done(ctx, err != nil || resp.HasStatusCode(p.options.StatusCodes...))
+	return resp, err
+}
+
+// breaker returns the circuit breaker of the endpoint.
+func (p *circuitBreakerPolicy) breaker(endpoint string) *CircuitBreaker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.breakers[endpoint]
+	if !ok {
+		b = NewCircuitBreaker(p.options)
+		p.breakers[endpoint] = b
+	}
+	return b
+}
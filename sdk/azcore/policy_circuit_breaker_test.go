@@ -0,0 +1,117 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// statusTransport responds with the status code returned by status, counting the requests it receives
+func statusTransport(requests *int, status func() int) Transport {
+	return TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		*requests++
+		return &http.Response{StatusCode: status(), Header: http.Header{}, Request: req}, nil
+	})
+}
+
+func TestCircuitBreakerPolicy(t *testing.T) {
+	statusCode := http.StatusServiceUnavailable
+	requests := 0
+	pl := NewPipeline(statusTransport(&requests, func() int { return statusCode }),
+		NewCircuitBreakerPolicy(CircuitBreakerOptions{FailureThreshold: 2, Cooldown: 50 * time.Millisecond}))
+	failing, _ := url.Parse("https://failing.contoso.com/path")
+	healthy, _ := url.Parse("https://healthy.contoso.com/path")
+	for i := 0; i < 2; i++ {
+		if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, *failing)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_, err := pl.Do(context.Background(), NewRequest(http.MethodGet, *failing))
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.Endpoint != "https://failing.contoso.com" || !openErr.IsNotRetriable() {
+		t.Fatalf("expected a *CircuitOpenError, got %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected the request to fail fast, got %d requests", requests)
+	}
+	// other endpoints have their own circuits
+	if _, err = pl.Do(context.Background(), NewRequest(http.MethodGet, *healthy)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	// after the cooldown a failed probe opens the circuit again
+	if _, err = pl.Do(context.Background(), NewRequest(http.MethodGet, *failing)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = pl.Do(context.Background(), NewRequest(http.MethodGet, *failing)); !errors.As(err, &openErr) {
+		t.Fatalf("expected a *CircuitOpenError, got %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	// a successful probe closes it
+	statusCode = http.StatusOK
+	for i := 0; i < 3; i++ {
+		if _, err = pl.Do(context.Background(), NewRequest(http.MethodGet, *failing)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestCircuitBreakerPolicyIgnoresCancellation(t *testing.T) {
+	requests := 0
+	transport := TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		requests++
+		return nil, ctx.Err()
+	})
+	pl := NewPipeline(transport, NewCircuitBreakerPolicy(CircuitBreakerOptions{FailureThreshold: 1}))
+	u, _ := url.Parse("https://contoso.com")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		if _, err := pl.Do(ctx, NewRequest(http.MethodGet, *u)); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	}
+	if requests != 3 {
+		t.Fatalf("expected cancelled requests not to open the circuit, got %d requests", requests)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 2, Cooldown: 20 * time.Millisecond})
+	for i := 0; i < 2; i++ {
+		if ok, _ := b.Allow(); !ok {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+		b.Done(context.Background(), true)
+	}
+	ok, retryAfter := b.Allow()
+	if ok || time.Until(retryAfter) <= 0 {
+		t.Fatalf("expected the circuit to be open, got %v, %v", ok, retryAfter)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if ok, _ = b.Allow(); !ok {
+		t.Fatal("expected a probe to be allowed after the cooldown")
+	}
+	if ok, _ = b.Allow(); ok {
+		t.Fatal("expected a single probe at a time")
+	}
+	b.Done(context.Background(), false)
+	if ok, _ = b.Allow(); !ok {
+		t.Fatal("expected a successful probe to close the circuit")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.Done(ctx, true)
+	b.Done(ctx, true)
+	if ok, _ = b.Allow(); !ok {
+		t.Fatal("expected cancelled requests not to open the circuit")
+	}
+}
@@ -0,0 +1,132 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+)
+
+// HeaderXmsContentCRC64 is the header containing the CRC64 of a Storage request's or response's body.
+const HeaderXmsContentCRC64 = "x-ms-content-crc64"
+
+// ContentIntegrityAlgorithm is the hash the content integrity policy attaches to request bodies.
+type ContentIntegrityAlgorithm int
+
+const (
+	// ContentMD5 attaches the MD5 hash of request bodies in the Content-MD5 header.
+	ContentMD5 ContentIntegrityAlgorithm = iota
+
+	// ContentCRC64 attaches the Azure Storage CRC64 of request bodies in the x-ms-content-crc64 header.
+	ContentCRC64
+)
+
+// ErrContentIntegrity is returned when a response's body doesn't match the hash the service sent with it.
+var ErrContentIntegrity = errors.New("the response body doesn't match its content hash")
+
+// crc64Table is the table of the polynomial Azure Storage computes CRC64s with.
+var crc64Table = crc64.MakeTable(0x9A6C9329AC4BC9B5)
+
+// ContentIntegrityOptions configures the content integrity policy's behavior.
+type ContentIntegrityOptions struct {
+	// Algorithm is the hash attached to request bodies. The default is ContentMD5.
+	Algorithm ContentIntegrityAlgorithm
+}
+
+type contentIntegrityPolicy struct {
+	options ContentIntegrityOptions
+}
+
+// NewContentIntegrityPolicy creates a policy that attaches the hash of each request's body, unless the request
+// already has one, and validates the body of each response that has a Content-MD5 or x-ms-content-crc64 header
+// against it. A mismatch fails the request with ErrContentIntegrity or, when the response's body download was
+// skipped, fails reading the end of the body. Request bodies must be seekable, because they're read to compute
+// their hash. Add the policy to PipelineOptions.PerCallPolicies so each request's hash is computed once.
+func NewContentIntegrityPolicy(o ContentIntegrityOptions) Policy {
+	return &contentIntegrityPolicy{options: o}
+}
+
+func (p *contentIntegrityPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	if req.Body != nil && req.Header.Get(HeaderContentMD5) == "" && req.Header.Get(HeaderXmsContentCRC64) == "" {
+		if _, ok := req.Body.(io.Seeker); !ok {
+			return nil, fmt.Errorf("content integrity policy: %w", ErrNonSeekableBody)
+		}
+		header, h := HeaderContentMD5, md5.New()
+		if p.options.Algorithm == ContentCRC64 {
+			header, h = HeaderXmsContentCRC64, crc64.New(crc64Table)
+		}
+		if _, err := io.Copy(h, req.Body); err != nil {
+			return nil, err
+		}
+		if err := req.RewindBody(); err != nil {
+			return nil, err
+		}
+		req.Header.Set(header, encodeContentHash(h))
+	}
+	resp, err := req.Next(ctx)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+	header, h := HeaderContentMD5, md5.New()
+	expected := resp.Header.Get(HeaderContentMD5)
+	if crc := resp.Header.Get(HeaderXmsContentCRC64); crc != "" {
+		header, h, expected = HeaderXmsContentCRC64, crc64.New(crc64Table), crc
+	}
+	if expected == "" {
+		return resp, nil
+	}
+	if payload := resp.payload(); payload != nil {
+		h.Write(payload)
+		if actual := encodeContentHash(h); actual != expected {
+			return resp, fmt.Errorf("%w: the %s header is %s but the body's is %s", ErrContentIntegrity, header, expected, actual)
+		}
+		return resp, nil
+	}
+	// the body's download was skipped, validate it as it's read
+	resp.Body = &contentValidatingReader{body: resp.Body, h: h, header: header, expected: expected}
+	return resp, nil
+}
+
+// encodeContentHash returns the base64 encoding of the hash, the CRC64 in little-endian byte order as Storage expects.
+func encodeContentHash(h hash.Hash) string {
+	if h64, ok := h.(hash.Hash64); ok {
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, h64.Sum64())
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// contentValidatingReader hashes a response's body as it's read and returns ErrContentIntegrity instead of
+// io.EOF when the body doesn't match the expected hash.
+type contentValidatingReader struct {
+	body     io.ReadCloser
+	h        hash.Hash
+	header   string
+	expected string
+}
+
+func (r *contentValidatingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF {
+		if actual := encodeContentHash(r.h); actual != r.expected {
+			return n, fmt.Errorf("%w: the %s header is %s but the body's is %s", ErrContentIntegrity, r.header, r.expected, actual)
+		}
+	}
+	return n, err
+}
+
+func (r *contentValidatingReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,117 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"hash/crc64"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+const testContent = "the quick brown fox jumps over the lazy dog"
+
+func testContentMD5() string {
+	sum := md5.Sum([]byte(testContent))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestContentIntegrityPolicyUpload(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusCreated))
+	for _, algorithm := range []ContentIntegrityAlgorithm{ContentMD5, ContentCRC64} {
+		var body string
+		recordBody := PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			body = string(b)
+			if err = req.RewindBody(); err != nil {
+				return nil, err
+			}
+			return req.Next(ctx)
+		})
+		pl := NewPipeline(srv, NewContentIntegrityPolicy(ContentIntegrityOptions{Algorithm: algorithm}), recordBody)
+		req := NewRequest(http.MethodPut, srv.URL())
+		if err := req.SetBody(NopCloser(strings.NewReader(testContent))); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := pl.Do(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if body != testContent {
+			t.Fatalf("expected the body to be rewound, got %q", body)
+		}
+		switch algorithm {
+		case ContentMD5:
+			if h := resp.Request.Header.Get(HeaderContentMD5); h != testContentMD5() {
+				t.Fatalf("unexpected Content-MD5: %s", h)
+			}
+		case ContentCRC64:
+			h := crc64.New(crc64Table)
+			h.Write([]byte(testContent))
+			if v := resp.Request.Header.Get(HeaderXmsContentCRC64); v != encodeContentHash(h) || len(v) != 12 {
+				t.Fatalf("unexpected x-ms-content-crc64: %s", v)
+			}
+		}
+	}
+}
+
+func TestContentIntegrityPolicyNonSeekableBody(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	pl := NewPipeline(srv, NewContentIntegrityPolicy(ContentIntegrityOptions{}))
+	req := NewRequest(http.MethodPut, srv.URL())
+	req.SetStreamBody(ioutil.NopCloser(strings.NewReader(testContent)), int64(len(testContent)))
+	if _, err := pl.Do(context.Background(), req); !errors.Is(err, ErrNonSeekableBody) {
+		t.Fatalf("expected ErrNonSeekableBody, got %v", err)
+	}
+}
+
+func TestContentIntegrityPolicyDownload(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(testContent)), mock.WithHeader(HeaderContentMD5, testContentMD5()))
+	srv.AppendResponse(mock.WithBody([]byte("tampered")), mock.WithHeader(HeaderContentMD5, testContentMD5()))
+	pl := NewPipeline(srv, NewContentIntegrityPolicy(ContentIntegrityOptions{}))
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); !errors.Is(err, ErrContentIntegrity) {
+		t.Fatalf("expected ErrContentIntegrity, got %v", err)
+	}
+}
+
+func TestContentIntegrityPolicySkippedDownload(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(testContent)), mock.WithHeader(HeaderContentMD5, testContentMD5()))
+	srv.AppendResponse(mock.WithBody([]byte("tampered")), mock.WithHeader(HeaderContentMD5, testContentMD5()))
+	pl := NewPipeline(srv, NewContentIntegrityPolicy(ContentIntegrityOptions{}))
+	for i, expected := range []error{nil, ErrContentIntegrity} {
+		req := NewRequest(http.MethodGet, srv.URL())
+		req.SkipBodyDownload()
+		resp, err := pl.Do(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !errors.Is(err, expected) {
+			t.Fatalf("expected %v reading body %d, got %v", expected, i, err)
+		}
+	}
+}
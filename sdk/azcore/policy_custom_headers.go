@@ -0,0 +1,68 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// used as context keys for adding/retrieving the custom headers and query parameters
+type ctxWithHTTPHeaderKey struct{}
+type ctxWithQueryParametersKey struct{}
+
+// WithHTTPHeader adds the specified headers to the parent context. The custom headers policy sets them on the requests
+// sent with the context, replacing any values the client set, for example x-ms-client-tenant-id or a feature flag
+// header an operation doesn't expose. Headers added by the parent context are kept unless header replaces them.
+func WithHTTPHeader(parent context.Context, header http.Header) context.Context {
+	merged := http.Header{}
+	if h, ok := parent.Value(ctxWithHTTPHeaderKey{}).(http.Header); ok {
+		for k, v := range h {
+			merged[k] = v
+		}
+	}
+	for k, v := range header {
+		merged[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	return context.WithValue(parent, ctxWithHTTPHeaderKey{}, merged)
+}
+
+// WithQueryParameters adds the specified query parameters to the parent context. The custom headers policy sets them
+// in the URLs of the requests sent with the context, replacing any values the client set. Query parameters added by
+// the parent context are kept unless params replaces them.
+func WithQueryParameters(parent context.Context, params url.Values) context.Context {
+	merged := url.Values{}
+	if q, ok := parent.Value(ctxWithQueryParametersKey{}).(url.Values); ok {
+		for k, v := range q {
+			merged[k] = v
+		}
+	}
+	for k, v := range params {
+		merged[k] = append([]string(nil), v...)
+	}
+	return context.WithValue(parent, ctxWithQueryParametersKey{}, merged)
+}
+
+// NewCustomHeadersPolicy creates a policy object that sets the headers and query parameters added to the context by
+// WithHTTPHeader and WithQueryParameters on each request, so that callers can customize an individual operation.
+func NewCustomHeadersPolicy() Policy {
+	return PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		if h, ok := ctx.Value(ctxWithHTTPHeaderKey{}).(http.Header); ok {
+			for k, v := range h {
+				req.Request.Header[k] = append([]string(nil), v...)
+			}
+		}
+		if params, ok := ctx.Value(ctxWithQueryParametersKey{}).(url.Values); ok && len(params) > 0 {
+			q := req.URL.Query()
+			for k, v := range params {
+				q[k] = append([]string(nil), v...)
+			}
+			req.URL.RawQuery = q.Encode()
+		}
+		return req.Next(ctx)
+	})
+}
@@ -0,0 +1,60 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestCustomHeadersPolicy(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	pl := NewPipeline(srv, NewCustomHeadersPolicy())
+	u := srv.URL()
+	u.RawQuery = "api-version=1&keep=yes"
+	req := NewRequest(http.MethodGet, u)
+	req.Header.Set("x-ms-feature", "client")
+	ctx := WithHTTPHeader(context.Background(), http.Header{"x-ms-client-tenant-id": {"tenant"}, "X-Ms-Feature": {"a"}})
+	ctx = WithHTTPHeader(ctx, http.Header{"x-ms-feature": {"b"}})
+	ctx = WithQueryParameters(ctx, url.Values{"api-version": {"2"}, "extra": {"1", "2"}})
+	resp, err := pl.Do(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get("x-ms-client-tenant-id"); v != "tenant" {
+		t.Fatalf("unexpected tenant header %q", v)
+	}
+	if v := resp.Request.Header["X-Ms-Feature"]; !reflect.DeepEqual(v, []string{"b"}) {
+		t.Fatalf("unexpected feature header %v", v)
+	}
+	expected := url.Values{"api-version": {"2"}, "keep": {"yes"}, "extra": {"1", "2"}}
+	if q := resp.Request.URL.Query(); !reflect.DeepEqual(q, expected) {
+		t.Fatalf("unexpected query %v", q)
+	}
+}
+
+func TestCustomHeadersPolicyNoValues(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	pl := NewDefaultPipeline(&PipelineOptions{HTTPClient: srv})
+	u := srv.URL()
+	u.RawQuery = "b=1&a=2"
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, u))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Request.URL.RawQuery != "b=1&a=2" {
+		t.Fatalf("expected the query to be left unchanged, got %s", resp.Request.URL.RawQuery)
+	}
+}
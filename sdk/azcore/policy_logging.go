@@ -17,12 +17,66 @@ import (
 )
 
 // RequestLogOptions configures the retry policy's behavior.
+// The request logging policy logs the URL and headers of requests and responses, never their bodies.
 type RequestLogOptions struct {
 	// LogWarningIfTryOverThreshold logs a warning if a tried operation takes longer than the specified
 	// duration (-1=no logging; 0=default threshold).
 	LogWarningIfTryOverThreshold time.Duration
+
+	// AllowedHeaders are the names of request and response headers whose values are logged, in addition to
+	// common headers such as Content-Type and x-ms-request-id. The values of other headers are logged as
+	// "REDACTED". Clients add the headers that help diagnose their requests and carry no secrets.
+	AllowedHeaders []string
+
+	// AllowedQueryParameters are the names of query parameters whose values are logged, in addition to
+	// "api-version". The values of other query parameters are logged as "REDACTED".
+	AllowedQueryParameters []string
+
+	// RedactedHeaders are the names of request and response headers whose values are logged as "REDACTED",
+	// in addition to Authorization, even when they're allowed. Clients add the headers that carry their secrets.
+	RedactedHeaders []string
+
+	// RedactedQueryParameters are the names of query parameters whose values are logged as "REDACTED",
+	// in addition to "sig", even when they're allowed. Clients add the parameters that carry their secrets.
+	RedactedQueryParameters []string
+}
+
+// defaultAllowedHeaders are the headers whose values are logged by every request logging policy.
+var defaultAllowedHeaders = []string{
+	"Accept",
+	"Cache-Control",
+	"Connection",
+	HeaderContentLength,
+	HeaderContentType,
+	HeaderDate,
+	"ETag",
+	"Expires",
+	HeaderIfMatch,
+	HeaderIfModifiedSince,
+	HeaderIfNoneMatch,
+	HeaderIfUnmodifiedSince,
+	"Last-Modified",
+	"Pragma",
+	"Request-Id",
+	HeaderRetryAfter,
+	HeaderRetryAfterMS,
+	"Server",
+	"traceparent",
+	"Transfer-Encoding",
+	HeaderUserAgent,
+	HeaderWWWAuthenticate,
+	xMsClientRequestID,
+	"x-ms-correlation-request-id",
+	HeaderXmsDate,
+	"x-ms-request-id",
+	HeaderXmsRetryAfterMS,
+	"x-ms-return-client-request-id",
+	HeaderXmsVersion,
 }
 
+// defaultAllowedQueryParameters are the query parameters whose values are logged by every request logging policy.
+var defaultAllowedQueryParameters = []string{"api-version"}
+
 func (o RequestLogOptions) defaults() RequestLogOptions {
 	if o.LogWarningIfTryOverThreshold == 0 {
 		// It would be good to relate this to https://azure.microsoft.com/en-us/support/legal/sla/storage/v1_2/
@@ -34,12 +88,34 @@ func (o RequestLogOptions) defaults() RequestLogOptions {
 
 type requestLogPolicy struct {
 	options RequestLogOptions
+	// allowedHeaders and allowedQueryParameters hold the lowercase names of the headers and query parameters
+	// whose values are logged
+	allowedHeaders         map[string]bool
+	allowedQueryParameters map[string]bool
 }
 
 // NewRequestLogPolicy creates a RequestLogPolicy object configured using the specified options.
 func NewRequestLogPolicy(o RequestLogOptions) Policy {
 	o = o.defaults() // Force defaults to be calculated
-	return &requestLogPolicy{options: o}
+	return &requestLogPolicy{
+		options:                o,
+		allowedHeaders:         allowList(defaultAllowedHeaders, o.AllowedHeaders, append([]string{HeaderAuthorization}, o.RedactedHeaders...)),
+		allowedQueryParameters: allowList(defaultAllowedQueryParameters, o.AllowedQueryParameters, append([]string{"sig"}, o.RedactedQueryParameters...)),
+	}
+}
+
+// allowList returns the lowercase names that are either in defaults or allowed, and not redacted.
+func allowList(defaults []string, allowed []string, redacted []string) map[string]bool {
+	names := map[string]bool{}
+	for _, list := range [][]string{defaults, allowed} {
+		for _, name := range list {
+			names[strings.ToLower(name)] = true
+		}
+	}
+	for _, name := range redacted {
+		delete(names, strings.ToLower(name))
+	}
+	return names
 }
 
 // logPolicyOpValues is the struct containing the per-operation values
@@ -61,7 +137,7 @@ func (p *requestLogPolicy) Do(ctx context.Context, req *Request) (*Response, err
 	if Log().Should(LogRequest) {
 		b := &bytes.Buffer{}
 		fmt.Fprintf(b, "==> OUTGOING REQUEST (Try=%d)\n", opValues.try)
-		WriteRequestWithResponse(b, prepareRequestForLogging(req), nil, nil)
+		WriteRequestWithResponse(b, p.prepareRequestForLogging(req), nil, nil)
 		Log().Write(LogRequest, b.String())
 	}
 
@@ -109,7 +185,7 @@ func (p *requestLogPolicy) Do(ctx context.Context, req *Request) (*Response, err
 			}
 		}
 
-		WriteRequestWithResponse(b, prepareRequestForLogging(req), response, err)
+		WriteRequestWithResponse(b, p.prepareRequestForLogging(req), p.prepareResponseForLogging(response), err)
 		if logClass == LogError {
 			b.Write(stack()) // For errors (or lower levels), we append the stack trace (an expensive operation)
 		}
@@ -138,16 +214,38 @@ func RedactSigQueryParam(rawQuery string) (bool, string) {
 	return sigFound, values.Encode()
 }
 
-func prepareRequestForLogging(req *Request) *Request {
-	request := req
-	if sigFound, rawQuery := RedactSigQueryParam(request.URL.RawQuery); sigFound {
-		// Make copy so we don't destroy the query parameters we actually need to send in the request
-		request = req.copy()
-		request.URL.RawQuery = rawQuery
+func (p *requestLogPolicy) prepareRequestForLogging(req *Request) *Request {
+	// copy the request so the values actually sent aren't redacted
+	request := req.copy()
+	if request.URL.RawQuery != "" {
+		qp := request.URL.Query()
+		redactValues(qp, p.allowedQueryParameters)
+		request.URL.RawQuery = qp.Encode()
 	}
+	redactValues(request.Header, p.allowedHeaders)
 	return request
 }
 
+func (p *requestLogPolicy) prepareResponseForLogging(resp *Response) *Response {
+	if resp == nil {
+		return nil
+	}
+	// copy the response so the caller still receives the header values
+	logged := *resp.Response
+	logged.Header = resp.Header.Clone()
+	redactValues(logged.Header, p.allowedHeaders)
+	return &Response{Response: &logged}
+}
+
+// redactValues replaces the values whose names aren't in allowed, which holds lowercase names, with "REDACTED".
+func redactValues(values map[string][]string, allowed map[string]bool) {
+	for k := range values {
+		if !allowed[strings.ToLower(k)] {
+			values[k] = []string{"REDACTED"}
+		}
+	}
+}
+
 func stack() []byte {
 	buf := make([]byte, 1024)
 	for {
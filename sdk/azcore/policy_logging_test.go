@@ -116,3 +116,88 @@ func TestPolicyLoggingError(t *testing.T) {
 }
 
 // TODO: add test for slow response
+
+func TestPolicyLoggingRedaction(t *testing.T) {
+	log := map[LogClassification]string{}
+	Log().SetListener(func(cls LogClassification, s string) {
+		log[cls] = s
+	})
+	defer Log().SetListener(nil)
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithHeader("X-Secret", "response-secret"))
+	// redaction takes precedence over the allow-lists
+	pl := NewPipeline(srv, NewRequestLogPolicy(RequestLogOptions{
+		AllowedHeaders:          []string{"X-Secret"},
+		AllowedQueryParameters:  []string{"one", "client_secret"},
+		RedactedHeaders:         []string{"x-secret"},
+		RedactedQueryParameters: []string{"client_secret"},
+	}))
+	req := NewRequest(http.MethodGet, srv.URL())
+	qp := req.URL.Query()
+	qp.Set("one", "fish")
+	qp.Set("client_secret", "query-secret")
+	req.URL.RawQuery = qp.Encode()
+	req.Header.Set("X-Secret", "request-secret")
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, cls := range []LogClassification{LogRequest, LogResponse} {
+		msg, ok := log[cls]
+		if !ok {
+			t.Fatalf("missing %s", cls)
+		}
+		if strings.Contains(msg, "secret=query-secret") || strings.Contains(msg, "request-secret") || strings.Contains(msg, "response-secret") {
+			t.Fatalf("secret logged: %s", msg)
+		}
+		if !strings.Contains(msg, "one=fish") || !strings.Contains(msg, "client_secret=REDACTED") || !strings.Contains(msg, "X-Secret: [REDACTED]") {
+			t.Fatalf("expected redacted values: %s", msg)
+		}
+	}
+	// the request sent and the response returned keep their values
+	if req.Header.Get("X-Secret") != "request-secret" || req.URL.Query().Get("client_secret") != "query-secret" {
+		t.Fatal("the request was modified")
+	}
+	if resp.Header.Get("X-Secret") != "response-secret" {
+		t.Fatal("the response was modified")
+	}
+}
+
+func TestPolicyLoggingAllowLists(t *testing.T) {
+	log := map[LogClassification]string{}
+	Log().SetListener(func(cls LogClassification, s string) {
+		log[cls] = s
+	})
+	defer Log().SetListener(nil)
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithHeader("x-ms-request-id", "request-id"), mock.WithHeader("X-Custom", "custom"), mock.WithHeader("X-Unknown", "unknown"))
+	pl := NewPipeline(srv, NewRequestLogPolicy(RequestLogOptions{
+		AllowedHeaders:         []string{"x-custom"},
+		AllowedQueryParameters: []string{"Comp"},
+	}))
+	req := NewRequest(http.MethodGet, srv.URL())
+	qp := req.URL.Query()
+	qp.Set("api-version", "2020-01-01")
+	qp.Set("comp", "list")
+	qp.Set("se", "2030-01-01")
+	req.URL.RawQuery = qp.Encode()
+	req.Header.Set(HeaderContentType, "application/json")
+	req.Header.Set("X-Unknown", "unknown")
+	if _, err := pl.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg, ok := log[LogResponse]
+	if !ok {
+		t.Fatal("missing LogResponse")
+	}
+	for _, logged := range []string{"api-version=2020-01-01", "comp=list", "se=REDACTED", "Content-Type: [application/json]", "X-Ms-Request-Id: [request-id]", "X-Custom: [custom]"} {
+		if !strings.Contains(msg, logged) {
+			t.Fatalf("expected %q in the log: %s", logged, msg)
+		}
+	}
+	if strings.Contains(msg, "unknown") {
+		t.Fatalf("expected headers that aren't allowed to be redacted: %s", msg)
+	}
+}
@@ -0,0 +1,122 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestMetrics are the metrics of a request sent through a pipeline, reported by the metrics policy.
+type RequestMetrics struct {
+	// Method is the request's HTTP method.
+	Method string
+
+	// URL is the request's URL without its query, which may contain secrets.
+	URL string
+
+	// Tries is how many times the request was sent.
+	Tries int
+
+	// TryDurations is how long each try took, from sending the request to receiving the response's headers, or
+	// its body when the body was downloaded by the pipeline.
+	TryDurations []time.Duration
+
+	// Duration is how long the request took, including the delays between tries.
+	Duration time.Duration
+
+	// BytesSent is the size of the request's body, counted once per try. Bodies of unknown size aren't counted.
+	BytesSent int64
+
+	// BytesReceived is the size of the final response's body, or -1 when it's unknown because the body's
+	// download was skipped and the service didn't send its length.
+	BytesReceived int64
+
+	// StatusCode is the final response's status code, zero when no response was received.
+	StatusCode int
+
+	// Err is the error returned by the pipeline, if any.
+	Err error
+}
+
+// MetricsOptions configures the metrics policy's behavior.
+type MetricsOptions struct {
+	// Callback is called with the metrics of each request once it completes, from the goroutine that sent it.
+	// Use it to feed a metrics system such as OpenTelemetry.
+	Callback func(RequestMetrics)
+}
+
+type metricsPolicy struct {
+	options MetricsOptions
+}
+
+// metricsPolicyOpValues carries the metrics of the request to the retry policy, which records each try
+type metricsPolicyOpValues struct {
+	metrics *requestMetrics
+}
+
+// requestMetrics collects the tries of a request
+type requestMetrics struct {
+	mu           sync.Mutex
+	tryDurations []time.Duration
+	bytesSent    int64
+}
+
+// recordTry records a try that took d and sent a body of contentLength bytes, -1 when its size is unknown.
+func (m *requestMetrics) recordTry(d time.Duration, contentLength int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tryDurations = append(m.tryDurations, d)
+	if contentLength > 0 {
+		m.bytesSent += contentLength
+	}
+}
+
+// NewMetricsPolicy creates a policy that reports the metrics of each request to o.Callback: its tries, the
+// duration of each try, the bytes sent and received and the final status. The policy must precede the retry
+// policy, which records each try; NewDefaultPipeline adds it when PipelineOptions.Metrics.Callback is set.
+func NewMetricsPolicy(o MetricsOptions) Policy {
+	return &metricsPolicy{options: o}
+}
+
+func (p *metricsPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	if p.options.Callback == nil {
+		return req.Next(ctx)
+	}
+	u := *req.URL
+	u.RawQuery, u.User = "", nil
+	rm := RequestMetrics{Method: req.Method, URL: u.String(), BytesReceived: -1}
+	m := &requestMetrics{}
+	req.SetOperationValue(metricsPolicyOpValues{metrics: m})
+	start := time.Now()
+	resp, err := req.Next(ctx)
+	rm.Duration = time.Since(start)
+	m.mu.Lock()
+	if len(m.tryDurations) == 0 {
+		// there's no retry policy, the request was sent once
+		m.tryDurations = append(m.tryDurations, rm.Duration)
+		if req.ContentLength > 0 {
+			m.bytesSent = req.ContentLength
+		}
+	}
+	rm.Tries, rm.TryDurations, rm.BytesSent = len(m.tryDurations), m.tryDurations, m.bytesSent
+	m.mu.Unlock()
+	rm.Err = err
+	if resp != nil {
+		rm.StatusCode = resp.StatusCode
+		if buf, ok := resp.Body.(*nopClosingBytesReader); ok {
+			rm.BytesReceived = int64(len(buf.Bytes()))
+		} else if resp.Body == nil || resp.Body == http.NoBody {
+			rm.BytesReceived = 0
+		} else {
+			rm.BytesReceived = resp.ContentLength
+		}
+	}
+	p.options.Callback(rm)
+	return resp, err
+}
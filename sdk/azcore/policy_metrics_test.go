@@ -0,0 +1,66 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestMetricsPolicyRetries(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte("content")))
+	var metrics []RequestMetrics
+	pl := NewDefaultPipeline(&PipelineOptions{
+		HTTPClient: srv,
+		Retry:      testRetryOptions(),
+		Metrics:    MetricsOptions{Callback: func(m RequestMetrics) { metrics = append(metrics, m) }},
+	})
+	u := srv.URL()
+	u.RawQuery = "sig=secret"
+	req := NewRequest(http.MethodPut, u)
+	if err := req.SetBody(NopCloser(strings.NewReader("body"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pl.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(metrics) != 1 {
+		t.Fatalf("expected metrics for one request, got %d", len(metrics))
+	}
+	m := metrics[0]
+	if m.Method != http.MethodPut || strings.Contains(m.URL, "secret") || m.StatusCode != http.StatusOK || m.Err != nil {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+	if m.Tries != 2 || len(m.TryDurations) != 2 || m.Duration < m.TryDurations[0]+m.TryDurations[1] {
+		t.Fatalf("unexpected tries: %+v", m)
+	}
+	if m.BytesSent != 8 || m.BytesReceived != 7 {
+		t.Fatalf("unexpected bytes sent %d and received %d", m.BytesSent, m.BytesReceived)
+	}
+}
+
+func TestMetricsPolicyWithoutRetryPolicy(t *testing.T) {
+	var metrics []RequestMetrics
+	transport := TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return nil, context.DeadlineExceeded
+	})
+	pl := NewPipeline(transport, NewMetricsPolicy(MetricsOptions{Callback: func(m RequestMetrics) { metrics = append(metrics, m) }}))
+	u, _ := url.Parse("https://contoso.com")
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, *u)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(metrics) != 1 || metrics[0].Tries != 1 || metrics[0].StatusCode != 0 || metrics[0].Err != context.DeadlineExceeded || metrics[0].BytesReceived != -1 {
+		t.Fatalf("unexpected metrics: %+v", metrics)
+	}
+}
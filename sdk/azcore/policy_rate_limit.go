@@ -0,0 +1,103 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitOptions configures the rate limiting policy's behavior.
+type RateLimitOptions struct {
+	// RequestsPerSecond is the sustained rate at which requests are sent.
+	// Leave this as zero to not limit the rate.
+	RequestsPerSecond float64
+
+	// Burst is how many requests can be sent at once after a quiet period, before the rate applies.
+	// The default is 1.
+	Burst int
+
+	// MaxConcurrentRequests limits how many requests are in flight at once.
+	// Leave this as zero to not limit concurrency.
+	MaxConcurrentRequests int
+}
+
+type rateLimitPolicy struct {
+	// mu protects tokens and last
+	mu sync.Mutex
+	// tokens is how many requests can be sent now, the bucket holds up to burst
+	tokens float64
+	// last is when tokens was last refilled
+	last time.Time
+
+	// the following fields are read-only
+	rate  float64
+	burst float64
+	// inFlight has a slot for each request allowed to be in flight, it's nil when concurrency isn't limited
+	inFlight chan struct{}
+}
+
+// NewRateLimitPolicy creates a policy that holds back requests so they're sent no faster than o allows, using a
+// token bucket that refills at RequestsPerSecond and holds up to Burst requests. Waiting stops with an error when
+// the request's context is done. Add the policy to PipelineOptions.PerRetryPolicies so retries are limited too,
+// and share one policy among the clients whose combined traffic must stay under the service's limits.
+// A request stops counting against MaxConcurrentRequests when its response is returned, before its body is read
+// when body downloading was skipped.
+func NewRateLimitPolicy(o RateLimitOptions) Policy {
+	burst := float64(o.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	p := &rateLimitPolicy{rate: o.RequestsPerSecond, burst: burst, tokens: burst, last: time.Now()}
+	if o.MaxConcurrentRequests > 0 {
+		p.inFlight = make(chan struct{}, o.MaxConcurrentRequests)
+	}
+	return p
+}
+
+func (p *rateLimitPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	if p.inFlight != nil {
+		select {
+		case p.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-p.inFlight }()
+	}
+	return req.Next(ctx)
+}
+
+// wait takes a token from the bucket, waiting for one when it's empty.
+func (p *rateLimitPolicy) wait(ctx context.Context) error {
+	if p.rate <= 0 {
+		return nil
+	}
+	for {
+		p.mu.Lock()
+		now := time.Now()
+		p.tokens += now.Sub(p.last).Seconds() * p.rate
+		if p.tokens > p.burst {
+			p.tokens = p.burst
+		}
+		p.last = now
+		if p.tokens >= 1 {
+			p.tokens--
+			p.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - p.tokens) / p.rate * float64(time.Second))
+		p.mu.Unlock()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
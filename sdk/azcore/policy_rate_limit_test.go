@@ -0,0 +1,83 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// okTransport responds to every request with a 200 after delay, recording the most requests in flight at once
+func okTransport(delay time.Duration, inFlight, maxInFlight *int32) Transport {
+	return TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			max := atomic.LoadInt32(maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Request: req}, nil
+	})
+}
+
+func TestRateLimitPolicyRate(t *testing.T) {
+	var inFlight, maxInFlight int32
+	pl := NewPipeline(okTransport(0, &inFlight, &maxInFlight), NewRateLimitPolicy(RateLimitOptions{RequestsPerSecond: 50, Burst: 5}))
+	u, _ := url.Parse("https://contoso.com")
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, *u)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// the first 5 requests are sent at once, the following 5 at 50 per second
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("requests weren't held back, they took %v", elapsed)
+	}
+}
+
+func TestRateLimitPolicyConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	pl := NewPipeline(okTransport(10*time.Millisecond, &inFlight, &maxInFlight), NewRateLimitPolicy(RateLimitOptions{MaxConcurrentRequests: 2}))
+	u, _ := url.Parse("https://contoso.com")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, *u)); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight != 2 {
+		t.Fatalf("expected at most 2 requests in flight, got %d", maxInFlight)
+	}
+}
+
+func TestRateLimitPolicyContextDone(t *testing.T) {
+	var inFlight, maxInFlight int32
+	pl := NewPipeline(okTransport(0, &inFlight, &maxInFlight), NewRateLimitPolicy(RateLimitOptions{RequestsPerSecond: 0.01}))
+	u, _ := url.Parse("https://contoso.com")
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, *u)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pl.Do(ctx, NewRequest(http.MethodGet, *u)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to end with the context, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// RedactionOptions configures the secret redaction policy's behavior.
+type RedactionOptions struct {
+	// Headers are the names of the request headers whose values are secrets.
+	Headers []string
+
+	// Fields are the names of the application/x-www-form-urlencoded fields and top-level application/json
+	// properties of request bodies whose values are secrets.
+	Fields []string
+}
+
+type redactionPolicy struct {
+	headers []string // canonical names
+	fields  []string
+}
+
+// redactionOpValues holds the secrets the redaction policy removed from a request, which the transport restores.
+type redactionOpValues struct {
+	headers http.Header
+	body    []byte
+}
+
+// NewRedactionPolicy creates a policy that replaces the values of secret headers and body fields of each request
+// with "REDACTED", so that the policies that follow it, such as logging policies that log request bodies, never see
+// the secrets. The pipeline restores the secrets just before its transport sends the request. Bodies are redacted
+// when they're seekable application/x-www-form-urlencoded or application/json documents. Add the policy to
+// PipelineOptions.PerCallPolicies, before the policies that must see the secrets, such as the content integrity policy.
+func NewRedactionPolicy(o RedactionOptions) Policy {
+	p := &redactionPolicy{fields: o.Fields}
+	for _, h := range o.Headers {
+		p.headers = append(p.headers, http.CanonicalHeaderKey(h))
+	}
+	return p
+}
+
+func (p *redactionPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	var secrets redactionOpValues
+	if req.OperationValue(&secrets) {
+		// the request was redacted by an earlier try
+		return req.Next(ctx)
+	}
+	// copy the request so the caller's request keeps its secrets
+	redacted := *req.Request
+	redacted.Header = req.Header.Clone()
+	for _, name := range p.headers {
+		if v, ok := redacted.Header[name]; ok {
+			if secrets.headers == nil {
+				secrets.headers = http.Header{}
+			}
+			secrets.headers[name] = v
+			redacted.Header[name] = []string{"REDACTED"}
+		}
+	}
+	body, err := p.redactBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		secrets.body = body.secret
+		redacted.Body = NopCloser(bytes.NewReader(body.redacted))
+		redacted.ContentLength = int64(len(body.redacted))
+		redacted.GetBody = nil
+	}
+	if secrets.headers == nil && secrets.body == nil {
+		return req.Next(ctx)
+	}
+	req.Request = &redacted
+	req.SetOperationValue(secrets)
+	return req.Next(ctx)
+}
+
+type redactedBody struct {
+	secret   []byte
+	redacted []byte
+}
+
+// redactBody returns the request's body with its secret fields redacted, or nil when it has none.
+func (p *redactionPolicy) redactBody(req *Request) (*redactedBody, error) {
+	if len(p.fields) == 0 || req.Body == nil {
+		return nil, nil
+	}
+	if _, ok := req.Body.(io.Seeker); !ok {
+		return nil, nil
+	}
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get(HeaderContentType))
+	if contentType != HeaderURLEncoded && contentType != contentTypeAppJSON {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.RewindBody(); err != nil {
+		return nil, err
+	}
+	var redacted []byte
+	if contentType == HeaderURLEncoded {
+		redacted = p.redactForm(b)
+	} else {
+		redacted = p.redactJSON(b)
+	}
+	if redacted == nil {
+		return nil, nil
+	}
+	return &redactedBody{secret: b, redacted: redacted}, nil
+}
+
+// redactForm returns the form with its secret fields redacted, or nil when it has none or can't be parsed.
+func (p *redactionPolicy) redactForm(b []byte) []byte {
+	form, err := url.ParseQuery(string(b))
+	if err != nil {
+		return nil
+	}
+	found := false
+	for _, name := range p.fields {
+		if v, ok := form[name]; ok {
+			found = true
+			for i := range v {
+				v[i] = "REDACTED"
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	return []byte(form.Encode())
+}
+
+// redactJSON returns the JSON object with its secret properties redacted, or nil when it has none or isn't an object.
+func (p *redactionPolicy) redactJSON(b []byte) []byte {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(b, &object); err != nil {
+		return nil
+	}
+	found := false
+	for _, name := range p.fields {
+		if _, ok := object[name]; ok {
+			found = true
+			object[name] = json.RawMessage(`"REDACTED"`)
+		}
+	}
+	if !found {
+		return nil
+	}
+	// marshalling raw messages that were just unmarshalled can't fail
+	redacted, _ := json.Marshal(object)
+	return redacted
+}
+
+// restore returns a copy of req, the request redacted by the redaction policy, with its secrets.
+func (v redactionOpValues) restore(req *http.Request) *http.Request {
+	restored := *req
+	restored.Header = req.Header.Clone()
+	for name, values := range v.headers {
+		restored.Header[name] = values
+	}
+	if v.body != nil {
+		restored.Body = ioutil.NopCloser(bytes.NewReader(v.body))
+		restored.ContentLength = int64(len(v.body))
+	}
+	return &restored
+}
@@ -0,0 +1,135 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bodyLogPolicy records the headers and body of each request it sees, like a logging policy that logs bodies.
+type bodyLogPolicy struct {
+	logged []string
+}
+
+func (p *bodyLogPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	entry := req.Header.Get("x-secret")
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err = req.RewindBody(); err != nil {
+			return nil, err
+		}
+		entry += " " + string(b)
+	}
+	p.logged = append(p.logged, entry)
+	return req.Next(ctx)
+}
+
+func TestRedactionPolicy(t *testing.T) {
+	u, _ := url.Parse("https://localhost/token")
+	for _, test := range []struct {
+		name     string
+		setBody  func(req *Request) error
+		sentBody string
+		logged   string
+	}{
+		{
+			name: "form",
+			setBody: func(req *Request) error {
+				return req.SetFormData(url.Values{"client_secret": {"s3cret"}, "scope": {"x"}})
+			},
+			sentBody: "client_secret=s3cret&scope=x",
+			logged:   "REDACTED client_secret=REDACTED&scope=x",
+		},
+		{
+			name: "json",
+			setBody: func(req *Request) error {
+				return req.MarshalAsJSON(map[string]string{"client_secret": "s3cret", "scope": "x"})
+			},
+			sentBody: `{"client_secret":"s3cret","scope":"x"}`,
+			logged:   `REDACTED {"client_secret":"REDACTED","scope":"x"}`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var sent []string
+			transport := TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				b, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				sent = append(sent, req.Header.Get("x-secret")+" "+string(b))
+				status := http.StatusOK
+				if len(sent) == 1 {
+					status = http.StatusServiceUnavailable
+				}
+				return &http.Response{StatusCode: status, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+			})
+			logger := &bodyLogPolicy{}
+			retry := DefaultRetryOptions()
+			retry.RetryDelay = time.Millisecond
+			pl := NewPipeline(transport,
+				NewRedactionPolicy(RedactionOptions{Headers: []string{"X-SECRET"}, Fields: []string{"client_secret"}}),
+				NewRetryPolicy(&retry),
+				logger)
+			req := NewRequest(http.MethodPost, *u)
+			req.Header.Set("x-secret", "h3ader")
+			if err := test.setBody(req); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := pl.Do(context.Background(), req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(sent) != 2 {
+				t.Fatalf("expected a retry, got %d tries", len(sent))
+			}
+			for _, s := range sent {
+				if s != "h3ader "+test.sentBody {
+					t.Fatalf("expected the secrets to be sent, got %q", s)
+				}
+			}
+			for _, l := range logger.logged {
+				if l != test.logged {
+					t.Fatalf("expected the secrets to be redacted, got %q", l)
+				}
+			}
+			if v := resp.Request.Header.Get("x-secret"); v != "REDACTED" {
+				t.Fatalf("expected the response's request to be redacted, got %q", v)
+			}
+			if v := req.Header.Get("x-secret"); v != "h3ader" {
+				t.Fatalf("expected the caller's request to keep its secrets, got %q", v)
+			}
+		})
+	}
+}
+
+func TestRedactionPolicyNoSecrets(t *testing.T) {
+	u, _ := url.Parse("https://localhost")
+	var sent *http.Request
+	transport := TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	pl := NewPipeline(transport, NewRedactionPolicy(RedactionOptions{Headers: []string{"x-secret"}, Fields: []string{"client_secret"}}))
+	req := NewRequest(http.MethodPost, *u)
+	if err := req.SetBody(NopCloser(strings.NewReader("client_secret=plain text"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pl.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != req.Request {
+		t.Fatalf("expected a request without a form or JSON body or secret headers to be sent unchanged")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"net/http"
 	"time"
@@ -16,6 +17,11 @@ import (
 
 const (
 	defaultMaxRetries = 3
+
+	defaultRetryMultiplier = 2
+
+	// maxRetryJitter keeps a randomized delay at no less than a tenth of the computed one
+	maxRetryJitter = 0.9
 )
 
 // RetryOptions configures the retry policy's behavior.
@@ -41,6 +47,22 @@ type RetryOptions struct {
 	// StatusCodes specifies the HTTP status codes that indicate the operation should be retried.
 	// If unspecified it will default to the status codes in StatusCodesForRetry.
 	StatusCodes []int
+
+	// Multiplier is the factor by which the delay grows with each retry: the delay before retry n is
+	// (Multiplier^n - 1) * RetryDelay, up to MaxRetryDelay. It must be greater than 1; zero means 2.
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to the specified fraction of it in either direction, so that clients
+	// failing at the same time don't retry at the same time. For example, 0.5 picks a delay between half and
+	// one and a half times the computed one. Values greater than 0.9 are treated as 0.9, so a delay is never
+	// less than a tenth of the computed one. Specify a negative value to disable jitter.
+	// If unspecified, each delay is between 0.8 and 1.3 times the computed one.
+	Jitter float64
+
+	// MaxElapsedTime limits the total time spent on an operation, including its tries and the delays between them.
+	// A try still in progress when MaxElapsedTime is reached fails with context.DeadlineExceeded, and a retry
+	// whose delay would end after MaxElapsedTime isn't attempted. If unspecified there is no limit.
+	MaxElapsedTime time.Duration
 }
 
 var (
@@ -76,22 +98,25 @@ func WithRetryOptions(parent context.Context, options RetryOptions) context.Cont
 }
 
 func (o RetryOptions) calcDelay(try int32) time.Duration { // try is >=1; never 0
-	pow := func(number int64, exponent int32) int64 { // pow is nested helper function
-		var result int64 = 1
-		for n := int32(0); n < exponent; n++ {
-			result *= number
-		}
-		return result
+	multiplier := o.Multiplier
+	if multiplier <= 1 {
+		multiplier = defaultRetryMultiplier
 	}
-
-	delay := time.Duration(pow(2, try)-1) * o.RetryDelay
-
-	// Introduce some jitter:  [0.0, 1.0) / 2 = [0.0, 0.5) + 0.8 = [0.8, 1.3)
-	delay = time.Duration(delay.Seconds() * (rand.Float64()/2 + 0.8) * float64(time.Second)) // NOTE: We want math/rand; not crypto/rand
-	if delay > o.MaxRetryDelay {
-		delay = o.MaxRetryDelay
+	delay := (math.Pow(multiplier, float64(try)) - 1) * float64(o.RetryDelay)
+
+	// NOTE: We want math/rand; not crypto/rand
+	if o.Jitter > 0 {
+		// [0.0, 1.0) * 2 * jitter = [0.0, 2 * jitter) + 1 - jitter = [1 - jitter, 1 + jitter)
+		jitter := math.Min(o.Jitter, maxRetryJitter)
+		delay *= rand.Float64()*2*jitter + 1 - jitter
+	} else if o.Jitter == 0 {
+		// Introduce some jitter:  [0.0, 1.0) / 2 = [0.0, 0.5) + 0.8 = [0.8, 1.3)
+		delay *= rand.Float64()/2 + 0.8
 	}
-	return delay
+	if delay > float64(o.MaxRetryDelay) {
+		return o.MaxRetryDelay
+	}
+	return time.Duration(delay)
 }
 
 // NewRetryPolicy creates a policy object configured using the specified options.
@@ -109,6 +134,12 @@ type retryPolicy struct {
 	options RetryOptions
 }
 
+// retryPolicyOpValues is the struct containing the per-operation values, which the policies
+// following the retry policy read to learn which try of the request they're sending
+type retryPolicyOpValues struct {
+	try int32
+}
+
 func (p *retryPolicy) Do(ctx context.Context, req *Request) (resp *Response, err error) {
 	options := p.options
 	// check if the retry options have been overridden for this call
@@ -117,18 +148,25 @@ func (p *retryPolicy) Do(ctx context.Context, req *Request) (resp *Response, err
 	}
 	// Exponential retry algorithm: ((2 ^ attempt) - 1) * delay * random(0.8, 1.2)
 	// When to retry: connection failure or temporary/timeout.
-	if req.Body != nil {
+	// a body that can't be rewound is sent once, retrying would send whatever remains of it
+	seekable := true
+	if body, ok := req.Body.(ReadSeekCloser); ok {
 		// wrap the body so we control when it's actually closed
-		rwbody := &retryableRequestBody{body: req.Body.(ReadSeekCloser)}
+		rwbody := &retryableRequestBody{body: body}
 		req.Body = rwbody
 		req.Request.GetBody = func() (io.ReadCloser, error) {
 			_, err := rwbody.Seek(0, io.SeekStart) // Seek back to the beginning of the stream
 			return rwbody, err
 		}
 		defer rwbody.realClose()
+	} else if req.Body != nil {
+		seekable = false
 	}
+	start := time.Now()
 	try := int32(1)
 	shouldLog := Log().Should(LogRetryPolicy)
+	var metrics metricsPolicyOpValues
+	req.OperationValue(&metrics)
 	for {
 		resp = nil // reset
 		if shouldLog {
@@ -138,20 +176,33 @@ func (p *retryPolicy) Do(ctx context.Context, req *Request) (resp *Response, err
 		// For each try, seek to the beginning of the Body stream. We do this even for the 1st try because
 		// the stream may not be at offset 0 when we first get it and we want the same behavior for the
 		// 1st try as for additional tries.
-		err = req.RewindBody()
-		if err != nil {
-			return
+		if seekable {
+			err = req.RewindBody()
+			if err != nil {
+				return
+			}
 		}
 
+		req.SetOperationValue(retryPolicyOpValues{try: try})
+
 		// Set the per-try time for this particular retry operation and then Do the operation.
-		tryCtx, tryCancel := context.WithTimeout(ctx, options.TryTimeout)
+		// The try ends no later than the operation's time limit.
+		tryTimeout := options.TryTimeout
+		if remaining := options.MaxElapsedTime - time.Since(start); options.MaxElapsedTime > 0 && remaining < tryTimeout {
+			tryTimeout = remaining
+		}
+		tryCtx, tryCancel := context.WithTimeout(ctx, tryTimeout)
+		tryStart := time.Now()
 		resp, err = req.Next(tryCtx) // Make the request
-		if req.bodyDownloadEnabled() || err != nil || resp.Body == nil {
+		if metrics.metrics != nil {
+			metrics.metrics.recordTry(time.Since(tryStart), req.ContentLength)
+		}
+		if err != nil || req.bodyDownloadEnabled(ctx, resp) || resp.Body == nil {
 			// immediately cancel the per-try timeout if any of the following are true
-			// 1.  auto-download of the response body is enabled
-			// 2.  an error was returned
+			// 1.  an error was returned
+			// 2.  auto-download of the response body is enabled
 			// 3.  there is no response body
-			// note that we have to check 2 before 3 as if 2 is true then we can't touch resp
+			// note that we have to check 1 before 3 as if 1 is true then we can't touch resp
 			tryCancel()
 		} else {
 			// wrap the response body in a responseBodyReader.
@@ -171,6 +222,12 @@ func (p *retryPolicy) Do(ctx context.Context, req *Request) (resp *Response, err
 		} else if retrier, ok := err.(Retrier); ok && retrier.IsNotRetriable() {
 			// the error says it's not retriable so don't retry
 			return
+		} else if !seekable {
+			// the body can't be sent again
+			if shouldLog {
+				Log().Write(LogRetryPolicy, "The request body isn't seekable so the request won't be retried\n")
+			}
+			return
 		}
 
 		// drain before retrying so nothing is leaked
@@ -186,6 +243,13 @@ func (p *retryPolicy) Do(ctx context.Context, req *Request) (resp *Response, err
 		if delay <= 0 {
 			delay = options.calcDelay(try)
 		}
+		if options.MaxElapsedTime > 0 && time.Since(start)+delay > options.MaxElapsedTime {
+			// the retry would end after the operation's time limit, don't sleep again
+			if shouldLog {
+				Log().Write(LogRetryPolicy, fmt.Sprintf("Try=%d, Delay=%v exceeds MaxElapsedTime=%v\n", try, delay, options.MaxElapsedTime))
+			}
+			return
+		}
 		if shouldLog {
 			Log().Write(LogRetryPolicy, fmt.Sprintf("Try=%d, Delay=%v\n", try, delay))
 		}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"testing"
@@ -426,3 +427,118 @@ func (r *rewindTrackingBody) Seek(offset int64, whence int) (int64, error) {
 	}
 	return r.body.Seek(offset, whence)
 }
+
+func TestRetryOptionsCalcDelay(t *testing.T) {
+	o := RetryOptions{RetryDelay: time.Second, MaxRetryDelay: time.Hour, Multiplier: 3, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		// (3^2 - 1) * 1s = 8s, randomized by up to half of it
+		if d := o.calcDelay(2); d < 4*time.Second || d >= 12*time.Second {
+			t.Fatalf("delay %v outside [4s, 12s)", d)
+		}
+	}
+	o = RetryOptions{RetryDelay: time.Second, MaxRetryDelay: time.Hour}
+	for i := 0; i < 100; i++ {
+		// (2^2 - 1) * 1s = 3s, randomized by the default jitter
+		if d := o.calcDelay(2); d < 2400*time.Millisecond || d >= 3900*time.Millisecond {
+			t.Fatalf("delay %v outside [2.4s, 3.9s)", d)
+		}
+	}
+	o.Jitter = -1
+	if d := o.calcDelay(2); d != 3*time.Second {
+		t.Fatalf("expected no jitter, got %v", d)
+	}
+	o.Jitter = 1
+	for i := 0; i < 100; i++ {
+		// the jitter is clamped to 0.9, so the delay is at least 0.3s
+		if d := o.calcDelay(2); d < 300*time.Millisecond || d >= 5700*time.Millisecond {
+			t.Fatalf("delay %v outside [0.3s, 5.7s)", d)
+		}
+	}
+	o.MaxRetryDelay = 5 * time.Second
+	if d := o.calcDelay(62); d != o.MaxRetryDelay {
+		t.Fatalf("expected the delay to be capped at %v, got %v", o.MaxRetryDelay, d)
+	}
+}
+
+func TestRetryPolicyMaxElapsedTime(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusInternalServerError))
+	opts := testRetryOptions()
+	opts.MaxRetries = 10
+	opts.RetryDelay = 50 * time.Millisecond
+	opts.Jitter = 0.01
+	// the delays are about 50ms, 150ms and 350ms, so the third retry would end after 500ms
+	opts.MaxElapsedTime = 500 * time.Millisecond
+	pl := NewPipeline(srv, NewRetryPolicy(opts))
+	start := time.Now()
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+	if r := srv.Requests(); r != 3 {
+		t.Fatalf("wrong request count, got %d expected %d", r, 3)
+	}
+	if elapsed := time.Since(start); elapsed > opts.MaxElapsedTime {
+		t.Fatalf("expected retries to stop within %v, took %v", opts.MaxElapsedTime, elapsed)
+	}
+}
+
+func TestRetryPolicyMaxElapsedTimeBoundsTry(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithSlowResponse(time.Second))
+	opts := testRetryOptions()
+	opts.MaxElapsedTime = 100 * time.Millisecond
+	pl := NewPipeline(srv, NewRetryPolicy(opts))
+	start := time.Now()
+	_, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Fatalf("expected the try to end at MaxElapsedTime, took %v", elapsed)
+	}
+}
+
+func TestRetryPolicyRetryAfterMS(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable), mock.WithHeader(HeaderXmsRetryAfterMS, "10"))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	opts := testRetryOptions()
+	// the computed delay would exceed the test's timeout
+	opts.RetryDelay = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pl := NewPipeline(srv, NewRetryPolicy(opts))
+	resp, err := pl.Do(ctx, NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+func TestRetryPolicyNonSeekableBody(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusInternalServerError))
+	pl := NewPipeline(srv, NewRetryPolicy(testRetryOptions()))
+	req := NewRequest(http.MethodPost, srv.URL())
+	req.SetStreamBody(ioutil.NopCloser(strings.NewReader("stuff")), int64(len("stuff")))
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+	if r := srv.Requests(); r != 1 {
+		t.Fatalf("expected a request with a non-seekable body not to be retried, got %d requests", r)
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
 )
 
 // TelemetryOptions configures the telemetry policy's behavior.
@@ -18,25 +21,61 @@ type TelemetryOptions struct {
 	// Value is a string prepended to each request's User-Agent and sent to the service.
 	// The service records the user-agent in logs for diagnostics and tracking of client requests.
 	Value string
+
+	// ApplicationID identifies the application and is prepended to the User-Agent, before Value. It takes
+	// precedence over the application ID set with SetTelemetryApplicationID.
+	ApplicationID string
+
+	// Disabled removes the SDK's platform information from the User-Agent, leaving only Value and the application
+	// ID. No User-Agent is sent when neither is set. Setting the AZURE_TELEMETRY_DISABLED
+	// environment variable to a true value, such as "true" or "1", disables telemetry for every pipeline.
+	Disabled bool
+}
+
+// telemetryDisabledEnvVar disables telemetry for every pipeline when set to a true value.
+const telemetryDisabledEnvVar = "AZURE_TELEMETRY_DISABLED"
+
+// telemetryApplicationID holds the string set with SetTelemetryApplicationID.
+var telemetryApplicationID atomic.Value
+
+// SetTelemetryApplicationID sets an identifier of the application that's prepended to the User-Agent of the requests
+// sent by every pipeline, including those whose telemetry is disabled. Pass an empty string to remove it.
+func SetTelemetryApplicationID(applicationID string) {
+	telemetryApplicationID.Store(applicationID)
 }
 
 type telemetryPolicy struct {
 	telemetryValue string
+	applicationID  string
 }
 
 // NewTelemetryPolicy creates a telemetry policy object that adds telemetry information to outgoing HTTP requests.
 func NewTelemetryPolicy(o TelemetryOptions) Policy {
+	if disabled, err := strconv.ParseBool(os.Getenv(telemetryDisabledEnvVar)); err == nil && disabled {
+		o.Disabled = true
+	}
 	b := &bytes.Buffer{}
 	b.WriteString(o.Value)
-	if b.Len() > 0 {
-		b.WriteRune(' ')
+	if !o.Disabled {
+		if b.Len() > 0 {
+			b.WriteRune(' ')
+		}
+		b.WriteString(platformInfo)
 	}
-	b.WriteString(platformInfo)
-	return &telemetryPolicy{telemetryValue: b.String()}
+	return &telemetryPolicy{telemetryValue: b.String(), applicationID: o.ApplicationID}
 }
 
 func (p telemetryPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
-	req.Request.Header.Set(HeaderUserAgent, p.telemetryValue)
+	v := p.telemetryValue
+	appID := p.applicationID
+	if appID == "" {
+		appID, _ = telemetryApplicationID.Load().(string)
+	}
+	if appID != "" {
+		v = strings.TrimSpace(appID + " " + v)
+	}
+	// an empty User-Agent stops net/http from sending its default
+	req.Request.Header.Set(HeaderUserAgent, v)
 	return req.Next(ctx)
 }
 
@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
@@ -42,3 +43,83 @@ func TestPolicyTelemetryWithCustomInfo(t *testing.T) {
 		t.Fatalf("unexpected user agent value: %s", v)
 	}
 }
+
+func TestPolicyTelemetryDisabled(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	for value, expected := range map[string]string{"": "", "azcore_test": "azcore_test"} {
+		pl := NewPipeline(srv, NewTelemetryPolicy(TelemetryOptions{Value: value, Disabled: true}))
+		resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v := resp.Request.Header.Get(HeaderUserAgent); v != expected {
+			t.Fatalf("unexpected user agent value: %s", v)
+		}
+	}
+}
+
+func TestPolicyTelemetryDisabledByEnvironment(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	defer os.Setenv(telemetryDisabledEnvVar, os.Getenv(telemetryDisabledEnvVar))
+	os.Setenv(telemetryDisabledEnvVar, "true")
+	pl := NewPipeline(srv, NewTelemetryPolicy(TelemetryOptions{Value: "azcore_test"}))
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get(HeaderUserAgent); v != "azcore_test" {
+		t.Fatalf("unexpected user agent value: %s", v)
+	}
+}
+
+func TestPolicyTelemetryApplicationID(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	SetTelemetryApplicationID("my-app")
+	defer SetTelemetryApplicationID("")
+	pl := NewPipeline(srv, NewTelemetryPolicy(TelemetryOptions{Value: "azcore_test"}))
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get(HeaderUserAgent); v != fmt.Sprintf("my-app azcore_test %s", platformInfo) {
+		t.Fatalf("unexpected user agent value: %s", v)
+	}
+	pl = NewPipeline(srv, NewTelemetryPolicy(TelemetryOptions{Disabled: true}))
+	resp, err = pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get(HeaderUserAgent); v != "my-app" {
+		t.Fatalf("unexpected user agent value: %s", v)
+	}
+}
+
+func TestPolicyTelemetryOptionsApplicationID(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	SetTelemetryApplicationID("global-app")
+	defer SetTelemetryApplicationID("")
+	pl := NewPipeline(srv, NewTelemetryPolicy(TelemetryOptions{Value: "azcore_test", ApplicationID: "my-app"}))
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get(HeaderUserAgent); v != fmt.Sprintf("my-app azcore_test %s", platformInfo) {
+		t.Fatalf("unexpected user agent value: %s", v)
+	}
+	pl = NewPipeline(srv, NewTelemetryPolicy(TelemetryOptions{ApplicationID: "my-app", Disabled: true}))
+	resp, err = pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get(HeaderUserAgent); v != "my-app" {
+		t.Fatalf("unexpected user agent value: %s", v)
+	}
+}
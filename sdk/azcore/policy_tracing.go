@@ -0,0 +1,70 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	headerTraceParent = "traceparent"
+	headerTraceState  = "tracestate"
+)
+
+type tracingPolicy struct {
+	tracer Tracer
+}
+
+// NewTracingPolicy creates a policy that records each attempt of a request as a span with tracer, named for the
+// request's method, such as "HTTP GET". The span records the request's method, URL without its query, status code,
+// request IDs and how many times the request was sent before. When the span is a TraceContextSpan, the policy sends
+// its trace context to the service in the traceparent and tracestate headers. The policy must follow the retry
+// policy; NewDefaultPipeline adds it when PipelineOptions.Tracer is set.
+func NewTracingPolicy(tracer Tracer) Policy {
+	return &tracingPolicy{tracer: tracer}
+}
+
+func (p *tracingPolicy) Do(ctx context.Context, req *Request) (*Response, error) {
+	// the retry policy records which try this is, without it the request is sent once
+	opValues := retryPolicyOpValues{try: 1}
+	req.OperationValue(&opValues)
+
+	ctx, span := StartSpan(ctx, p.tracer, "HTTP "+req.Method)
+	defer span.End()
+	u := *req.URL
+	u.RawQuery, u.User = "", nil
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", u.String())
+	span.SetAttribute("http.resend_count", int(opValues.try-1))
+	if id := req.Header.Get(xMsClientRequestID); id != "" {
+		span.SetAttribute("az.client_request_id", id)
+	}
+	if tc, ok := span.(TraceContextSpan); ok {
+		if tp := tc.TraceParent(); tp != "" {
+			req.Header.Set(headerTraceParent, tp)
+			if ts := tc.TraceState(); ts != "" {
+				req.Header.Set(headerTraceState, ts)
+			} else {
+				req.Header.Del(headerTraceState)
+			}
+		}
+	}
+	resp, err := req.Next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return resp, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if id := resp.Header.Get("x-ms-request-id"); id != "" {
+		span.SetAttribute("az.service_request_id", id)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.RecordError(fmt.Errorf("the service responded with status %s", resp.Status))
+	}
+	return resp, nil
+}
@@ -0,0 +1,116 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// traceContextTracer starts spans that identify themselves in the W3C Trace Context format
+type traceContextTracer struct {
+	testTracer
+}
+
+func (t *traceContextTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	ctx, s := t.testTracer.Start(ctx, name)
+	return ctx, &traceContextSpan{testSpan: s.(*testSpan)}
+}
+
+type traceContextSpan struct {
+	*testSpan
+}
+
+func (s *traceContextSpan) TraceParent() string {
+	return "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+}
+
+func (s *traceContextSpan) TraceState() string {
+	return "vendor=value"
+}
+
+func TestTracingPolicy(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusInternalServerError))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader("x-ms-request-id", "service-id"))
+	tracer := &traceContextTracer{}
+	var traceParents []string
+	recordHeaders := PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		traceParents = append(traceParents, req.Header.Get(headerTraceParent)+" "+req.Header.Get(headerTraceState))
+		return req.Next(ctx)
+	})
+	pl := NewPipeline(srv, NewUniqueRequestIDPolicy(), NewRetryPolicy(testRetryOptions()), NewTracingPolicy(tracer), recordHeaders)
+	u := srv.URL()
+	srvURL := u.String()
+	u.RawQuery = "sig=secret"
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, u)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected a span per attempt, got %d", len(tracer.spans))
+	}
+	for i, s := range tracer.spans {
+		if s.name != "HTTP GET" || !s.ended {
+			t.Fatalf("unexpected span: %+v", s)
+		}
+		if s.attributes["http.resend_count"] != i {
+			t.Fatalf("expected resend count %d, got %v", i, s.attributes["http.resend_count"])
+		}
+		if url := s.attributes["http.url"].(string); url != srvURL {
+			t.Fatalf("expected the URL without its query, got %s", url)
+		}
+		if s.attributes["az.client_request_id"] == nil {
+			t.Fatal("missing client request ID")
+		}
+	}
+	if s := tracer.spans[0]; s.attributes["http.status_code"] != http.StatusInternalServerError || s.err == nil {
+		t.Fatalf("expected the failed attempt to be recorded: %+v", s)
+	}
+	if s := tracer.spans[1]; s.attributes["http.status_code"] != http.StatusOK || s.err != nil || s.attributes["az.service_request_id"] != "service-id" {
+		t.Fatalf("unexpected span for the successful attempt: %+v", s)
+	}
+	for _, tp := range traceParents {
+		if tp != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01 vendor=value" {
+			t.Fatalf("expected the trace context to be propagated, got %q", tp)
+		}
+	}
+}
+
+func TestTracingPolicyNoTraceContext(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	tracer := &testTracer{}
+	pl := NewPipeline(srv, NewTracingPolicy(tracer))
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Request.Header.Get(headerTraceParent) != "" {
+		t.Fatal("expected no traceparent header for spans without a trace context")
+	}
+	if len(tracer.spans) != 1 || tracer.spans[0].attributes["http.status_code"] != http.StatusOK {
+		t.Fatalf("unexpected spans: %+v", tracer.spans)
+	}
+}
+
+func TestNewDefaultPipelineTracer(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	tracer := &testTracer{}
+	pl := NewDefaultPipeline(&PipelineOptions{HTTPClient: srv, Tracer: tracer})
+	if _, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tracer.spans) != 1 {
+		t.Fatalf("expected one span, got %d", len(tracer.spans))
+	}
+}
@@ -7,20 +7,62 @@ package azcore
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/uuid"
 )
 
-const xMsClientRequestID = "x-ms-client-request-id"
+const (
+	xMsClientRequestID       = "x-ms-client-request-id"
+	xMsReturnClientRequestID = "x-ms-return-client-request-id"
+)
+
+// RequestIDOptions configures the unique request ID policy's behavior.
+type RequestIDOptions struct {
+	// VerifyEcho asks the service to return the request's x-ms-client-request-id header in its response and
+	// fails requests whose response contains a different ID, which indicates a response was received for
+	// another request, for example because of a misbehaving proxy. Responses without the header are accepted.
+	VerifyEcho bool
+}
+
+type ctxWithClientRequestIDKey struct{}
+
+// WithClientRequestID adds the specified client request ID to the parent context. The unique request ID policy
+// sends it in the x-ms-client-request-id header of requests that don't already have one, instead of a generated ID,
+// so the operation can be correlated with the caller's own logs.
+func WithClientRequestID(parent context.Context, id string) context.Context {
+	return context.WithValue(parent, ctxWithClientRequestIDKey{}, id)
+}
 
 // NewUniqueRequestIDPolicy creates a policy object that sets the request's x-ms-client-request-id header if it doesn't already exist.
 func NewUniqueRequestIDPolicy() Policy {
+	return NewRequestIDPolicy(RequestIDOptions{})
+}
+
+// NewRequestIDPolicy creates a policy object that sets the request's x-ms-client-request-id header if it doesn't
+// already exist, to the ID added to the context by WithClientRequestID or else to a new unique ID.
+// The ID is available from errors as ResponseError.ClientRequestID, for support cases.
+func NewRequestIDPolicy(o RequestIDOptions) Policy {
 	return PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
 		id := req.Request.Header.Get(xMsClientRequestID)
 		if id == "" {
-			// Add a unique request ID if the caller didn't specify one already
-			req.Request.Header.Set(xMsClientRequestID, uuid.New().String())
+			// Add the caller's request ID from the context or a unique one if the caller didn't specify one already
+			id, _ = ctx.Value(ctxWithClientRequestIDKey{}).(string)
+			if id == "" {
+				id = uuid.New().String()
+			}
+			req.Request.Header.Set(xMsClientRequestID, id)
+		}
+		if o.VerifyEcho {
+			req.Request.Header.Set(xMsReturnClientRequestID, "true")
+		}
+		resp, err := req.Next(ctx)
+		if err != nil || !o.VerifyEcho {
+			return resp, err
+		}
+		if echo := resp.Header.Get(xMsClientRequestID); echo != "" && echo != id {
+			return resp, fmt.Errorf("the response's client request ID %q doesn't match the request's %q", echo, id)
 		}
-		return req.Next(ctx)
+		return resp, nil
 	})
 }
@@ -8,6 +8,7 @@ package azcore
 import (
 	"context"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
@@ -43,3 +44,50 @@ func TestUniqueRequestIDPolicyUserDefined(t *testing.T) {
 		t.Fatalf("unexpected request ID value: %s", v)
 	}
 }
+
+func TestRequestIDPolicyContext(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusNotFound))
+	pl := NewPipeline(srv, NewRequestIDPolicy(RequestIDOptions{}))
+	ctx := WithClientRequestID(context.Background(), "caller-id")
+	resp, err := pl.Do(ctx, NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := resp.Request.Header.Get(xMsClientRequestID); v != "caller-id" {
+		t.Fatalf("unexpected request ID value: %s", v)
+	}
+	if resp.Request.Header.Get(xMsReturnClientRequestID) != "" {
+		t.Fatal("unexpected request to echo the ID")
+	}
+	respErr := NewResponseError(resp).(*ResponseError)
+	if respErr.ClientRequestID != "caller-id" || !strings.Contains(respErr.Error(), "CLIENT REQUEST ID: caller-id") {
+		t.Fatalf("expected the error to contain the client request ID: %v", respErr)
+	}
+}
+
+func TestRequestIDPolicyVerifyEcho(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithHeader(xMsClientRequestID, "my-id"))
+	srv.AppendResponse()
+	srv.AppendResponse(mock.WithHeader(xMsClientRequestID, "another-id"))
+	pl := NewPipeline(srv, NewRequestIDPolicy(RequestIDOptions{VerifyEcho: true}))
+	for i := 0; i < 2; i++ {
+		req := NewRequest(http.MethodGet, srv.URL())
+		req.Header.Set(xMsClientRequestID, "my-id")
+		resp, err := pl.Do(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Request.Header.Get(xMsReturnClientRequestID) != "true" {
+			t.Fatal("expected the service to be asked to echo the ID")
+		}
+	}
+	req := NewRequest(http.MethodGet, srv.URL())
+	req.Header.Set(xMsClientRequestID, "my-id")
+	if _, err := pl.Do(context.Background(), req); err == nil {
+		t.Fatal("expected an error for a mismatched ID")
+	}
+}
@@ -0,0 +1,304 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// the statuses of a long-running operation
+const (
+	pollerStatusInProgress = "InProgress"
+	pollerStatusSucceeded  = "Succeeded"
+	pollerStatusFailed     = "Failed"
+	pollerStatusCanceled   = "Canceled"
+)
+
+// ErrPollerNotDone is returned by Poller.FinalResponse when the operation hasn't finished.
+var ErrPollerNotDone = errors.New("the long-running operation hasn't finished")
+
+// Poller tracks a long-running operation that follows the Azure asynchronous operation conventions, polling
+// the Azure-AsyncOperation header's URL, the Location header's URL, or the resource's provisioningState,
+// in that order of preference.
+type Poller interface {
+	// Done returns true when the operation has finished, successfully or not.
+	Done() bool
+
+	// Poll fetches the operation's status once and returns the response. It returns a *ResponseError
+	// when the service responded with an error or the operation failed or was canceled.
+	Poll(ctx context.Context) (*Response, error)
+
+	// FinalResponse returns the response containing the operation's result, fetching the resource when
+	// the conventions require it, and unmarshals its JSON body into v unless v is nil or the body is empty.
+	// It returns ErrPollerNotDone when the operation hasn't finished.
+	FinalResponse(ctx context.Context, v interface{}) (*Response, error)
+
+	// PollUntilDone polls the operation every freq, or as often as the service's Retry-After header asks,
+	// until it finishes or ctx is done, then returns FinalResponse.
+	PollUntilDone(ctx context.Context, freq time.Duration, v interface{}) (*Response, error)
+
+	// ResumeToken returns a token from which NewPollerFromResumeToken creates a poller for the operation,
+	// which can be done in another process. The token contains the URLs being polled.
+	ResumeToken() (string, error)
+}
+
+// pollerState is the state of a poller that's serialized in resume tokens
+type pollerState struct {
+	// PollerType identifies the operation, so that a token isn't resumed by the poller of another one
+	PollerType  string `json:"type"`
+	Method      string `json:"method"`
+	ResourceURL string `json:"resourceURL"`
+	AsyncURL    string `json:"asyncURL,omitempty"`
+	LocationURL string `json:"locationURL,omitempty"`
+	Status      string `json:"status"`
+}
+
+type poller struct {
+	pl    Pipeline
+	state pollerState
+	// resp is the latest response
+	resp *Response
+	// final caches the final response
+	final *Response
+}
+
+// NewPoller creates a Poller for the long-running operation that resp is the initial response of.
+// pollerType identifies the operation, for example "WidgetsClient.BeginCreate". The poller sends its
+// requests through pl.
+func NewPoller(pollerType string, resp *Response, pl Pipeline) (Poller, error) {
+	if !resp.HasStatusCode(http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent) {
+		return nil, NewResponseError(resp)
+	}
+	p := &poller{
+		pl:   pl,
+		resp: resp,
+		state: pollerState{
+			PollerType:  pollerType,
+			Method:      resp.Request.Method,
+			ResourceURL: resp.Request.URL.String(),
+			AsyncURL:    resp.Header.Get(HeaderAzureAsync),
+			LocationURL: resp.Header.Get(HeaderLocation),
+			Status:      pollerStatusInProgress,
+		},
+	}
+	switch {
+	case p.state.AsyncURL != "":
+		// the service may have already reported the status in the body
+		if status, ok := statusFromBody(resp, "status"); ok {
+			p.state.Status = status
+		}
+	case p.state.LocationURL != "" && resp.StatusCode == http.StatusAccepted:
+		// the Location URL responds with 202 until the operation finishes
+	case p.state.Method == http.MethodPut || p.state.Method == http.MethodPatch:
+		if status, ok := statusFromBody(resp, "provisioningState"); ok {
+			p.state.Status = status
+		} else {
+			p.state.Status = pollerStatusSucceeded
+		}
+	default:
+		// the operation finished synchronously
+		p.state.Status = pollerStatusSucceeded
+	}
+	if p.failed() {
+		return nil, NewResponseError(resp)
+	}
+	return p, nil
+}
+
+// NewPollerFromResumeToken creates a Poller for the operation whose poller returned token.
+// pollerType must be the same as the original poller's.
+func NewPollerFromResumeToken(pollerType string, token string, pl Pipeline) (Poller, error) {
+	var state pollerState
+	if err := json.Unmarshal([]byte(token), &state); err != nil {
+		return nil, fmt.Errorf("invalid resume token: %w", err)
+	}
+	if state.PollerType != pollerType {
+		return nil, fmt.Errorf("cannot resume a %s poller from the resume token of a %s poller", pollerType, state.PollerType)
+	}
+	if state.ResourceURL == "" {
+		return nil, errors.New("invalid resume token: the resource URL is missing")
+	}
+	return &poller{pl: pl, state: state}, nil
+}
+
+func (p *poller) Done() bool {
+	return p.state.Status != pollerStatusInProgress
+}
+
+func (p *poller) failed() bool {
+	return p.state.Status == pollerStatusFailed || p.state.Status == pollerStatusCanceled
+}
+
+func (p *poller) Poll(ctx context.Context) (*Response, error) {
+	if p.Done() {
+		return p.resp, nil
+	}
+	u := p.state.ResourceURL
+	if p.state.AsyncURL != "" {
+		u = p.state.AsyncURL
+	} else if p.state.LocationURL != "" {
+		u = p.state.LocationURL
+	}
+	resp, err := p.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp, NewResponseError(resp)
+	}
+	switch {
+	case p.state.AsyncURL != "":
+		status, ok := statusFromBody(resp, "status")
+		if !ok {
+			return resp, errors.New("the operation's status is missing from the polling response")
+		}
+		p.state.Status = status
+	case p.state.LocationURL != "":
+		if resp.StatusCode == http.StatusAccepted {
+			if loc := resp.Header.Get(HeaderLocation); loc != "" {
+				p.state.LocationURL = loc
+			}
+		} else {
+			p.state.Status = pollerStatusSucceeded
+		}
+	default:
+		if status, ok := statusFromBody(resp, "provisioningState"); ok {
+			p.state.Status = status
+		} else {
+			p.state.Status = pollerStatusSucceeded
+		}
+	}
+	p.resp = resp
+	if p.failed() {
+		return resp, NewResponseError(resp)
+	}
+	return resp, nil
+}
+
+func (p *poller) FinalResponse(ctx context.Context, v interface{}) (*Response, error) {
+	if !p.Done() {
+		return nil, ErrPollerNotDone
+	}
+	if p.failed() {
+		return nil, NewResponseError(p.resp)
+	}
+	if p.final == nil {
+		final := p.resp
+		if p.state.AsyncURL != "" {
+			// the Azure-AsyncOperation URL reports the status, the result is elsewhere
+			var u string
+			switch p.state.Method {
+			case http.MethodPut, http.MethodPatch:
+				u = p.state.ResourceURL
+			case http.MethodPost:
+				u = p.state.LocationURL
+			}
+			if u != "" {
+				resp, err := p.get(ctx, u)
+				if err != nil {
+					return nil, err
+				}
+				if resp.StatusCode >= http.StatusBadRequest {
+					return nil, NewResponseError(resp)
+				}
+				final = resp
+			}
+		}
+		p.final = final
+	}
+	if v != nil && len(p.final.payload()) > 0 {
+		if err := p.final.UnmarshalAsJSON(v); err != nil {
+			return nil, err
+		}
+	}
+	return p.final, nil
+}
+
+func (p *poller) PollUntilDone(ctx context.Context, freq time.Duration, v interface{}) (*Response, error) {
+	for !p.Done() {
+		resp, err := p.Poll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if p.Done() {
+			break
+		}
+		delay := resp.retryAfter()
+		if delay <= 0 {
+			delay = freq
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return p.FinalResponse(ctx, v)
+}
+
+func (p *poller) ResumeToken() (string, error) {
+	if p.Done() {
+		return "", errors.New("the long-running operation has finished so it can't be resumed")
+	}
+	b, err := json.Marshal(p.state)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// get sends a GET request for u, which is relative to the resource's URL, through the poller's pipeline
+func (p *poller) get(ctx context.Context, u string) (*Response, error) {
+	base, err := url.Parse(p.state.ResourceURL)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := base.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	return p.pl.Do(ctx, NewRequest(http.MethodGet, *endpoint))
+}
+
+// statusFromBody returns the status in field of the response's JSON body, or in field of its properties
+// object, normalized to one of the poller's statuses. Statuses other than the terminal ones, such as
+// "Creating", mean the operation is in progress.
+func statusFromBody(resp *Response, field string) (string, bool) {
+	var body map[string]json.RawMessage
+	if len(resp.payload()) == 0 || resp.UnmarshalAsJSON(&body) != nil {
+		return "", false
+	}
+	raw, ok := body[field]
+	if !ok {
+		var props map[string]json.RawMessage
+		if json.Unmarshal(body["properties"], &props) != nil {
+			return "", false
+		}
+		if raw, ok = props[field]; !ok {
+			return "", false
+		}
+	}
+	var status string
+	if json.Unmarshal(raw, &status) != nil {
+		return "", false
+	}
+	for _, s := range []string{pollerStatusSucceeded, pollerStatusFailed, pollerStatusCanceled} {
+		if strings.EqualFold(status, s) {
+			return s, true
+		}
+	}
+	if strings.EqualFold(status, "Cancelled") {
+		return pollerStatusCanceled, true
+	}
+	return pollerStatusInProgress, true
+}
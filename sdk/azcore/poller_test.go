@@ -0,0 +1,176 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+type testWidget struct {
+	Name string `json:"name"`
+}
+
+// newPollerTestPipeline returns a pipeline sending requests to srv that records the path of each request
+func newPollerTestPipeline(srv *mock.Server, paths *[]string) Pipeline {
+	return NewPipeline(TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		*paths = append(*paths, req.Method+" "+req.URL.Path)
+		return srv.Do(ctx, req)
+	}))
+}
+
+func startTestOperation(t *testing.T, pl Pipeline, srv *mock.Server, method string) *Response {
+	u := srv.URL()
+	u.Path = "/widgets/w"
+	resp, err := pl.Do(context.Background(), NewRequest(method, u))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp
+}
+
+func TestPollerAzureAsyncOperation(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srvURL := srv.URL()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated), mock.WithHeader(HeaderAzureAsync, srvURL.String()+"/operations/op"))
+	srv.AppendResponse(mock.WithBody([]byte(`{"status":"InProgress"}`)))
+	srv.AppendResponse(mock.WithBody([]byte(`{"status":"Succeeded"}`)))
+	srv.AppendResponse(mock.WithBody([]byte(`{"name":"w"}`)))
+	var paths []string
+	pl := newPollerTestPipeline(srv, &paths)
+	poller, err := NewPoller("WidgetsClient.BeginCreate", startTestOperation(t, pl, srv, http.MethodPut), pl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if poller.Done() {
+		t.Fatal("expected the operation to be in progress")
+	}
+	if _, err = poller.FinalResponse(context.Background(), nil); !errors.Is(err, ErrPollerNotDone) {
+		t.Fatalf("expected ErrPollerNotDone, got %v", err)
+	}
+	var widget testWidget
+	if _, err = poller.PollUntilDone(context.Background(), time.Millisecond, &widget); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if widget.Name != "w" {
+		t.Fatalf("unexpected result: %+v", widget)
+	}
+	expected := []string{"PUT /widgets/w", "GET /operations/op", "GET /operations/op", "GET /widgets/w"}
+	if len(paths) != len(expected) {
+		t.Fatalf("expected requests %v, got %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Fatalf("expected requests %v, got %v", expected, paths)
+		}
+	}
+}
+
+func TestPollerLocation(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusAccepted), mock.WithHeader(HeaderLocation, "/operations/op"))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusAccepted), mock.WithHeader(HeaderRetryAfter, "0"))
+	srv.AppendResponse(mock.WithBody([]byte(`{"name":"result"}`)))
+	var paths []string
+	pl := newPollerTestPipeline(srv, &paths)
+	poller, err := NewPoller("WidgetsClient.BeginExport", startTestOperation(t, pl, srv, http.MethodPost), pl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var widget testWidget
+	if _, err = poller.PollUntilDone(context.Background(), time.Millisecond, &widget); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if widget.Name != "result" || len(paths) != 3 || paths[2] != "GET /operations/op" {
+		t.Fatalf("unexpected result %+v from requests %v", widget, paths)
+	}
+}
+
+func TestPollerProvisioningStateFailed(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated), mock.WithBody([]byte(`{"properties":{"provisioningState":"Creating"}}`)))
+	srv.AppendResponse(mock.WithBody([]byte(`{"properties":{"provisioningState":"Failed"}}`)))
+	var paths []string
+	pl := newPollerTestPipeline(srv, &paths)
+	poller, err := NewPoller("WidgetsClient.BeginCreate", startTestOperation(t, pl, srv, http.MethodPut), pl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = poller.Poll(context.Background())
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		t.Fatalf("expected a *ResponseError, got %v", err)
+	}
+	if !poller.Done() || paths[1] != "GET /widgets/w" {
+		t.Fatalf("expected the resource to be polled until it failed, got %v", paths)
+	}
+	if _, err = poller.FinalResponse(context.Background(), nil); !errors.As(err, &respErr) {
+		t.Fatalf("expected a *ResponseError, got %v", err)
+	}
+}
+
+func TestPollerSynchronousCompletion(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNoContent))
+	var paths []string
+	pl := newPollerTestPipeline(srv, &paths)
+	poller, err := NewPoller("WidgetsClient.BeginDelete", startTestOperation(t, pl, srv, http.MethodDelete), pl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !poller.Done() {
+		t.Fatal("expected the operation to be done")
+	}
+	if resp, err := poller.FinalResponse(context.Background(), nil); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected final response: %v", err)
+	}
+	if _, err = poller.ResumeToken(); err == nil {
+		t.Fatal("expected an error for the resume token of a finished operation")
+	}
+}
+
+func TestPollerResumeToken(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusAccepted), mock.WithHeader(HeaderAzureAsync, "/operations/op"))
+	srv.AppendResponse(mock.WithBody([]byte(`{"status":"Succeeded"}`)))
+	var paths []string
+	pl := newPollerTestPipeline(srv, &paths)
+	poller, err := NewPoller("WidgetsClient.BeginDelete", startTestOperation(t, pl, srv, http.MethodDelete), pl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token, err := poller.ResumeToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = NewPollerFromResumeToken("WidgetsClient.BeginCreate", token, pl); err == nil {
+		t.Fatal("expected an error resuming another operation's poller")
+	}
+	if _, err = NewPollerFromResumeToken("WidgetsClient.BeginDelete", "not a token", pl); err == nil {
+		t.Fatal("expected an error for an invalid token")
+	}
+	resumed, err := NewPollerFromResumeToken("WidgetsClient.BeginDelete", token, pl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := resumed.PollUntilDone(context.Background(), time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(paths) != 2 || paths[1] != "GET /operations/op" {
+		t.Fatalf("unexpected requests %v", paths)
+	}
+}
@@ -28,13 +28,13 @@ func NewRequestBodyProgress(requestBody ReadSeekCloser, pr ProgressReceiver) Rea
 // Read reads a block of data from an inner stream and reports progress
 func (rbp *requestBodyProgress) Read(p []byte) (n int, err error) {
 	n, err = rbp.requestBody.Read(p)
-	if err != nil {
+	if n == 0 {
 		return
 	}
 	// Invokes the user's callback method to report progress
-	position, err := rbp.requestBody.Seek(0, io.SeekCurrent)
-	if err != nil {
-		return
+	position, seekErr := rbp.requestBody.Seek(0, io.SeekCurrent)
+	if seekErr != nil {
+		return n, seekErr
 	}
 	rbp.pr(position)
 	return
@@ -59,3 +59,67 @@ func TestProgressReporting(t *testing.T) {
 		t.Fatal("request and response bodies don't match")
 	}
 }
+
+func TestRequestProgress(t *testing.T) {
+	const contentSize = 4096
+	content := bytes.Repeat([]byte{1}, contentSize)
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusInternalServerError))
+	srv.AppendResponse(mock.WithBody(content))
+	pl := NewPipeline(srv, NewRetryPolicy(testRetryOptions()))
+	req := NewRequest(http.MethodPut, srv.URL())
+	if err := req.SetBody(NopCloser(bytes.NewReader(content))); err != nil {
+		t.Fatal(err)
+	}
+	var sent []int64
+	req.SetUploadProgress(func(bytesTransferred int64) {
+		sent = append(sent, bytesTransferred)
+	})
+	var bytesReceived int64
+	req.SetDownloadProgress(func(bytesTransferred int64) {
+		bytesReceived = bytesTransferred
+	})
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+	// each try reports the whole body, progress starts again when the request is retried
+	completed := 0
+	for _, n := range sent {
+		if n > contentSize {
+			t.Fatalf("unexpected progress: %v", sent)
+		} else if n == contentSize {
+			completed++
+		}
+	}
+	if completed != 2 {
+		t.Fatalf("expected the body to be sent twice, got progress %v", sent)
+	}
+	if bytesReceived != contentSize {
+		t.Fatalf("wrong bytes received: %d", bytesReceived)
+	}
+}
+
+func TestRequestProgressStreamBody(t *testing.T) {
+	const contentSize = 1024
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	pl := NewPipeline(srv)
+	req := NewRequest(http.MethodPut, srv.URL())
+	req.SetStreamBody(ioutil.NopCloser(bytes.NewReader(make([]byte, contentSize))), contentSize)
+	var bytesSent int64
+	req.SetUploadProgress(func(bytesTransferred int64) {
+		bytesSent = bytesTransferred
+	})
+	if _, err := pl.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytesSent != contentSize {
+		t.Fatalf("wrong bytes sent: %d", bytesSent)
+	}
+}
@@ -9,13 +9,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -113,10 +116,12 @@ func (req *Request) MarshalAsByteArray(v []byte, format Base64Encoding) error {
 	return req.SetBody(NopCloser(strings.NewReader(encode)))
 }
 
-// MarshalAsJSON calls json.Marshal() to get the JSON encoding of v then calls SetBody.
+// MarshalAsJSON calls json.Marshal(), or the codec set with SetJSONCodec, to get the JSON encoding of v then
+// calls SetBody.
+// Pointers set to a NullValue are encoded as null.
 // If json.Marshal fails a MarshalError is returned.  Any error from SetBody is returned.
 func (req *Request) MarshalAsJSON(v interface{}) error {
-	b, err := json.Marshal(v)
+	b, err := marshalJSON(v)
 	if err != nil {
 		return fmt.Errorf("error marshalling type %s: %w", reflect.TypeOf(v).Name(), err)
 	}
@@ -127,14 +132,134 @@ func (req *Request) MarshalAsJSON(v interface{}) error {
 // MarshalAsXML calls xml.Marshal() to get the XML encoding of v then calls SetBody.
 // If xml.Marshal fails a MarshalError is returned.  Any error from SetBody is returned.
 func (req *Request) MarshalAsXML(v interface{}) error {
+	return req.marshalAsXML(v, false)
+}
+
+// MarshalAsXMLWithDeclaration is like MarshalAsXML but precedes the encoding with the XML declaration,
+// which some services such as Azure Storage require.
+func (req *Request) MarshalAsXMLWithDeclaration(v interface{}) error {
+	return req.marshalAsXML(v, true)
+}
+
+func (req *Request) marshalAsXML(v interface{}, declaration bool) error {
 	b, err := xml.Marshal(v)
 	if err != nil {
 		return fmt.Errorf("error marshalling type %s: %w", reflect.TypeOf(v).Name(), err)
 	}
+	if declaration {
+		b = append([]byte(xml.Header), b...)
+	}
 	req.Header.Set(HeaderContentType, contentTypeAppXML)
 	return req.SetBody(NopCloser(bytes.NewReader(b)))
 }
 
+// SetFormData encodes data as an application/x-www-form-urlencoded body then calls SetBody.
+func (req *Request) SetFormData(data url.Values) error {
+	req.Header.Set(HeaderContentType, HeaderURLEncoded)
+	return req.SetBody(NopCloser(strings.NewReader(data.Encode())))
+}
+
+// MultipartFile is a file part of a multipart/form-data body.
+type MultipartFile struct {
+	// Body is the file's content. It's read as the request is sent.
+	Body io.Reader
+
+	// Filename is the name of the file sent to the service.
+	Filename string
+
+	// ContentType is the file's media type. The default is "application/octet-stream".
+	ContentType string
+}
+
+// SetMultipartFormData sets a multipart/form-data body with a part for each field, in the order of the field names.
+// A field's value is a string, a []string, a MultipartFile or a []MultipartFile. Without file parts the body is
+// encoded up front and calls SetBody. With file parts the body is streamed as the request is sent, reading each
+// file's Body, and set with SetStreamBody, so the retry policy doesn't retry the request; call Close when the
+// request isn't sent. An unsupported value returns an error.
+func (req *Request) SetMultipartFormData(fields map[string]interface{}) error {
+	names := make([]string, 0, len(fields))
+	streaming := false
+	for name, value := range fields {
+		switch v := value.(type) {
+		case string, []string:
+		case MultipartFile:
+			streaming = true
+		case []MultipartFile:
+			streaming = streaming || len(v) > 0
+		default:
+			return fmt.Errorf("unsupported multipart form field %s of type %T", name, value)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if !streaming {
+		b := &bytes.Buffer{}
+		w := multipart.NewWriter(b)
+		if err := writeMultipartFields(w, names, fields); err != nil {
+			return err
+		}
+		req.Header.Set(HeaderContentType, w.FormDataContentType())
+		return req.SetBody(NopCloser(bytes.NewReader(b.Bytes())))
+	}
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	go func() {
+		// closing the pipe with an error fails the request's read of the body
+		pw.CloseWithError(writeMultipartFields(w, names, fields))
+	}()
+	req.Header.Set(HeaderContentType, w.FormDataContentType())
+	req.SetStreamBody(pr, -1)
+	return nil
+}
+
+// writeMultipartFields writes the parts of the named fields and the closing boundary to w.
+func writeMultipartFields(w *multipart.Writer, names []string, fields map[string]interface{}) error {
+	for _, name := range names {
+		var err error
+		switch v := fields[name].(type) {
+		case string:
+			err = w.WriteField(name, v)
+		case []string:
+			for _, s := range v {
+				if err = w.WriteField(name, s); err != nil {
+					break
+				}
+			}
+		case MultipartFile:
+			err = writeMultipartFile(w, name, v)
+		case []MultipartFile:
+			for _, f := range v {
+				if err = writeMultipartFile(w, name, f); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// writeMultipartFile writes a part with the file's content to w.
+func writeMultipartFile(w *multipart.Writer, name string, f MultipartFile) error {
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := textproto.MIMEHeader{}
+	h.Set(HeaderContentDisposition, fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(name), quoteEscaper.Replace(f.Filename)))
+	h.Set(HeaderContentType, contentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f.Body)
+	return err
+}
+
 // SetOperationValue adds/changes a mutable key/value associated with a single operation.
 func (req *Request) SetOperationValue(value interface{}) {
 	if req.values == nil {
@@ -171,23 +296,65 @@ func (req *Request) SetBody(body ReadSeekCloser) error {
 	return nil
 }
 
+// SetStreamBody sets a body that can only be read once, such as a network stream, as the HTTP request body.
+// contentLength is the body's size in bytes, or -1 when it's unknown. Unlike a body set with SetBody, the body
+// can't be rewound, so the retry policy doesn't retry the request.
+func (req *Request) SetStreamBody(body io.ReadCloser, contentLength int64) {
+	req.Request.Body = body
+	req.Request.ContentLength = contentLength
+}
+
+// SetRange sets the request's Range header to r. It removes the header when r is the whole resource.
+func (req *Request) SetRange(r HTTPRange) {
+	if v := r.Format(); v != "" {
+		req.Header.Set(HeaderRange, v)
+	} else {
+		req.Header.Del(HeaderRange)
+	}
+}
+
 // SkipBodyDownload will disable automatic downloading of the response body.
+// To skip downloading the bodies of the requests an operation sends, use WithSkipBodyDownload.
 func (req *Request) SkipBodyDownload() {
 	req.SetOperationValue(bodyDownloadPolicyOpValues{skip: true})
 }
 
-// returns true if auto-body download policy is enabled
-func (req *Request) bodyDownloadEnabled() bool {
+// SetUploadProgress reports the number of bytes of the request's body sent so far to pr, as the body is sent.
+// The count starts again from zero when the request is retried.
+func (req *Request) SetUploadProgress(pr ProgressReceiver) {
+	req.SetOperationValue(uploadProgressOpValues{pr: pr})
+}
+
+// SetDownloadProgress reports the number of bytes of the response's body received so far to pr, as the body
+// is read, either by the body download policy or, when downloading was skipped, by the caller.
+// The count starts again from zero when the request is retried.
+func (req *Request) SetDownloadProgress(pr ProgressReceiver) {
+	req.SetOperationValue(downloadProgressOpValues{pr: pr})
+}
+
+// returns true if the body download policy downloads the body of resp, the response to the request sent with ctx
+func (req *Request) bodyDownloadEnabled(ctx context.Context, resp *Response) bool {
 	var opValues bodyDownloadPolicyOpValues
 	req.OperationValue(&opValues)
-	return !opValues.skip
+	if opValues.skip {
+		return false
+	}
+	if o, ok := ctx.Value(ctxWithSkipBodyDownloadKey{}).(SkipBodyDownloadOptions); ok {
+		return o.DownloadErrors && resp != nil && resp.StatusCode >= http.StatusBadRequest
+	}
+	return true
 }
 
 // RewindBody seeks the request's Body stream back to the beginning so it can be resent when retrying an operation.
+// It returns ErrNonSeekableBody when the body isn't seekable.
 func (req *Request) RewindBody() error {
 	if req.Body != nil {
+		seeker, ok := req.Body.(io.Seeker)
+		if !ok {
+			return ErrNonSeekableBody
+		}
 		// Reset the stream back to the beginning
-		_, err := req.Body.(io.Seeker).Seek(0, io.SeekStart)
+		_, err := seeker.Seek(0, io.SeekStart)
 		return err
 	}
 	return nil
@@ -201,6 +368,40 @@ func (req *Request) Close() error {
 	return req.Body.Close()
 }
 
+// Clone returns a deep copy of the request with its context changed to ctx, for policies that send the same
+// request to several endpoints, such as geo-redundant reads. The clone's URL, headers, operation values and body
+// are independent of the request's, and calling its Next method runs the policies that follow the calling policy.
+// The body is rewound and read into memory so that each clone can be sent concurrently, then rewound again.
+// It returns ErrNonSeekableBody when the request has a body that isn't seekable.
+func (req *Request) Clone(ctx context.Context) (*Request, error) {
+	clone := &Request{
+		Request:  req.Request.Clone(ctx),
+		policies: req.policies,
+	}
+	if req.values != nil {
+		clone.values = make(opValues, len(req.values))
+		for k, v := range req.values {
+			clone.values[k] = v
+		}
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+	if err := req.RewindBody(); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.RewindBody(); err != nil {
+		return nil, err
+	}
+	clone.Request.Body = NopCloser(bytes.NewReader(b))
+	clone.Request.GetBody = nil
+	return clone, nil
+}
+
 func (req *Request) copy() *Request {
 	clonedURL := *req.URL
 	// Copy the values and immutable references
@@ -8,10 +8,16 @@ package azcore
 import (
 	"context"
 	"errors"
+	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
 )
 
 type testJSON struct {
@@ -43,6 +49,27 @@ func TestRequestMarshalXML(t *testing.T) {
 	if req.ContentLength == 0 {
 		t.Fatal("unexpected zero content length")
 	}
+	if b, _ := ioutil.ReadAll(req.Body); string(b) != "<testXML><SomeInt>1</SomeInt><SomeString>s</SomeString></testXML>" {
+		t.Fatalf("unexpected body: %s", b)
+	}
+}
+
+func TestRequestMarshalXMLWithDeclaration(t *testing.T) {
+	u, err := url.Parse("https://contoso.com")
+	if err != nil {
+		panic(err)
+	}
+	req := NewRequest(http.MethodPost, *u)
+	err = req.MarshalAsXMLWithDeclaration(testXML{SomeInt: 1, SomeString: "s"})
+	if err != nil {
+		t.Fatalf("marshal failure: %v", err)
+	}
+	if ct := req.Header.Get(HeaderContentType); ct != contentTypeAppXML {
+		t.Fatalf("unexpected content type, got %s wanted %s", ct, contentTypeAppXML)
+	}
+	if b, _ := ioutil.ReadAll(req.Body); !strings.HasPrefix(string(b), `<?xml version="1.0" encoding="UTF-8"?>`+"\n<testXML>") {
+		t.Fatalf("missing XML declaration: %s", b)
+	}
 }
 
 func TestRequestEmptyPipeline(t *testing.T) {
@@ -138,3 +165,164 @@ func TestRequestMarshalAsByteArrayStdFormat(t *testing.T) {
 		t.Fatalf("bad body, got %s", string(b))
 	}
 }
+
+func TestRequestRewindNonSeekableBody(t *testing.T) {
+	u, err := url.Parse("https://contoso.com")
+	if err != nil {
+		panic(err)
+	}
+	req := NewRequest(http.MethodPost, *u)
+	req.SetStreamBody(ioutil.NopCloser(strings.NewReader("stuff")), -1)
+	if req.ContentLength != -1 {
+		t.Fatalf("unexpected content length: %d", req.ContentLength)
+	}
+	if err := req.RewindBody(); !errors.Is(err, ErrNonSeekableBody) {
+		t.Fatalf("expected ErrNonSeekableBody, got %v", err)
+	}
+}
+
+func TestRequestSetFormData(t *testing.T) {
+	u, _ := url.Parse("https://contoso.com")
+	req := NewRequest(http.MethodPost, *u)
+	if err := req.SetFormData(url.Values{"grant_type": {"client_credentials"}, "scope": {"a b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if ct := req.Header.Get(HeaderContentType); ct != HeaderURLEncoded {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+	b, _ := ioutil.ReadAll(req.Body)
+	if string(b) != "grant_type=client_credentials&scope=a+b" || req.ContentLength != int64(len(b)) {
+		t.Fatalf("unexpected body %s", b)
+	}
+}
+
+// readMultipart returns the parts of req's multipart/form-data body as "name:filename:content" strings
+func readMultipart(t *testing.T, req *Request) []string {
+	_, params, err := mime.ParseMediaType(req.Header.Get(HeaderContentType))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := multipart.NewReader(req.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(part)
+		parts = append(parts, part.FormName()+":"+part.FileName()+":"+string(b))
+	}
+}
+
+func TestRequestSetMultipartFormData(t *testing.T) {
+	u, _ := url.Parse("https://contoso.com")
+	req := NewRequest(http.MethodPost, *u)
+	if err := req.SetMultipartFormData(map[string]interface{}{"tag": []string{"a", "b"}, "name": "value"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := req.Body.(io.Seeker); !ok || req.ContentLength <= 0 {
+		t.Fatal("expected a body without file parts to be rewindable")
+	}
+	if parts := strings.Join(readMultipart(t, req), ","); parts != "name::value,tag::a,tag::b" {
+		t.Fatalf("unexpected parts %s", parts)
+	}
+}
+
+func TestRequestSetMultipartFormDataFiles(t *testing.T) {
+	u, _ := url.Parse("https://contoso.com")
+	req := NewRequest(http.MethodPost, *u)
+	err := req.SetMultipartFormData(map[string]interface{}{
+		"image": MultipartFile{Body: strings.NewReader("content"), Filename: "image.png", ContentType: "image/png"},
+		"name":  "value",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.ContentLength != -1 {
+		t.Fatalf("expected a streamed body, got content length %d", req.ContentLength)
+	}
+	if parts := strings.Join(readMultipart(t, req), ","); parts != "image:image.png:content,name::value" {
+		t.Fatalf("unexpected parts %s", parts)
+	}
+	if err = req.SetMultipartFormData(map[string]interface{}{"count": 1}); err == nil {
+		t.Fatal("expected an error for an unsupported value")
+	}
+}
+
+func TestRequestClone(t *testing.T) {
+	u, _ := url.Parse("https://contoso.com/path?a=1")
+	req := NewRequest(http.MethodPut, *u)
+	req.Header.Set("x-ms-test", "original")
+	req.SkipBodyDownload()
+	if err := req.SetBody(NopCloser(strings.NewReader("content"))); err != nil {
+		t.Fatal(err)
+	}
+	clone, err := req.Clone(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone.Header.Set("x-ms-test", "clone")
+	clone.URL.Host = "contoso-secondary.com"
+	clone.SetOperationValue(bodyDownloadPolicyOpValues{})
+	if req.Header.Get("x-ms-test") != "original" || req.URL.Host != "contoso.com" {
+		t.Fatalf("expected the request to be left unchanged, got %v %v", req.Header, req.URL)
+	}
+	if req.bodyDownloadEnabled(context.Background(), nil) {
+		t.Fatal("expected the request's operation values to be left unchanged")
+	}
+	for _, r := range []*Request{req, clone} {
+		if b, _ := ioutil.ReadAll(r.Body); string(b) != "content" || r.ContentLength != 7 {
+			t.Fatalf("unexpected body %q of length %d", b, r.ContentLength)
+		}
+	}
+}
+
+func TestRequestCloneNonSeekableBody(t *testing.T) {
+	u, _ := url.Parse("https://contoso.com")
+	req := NewRequest(http.MethodPut, *u)
+	req.SetStreamBody(ioutil.NopCloser(strings.NewReader("content")), -1)
+	if _, err := req.Clone(context.Background()); !errors.Is(err, ErrNonSeekableBody) {
+		t.Fatalf("expected ErrNonSeekableBody, got %v", err)
+	}
+}
+
+func TestRequestCloneFanOut(t *testing.T) {
+	primary, closePrimary := mock.NewServer()
+	defer closePrimary()
+	primary.SetResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	secondary, closeSecondary := mock.NewServer()
+	defer closeSecondary()
+	secondary.SetResponse(mock.WithStatusCode(http.StatusOK))
+	secondaryURL := secondary.URL()
+	// sends the request to the primary and secondary endpoints, returning the first successful response
+	fanOut := PolicyFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		clone, err := req.Clone(ctx)
+		if err != nil {
+			return nil, err
+		}
+		clone.URL.Host = secondaryURL.Host
+		resp, err := req.Next(ctx)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		return clone.Next(ctx)
+	})
+	pl := NewPipeline(nil, fanOut)
+	req := NewRequest(http.MethodPost, primary.URL())
+	if err := req.SetBody(NopCloser(strings.NewReader("content"))); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Host != secondaryURL.Host {
+		t.Fatalf("expected a successful response from the secondary endpoint, got %d from %s", resp.StatusCode, resp.Request.URL.Host)
+	}
+	if resp.Request.ContentLength != 7 {
+		t.Fatalf("unexpected content length %d", resp.Request.ContentLength)
+	}
+}
@@ -8,7 +8,6 @@ package azcore
 import (
 	"bytes"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -81,7 +80,8 @@ func (r *Response) UnmarshalAsByteArray(v **[]byte, format Base64Encoding) error
 	}
 }
 
-// UnmarshalAsJSON calls json.Unmarshal() to unmarshal the received payload into the value pointed to by v.
+// UnmarshalAsJSON calls json.Unmarshal(), or the codec set with SetJSONCodec, to unmarshal the received payload
+// into the value pointed to by v.
 // If no payload was received a RequestError is returned.  If json.Unmarshal fails a UnmarshalError is returned.
 func (r *Response) UnmarshalAsJSON(v interface{}) error {
 	// TODO: verify early exit is correct
@@ -89,7 +89,7 @@ func (r *Response) UnmarshalAsJSON(v interface{}) error {
 		return nil
 	}
 	r.removeBOM()
-	err := json.Unmarshal(r.payload(), v)
+	err := getJSONCodec().Unmarshal(r.payload(), v)
 	if err != nil {
 		err = fmt.Errorf("unmarshalling type %s: %w", reflect.TypeOf(v).Elem().Name(), err)
 	}
@@ -97,10 +97,10 @@ func (r *Response) UnmarshalAsJSON(v interface{}) error {
 }
 
 // UnmarshalAsXML calls xml.Unmarshal() to unmarshal the received payload into the value pointed to by v.
-// If no payload was received a RequestError is returned.  If xml.Unmarshal fails a UnmarshalError is returned.
+// A payload that's empty or only whitespace leaves v unchanged.  If xml.Unmarshal fails a UnmarshalError is returned.
+// Use XMLMap for elements whose child element names are keys, such as Azure Storage metadata.
 func (r *Response) UnmarshalAsXML(v interface{}) error {
-	// TODO: verify early exit is correct
-	if len(r.payload()) == 0 {
+	if len(bytes.TrimSpace(r.payload())) == 0 {
 		return nil
 	}
 	r.removeBOM()
@@ -136,11 +136,17 @@ func (r *Response) retryAfter() time.Duration {
 	return RetryAfter(r.Response)
 }
 
-// RetryAfter returns non-zero if the response contains a Retry-After header value.
+// RetryAfter returns non-zero if the response contains a Retry-After, retry-after-ms or x-ms-retry-after-ms header value.
+// The millisecond headers take precedence because they're more precise.
 func RetryAfter(resp *http.Response) time.Duration {
 	if resp == nil {
 		return 0
 	}
+	for _, h := range []string{HeaderRetryAfterMS, HeaderXmsRetryAfterMS} {
+		if retryAfter, _ := strconv.Atoi(resp.Header.Get(h)); retryAfter > 0 {
+			return time.Duration(retryAfter) * time.Millisecond
+		}
+	}
 	ra := resp.Header.Get(HeaderRetryAfter)
 	if ra == "" {
 		return 0
@@ -149,6 +155,8 @@ func RetryAfter(resp *http.Response) time.Duration {
 	// seconds or an HTTP-date indicating when to try again
 	if retryAfter, _ := strconv.Atoi(ra); retryAfter > 0 {
 		return time.Duration(retryAfter) * time.Second
+	} else if t, err := http.ParseTime(ra); err == nil {
+		return time.Until(t)
 	} else if t, err := time.Parse(time.RFC1123, ra); err == nil {
 		return time.Until(t)
 	}
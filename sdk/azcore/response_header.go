@@ -0,0 +1,132 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderError is the error returned by the typed header methods of Response when a header's value is malformed.
+// Use errors.As() to inspect it.
+type HeaderError struct {
+	// Name is the name of the header.
+	Name string
+
+	// Value is the header's malformed value.
+	Value string
+
+	err error
+}
+
+// Error implements the error interface for type HeaderError.
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("invalid %s header %q: %v", e.Name, e.Value, e.err)
+}
+
+// Unwrap returns the error parsing the header's value.
+func (e *HeaderError) Unwrap() error {
+	return e.err
+}
+
+// HeaderTime returns the value of the named header, an RFC 1123 date such as Last-Modified, or nil when the
+// response doesn't have the header. A malformed value returns a *HeaderError.
+func (r *Response) HeaderTime(name string) (*time.Time, error) {
+	v := r.Header.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := ParseRFC1123(v)
+	if err != nil {
+		return nil, &HeaderError{Name: name, Value: v, err: err}
+	}
+	return &t, nil
+}
+
+// HeaderInt64 returns the value of the named header, a decimal integer such as x-ms-blob-content-length, or nil
+// when the response doesn't have the header. A malformed value returns a *HeaderError.
+func (r *Response) HeaderInt64(name string) (*int64, error) {
+	v := r.Header.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		return nil, &HeaderError{Name: name, Value: v, err: err}
+	}
+	return &i, nil
+}
+
+// HeaderBool returns the value of the named header, "true" or "false" in any case such as x-ms-server-encrypted,
+// or nil when the response doesn't have the header. A malformed value returns a *HeaderError.
+func (r *Response) HeaderBool(name string) (*bool, error) {
+	v := r.Header.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	var b bool
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true":
+		b = true
+	case "false":
+	default:
+		return nil, &HeaderError{Name: name, Value: v, err: errors.New("expected true or false")}
+	}
+	return &b, nil
+}
+
+// HeaderETags returns the entity tags in the values of the named header, a comma-separated list such as the
+// If-Match header of a request, with their quotes and weak W/ prefixes. An ETag header's single entity tag is
+// returned as a list of one. It returns nil when the response doesn't have the header. Entity tags without
+// quotes are returned as they are, since some services send them that way. A malformed value, such as an entity
+// tag missing its closing quote, returns a *HeaderError.
+func (r *Response) HeaderETags(name string) ([]string, error) {
+	var etags []string
+	for _, v := range r.Header[http.CanonicalHeaderKey(name)] {
+		tags, err := parseETags(v)
+		if err != nil {
+			return nil, &HeaderError{Name: name, Value: v, err: err}
+		}
+		etags = append(etags, tags...)
+	}
+	return etags, nil
+}
+
+// parseETags returns the entity tags in v, a comma-separated list.
+func parseETags(v string) ([]string, error) {
+	var etags []string
+	for v = strings.TrimSpace(v); v != ""; v = strings.TrimSpace(v) {
+		if v[0] == ',' {
+			v = v[1:]
+			continue
+		}
+		start := 0
+		if strings.HasPrefix(v, "W/") {
+			start = 2
+		}
+		var end int
+		if start < len(v) && v[start] == '"' {
+			// a quoted entity tag can't contain quotes, but may contain commas
+			closing := strings.IndexByte(v[start+1:], '"')
+			if closing < 0 {
+				return nil, errors.New("missing closing quote")
+			}
+			end = start + closing + 2
+		} else if end = strings.IndexByte(v, ','); end < 0 {
+			end = len(v)
+		}
+		etags = append(etags, strings.TrimSpace(v[:end]))
+		v = v[end:]
+		if v = strings.TrimSpace(v); v != "" && v[0] != ',' {
+			return nil, fmt.Errorf("unexpected %q after an entity tag", v)
+		}
+	}
+	return etags, nil
+}
@@ -0,0 +1,91 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newHeaderResponse(header http.Header) *Response {
+	return &Response{Response: &http.Response{Header: header}}
+}
+
+func TestResponseHeaderTime(t *testing.T) {
+	resp := newHeaderResponse(http.Header{"Last-Modified": {"Mon, 02 Jan 2006 15:04:05 GMT"}, "X-Ms-Bad": {"yesterday"}})
+	tm, err := resp.HeaderTime("last-modified")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tm.Equal(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Fatalf("unexpected time %v", tm)
+	}
+	if tm, err = resp.HeaderTime(HeaderDate); tm != nil || err != nil {
+		t.Fatalf("expected nil for a missing header, got %v, %v", tm, err)
+	}
+	var headerErr *HeaderError
+	if _, err = resp.HeaderTime("x-ms-bad"); !errors.As(err, &headerErr) || headerErr.Name != "x-ms-bad" || headerErr.Value != "yesterday" {
+		t.Fatalf("expected a HeaderError, got %v", err)
+	}
+}
+
+func TestResponseHeaderInt64(t *testing.T) {
+	resp := newHeaderResponse(http.Header{"X-Ms-Blob-Content-Length": {"8589934592"}, "X-Ms-Bad": {"12ab"}})
+	i, err := resp.HeaderInt64("x-ms-blob-content-length")
+	if err != nil || *i != 8589934592 {
+		t.Fatalf("unexpected value %v, %v", i, err)
+	}
+	if i, err = resp.HeaderInt64("x-ms-missing"); i != nil || err != nil {
+		t.Fatalf("expected nil for a missing header, got %v, %v", i, err)
+	}
+	var headerErr *HeaderError
+	if _, err = resp.HeaderInt64("x-ms-bad"); !errors.As(err, &headerErr) {
+		t.Fatalf("expected a HeaderError, got %v", err)
+	}
+}
+
+func TestResponseHeaderBool(t *testing.T) {
+	resp := newHeaderResponse(http.Header{"X-Ms-Server-Encrypted": {"True"}, "X-Ms-Deleted": {"false"}, "X-Ms-Bad": {"1"}})
+	if b, err := resp.HeaderBool("x-ms-server-encrypted"); err != nil || !*b {
+		t.Fatalf("unexpected value %v, %v", b, err)
+	}
+	if b, err := resp.HeaderBool("x-ms-deleted"); err != nil || *b {
+		t.Fatalf("unexpected value %v, %v", b, err)
+	}
+	if b, err := resp.HeaderBool("x-ms-missing"); b != nil || err != nil {
+		t.Fatalf("expected nil for a missing header, got %v, %v", b, err)
+	}
+	var headerErr *HeaderError
+	if _, err := resp.HeaderBool("x-ms-bad"); !errors.As(err, &headerErr) {
+		t.Fatalf("expected a HeaderError, got %v", err)
+	}
+}
+
+func TestResponseHeaderETags(t *testing.T) {
+	resp := newHeaderResponse(http.Header{
+		"Etag":     {`"0x8D8A"`},
+		"If-Match": {`"a", W/"b,c" ,0x8D8B`, `*`},
+		"X-Ms-Bad": {`"a", "b`},
+	})
+	etags, err := resp.HeaderETags("etag")
+	if err != nil || !reflect.DeepEqual(etags, []string{`"0x8D8A"`}) {
+		t.Fatalf("unexpected entity tags %v, %v", etags, err)
+	}
+	etags, err = resp.HeaderETags(HeaderIfMatch)
+	if err != nil || !reflect.DeepEqual(etags, []string{`"a"`, `W/"b,c"`, "0x8D8B", "*"}) {
+		t.Fatalf("unexpected entity tags %v, %v", etags, err)
+	}
+	if etags, err = resp.HeaderETags("x-ms-missing"); etags != nil || err != nil {
+		t.Fatalf("expected nil for a missing header, got %v, %v", etags, err)
+	}
+	if _, err = resp.HeaderETags("x-ms-bad"); err == nil || !strings.Contains(err.Error(), "closing quote") {
+		t.Fatalf("expected a missing quote error, got %v", err)
+	}
+}
@@ -105,6 +105,24 @@ func TestResponseUnmarshalXMLNoBody(t *testing.T) {
 	}
 }
 
+func TestResponseUnmarshalXMLWhitespace(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithBody([]byte("\r\n")))
+	pl := NewPipeline(srv)
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, srv.URL()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tx := testXML{SomeInt: 1}
+	if err := resp.UnmarshalAsXML(&tx); err != nil {
+		t.Fatalf("unexpected error unmarshalling: %v", err)
+	}
+	if tx.SomeInt != 1 {
+		t.Fatalf("unexpected value: %v", tx)
+	}
+}
+
 func TestRetryAfter(t *testing.T) {
 	raw := &http.Response{
 		Header: http.Header{},
@@ -131,6 +149,18 @@ func TestRetryAfter(t *testing.T) {
 	if s := d / time.Second; s < 598 || s > 602 {
 		t.Fatalf("expected ~600 seconds, got %d", s)
 	}
+	raw.Header.Set(HeaderRetryAfter, atDate.UTC().Format(http.TimeFormat))
+	if s := resp.retryAfter() / time.Second; s < 598 || s > 602 {
+		t.Fatalf("expected ~600 seconds from an HTTP-date, got %d", s)
+	}
+	raw.Header.Set(HeaderXmsRetryAfterMS, "1500")
+	if d = resp.retryAfter(); d != 1500*time.Millisecond {
+		t.Fatalf("expected x-ms-retry-after-ms to take precedence, got %v", d)
+	}
+	raw.Header.Set(HeaderRetryAfterMS, "250")
+	if d = resp.retryAfter(); d != 250*time.Millisecond {
+		t.Fatalf("expected retry-after-ms to take precedence, got %v", d)
+	}
 }
 
 func TestResponseUnmarshalAsByteArrayURLFormat(t *testing.T) {
@@ -0,0 +1,103 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// RetryReaderGetter sends the request for the rest of a body whose stream broke, starting at offset, the number of
+// bytes of the body received so far. count is how many bytes remain, or -1 when the body's size is unknown.
+// It typically sets the request's Range header and must skip the body's download.
+type RetryReaderGetter func(ctx context.Context, offset, count int64) (*Response, error)
+
+// RetryReaderOptions configures the behavior of the reader created by NewRetryReader.
+type RetryReaderOptions struct {
+	// MaxRetryRequests is how many times the reader re-issues the request after the stream breaks.
+	// The default is 3. Pass a negative value to never re-issue it.
+	MaxRetryRequests int
+
+	// NotifyFailedRead, when not nil, is called each time reading the body fails, with the number of failures
+	// so far, the error, the offset the body will be resumed from and whether the request will be re-issued.
+	NotifyFailedRead func(failureCount int, lastError error, offset, count int64, willRetry bool)
+}
+
+type retryReader struct {
+	ctx     context.Context
+	resp    *Response
+	getter  RetryReaderGetter
+	options RetryReaderOptions
+	// offset is how many bytes were received, count the body's size or -1 when it's unknown
+	offset, count int64
+	failures      int
+}
+
+// NewRetryReader returns a reader of resp's body that, when the stream breaks before the end of the body, calls
+// getter to request the rest of the body from the last offset received, up to MaxRetryRequests times. count is the
+// body's size, or -1 when it's unknown; with a known size a stream ending early is treated as broken. resp must be
+// the response of a request whose body download was skipped. Failing to read the body returns the last error once
+// the reader stops re-issuing the request, or when ctx is done.
+func NewRetryReader(ctx context.Context, resp *Response, count int64, getter RetryReaderGetter, o RetryReaderOptions) io.ReadCloser {
+	if o.MaxRetryRequests == 0 {
+		o.MaxRetryRequests = 3
+	}
+	return &retryReader{ctx: ctx, resp: resp, getter: getter, options: o, count: count}
+}
+
+func (r *retryReader) Read(p []byte) (int, error) {
+	for {
+		if r.resp == nil {
+			remaining := int64(-1)
+			if r.count >= 0 {
+				remaining = r.count - r.offset
+			}
+			resp, err := r.getter(r.ctx, r.offset, remaining)
+			if err != nil {
+				return 0, err
+			}
+			if resp.StatusCode >= http.StatusBadRequest {
+				return 0, NewResponseError(resp)
+			}
+			r.resp = resp
+		}
+		n, err := r.resp.Body.Read(p)
+		r.offset += int64(n)
+		if err == nil || (err == io.EOF && (r.count < 0 || r.offset >= r.count)) {
+			return n, err
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if r.ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// the caller cancelled the download
+			return n, err
+		}
+		r.failures++
+		willRetry := r.failures <= r.options.MaxRetryRequests
+		if r.options.NotifyFailedRead != nil {
+			r.options.NotifyFailedRead(r.failures, err, r.offset, r.count, willRetry)
+		}
+		if !willRetry {
+			return n, err
+		}
+		r.resp.Body.Close()
+		r.resp = nil
+		if n > 0 {
+			// return what was read, the request is re-issued by the next Read
+			return n, nil
+		}
+	}
+}
+
+func (r *retryReader) Close() error {
+	if r.resp != nil && r.resp.Body != nil {
+		return r.resp.Body.Close()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+var errBrokenStream = errors.New("connection reset by peer")
+
+// brokenBody returns content then fails with err, which is io.EOF for a body that ends early
+type brokenBody struct {
+	r   io.Reader
+	err error
+}
+
+func (b *brokenBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		err = b.err
+	}
+	return n, err
+}
+
+func (b *brokenBody) Close() error {
+	return nil
+}
+
+func newBrokenResponse(content []byte, err error) *Response {
+	return &Response{&http.Response{StatusCode: http.StatusPartialContent, Header: http.Header{}, Body: &brokenBody{r: bytes.NewReader(content), err: err}}}
+}
+
+func TestRetryReader(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	var offsets []int64
+	getter := func(ctx context.Context, offset, count int64) (*Response, error) {
+		offsets = append(offsets, offset)
+		if count != int64(len(content))-offset {
+			t.Fatalf("unexpected count %d at offset %d", count, offset)
+		}
+		if len(offsets) == 1 {
+			// the stream breaks again, ending early
+			return newBrokenResponse(content[offset:offset+5], io.EOF), nil
+		}
+		return newBrokenResponse(content[offset:], io.EOF), nil
+	}
+	var failures []error
+	reader := NewRetryReader(context.Background(), newBrokenResponse(content[:7], errBrokenStream), int64(len(content)), getter, RetryReaderOptions{
+		NotifyFailedRead: func(failureCount int, lastError error, offset, count int64, willRetry bool) {
+			failures = append(failures, lastError)
+		},
+	})
+	defer reader.Close()
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Fatalf("unexpected content: %s", b)
+	}
+	if len(offsets) != 2 || offsets[0] != 7 || offsets[1] != 12 {
+		t.Fatalf("unexpected offsets: %v", offsets)
+	}
+	if len(failures) != 2 || failures[0] != errBrokenStream || failures[1] != io.ErrUnexpectedEOF {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+}
+
+func TestRetryReaderMaxRetryRequests(t *testing.T) {
+	requests := 0
+	getter := func(ctx context.Context, offset, count int64) (*Response, error) {
+		requests++
+		return newBrokenResponse(nil, errBrokenStream), nil
+	}
+	reader := NewRetryReader(context.Background(), newBrokenResponse([]byte("abc"), errBrokenStream), -1, getter, RetryReaderOptions{MaxRetryRequests: 2})
+	b, err := ioutil.ReadAll(reader)
+	if err != errBrokenStream {
+		t.Fatalf("expected the stream's error, got %v", err)
+	}
+	if string(b) != "abc" || requests != 2 {
+		t.Fatalf("unexpected content %q after %d requests", b, requests)
+	}
+}
+
+func TestRetryReaderUnknownSize(t *testing.T) {
+	getter := func(ctx context.Context, offset, count int64) (*Response, error) {
+		t.Fatal("unexpected request")
+		return nil, nil
+	}
+	reader := NewRetryReader(context.Background(), newBrokenResponse([]byte("abc"), io.EOF), -1, getter, RetryReaderOptions{})
+	if b, err := ioutil.ReadAll(reader); err != nil || string(b) != "abc" {
+		t.Fatalf("unexpected result %q, %v", b, err)
+	}
+}
+
+func TestRetryReaderContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	getter := func(ctx context.Context, offset, count int64) (*Response, error) {
+		t.Fatal("unexpected request")
+		return nil, nil
+	}
+	reader := NewRetryReader(ctx, newBrokenResponse([]byte("abc"), errBrokenStream), 10, getter, RetryReaderOptions{})
+	if _, err := ioutil.ReadAll(reader); err != errBrokenStream {
+		t.Fatalf("expected the stream's error, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+)
+
+// Tracer records the operations of SDK clients as spans in the application's distributed tracing system.
+// Implement it with an adapter for the tracing library the application uses, for example by wrapping an
+// OpenTelemetry trace.Tracer, so that the spans join the traces of the application's own operations.
+type Tracer interface {
+	// Start begins a span named name as a child of the span in ctx, if any, and returns a context containing the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation recorded by a Tracer.
+type Span interface {
+	// SetAttribute records a property of the operation. Values are strings, booleans or integers.
+	SetAttribute(key string, value interface{})
+	// RecordError records that the operation failed with err.
+	RecordError(err error)
+	// End completes the span.
+	End()
+}
+
+// StartSpan begins a span with tracer, which may be nil. When it is, the returned context is ctx and the span does nothing.
+func StartSpan(ctx context.Context, tracer Tracer, name string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return tracer.Start(ctx, name)
+}
+
+// nopSpan is the Span returned by StartSpan when there's no Tracer.
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(string, interface{}) {}
+
+func (nopSpan) RecordError(error) {}
+
+func (nopSpan) End() {}
+
+// TraceContextSpan is a Span that identifies itself in the W3C Trace Context format, so that the tracing policy
+// can propagate it to services in the traceparent and tracestate headers. Adapters for tracing libraries that
+// support W3C Trace Context, such as OpenTelemetry, should return spans implementing it.
+type TraceContextSpan interface {
+	Span
+	// TraceParent returns the value of the traceparent header identifying the span, for example
+	// "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01".
+	TraceParent() string
+	// TraceState returns the value of the tracestate header, or an empty string when there's none.
+	TraceState() string
+}
@@ -0,0 +1,66 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"testing"
+)
+
+type spanKey struct{}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &testSpan{name: name, attributes: map[string]interface{}{}}
+	if parent, ok := ctx.Value(spanKey{}).(*testSpan); ok {
+		s.parent = parent
+	}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+type testSpan struct {
+	name       string
+	parent     *testSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+
+func (s *testSpan) RecordError(err error) { s.err = err }
+
+func (s *testSpan) End() { s.ended = true }
+
+func TestStartSpan(t *testing.T) {
+	tracer := &testTracer{}
+	ctx, parent := StartSpan(context.Background(), tracer, "parent")
+	_, child := StartSpan(ctx, tracer, "child")
+	child.SetAttribute("key", "value")
+	child.End()
+	parent.End()
+	if len(tracer.spans) != 2 || tracer.spans[1].parent != tracer.spans[0] {
+		t.Fatalf("Expected the child span to join the parent span")
+	}
+	if s := tracer.spans[1]; !s.ended || s.attributes["key"] != "value" {
+		t.Fatalf("Unexpected span: %+v", s)
+	}
+}
+
+func TestStartSpanNilTracer(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := StartSpan(ctx, nil, "span")
+	if spanCtx != ctx {
+		t.Fatalf("Expected the context to be unchanged")
+	}
+	span.SetAttribute("key", "value")
+	span.RecordError(nil)
+	span.End()
+}
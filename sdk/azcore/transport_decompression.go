@@ -0,0 +1,92 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	headerAcceptEncoding = "Accept-Encoding"
+
+	// acceptEncodings are the encodings requested when TransportOptions.Decompression is set
+	acceptEncodings = "gzip, deflate"
+)
+
+// decompressingRoundTripper requests compressed responses and decompresses their bodies as they're read
+type decompressingRoundTripper struct {
+	rt http.RoundTripper
+}
+
+func (d *decompressingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// requests that set their own encoding, or request a range of the encoded content, receive it as is
+	requested := req.Header.Get(headerAcceptEncoding) == "" && req.Header.Get(HeaderRange) == "" && req.Method != http.MethodHead
+	if requested {
+		req = req.Clone(req.Context())
+		req.Header.Set(headerAcceptEncoding, acceptEncodings)
+	}
+	resp, err := d.rt.RoundTrip(req)
+	if err != nil || !requested || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get(HeaderContentEncoding)))
+	if encoding != "gzip" && encoding != "deflate" {
+		return resp, nil
+	}
+	// like net/http, the decompressed body's length is unknown
+	resp.Body = &decompressingReader{body: resp.Body, encoding: encoding}
+	resp.Header.Del(HeaderContentEncoding)
+	resp.Header.Del(HeaderContentLength)
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// decompressingReader decompresses body, creating the decompressor on the first Read so that
+// a malformed body fails the read instead of the request
+type decompressingReader struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (d *decompressingReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = newDecompressor(d.body, d.encoding)
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+func (d *decompressingReader) Close() error {
+	return d.body.Close()
+}
+
+// newDecompressor returns a reader of the content of body, encoded with encoding.
+func newDecompressor(body io.Reader, encoding string) (io.Reader, error) {
+	if encoding == "gzip" {
+		return gzip.NewReader(body)
+	}
+	// the deflate encoding is zlib-wrapped, but some servers send a raw deflate stream
+	br := bufio.NewReader(body)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
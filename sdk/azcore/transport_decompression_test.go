@@ -0,0 +1,105 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const decompressionContent = `{"value":["a large list of resources"]}`
+
+// newCompressingServer responds with decompressionContent encoded as the encoding query parameter names,
+// recording the Accept-Encoding of each request
+func newCompressingServer(t *testing.T, acceptEncodings *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		*acceptEncodings = append(*acceptEncodings, req.Header.Get(headerAcceptEncoding))
+		encoding := req.URL.Query().Get("encoding")
+		b := &bytes.Buffer{}
+		var wc io.WriteCloser
+		switch encoding {
+		case "gzip":
+			wc = gzip.NewWriter(b)
+		case "deflate":
+			wc = zlib.NewWriter(b)
+		case "rawdeflate":
+			wc, _ = flate.NewWriter(b, flate.DefaultCompression)
+			encoding = "deflate"
+		}
+		if wc != nil {
+			if _, err := wc.Write([]byte(decompressionContent)); err != nil {
+				t.Fatal(err)
+			}
+			wc.Close()
+			w.Header().Set(HeaderContentEncoding, encoding)
+		} else {
+			b.WriteString(decompressionContent)
+		}
+		w.Write(b.Bytes())
+	}))
+}
+
+func TestDecompression(t *testing.T) {
+	var acceptEncodings []string
+	srv := newCompressingServer(t, &acceptEncodings)
+	defer srv.Close()
+	pl := NewPipeline(NewDefaultHTTPClientTransport(TransportOptions{Decompression: true}))
+	for _, encoding := range []string{"gzip", "deflate", "rawdeflate", "identity"} {
+		u, _ := url.Parse(srv.URL + "?encoding=" + encoding)
+		resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, *u))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(resp.payload()) != decompressionContent {
+			t.Fatalf("unexpected %s body: %q", encoding, resp.payload())
+		}
+		if encoding != "identity" && (resp.Header.Get(HeaderContentEncoding) != "" || resp.Header.Get(HeaderContentLength) != "" || resp.ContentLength != -1) {
+			t.Fatalf("expected the %s encoding's headers to be removed, got %v", encoding, resp.Header)
+		}
+	}
+	for _, ae := range acceptEncodings {
+		if ae != "gzip, deflate" {
+			t.Fatalf("unexpected Accept-Encoding %q", ae)
+		}
+	}
+}
+
+func TestDecompressionOwnAcceptEncoding(t *testing.T) {
+	var acceptEncodings []string
+	srv := newCompressingServer(t, &acceptEncodings)
+	defer srv.Close()
+	pl := NewPipeline(NewDefaultHTTPClientTransport(TransportOptions{Decompression: true}))
+	u, _ := url.Parse(srv.URL + "?encoding=deflate")
+	req := NewRequest(http.MethodGet, *u)
+	req.Header.Set(headerAcceptEncoding, "deflate")
+	resp, err := pl.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Header.Get(HeaderContentEncoding) != "deflate" {
+		t.Fatal("expected the response to be left encoded")
+	}
+	r, err := zlib.NewReader(bytes.NewReader(resp.payload()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(r); string(b) != decompressionContent {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if strings.Join(acceptEncodings, ",") != "deflate" {
+		t.Fatalf("unexpected Accept-Encoding %v", acceptEncodings)
+	}
+}
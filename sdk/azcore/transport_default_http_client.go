@@ -8,28 +8,197 @@ package azcore
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 var defaultHTTPClient *http.Client
 
 func init() {
+	// TODO: in track 1 we created a cookiejar, do we need one here?  make it an option?  user-specified HTTP client policy?
+	defaultHTTPClient = &http.Client{
+		Transport: newHTTPTransport(TransportOptions{}),
+	}
+}
+
+// TransportOptions configures the transport created by NewDefaultHTTPClientTransport.
+// The zero value creates a transport that behaves like DefaultHTTPClientTransport.
+type TransportOptions struct {
+	// MinTLSVersion is the minimum TLS version the transport accepts, such as tls.VersionTLS13.
+	// Versions below TLS 1.2, the default, aren't supported and are raised to it.
+	MinTLSVersion uint16
+
+	// RootCAs are the certificate authorities that server certificates are verified against, for example
+	// when a proxy intercepts TLS with a private CA. Leave this as nil to use the host's root CA set.
+	RootCAs *x509.CertPool
+
+	// ClientCertificates are presented to servers that request a client certificate (mutual TLS).
+	ClientCertificates []tls.Certificate
+
+	// ProxyURL is the URL of the proxy that requests are sent through, such as "http://proxy.contoso.com:8080".
+	// Leave this as nil to use the proxy named by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	ProxyURL *url.URL
+
+	// NoProxy lists the hosts that are connected to directly instead of through the proxy, whether it's ProxyURL
+	// or the environment's. Like NO_PROXY, entries are host names, which match their subdomains too, with an
+	// optional port, IP addresses, CIDR ranges such as "10.0.0.0/8", or "*" to match every host.
+	NoProxy []string
+
+	// MaxIdleConns is the maximum number of idle connections kept open across all hosts.
+	// Leave this as zero to use the default of 100.
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost is the maximum number of idle connections kept open to each host. Processes sending
+	// many concurrent requests to one service, such as a storage account, should raise it close to the number
+	// of concurrent requests. Leave this as zero to use the net/http default of 2.
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost limits the number of connections to each host, requests beyond it wait for a connection.
+	// Leave this as zero for no limit.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout is how long an idle connection is kept open. Leave this as zero to use the default of 90 seconds.
+	IdleConnTimeout time.Duration
+
+	// EnableHTTP2 attempts to use HTTP/2 with servers that support it.
+	EnableHTTP2 bool
+
+	// DialTimeout limits how long establishing a TCP connection may take, so requests to an unreachable host
+	// fail fast. Leave this as zero to use the default of 30 seconds.
+	DialTimeout time.Duration
+
+	// KeepAlive is the interval between TCP keep-alive probes of open connections. Leave this as zero to use
+	// the default of 30 seconds, or set it to a negative value to disable keep-alive probes.
+	KeepAlive time.Duration
+
+	// TLSHandshakeTimeout limits how long the TLS handshake of a new connection may take.
+	// Leave this as zero to use the default of 10 seconds.
+	TLSHandshakeTimeout time.Duration
+
+	// Decompression requests compressed responses, with an Accept-Encoding of "gzip, deflate", and decompresses
+	// their bodies as they're read. Like net/http, which only requests gzip, decompressed responses have no
+	// Content-Encoding and Content-Length headers and a ContentLength of -1. Requests that set their own
+	// Accept-Encoding or a Range header receive the content as the service encodes it. Don't combine this with
+	// the content integrity policy, the hashes services send are of the encoded content.
+	Decompression bool
+}
+
+// defaultDialTimeout and defaultKeepAlive are the dialer settings of http.DefaultTransport.
+const (
+	defaultDialTimeout = 30 * time.Second
+	defaultKeepAlive   = 30 * time.Second
+)
+
+func newHTTPTransport(o TransportOptions) *http.Transport {
 	defaultTransport := http.DefaultTransport.(*http.Transport)
-	transport := &http.Transport{
-		Proxy:                 defaultTransport.Proxy,
-		DialContext:           defaultTransport.DialContext,
-		MaxIdleConns:          defaultTransport.MaxIdleConns,
-		IdleConnTimeout:       defaultTransport.IdleConnTimeout,
-		TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
+	minVersion := o.MinTLSVersion
+	if minVersion < tls.VersionTLS12 {
+		minVersion = tls.VersionTLS12
+	}
+	proxy := defaultTransport.Proxy
+	if o.ProxyURL != nil || len(o.NoProxy) > 0 {
+		proxy = newProxyFunc(o.ProxyURL, o.NoProxy, defaultTransport.Proxy)
+	}
+	maxIdleConns := o.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = defaultTransport.MaxIdleConns
+	}
+	idleConnTimeout := o.IdleConnTimeout
+	if idleConnTimeout == 0 {
+		idleConnTimeout = defaultTransport.IdleConnTimeout
+	}
+	dialContext := defaultTransport.DialContext
+	if o.DialTimeout != 0 || o.KeepAlive != 0 {
+		dialer := &net.Dialer{Timeout: o.DialTimeout, KeepAlive: o.KeepAlive}
+		if dialer.Timeout == 0 {
+			dialer.Timeout = defaultDialTimeout
+		}
+		if dialer.KeepAlive == 0 {
+			dialer.KeepAlive = defaultKeepAlive
+		}
+		dialContext = dialer.DialContext
+	}
+	tlsHandshakeTimeout := o.TLSHandshakeTimeout
+	if tlsHandshakeTimeout == 0 {
+		tlsHandshakeTimeout = defaultTransport.TLSHandshakeTimeout
+	}
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialContext,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   o.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       o.MaxConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
 		ExpectContinueTimeout: defaultTransport.ExpectContinueTimeout,
+		// a custom TLS configuration disables HTTP/2 unless it's forced
+		ForceAttemptHTTP2: o.EnableHTTP2,
 		TLSClientConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12,
+			MinVersion:   minVersion,
+			RootCAs:      o.RootCAs,
+			Certificates: o.ClientCertificates,
 		},
 	}
-	// TODO: in track 1 we created a cookiejar, do we need one here?  make it an option?  user-specified HTTP client policy?
-	defaultHTTPClient = &http.Client{
-		Transport: transport,
+}
+
+// newProxyFunc returns a function selecting proxyURL, or the proxy chosen by fallback when proxyURL is nil,
+// for requests to hosts that don't match noProxy.
+func newProxyFunc(proxyURL *url.URL, noProxy []string, fallback func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if matchesNoProxy(req.URL, noProxy) {
+			return nil, nil
+		}
+		if proxyURL != nil {
+			return proxyURL, nil
+		}
+		return fallback(req)
+	}
+}
+
+// matchesNoProxy returns true when u's host matches one of the entries of a NO_PROXY list.
+func matchesNoProxy(u *url.URL, noProxy []string) bool {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(strings.Trim(entryHost, "[]")); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost = strings.TrimPrefix(entryHost, ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
 	}
+	return false
 }
 
 // DefaultHTTPClientTransport ...
@@ -38,3 +207,19 @@ func DefaultHTTPClientTransport() Transport {
 		return defaultHTTPClient.Do(req.WithContext(ctx))
 	})
 }
+
+// NewDefaultHTTPClientTransport creates a Transport like DefaultHTTPClientTransport's, configured by o.
+// Unlike DefaultHTTPClientTransport, it doesn't share connections with other transports, so create one
+// per configuration and reuse it.
+func NewDefaultHTTPClientTransport(o TransportOptions) Transport {
+	transport := newHTTPTransport(o)
+	var rt http.RoundTripper = transport
+	if o.Decompression {
+		transport.DisableCompression = true
+		rt = &decompressingRoundTripper{rt: transport}
+	}
+	client := &http.Client{Transport: rt}
+	return TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return client.Do(req.WithContext(ctx))
+	})
+}
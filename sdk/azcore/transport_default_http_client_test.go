@@ -0,0 +1,196 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func sendTestRequest(t *testing.T, transport Transport, srv *httptest.Server) (*Response, error) {
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPipeline(transport).Do(context.Background(), NewRequest(http.MethodGet, *u))
+}
+
+func TestNewDefaultHTTPClientTransportRootCAs(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	if _, err := sendTestRequest(t, NewDefaultHTTPClientTransport(TransportOptions{}), srv); err == nil {
+		t.Fatal("expected an error for a certificate from an unknown authority")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	resp, err := sendTestRequest(t, NewDefaultHTTPClientTransport(TransportOptions{RootCAs: roots}), srv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+func TestNewDefaultHTTPClientTransportClientCertificates(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	if _, err := sendTestRequest(t, NewDefaultHTTPClientTransport(TransportOptions{RootCAs: roots}), srv); err == nil {
+		t.Fatal("expected an error without a client certificate")
+	}
+	transport := NewDefaultHTTPClientTransport(TransportOptions{RootCAs: roots, ClientCertificates: srv.TLS.Certificates})
+	resp, err := sendTestRequest(t, transport, srv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+func TestNewHTTPTransportMinTLSVersion(t *testing.T) {
+	if v := newHTTPTransport(TransportOptions{MinTLSVersion: tls.VersionTLS10}).TLSClientConfig.MinVersion; v != tls.VersionTLS12 {
+		t.Fatalf("expected TLS 1.2, got %x", v)
+	}
+	if v := newHTTPTransport(TransportOptions{MinTLSVersion: tls.VersionTLS13}).TLSClientConfig.MinVersion; v != tls.VersionTLS13 {
+		t.Fatalf("expected TLS 1.3, got %x", v)
+	}
+}
+
+func TestMatchesNoProxy(t *testing.T) {
+	noProxy := []string{"contoso.com", ".fabrikam.com", "storage.test:8443", "10.0.0.0/8", "192.168.1.1", "[::1]"}
+	cases := map[string]bool{
+		"https://contoso.com":              true,
+		"https://api.contoso.com/path":     true,
+		"https://notcontoso.com":           false,
+		"https://fabrikam.com":             true,
+		"https://a.b.FABRIKAM.com":         true,
+		"https://storage.test:8443":        true,
+		"https://storage.test":             false,
+		"http://10.1.2.3:8080":             true,
+		"http://11.1.2.3":                  false,
+		"http://192.168.1.1":               true,
+		"http://[::1]:80":                  true,
+		"https://management.azure.com":     false,
+		"https://management.azure.com:443": false,
+	}
+	for rawurl, expected := range cases {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := matchesNoProxy(u, noProxy); actual != expected {
+			t.Fatalf("expected %t for %s, got %t", expected, rawurl, actual)
+		}
+	}
+	u, _ := url.Parse("https://anything.test:443")
+	if !matchesNoProxy(u, []string{"*"}) || !matchesNoProxy(u, []string{"anything.test:443"}) {
+		t.Fatal("expected a match")
+	}
+}
+
+func TestNewDefaultHTTPClientTransportProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxied request's URL is absolute
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := NewDefaultHTTPClientTransport(TransportOptions{ProxyURL: proxyURL, NoProxy: []string{"direct.test"}})
+	pl := NewPipeline(transport)
+	u, _ := url.Parse("http://service.test/path")
+	resp, err := pl.Do(context.Background(), NewRequest(http.MethodGet, *u))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(proxied) != 1 || proxied[0] != "http://service.test/path" {
+		t.Fatalf("expected the request to be sent through the proxy, got %v", proxied)
+	}
+	u, _ = url.Parse("http://direct.test/path")
+	if _, err = pl.Do(context.Background(), NewRequest(http.MethodGet, *u)); err == nil {
+		t.Fatal("expected a direct connection to an unknown host to fail")
+	}
+	if len(proxied) != 1 {
+		t.Fatalf("expected the request to bypass the proxy, got %v", proxied)
+	}
+}
+
+func TestNewHTTPTransportConnectionOptions(t *testing.T) {
+	transport := newHTTPTransport(TransportOptions{})
+	if transport.MaxIdleConns != 100 || transport.IdleConnTimeout != 90*time.Second || transport.MaxConnsPerHost != 0 || transport.ForceAttemptHTTP2 {
+		t.Fatalf("unexpected defaults: %+v", transport)
+	}
+	transport = newHTTPTransport(TransportOptions{MaxIdleConns: 500, MaxIdleConnsPerHost: 50, MaxConnsPerHost: 64, IdleConnTimeout: time.Minute})
+	if transport.MaxIdleConns != 500 || transport.MaxIdleConnsPerHost != 50 || transport.MaxConnsPerHost != 64 || transport.IdleConnTimeout != time.Minute {
+		t.Fatalf("options weren't applied: %+v", transport)
+	}
+}
+
+func TestNewHTTPTransportTimeouts(t *testing.T) {
+	transport := newHTTPTransport(TransportOptions{})
+	if transport.TLSHandshakeTimeout != 10*time.Second {
+		t.Fatalf("unexpected default TLS handshake timeout %v", transport.TLSHandshakeTimeout)
+	}
+	transport = newHTTPTransport(TransportOptions{TLSHandshakeTimeout: time.Second})
+	if transport.TLSHandshakeTimeout != time.Second {
+		t.Fatalf("unexpected TLS handshake timeout %v", transport.TLSHandshakeTimeout)
+	}
+	// a listener that never accepts connections holds TLS handshakes until they time out
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	client := NewDefaultHTTPClientTransport(TransportOptions{DialTimeout: time.Second, KeepAlive: -1, TLSHandshakeTimeout: 50 * time.Millisecond})
+	req, err := http.NewRequest(http.MethodGet, "https://"+l.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err = client.Do(context.Background(), req); err == nil {
+		t.Fatal("expected the TLS handshake to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the handshake to time out quickly, it took %v", elapsed)
+	}
+}
+
+func TestNewDefaultHTTPClientTransportHTTP2(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	for _, enable := range []bool{false, true} {
+		resp, err := sendTestRequest(t, NewDefaultHTTPClientTransport(TransportOptions{RootCAs: roots, EnableHTTP2: enable}), srv)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if (resp.ProtoMajor == 2) != enable {
+			t.Fatalf("unexpected protocol %s when EnableHTTP2 is %t", resp.Proto, enable)
+		}
+	}
+}
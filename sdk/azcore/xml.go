@@ -0,0 +1,58 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+// XMLMap is a map encoded as an XML element with a child element per key, such as the metadata of Azure Storage
+// containers and blobs, <Metadata><key>value</key></Metadata>, which encoding/xml can't encode as a map. Marshaling
+// writes the keys in sorted order. An empty element unmarshals as an empty map.
+type XMLMap map[string]string
+
+// MarshalXML implements the xml.Marshaler interface for XMLMap.
+func (m XMLMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := e.EncodeElement(m[k], xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface for XMLMap.
+// Child elements are matched by local name, their namespace is ignored.
+func (m *XMLMap) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if *m == nil {
+		*m = XMLMap{}
+	}
+	for {
+		tk, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tk.(type) {
+		case xml.StartElement:
+			var v string
+			if err = d.DecodeElement(&v, &t); err != nil {
+				return err
+			}
+			(*m)[t.Name.Local] = v
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
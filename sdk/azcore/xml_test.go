@@ -0,0 +1,48 @@
+// +build go1.13
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+type testXMLMetadata struct {
+	XMLName  xml.Name `xml:"Blob"`
+	Name     string   `xml:"Name"`
+	Metadata XMLMap   `xml:"Metadata,omitempty"`
+}
+
+func TestXMLMapMarshal(t *testing.T) {
+	b, err := xml.Marshal(testXMLMetadata{Name: "blob", Metadata: XMLMap{"b": "2", "a": "1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := string(b); s != "<Blob><Name>blob</Name><Metadata><a>1</a><b>2</b></Metadata></Blob>" {
+		t.Fatalf("unexpected XML: %s", s)
+	}
+	if b, err = xml.Marshal(testXMLMetadata{Name: "blob"}); err != nil || string(b) != "<Blob><Name>blob</Name></Blob>" {
+		t.Fatalf("unexpected XML: %s, %v", b, err)
+	}
+}
+
+func TestXMLMapUnmarshal(t *testing.T) {
+	var v testXMLMetadata
+	err := xml.Unmarshal([]byte(`<Blob xmlns:x="urn:x"><Name>blob</Name><Metadata><a>1</a> <x:b>2</x:b><c /></Metadata></Blob>`), &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(v.Metadata) != 3 || v.Metadata["a"] != "1" || v.Metadata["b"] != "2" || v.Metadata["c"] != "" {
+		t.Fatalf("unexpected metadata: %v", v.Metadata)
+	}
+	v = testXMLMetadata{}
+	if err = xml.Unmarshal([]byte(`<Blob><Name>blob</Name><Metadata /></Blob>`), &v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Metadata == nil || len(v.Metadata) != 0 {
+		t.Fatalf("expected empty metadata, got %v", v.Metadata)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// retriableAADErrors are the OAuth error codes Azure Active Directory returns for failures that may succeed when
+// the request is sent again. Every other error, such as invalid_client or invalid_grant, is terminal.
+var retriableAADErrors = map[string]bool{
+	"temporarily_unavailable": true,
+	"server_error":            true,
+}
+
+// retriableAADSTSCodes are the numeric AADSTS error codes of transient failures, which Azure Active Directory
+// may return with an error such as invalid_request that is otherwise terminal.
+var retriableAADSTSCodes = map[int]bool{
+	80001: true, // the authentication agent can't connect to Active Directory
+	80002: true, // the authentication agent's password validation request timed out
+	80005: true, // the authentication agent had an unpredictable error
+	80007: true, // the authentication agent can't validate the user's password
+	90024: true, // RequestBudgetExceededError, a transient error
+	90033: true, // MsodsServiceUnavailable, a transient error
+	90055: true, // TenantThrottlingError, too many requests from the tenant
+}
+
+// isRetriableAADError returns true when the error returned by Azure Active Directory is transient.
+func isRetriableAADError(e *AADAuthenticationFailedError) bool {
+	if retriableAADErrors[e.Message] {
+		return true
+	}
+	for _, code := range e.ErrorCodes {
+		if retriableAADSTSCodes[code] {
+			return true
+		}
+	}
+	return false
+}
+
+// newAADErrorPolicy returns a policy that returns an *AuthenticationFailedError for error responses from Azure
+// Active Directory that are transient, so that the retry policy preceding it tries the request again even when
+// the response's status code, typically 400, isn't one it retries.
+func newAADErrorPolicy() azcore.Policy {
+	return azcore.PolicyFunc(func(ctx context.Context, req *azcore.Request) (*azcore.Response, error) {
+		resp, err := req.Next(ctx)
+		if err != nil || resp.StatusCode < http.StatusBadRequest || resp.StatusCode >= http.StatusInternalServerError {
+			// server errors are retried according to the retry policy's status codes
+			return resp, err
+		}
+		aadErr := &AADAuthenticationFailedError{Response: resp}
+		if resp.UnmarshalAsJSON(aadErr) != nil || !isRetriableAADError(aadErr) {
+			return resp, nil
+		}
+		return nil, &AuthenticationFailedError{inner: aadErr}
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// newRetryTestCredential returns a ClientSecretCredential that retries token requests twice without delay.
+func newRetryTestCredential(t *testing.T, srv *mock.Server) *ClientSecretCredential {
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{
+		HTTPClient:             srv,
+		AuthorityHost:          &srvURL,
+		AllowInsecureLocalhost: true,
+		Retry:                  &azcore.RetryOptions{MaxRetries: 2, TryTimeout: time.Minute, RetryDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	return cred
+}
+
+func TestAADErrorPolicy_RetriesTransientErrors(t *testing.T) {
+	for _, body := range []string{
+		`{"error": "temporarily_unavailable", "error_description": "try again"}`,
+		`{"error": "invalid_request", "error_description": "AADSTS90055: throttled", "error_codes": [90055]}`,
+	} {
+		srv, close := mock.NewServer()
+		srv.AppendResponse(mock.WithBody([]byte(body)), mock.WithStatusCode(http.StatusBadRequest))
+		srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+		cred := newRetryTestCredential(t, srv)
+		if _, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+			t.Fatalf("Expected the request to be retried after %s. Received: %v", body, err)
+		}
+		if srv.Requests() != 2 {
+			t.Fatalf("Expected 2 requests. Received: %d", srv.Requests())
+		}
+		close()
+	}
+}
+
+func TestAADErrorPolicy_TransientErrorExhaustsRetries(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithBody([]byte(`{"error": "temporarily_unavailable"}`)), mock.WithStatusCode(http.StatusBadRequest))
+	cred := newRetryTestCredential(t, srv)
+	_, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	var authFailed *AuthenticationFailedError
+	if !errors.As(err, &authFailed) {
+		t.Fatalf("Expected an AuthenticationFailedError. Received: %v", err)
+	}
+	if authFailed.IsNotRetriable() {
+		t.Fatalf("Expected a transient error to be retriable")
+	}
+	if srv.Requests() != 3 {
+		t.Fatalf("Expected 3 requests. Received: %d", srv.Requests())
+	}
+}
+
+func TestAADErrorPolicy_TerminalErrors(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithBody([]byte(accessTokenRespError)), mock.WithStatusCode(http.StatusUnauthorized))
+	cred := newRetryTestCredential(t, srv)
+	_, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	var authFailed *AuthenticationFailedError
+	if !errors.As(err, &authFailed) {
+		t.Fatalf("Expected an AuthenticationFailedError. Received: %v", err)
+	}
+	if !authFailed.IsNotRetriable() {
+		t.Fatalf("Expected invalid credentials to be terminal")
+	}
+	if srv.Requests() != 1 {
+		t.Fatalf("Expected 1 request. Received: %d", srv.Requests())
+	}
+}
+
+func TestIsRetriableAADError(t *testing.T) {
+	for e, expected := range map[*AADAuthenticationFailedError]bool{
+		{Message: "temporarily_unavailable"}:                    true,
+		{Message: "server_error"}:                               true,
+		{Message: "invalid_request", ErrorCodes: []int{90033}}:  true,
+		{Message: "invalid_client", ErrorCodes: []int{7000215}}: false,
+		{Message: "invalid_grant", ErrorCodes: []int{50126}}:    false,
+		{Message: "unauthorized_client"}:                        false,
+	} {
+		if isRetriableAADError(e) != expected {
+			t.Fatalf("Expected %v for %s %v", expected, e.Message, e.ErrorCodes)
+		}
+	}
+}
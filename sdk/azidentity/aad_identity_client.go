@@ -12,10 +12,12 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"regexp"
 	"strings"
 	"time"
 
@@ -24,18 +26,28 @@ import (
 
 const (
 	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	onBehalfOfGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
 	tokenEndpoint       = "/oauth2/v2.0/token/"
+	tokenEndpointV1     = "/oauth2/token/"
+	deviceCodeEndpoint  = "/oauth2/v2.0/devicecode"
+	// endpoint that will return a device code along with the other necessary authentication flow parameters for v1.0 requests
+	deviceCodeEndpointV1 = "/oauth2/devicecode"
 )
 
 const (
+	qpAssertion           = "assertion"
 	qpClientAssertionType = "client_assertion_type"
 	qpClientAssertion     = "client_assertion"
 	qpClientID            = "client_id"
+	qpClientInfo          = "client_info"
+	qpClaims              = "claims"
 	qpClientSecret        = "client_secret"
 	qpDeviceCode          = "device_code"
 	qpGrantType           = "grant_type"
 	qpPassword            = "password"
 	qpRefreshToken        = "refresh_token"
+	qpRequestedTokenUse   = "requested_token_use"
+	qpResource            = "resource"
 	qpResponseType        = "response_type"
 	qpScope               = "scope"
 	qpUsername            = "username"
@@ -46,6 +58,7 @@ const (
 type aadIdentityClient struct {
 	options  TokenCredentialOptions
 	pipeline azcore.Pipeline
+	cache    *TokenCache
 }
 
 // newAADIdentityClient creates a new instance of the aadIdentityClient with the TokenCredentialOptions
@@ -58,7 +71,51 @@ func newAADIdentityClient(options *TokenCredentialOptions) (*aadIdentityClient,
 	if err != nil {
 		return nil, err
 	}
-	return &aadIdentityClient{options: *options, pipeline: newDefaultPipeline(*options)}, nil
+	cache := options.TokenCache
+	if cache == nil {
+		cache = NewTokenCache(nil)
+	}
+	return &aadIdentityClient{options: *options, pipeline: newDefaultPipeline(*options), cache: cache}, nil
+}
+
+// cacheKey returns the token cache key for the account and tenant at the client's authority host, so that credentials
+// for different clouds sharing a cache never receive each other's tokens.
+func (c *aadIdentityClient) cacheKey(account string, tenantID string, opts azcore.TokenRequestOptions) cacheKey {
+	return tokenCacheKey(strings.ToLower(c.options.AuthorityHost.Host)+"|"+account, tenantID, opts)
+}
+
+// clearCache removes the tokens of the account from the client's cache, for every tenant. In a shared cache, this
+// may also remove the tokens of other credentials whose account begins with the same client ID.
+func (c *aadIdentityClient) clearCache(account string) {
+	c.cache.clear(strings.ToLower(c.options.AuthorityHost.Host) + "|" + account + "|")
+}
+
+// resolveTenant returns the tenant to request a token from: the tenant requested in opts when there is one and
+// TokenCredentialOptions.AdditionallyAllowedTenants allows it, otherwise the credential's tenant.
+func (c *aadIdentityClient) resolveTenant(tenantID string, opts azcore.TokenRequestOptions) (string, error) {
+	if opts.TenantID == "" || strings.EqualFold(opts.TenantID, tenantID) {
+		return tenantID, nil
+	}
+	if !validTenantID(opts.TenantID) {
+		return "", fmt.Errorf("invalid tenant ID %q: only alphanumeric characters, dots and hyphens are allowed", opts.TenantID)
+	}
+	for _, allowed := range c.options.AdditionallyAllowedTenants {
+		if allowed == "*" || strings.EqualFold(allowed, opts.TenantID) {
+			return opts.TenantID, nil
+		}
+	}
+	return "", fmt.Errorf("the credential isn't configured to request tokens from tenant %s, add it to AdditionallyAllowedTenants or the %s environment variable", opts.TenantID, additionallyAllowedTenantsEnvVar)
+}
+
+// validTenantID returns true when the tenant ID is safe to use in the path of a token request.
+func validTenantID(tenantID string) bool {
+	match, _ := regexp.MatchString("^[0-9a-zA-Z-.]+$", tenantID)
+	return match
+}
+
+// telemetry reports the token requests of the credential for the tenant and scopes.
+func (c *aadIdentityClient) telemetry(credentialType string, tenantID string, scopes []string) tokenTelemetry {
+	return tokenTelemetry{metrics: c.options.Metrics, tracer: c.options.Tracer, onTokenRefreshed: c.options.OnTokenRefreshed, credentialType: credentialType, authority: c.options.AuthorityHost.Host, tenantID: tenantID, scopes: scopes}
 }
 
 // refreshAccessToken creates a refresh token request and returns the resulting Access Token or
@@ -68,8 +125,8 @@ func newAADIdentityClient(options *TokenCredentialOptions) (*aadIdentityClient,
 // clientID: The client (application) ID of the service principal
 // clientSecret: A client secret that was generated for the App Registration used to authenticate the client
 // scopes: The scopes for the given access token
-func (c *aadIdentityClient) refreshAccessToken(ctx context.Context, tenantID string, clientID string, clientSecret string, refreshToken string, scopes []string) (*tokenResponse, error) {
-	msg, err := c.createRefreshTokenRequest(tenantID, clientID, clientSecret, refreshToken, scopes)
+func (c *aadIdentityClient) refreshAccessToken(ctx context.Context, tenantID string, clientID string, clientSecret string, refreshToken string, opts azcore.TokenRequestOptions) (*tokenResponse, error) {
+	msg, err := c.createRefreshTokenRequest(tenantID, clientID, clientSecret, refreshToken, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -93,8 +150,8 @@ func (c *aadIdentityClient) refreshAccessToken(ctx context.Context, tenantID str
 // clientID: The client (application) ID of the service principal
 // clientSecret: A client secret that was generated for the App Registration used to authenticate the client
 // scopes: The scopes required for the token
-func (c *aadIdentityClient) authenticate(ctx context.Context, tenantID string, clientID string, clientSecret string, scopes []string) (*azcore.AccessToken, error) {
-	msg, err := c.createClientSecretAuthRequest(tenantID, clientID, clientSecret, scopes)
+func (c *aadIdentityClient) authenticate(ctx context.Context, tenantID string, clientID string, clientSecret string, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
+	msg, err := c.createClientSecretAuthRequest(tenantID, clientID, clientSecret, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -118,8 +175,33 @@ func (c *aadIdentityClient) authenticate(ctx context.Context, tenantID string, c
 // clientID: The client (application) ID of the service principal
 // clientCertificatePath: The path to the client certificate PEM file
 // scopes: The scopes required for the token
-func (c *aadIdentityClient) authenticateCertificate(ctx context.Context, tenantID string, clientID string, clientCertificatePath string, scopes []string) (*azcore.AccessToken, error) {
-	msg, err := c.createClientCertificateAuthRequest(tenantID, clientID, clientCertificatePath, scopes)
+func (c *aadIdentityClient) authenticateCertificate(ctx context.Context, tenantID string, clientID string, clientCertificatePath string, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
+	msg, err := c.createClientCertificateAuthRequest(tenantID, clientID, clientCertificatePath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.pipeline.Do(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.HasStatusCode(successStatusCodes[:]...) {
+		return c.createAccessToken(resp)
+	}
+
+	return nil, &AuthenticationFailedError{inner: newAADAuthenticationFailedError(resp)}
+}
+
+// authenticateAssertion creates a client assertion authentication request and returns an Access Token or
+// an error.
+// ctx: The current request context
+// tenantID: The Azure Active Directory tenant (directory) ID of the service principal
+// clientID: The client (application) ID of the service principal
+// assertion: A signed JWT, such as a federated token, that the App Registration trusts
+// scopes: The scopes required for the token
+func (c *aadIdentityClient) authenticateAssertion(ctx context.Context, tenantID string, clientID string, assertion string, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
+	msg, err := c.createClientAssertionAuthRequest(tenantID, clientID, assertion, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -141,6 +223,7 @@ func (c *aadIdentityClient) createAccessToken(res *azcore.Response) (*azcore.Acc
 		Token     string      `json:"access_token"`
 		ExpiresIn json.Number `json:"expires_in"`
 		ExpiresOn string      `json:"expires_on"`
+		RefreshIn json.Number `json:"refresh_in"`
 	}{}
 	if err := res.UnmarshalAsJSON(&value); err != nil {
 		return nil, fmt.Errorf("internal AccessToken: %w", err)
@@ -152,9 +235,20 @@ func (c *aadIdentityClient) createAccessToken(res *azcore.Response) (*azcore.Acc
 	return &azcore.AccessToken{
 		Token:     value.Token,
 		ExpiresOn: time.Now().Add(time.Second * time.Duration(t)).UTC(),
+		RefreshOn: refreshOn(value.RefreshIn),
 	}, nil
 }
 
+// refreshOn converts the refresh_in hint, the number of seconds after which AAD recommends refreshing a token,
+// to a time. It returns the zero time when the response has no hint.
+func refreshOn(refreshIn json.Number) time.Time {
+	t, err := refreshIn.Int64()
+	if err != nil || t <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Second * time.Duration(t)).UTC()
+}
+
 func (c *aadIdentityClient) createRefreshAccessToken(res *azcore.Response) (*tokenResponse, error) {
 	// To know more about refreshing access tokens please see: https://docs.microsoft.com/en-us/azure/active-directory/develop/v1-protocols-oauth-code#refreshing-the-access-tokens
 	// DeviceCodeCredential uses refresh token, please see the authentication flow here: https://docs.microsoft.com/en-us/azure/active-directory/develop/v2-oauth2-device-code
@@ -163,6 +257,8 @@ func (c *aadIdentityClient) createRefreshAccessToken(res *azcore.Response) (*tok
 		RefreshToken string      `json:"refresh_token"`
 		ExpiresIn    json.Number `json:"expires_in"`
 		ExpiresOn    string      `json:"expires_on"`
+		RefreshIn    json.Number `json:"refresh_in"`
+		ClientInfo   string      `json:"client_info"`
 	}{}
 	if err := res.UnmarshalAsJSON(&value); err != nil {
 		return nil, fmt.Errorf("internal AccessToken: %w", err)
@@ -174,13 +270,13 @@ func (c *aadIdentityClient) createRefreshAccessToken(res *azcore.Response) (*tok
 	accessToken := &azcore.AccessToken{
 		Token:     value.Token,
 		ExpiresOn: time.Now().Add(time.Second * time.Duration(t)).UTC(),
+		RefreshOn: refreshOn(value.RefreshIn),
 	}
-	return &tokenResponse{token: accessToken, refreshToken: value.RefreshToken}, nil
+	return &tokenResponse{token: accessToken, refreshToken: value.RefreshToken, clientInfo: value.ClientInfo}, nil
 }
 
-func (c *aadIdentityClient) createRefreshTokenRequest(tenantID, clientID, clientSecret, refreshToken string, scopes []string) (*azcore.Request, error) {
-	u := *c.options.AuthorityHost
-	u.Path = path.Join(u.Path, tenantID, tokenEndpoint)
+func (c *aadIdentityClient) createRefreshTokenRequest(tenantID, clientID, clientSecret, refreshToken string, opts azcore.TokenRequestOptions) (*azcore.Request, error) {
+	u := c.tokenURL(tenantID)
 	data := url.Values{}
 	data.Set(qpGrantType, "refresh_token")
 	data.Set(qpClientID, clientID)
@@ -189,7 +285,12 @@ func (c *aadIdentityClient) createRefreshTokenRequest(tenantID, clientID, client
 		data.Set(qpClientSecret, clientSecret)
 	}
 	data.Set(qpRefreshToken, refreshToken)
-	data.Set(qpScope, strings.Join(scopes, " "))
+	// client_info identifies the signed in account in the shared token cache
+	data.Set(qpClientInfo, "1")
+	c.setScopes(data, opts.Scopes)
+	if err := c.setClaims(data, opts); err != nil {
+		return nil, err
+	}
 	dataEncoded := data.Encode()
 	body := azcore.NopCloser(strings.NewReader(dataEncoded))
 	req := azcore.NewRequest(http.MethodPost, u)
@@ -201,14 +302,16 @@ func (c *aadIdentityClient) createRefreshTokenRequest(tenantID, clientID, client
 	return req, nil
 }
 
-func (c *aadIdentityClient) createClientSecretAuthRequest(tenantID string, clientID string, clientSecret string, scopes []string) (*azcore.Request, error) {
-	u := *c.options.AuthorityHost
-	u.Path = path.Join(u.Path, tenantID, tokenEndpoint)
+func (c *aadIdentityClient) createClientSecretAuthRequest(tenantID string, clientID string, clientSecret string, opts azcore.TokenRequestOptions) (*azcore.Request, error) {
+	u := c.tokenURL(tenantID)
 	data := url.Values{}
 	data.Set(qpGrantType, "client_credentials")
 	data.Set(qpClientID, clientID)
 	data.Set(qpClientSecret, clientSecret)
-	data.Set(qpScope, strings.Join(scopes, " "))
+	c.setScopes(data, opts.Scopes)
+	if err := c.setClaims(data, opts); err != nil {
+		return nil, err
+	}
 	dataEncoded := data.Encode()
 	body := azcore.NopCloser(strings.NewReader(dataEncoded))
 	req := azcore.NewRequest(http.MethodPost, u)
@@ -221,10 +324,9 @@ func (c *aadIdentityClient) createClientSecretAuthRequest(tenantID string, clien
 	return req, nil
 }
 
-func (c *aadIdentityClient) createClientCertificateAuthRequest(tenantID string, clientID string, clientCertificate string, scopes []string) (*azcore.Request, error) {
-	u := *c.options.AuthorityHost
-	u.Path = path.Join(u.Path, tenantID, tokenEndpoint)
-	clientAssertion, err := createClientAssertionJWT(clientID, u.String(), clientCertificate)
+func (c *aadIdentityClient) createClientCertificateAuthRequest(tenantID string, clientID string, clientCertificate string, opts azcore.TokenRequestOptions) (*azcore.Request, error) {
+	u := c.tokenURL(tenantID)
+	clientAssertion, err := createClientAssertionJWT(clientID, u.String(), clientCertificate, c.options.ClientCertificatePassword, c.options.SendCertificateChain)
 	if err != nil {
 		return nil, err
 	}
@@ -234,7 +336,10 @@ func (c *aadIdentityClient) createClientCertificateAuthRequest(tenantID string,
 	data.Set(qpClientID, clientID)
 	data.Set(qpClientAssertionType, clientAssertionType)
 	data.Set(qpClientAssertion, clientAssertion)
-	data.Set(qpScope, strings.Join(scopes, " "))
+	c.setScopes(data, opts.Scopes)
+	if err = c.setClaims(data, opts); err != nil {
+		return nil, err
+	}
 	dataEncoded := data.Encode()
 	body := azcore.NopCloser(strings.NewReader(dataEncoded))
 	req := azcore.NewRequest(http.MethodPost, u)
@@ -246,6 +351,83 @@ func (c *aadIdentityClient) createClientCertificateAuthRequest(tenantID string,
 	return req, nil
 }
 
+func (c *aadIdentityClient) createClientAssertionAuthRequest(tenantID string, clientID string, assertion string, opts azcore.TokenRequestOptions) (*azcore.Request, error) {
+	u := c.tokenURL(tenantID)
+	data := url.Values{}
+	data.Set(qpGrantType, "client_credentials")
+	data.Set(qpClientID, clientID)
+	data.Set(qpClientAssertionType, clientAssertionType)
+	data.Set(qpClientAssertion, assertion)
+	c.setScopes(data, opts.Scopes)
+	if err := c.setClaims(data, opts); err != nil {
+		return nil, err
+	}
+	dataEncoded := data.Encode()
+	body := azcore.NopCloser(strings.NewReader(dataEncoded))
+	req := azcore.NewRequest(http.MethodPost, u)
+	req.Header.Set(azcore.HeaderContentType, azcore.HeaderURLEncoded)
+	err := req.SetBody(body)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// authenticateOnBehalfOf creates an on-behalf-of authentication request which exchanges the user's assertion for an Access Token
+// and returns it or an error.
+// ctx: The current request context
+// tenantID: The Azure Active Directory tenant (directory) ID of the middle-tier application
+// clientID: The client (application) ID of the middle-tier application
+// userAssertion: The access token that was sent to the middle-tier application by its caller
+// clientSecret: The client secret of the middle-tier application, used when clientAssertion is empty
+// clientAssertion: A signed JWT that authenticates the middle-tier application
+// scopes: The scopes required for the token
+func (c *aadIdentityClient) authenticateOnBehalfOf(ctx context.Context, tenantID string, clientID string, userAssertion string, clientSecret string, clientAssertion string, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
+	msg, err := c.createOnBehalfOfAuthRequest(tenantID, clientID, userAssertion, clientSecret, clientAssertion, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.pipeline.Do(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.HasStatusCode(successStatusCodes[:]...) {
+		return c.createAccessToken(resp)
+	}
+
+	return nil, &AuthenticationFailedError{inner: newAADAuthenticationFailedError(resp)}
+}
+
+func (c *aadIdentityClient) createOnBehalfOfAuthRequest(tenantID string, clientID string, userAssertion string, clientSecret string, clientAssertion string, opts azcore.TokenRequestOptions) (*azcore.Request, error) {
+	u := c.tokenURL(tenantID)
+	data := url.Values{}
+	data.Set(qpGrantType, onBehalfOfGrantType)
+	data.Set(qpRequestedTokenUse, "on_behalf_of")
+	data.Set(qpAssertion, userAssertion)
+	data.Set(qpClientID, clientID)
+	if len(clientAssertion) != 0 {
+		data.Set(qpClientAssertionType, clientAssertionType)
+		data.Set(qpClientAssertion, clientAssertion)
+	} else {
+		data.Set(qpClientSecret, clientSecret)
+	}
+	c.setScopes(data, opts.Scopes)
+	if err := c.setClaims(data, opts); err != nil {
+		return nil, err
+	}
+	dataEncoded := data.Encode()
+	body := azcore.NopCloser(strings.NewReader(dataEncoded))
+	req := azcore.NewRequest(http.MethodPost, u)
+	req.Header.Set(azcore.HeaderContentType, azcore.HeaderURLEncoded)
+	err := req.SetBody(body)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
 // authenticateUsernamePassword creates a client username and password authentication request and returns an Access Token or
 // an error.
 // ctx: The current request context
@@ -254,8 +436,8 @@ func (c *aadIdentityClient) createClientCertificateAuthRequest(tenantID string,
 // username: User's account username
 // password: User's account password
 // scopes: The scopes required for the token
-func (c *aadIdentityClient) authenticateUsernamePassword(ctx context.Context, tenantID string, clientID string, username string, password string, scopes []string) (*azcore.AccessToken, error) {
-	msg, err := c.createUsernamePasswordAuthRequest(tenantID, clientID, username, password, scopes)
+func (c *aadIdentityClient) authenticateUsernamePassword(ctx context.Context, tenantID string, clientID string, username string, password string, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
+	msg, err := c.createUsernamePasswordAuthRequest(tenantID, clientID, username, password, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -272,16 +454,18 @@ func (c *aadIdentityClient) authenticateUsernamePassword(ctx context.Context, te
 	return nil, &AuthenticationFailedError{inner: newAADAuthenticationFailedError(resp)}
 }
 
-func (c *aadIdentityClient) createUsernamePasswordAuthRequest(tenantID string, clientID string, username string, password string, scopes []string) (*azcore.Request, error) {
-	u := *c.options.AuthorityHost
-	u.Path = path.Join(u.Path, tenantID, tokenEndpoint)
+func (c *aadIdentityClient) createUsernamePasswordAuthRequest(tenantID string, clientID string, username string, password string, opts azcore.TokenRequestOptions) (*azcore.Request, error) {
+	u := c.tokenURL(tenantID)
 	data := url.Values{}
 	data.Set(qpResponseType, "token")
 	data.Set(qpGrantType, "password")
 	data.Set(qpClientID, clientID)
 	data.Set(qpUsername, username)
 	data.Set(qpPassword, password)
-	data.Set(qpScope, strings.Join(scopes, " "))
+	c.setScopes(data, opts.Scopes)
+	if err := c.setClaims(data, opts); err != nil {
+		return nil, err
+	}
 	dataEncoded := data.Encode()
 	body := azcore.NopCloser(strings.NewReader(dataEncoded))
 	req := azcore.NewRequest(http.MethodPost, u)
@@ -308,8 +492,8 @@ func createDeviceCodeResult(res *azcore.Response) (*deviceCodeResult, error) {
 // clientID: The client (application) ID of the service principal
 // deviceCode: The device code associated with the request
 // scopes: The scopes required for the token
-func (c *aadIdentityClient) authenticateDeviceCode(ctx context.Context, tenantID string, clientID string, deviceCode string, scopes []string) (*tokenResponse, error) {
-	msg, err := c.createDeviceCodeAuthRequest(tenantID, clientID, deviceCode, scopes)
+func (c *aadIdentityClient) authenticateDeviceCode(ctx context.Context, tenantID string, clientID string, deviceCode string, opts azcore.TokenRequestOptions) (*tokenResponse, error) {
+	msg, err := c.createDeviceCodeAuthRequest(tenantID, clientID, deviceCode, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -326,17 +510,20 @@ func (c *aadIdentityClient) authenticateDeviceCode(ctx context.Context, tenantID
 	return nil, &AuthenticationFailedError{inner: newAADAuthenticationFailedError(resp)}
 }
 
-func (c *aadIdentityClient) createDeviceCodeAuthRequest(tenantID string, clientID string, deviceCode string, scopes []string) (*azcore.Request, error) {
+func (c *aadIdentityClient) createDeviceCodeAuthRequest(tenantID string, clientID string, deviceCode string, opts azcore.TokenRequestOptions) (*azcore.Request, error) {
 	if len(tenantID) == 0 { // if the user did not pass in a tenantID then the default value is set
 		tenantID = "organizations"
 	}
-	u := *c.options.AuthorityHost
-	u.Path = path.Join(u.Path, tenantID, tokenEndpoint)
+	u := c.tokenURL(tenantID)
 	data := url.Values{}
 	data.Set(qpGrantType, deviceCodeGrantType)
 	data.Set(qpClientID, clientID)
 	data.Set(qpDeviceCode, deviceCode)
-	data.Set(qpScope, strings.Join(scopes, " "))
+	data.Set(qpClientInfo, "1")
+	c.setScopes(data, opts.Scopes)
+	if err := c.setClaims(data, opts); err != nil {
+		return nil, err
+	}
 	dataEncoded := data.Encode()
 	body := azcore.NopCloser(strings.NewReader(dataEncoded))
 	req := azcore.NewRequest(http.MethodPost, u)
@@ -370,10 +557,14 @@ func (c *aadIdentityClient) createDeviceCodeNumberRequest(tenantID string, clien
 		tenantID = "organizations"
 	}
 	u := *c.options.AuthorityHost
-	u.Path = path.Join(u.Path, tenantID, "/oauth2/v2.0/devicecode") // endpoint that will return a device code along with the other necessary authentication flow parameters in the DeviceCodeResult struct
+	if c.options.UseV1Endpoint {
+		u.Path = path.Join(u.Path, tenantID, deviceCodeEndpointV1)
+	} else {
+		u.Path = path.Join(u.Path, tenantID, deviceCodeEndpoint) // endpoint that will return a device code along with the other necessary authentication flow parameters in the DeviceCodeResult struct
+	}
 	data := url.Values{}
 	data.Set(qpClientID, clientID)
-	data.Set(qpScope, strings.Join(scopes, " "))
+	c.setScopes(data, scopes)
 	dataEncoded := data.Encode()
 	body := azcore.NopCloser(strings.NewReader(dataEncoded))
 	req := azcore.NewRequest(http.MethodPost, u)
@@ -385,7 +576,113 @@ func (c *aadIdentityClient) createDeviceCodeNumberRequest(tenantID string, clien
 	return req, nil
 }
 
-func getPrivateKey(cert string) (*rsa.PrivateKey, error) {
+// tokenURL returns the URL of the token endpoint for the specified tenant.
+// The v1.0 endpoint is used when TokenCredentialOptions.UseV1Endpoint is set.
+// The endpoint is derived from the authority host rather than discovered, so credentials don't request
+// instance discovery or OpenID configuration metadata before their first token request.
+func (c *aadIdentityClient) tokenURL(tenantID string) url.URL {
+	u := *c.options.AuthorityHost
+	if c.options.UseV1Endpoint {
+		u.Path = path.Join(u.Path, tenantID, tokenEndpointV1)
+	} else {
+		u.Path = path.Join(u.Path, tenantID, tokenEndpoint)
+	}
+	return u
+}
+
+// setScopes adds the requested scopes to the request data.  The v1.0 endpoint expects a single
+// resource instead of a list of scopes so in that case the scopes are translated to a resource.
+func (c *aadIdentityClient) setScopes(data url.Values, scopes []string) {
+	if c.options.UseV1Endpoint {
+		data.Set(qpResource, scopesToResource(scopes))
+		return
+	}
+	data.Set(qpScope, strings.Join(scopes, " "))
+}
+
+// setClaims adds the claims request for the token to the request data. It combines the claims challenge in opts with
+// the client capabilities configured in TokenCredentialOptions, and the CAE capability when opts.EnableCAE is set.
+func (c *aadIdentityClient) setClaims(data url.Values, opts azcore.TokenRequestOptions) error {
+	claims, err := requestClaims(opts.Claims, c.capabilities(opts))
+	if err != nil {
+		return err
+	}
+	if claims != "" {
+		data.Set(qpClaims, claims)
+	}
+	return nil
+}
+
+// capabilities returns the client capabilities for the token request.
+func (c *aadIdentityClient) capabilities(opts azcore.TokenRequestOptions) []string {
+	if !opts.EnableCAE {
+		return c.options.ClientCapabilities
+	}
+	for _, capability := range c.options.ClientCapabilities {
+		if capability == ClientCapabilityCAE {
+			return c.options.ClientCapabilities
+		}
+	}
+	return append(append([]string{}, c.options.ClientCapabilities...), ClientCapabilityCAE)
+}
+
+// requestClaims returns the claims request that adds the client's capabilities to the claims challenge,
+// or an empty string when there is neither.
+func requestClaims(challenge string, capabilities []string) (string, error) {
+	if challenge == "" {
+		return capabilitiesClaims(capabilities), nil
+	}
+	if len(capabilities) == 0 {
+		return challenge, nil
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(challenge), &claims); err != nil {
+		return "", fmt.Errorf("the claims challenge isn't valid JSON: %w", err)
+	}
+	accessToken, ok := claims["access_token"].(map[string]interface{})
+	if !ok {
+		accessToken = map[string]interface{}{}
+		claims["access_token"] = accessToken
+	}
+	accessToken["xms_cc"] = map[string]interface{}{"values": capabilities}
+	// marshalling values that were unmarshalled from JSON can't fail
+	b, _ := json.Marshal(claims)
+	return string(b), nil
+}
+
+// capabilitiesClaims returns the claims request that informs Azure Active Directory of the client's capabilities,
+// or an empty string when there are no capabilities.
+func capabilitiesClaims(capabilities []string) string {
+	if len(capabilities) == 0 {
+		return ""
+	}
+	claims := map[string]interface{}{
+		"access_token": map[string]interface{}{
+			"xms_cc": map[string]interface{}{
+				"values": capabilities,
+			},
+		},
+	}
+	// marshalling a map of strings can't fail
+	b, _ := json.Marshal(claims)
+	return string(b)
+}
+
+// scopesToResource returns the resource for the first scope that isn't an OpenID Connect scope,
+// with any "/.default" suffix removed.
+func scopesToResource(scopes []string) string {
+	for _, scope := range scopes {
+		switch scope {
+		case "offline_access", "openid", "profile", "email":
+			continue
+		}
+		return ScopeToResource(scope)
+	}
+	return ""
+}
+
+// getPrivateKey returns the first private key in the PEM file, decrypting it with the password when it's encrypted.
+func getPrivateKey(cert string, password string) (*rsa.PrivateKey, error) {
 	privateKeyFile, err := os.Open(cert)
 	if err != nil {
 		return nil, fmt.Errorf("Opening certificate file path: %w", err)
@@ -406,27 +703,67 @@ func getPrivateKey(cert string) (*rsa.PrivateKey, error) {
 	}
 
 	data, rest := pem.Decode([]byte(pemBytes))
-	const privateKeyBlock = "PRIVATE KEY"
-	// NOTE: check types of private keys
-	if data.Type != privateKeyBlock {
-		for len(rest) > 0 {
-			data, rest = pem.Decode(rest)
-			if data.Type == privateKeyBlock {
-				privateKeyImported, err := x509.ParsePKCS8PrivateKey(data.Bytes)
-				if err != nil {
-					return nil, fmt.Errorf("ParsePKCS8PrivateKey: %w", err)
-				}
-
-				return privateKeyImported.(*rsa.PrivateKey), nil
-			}
-		}
+	for data != nil && data.Type != "PRIVATE KEY" && data.Type != "RSA PRIVATE KEY" && data.Type != "ENCRYPTED PRIVATE KEY" {
+		data, rest = pem.Decode(rest)
+	}
+	if data == nil {
 		return nil, errors.New("Cannot find PRIVATE KEY in file")
 	}
-	// NOTE: this could be a function local closure
-	privateKeyImported, err := x509.ParsePKCS8PrivateKey(data.Bytes)
+	der, err := privateKeyBytes(data, password)
+	if err != nil {
+		return nil, err
+	}
+	if data.Type == "RSA PRIVATE KEY" {
+		privateKey, err := x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("ParsePKCS1PrivateKey: %w", err)
+		}
+		return privateKey, nil
+	}
+	privateKeyImported, err := x509.ParsePKCS8PrivateKey(der)
 	if err != nil {
 		return nil, fmt.Errorf("ParsePKCS8PrivateKey: %w", err)
 	}
+	privateKey, ok := privateKeyImported.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("The PRIVATE KEY in the file isn't an RSA key")
+	}
+	return privateKey, nil
+}
+
+// privateKeyBytes returns the DER encoded key in a PEM private key block, decrypting it with the password when the
+// block is encrypted with the legacy OpenSSL format ("Proc-Type: 4,ENCRYPTED").
+func privateKeyBytes(block *pem.Block, password string) ([]byte, error) {
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, errors.New("encrypted PKCS#8 private keys aren't supported, convert the key with 'openssl rsa -aes256' or store it unencrypted")
+	}
+	if !x509.IsEncryptedPEMBlock(block) {
+		return block.Bytes, nil
+	}
+	if password == "" {
+		return nil, errors.New("the private key is encrypted, set ClientCertificatePassword or the " + clientCertificatePasswordEnvVar + " environment variable to decrypt it")
+	}
+	return decryptPEMBlock(block, password)
+}
 
-	return privateKeyImported.(*rsa.PrivateKey), nil
+// certificateChain returns the DER encoded certificates in the PEM file, in the order they appear.
+func certificateChain(path string) ([][]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var chain [][]byte
+	for {
+		var block *pem.Block
+		if block, b = pem.Decode(b); block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			chain = append(chain, block.Bytes)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("Cannot find CERTIFICATE in file")
+	}
+	return chain, nil
 }
@@ -4,8 +4,14 @@
 package azidentity
 
 import (
+	"context"
+	"io/ioutil"
 	"net/url"
 	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
 )
 
 func TestAzurePublicCloudParse(t *testing.T) {
@@ -35,3 +41,197 @@ func TestAzureGovernmentParse(t *testing.T) {
 		t.Fatalf("Failed to parse AzureGovernment authority host: %v", err)
 	}
 }
+
+func TestAADIdentityClient_V1Endpoint(t *testing.T) {
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{UseV1Endpoint: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	req, err := cred.client.createClientSecretAuthRequest(cred.tenantID, cred.clientID, cred.clientSecret, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	if req.Request.URL.Path != "/"+tenantID+"/oauth2/token" {
+		t.Fatalf("Unexpected path for the v1.0 endpoint: %s", req.Request.URL.Path)
+	}
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		t.Fatalf("Unable to read request body")
+	}
+	reqQueryParams, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Unable to parse query params in request")
+	}
+	if reqQueryParams.Get(qpResource) != "http://storage.azure.com" {
+		t.Fatalf("Unexpected resource: %s", reqQueryParams.Get(qpResource))
+	}
+	if _, ok := reqQueryParams[qpScope]; ok {
+		t.Fatalf("Did not expect a scope parameter for the v1.0 endpoint")
+	}
+}
+
+func TestAADIdentityClient_NoMetadataRequests(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Received an unexpected error: %v", err)
+	}
+	if reqs := srv.Requests(); reqs != 1 {
+		t.Fatalf("Expected only the token request to be sent. Received %d requests", reqs)
+	}
+}
+
+func TestScopesToResource(t *testing.T) {
+	if r := scopesToResource([]string{"offline_access", "https://management.azure.com//.default"}); r != "https://management.azure.com/" {
+		t.Fatalf("Unexpected resource: %s", r)
+	}
+	if r := scopesToResource([]string{"openid"}); r != "" {
+		t.Fatalf("Expected an empty resource but received: %s", r)
+	}
+}
+
+func TestAADIdentityClient_ClientCapabilities(t *testing.T) {
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{ClientCapabilities: []string{ClientCapabilityCAE}})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	req, err := cred.client.createClientSecretAuthRequest(cred.tenantID, cred.clientID, cred.clientSecret, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		t.Fatalf("Unable to read request body")
+	}
+	reqQueryParams, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Unable to parse query params in request")
+	}
+	if claims := reqQueryParams.Get(qpClaims); claims != `{"access_token":{"xms_cc":{"values":["cp1"]}}}` {
+		t.Fatalf("Unexpected claims: %s", claims)
+	}
+}
+
+func TestAADIdentityClient_NoClientCapabilities(t *testing.T) {
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	req, err := cred.client.createClientSecretAuthRequest(cred.tenantID, cred.clientID, cred.clientSecret, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		t.Fatalf("Unable to read request body")
+	}
+	reqQueryParams, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Unable to parse query params in request")
+	}
+	if _, ok := reqQueryParams[qpClaims]; ok {
+		t.Fatalf("Did not expect a claims parameter")
+	}
+}
+
+func TestAADIdentityClient_ClaimsChallenge(t *testing.T) {
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	challenge := `{"access_token":{"nbf":{"essential":true,"value":"1604106651"}}}`
+	for _, test := range []struct {
+		opts     azcore.TokenRequestOptions
+		expected string
+	}{
+		{azcore.TokenRequestOptions{Scopes: []string{scope}, Claims: challenge}, challenge},
+		{azcore.TokenRequestOptions{Scopes: []string{scope}, EnableCAE: true}, `{"access_token":{"xms_cc":{"values":["cp1"]}}}`},
+		{azcore.TokenRequestOptions{Scopes: []string{scope}, Claims: challenge, EnableCAE: true}, `{"access_token":{"nbf":{"essential":true,"value":"1604106651"},"xms_cc":{"values":["cp1"]}}}`},
+	} {
+		req, err := cred.client.createClientSecretAuthRequest(cred.tenantID, cred.clientID, cred.clientSecret, test.opts)
+		if err != nil {
+			t.Fatalf("Unexpectedly received an error: %v", err)
+		}
+		body, err := ioutil.ReadAll(req.Request.Body)
+		if err != nil {
+			t.Fatalf("Unable to read request body")
+		}
+		reqQueryParams, err := url.ParseQuery(string(body))
+		if err != nil {
+			t.Fatalf("Unable to parse query params in request")
+		}
+		if claims := reqQueryParams.Get(qpClaims); claims != test.expected {
+			t.Fatalf("Unexpected claims: %s", claims)
+		}
+	}
+	if _, err = cred.client.createClientSecretAuthRequest(cred.tenantID, cred.clientID, cred.clientSecret, azcore.TokenRequestOptions{Scopes: []string{scope}, Claims: "not json", EnableCAE: true}); err == nil {
+		t.Fatalf("Expected an error for a claims challenge that isn't JSON")
+	}
+}
+
+func TestAADIdentityClient_EnableCAEWithClientCapabilities(t *testing.T) {
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{ClientCapabilities: []string{ClientCapabilityCAE}})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if caps := cred.client.capabilities(azcore.TokenRequestOptions{EnableCAE: true}); len(caps) != 1 || caps[0] != ClientCapabilityCAE {
+		t.Fatalf("Expected the CAE capability once. Received: %v", caps)
+	}
+}
+
+func TestAADIdentityClient_RefreshIn(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(`{"access_token": "` + tokenValue + `", "expires_in": 3600, "refresh_in": 1800}`)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	tk, err := cred.client.authenticate(context.Background(), tenantID, clientID, secret, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d := time.Until(tk.RefreshOn); d < 29*time.Minute || d > 30*time.Minute {
+		t.Fatalf("Expected RefreshOn to be set from refresh_in. Received: %v", tk.RefreshOn)
+	}
+	tk, err = cred.client.authenticate(context.Background(), tenantID, clientID, secret, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !tk.RefreshOn.IsZero() {
+		t.Fatalf("Expected a zero RefreshOn without refresh_in. Received: %v", tk.RefreshOn)
+	}
+}
+
+func TestAADIdentityClient_ResolveTenant(t *testing.T) {
+	client, err := newAADIdentityClient(&TokenCredentialOptions{AdditionallyAllowedTenants: []string{"allowed"}})
+	if err != nil {
+		t.Fatalf("Unable to create client: %v", err)
+	}
+	for requested, expected := range map[string]string{"": tenantID, "ALLOWED": "ALLOWED", tenantID: tenantID} {
+		tenant, err := client.resolveTenant(tenantID, azcore.TokenRequestOptions{TenantID: requested})
+		if err != nil || tenant != expected {
+			t.Fatalf("Expected tenant %q for %q. Received: %q, %v", expected, requested, tenant, err)
+		}
+	}
+	for _, requested := range []string{"other", "bad/tenant"} {
+		if _, err = client.resolveTenant(tenantID, azcore.TokenRequestOptions{TenantID: requested}); err == nil {
+			t.Fatalf("Expected an error for tenant %q", requested)
+		}
+	}
+	client, err = newAADIdentityClient(&TokenCredentialOptions{AdditionallyAllowedTenants: []string{"*"}})
+	if err != nil {
+		t.Fatalf("Unable to create client: %v", err)
+	}
+	if tenant, err := client.resolveTenant(tenantID, azcore.TokenRequestOptions{TenantID: "other"}); err != nil || tenant != "other" {
+		t.Fatalf("Expected any tenant to be allowed. Received: %q, %v", tenant, err)
+	}
+}
@@ -4,9 +4,14 @@
 package azidentity
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -25,7 +30,31 @@ const (
 	defaultSuffix = "/.default"
 )
 
+// Environment variables configuring credentials the same way as the Azure Identity libraries for other languages.
+const (
+	// additionallyAllowedTenantsEnvVar is a semicolon separated list of tenants, the default of TokenCredentialOptions.AdditionallyAllowedTenants.
+	additionallyAllowedTenantsEnvVar = "AZURE_ADDITIONALLY_ALLOWED_TENANTS"
+	// sendCertificateChainEnvVar enables TokenCredentialOptions.SendCertificateChain when set to a true value.
+	sendCertificateChainEnvVar = "AZURE_CLIENT_SEND_CERTIFICATE_CHAIN"
+	// clientCertificatePasswordEnvVar is the default of TokenCredentialOptions.ClientCertificatePassword.
+	clientCertificatePasswordEnvVar = "AZURE_CLIENT_CERTIFICATE_PASSWORD"
+)
+
+const (
+	// ClientCapabilityCAE is the client capability indicating that the client can handle Continuous Access Evaluation claims challenges.
+	ClientCapabilityCAE = "cp1"
+)
+
 var (
+	// KnownAuthorityHosts contains the authority hosts of the Azure clouds.
+	// Assign it to TokenCredentialOptions.AllowedAuthorityHosts to restrict credentials to these clouds.
+	KnownAuthorityHosts = []string{
+		AzureChina,
+		AzureGermany,
+		AzureGovernment,
+		AzurePublicCloud,
+	}
+
 	successStatusCodes = [2]int{
 		http.StatusOK,      // 200
 		http.StatusCreated, // 201
@@ -35,6 +64,7 @@ var (
 type tokenResponse struct {
 	token        *azcore.AccessToken
 	refreshToken string
+	clientInfo   string // base64url encoded JSON identifying the signed in account, when requested
 }
 
 // AADAuthenticationFailedError is used to unmarshal error responses received from Azure Active Directory.
@@ -45,7 +75,31 @@ type AADAuthenticationFailedError struct {
 	TraceID       string `json:"trace_id"`
 	CorrelationID string `json:"correlation_id"`
 	URI           string `json:"error_uri"`
-	Response      *azcore.Response
+	// ErrorCodes are the numeric AADSTS error codes, for example 50034 for AADSTS50034.
+	ErrorCodes []int `json:"error_codes"`
+	Response   *azcore.Response
+}
+
+// ErrorCode returns the first AADSTS error code, for example "AADSTS7000222" when the client secret has expired,
+// or an empty string when Azure Active Directory didn't return one.
+func (e *AADAuthenticationFailedError) ErrorCode() string {
+	if len(e.ErrorCodes) == 0 {
+		return ""
+	}
+	return "AADSTS" + strconv.Itoa(e.ErrorCodes[0])
+}
+
+// aadTimestampFormat is the format of the timestamp in Azure Active Directory's error responses.
+const aadTimestampFormat = "2006-01-02 15:04:05Z"
+
+// ServerTime returns the Timestamp as a time, or the zero time when it couldn't be parsed.
+func (e *AADAuthenticationFailedError) ServerTime() time.Time {
+	for _, layout := range []string{aadTimestampFormat, http.TimeFormat} {
+		if t, err := time.Parse(layout, e.Timestamp); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
 
 func (e *AADAuthenticationFailedError) Error() string {
@@ -56,8 +110,41 @@ func (e *AADAuthenticationFailedError) Error() string {
 	return msg
 }
 
+// troubleshootingURL is the guide to resolving azidentity errors.
+const troubleshootingURL = "https://aka.ms/azsdk/go/identity/troubleshoot"
+
+// troubleshootingAnchors maps credential types, without spaces, to their section of the troubleshooting guide.
+var troubleshootingAnchors = map[string]string{
+	"AzureCLICredential":                 "azure-cli",
+	"ClientAssertionCredential":          "client-assertion",
+	"ClientCertificateCredential":        "client-cert",
+	"ClientSecretCredential":             "client-secret",
+	"DefaultAzureCredential":             "default",
+	"DeviceCodeCredential":               "device-code",
+	"EnvironmentCredential":              "environment",
+	"ManagedIdentityCredential":          "managed-id",
+	"ManagedIdentityFederatedCredential": "managed-id",
+	"OnBehalfOfCredential":               "obo",
+	"UsernamePasswordCredential":         "username-password",
+}
+
+// troubleshootingLink returns a note pointing to the troubleshooting guide for the credential type,
+// for example "Client Secret Credential", or an empty string when the guide has no section for it.
+func troubleshootingLink(credentialType string) string {
+	anchor, ok := troubleshootingAnchors[strings.ReplaceAll(credentialType, " ", "")]
+	if !ok {
+		return ""
+	}
+	return " (to troubleshoot, see " + troubleshootingURL + "#" + anchor + ")"
+}
+
 // AuthenticationFailedError is returned when the authentication request has failed.
+// Use errors.As to find it in the errors returned by credentials, including ChainedTokenCredential
+// and errors wrapped by the application, and its accessors for the details of the failure.
 type AuthenticationFailedError struct {
+	// CredentialType holds the name of the credential that failed to authenticate, when known
+	CredentialType string
+
 	inner error
 	msg   string
 }
@@ -67,16 +154,104 @@ func (e *AuthenticationFailedError) Unwrap() error {
 	return e.inner
 }
 
-// IsNotRetriable returns true indicating that this is a terminal error.
+// IsNotRetriable returns false when Azure Active Directory reported a transient failure, such as
+// temporarily_unavailable, and true for every other failure, such as invalid credentials.
 func (e *AuthenticationFailedError) IsNotRetriable() bool {
+	if aadErr := e.aadError(); aadErr != nil {
+		return !isRetriableAADError(aadErr)
+	}
 	return true
 }
 
+// RawResponse returns the HTTP response of the failed token request,
+// or nil when the error didn't come from Azure Active Directory.
+func (e *AuthenticationFailedError) RawResponse() *http.Response {
+	if aadErr := e.aadError(); aadErr != nil && aadErr.Response != nil {
+		return aadErr.Response.Response
+	}
+	return nil
+}
+
+// ErrorCode returns the AADSTS error code returned by Azure Active Directory, for example "AADSTS7000222" when
+// the client secret has expired, or an empty string when there isn't one.
+func (e *AuthenticationFailedError) ErrorCode() string {
+	if aadErr := e.aadError(); aadErr != nil {
+		return aadErr.ErrorCode()
+	}
+	return ""
+}
+
+// CorrelationID returns the correlation ID of the failed token request, which identifies it in support requests,
+// or an empty string when the error didn't come from Azure Active Directory.
+func (e *AuthenticationFailedError) CorrelationID() string {
+	if aadErr := e.aadError(); aadErr != nil {
+		return aadErr.CorrelationID
+	}
+	return ""
+}
+
+// TraceID returns the ID Azure Active Directory gave the failed token request in its logs,
+// or an empty string when the error didn't come from Azure Active Directory.
+func (e *AuthenticationFailedError) TraceID() string {
+	if aadErr := e.aadError(); aadErr != nil {
+		return aadErr.TraceID
+	}
+	return ""
+}
+
+// Timestamp returns the time Azure Active Directory reported the failure, as formatted in its response,
+// or an empty string when the error didn't come from Azure Active Directory.
+func (e *AuthenticationFailedError) Timestamp() string {
+	if aadErr := e.aadError(); aadErr != nil {
+		return aadErr.Timestamp
+	}
+	return ""
+}
+
+// ServerTime returns the time Azure Active Directory reported the failure, for correlating it with sign-in logs,
+// or the zero time when the error didn't come from Azure Active Directory or the time couldn't be parsed.
+func (e *AuthenticationFailedError) ServerTime() time.Time {
+	if aadErr := e.aadError(); aadErr != nil {
+		return aadErr.ServerTime()
+	}
+	return time.Time{}
+}
+
+// aadError returns the Azure Active Directory error that caused e, or nil when there isn't one.
+func (e *AuthenticationFailedError) aadError() *AADAuthenticationFailedError {
+	var aadErr *AADAuthenticationFailedError
+	if errors.As(e.inner, &aadErr) {
+		return aadErr
+	}
+	return nil
+}
+
 func (e *AuthenticationFailedError) Error() string {
-	if len(e.msg) == 0 {
-		e.msg = e.inner.Error()
+	msg := e.msg
+	if len(msg) == 0 {
+		msg = e.inner.Error()
 	}
-	return e.msg
+	return msg + troubleshootingLink(e.CredentialType)
+}
+
+// withCredentialType sets the credential type of an AuthenticationFailedError in err that doesn't have one.
+// err must not have been returned to a caller yet.
+func withCredentialType(err error, credentialType string) error {
+	var authFailed *AuthenticationFailedError
+	if errors.As(err, &authFailed) && authFailed.CredentialType == "" {
+		authFailed.CredentialType = credentialType
+	}
+	return err
+}
+
+// aadstsCode returns the first AADSTS error code, for example "AADSTS50034", of an error
+// returned by Azure Active Directory, or an empty string when err doesn't have one.
+func aadstsCode(err error) string {
+	var aadErr *AADAuthenticationFailedError
+	if !errors.As(err, &aadErr) {
+		return ""
+	}
+	return aadErr.ErrorCode()
 }
 
 func newAADAuthenticationFailedError(resp *azcore.Response) error {
@@ -86,20 +261,39 @@ func newAADAuthenticationFailedError(resp *azcore.Response) error {
 		authFailed.Message = resp.Status
 		authFailed.Description = "Failed to unmarshal response: " + err.Error()
 	}
+	// responses without the details in their body, such as those from gateways and managed identity
+	// endpoints, may have them in their headers
+	if authFailed.CorrelationID == "" {
+		authFailed.CorrelationID = resp.Header.Get("client-request-id")
+	}
+	if authFailed.TraceID == "" {
+		authFailed.TraceID = resp.Header.Get("x-ms-request-id")
+	}
+	if authFailed.Timestamp == "" {
+		authFailed.Timestamp = resp.Header.Get("Date")
+	}
 	return authFailed
 }
 
 // CredentialUnavailableError is the error type returned when the conditions required to
-// create a credential do not exist or are unavailable.
+// create a credential do not exist or are unavailable. A ChainedTokenCredential tries its next
+// credential when one returns this error. Use errors.As to find it in the errors returned by credentials.
 type CredentialUnavailableError struct {
 	// CredentialType holds the name of the credential that is unavailable
 	CredentialType string
 	// Message contains the reason why the credential is unavailable
 	Message string
+
+	inner error
 }
 
 func (e *CredentialUnavailableError) Error() string {
-	return e.CredentialType + ": " + e.Message
+	return e.CredentialType + ": " + e.Message + troubleshootingLink(e.CredentialType)
+}
+
+// Unwrap provides access to the error that made the credential unavailable, if any.
+func (e *CredentialUnavailableError) Unwrap() error {
+	return e.inner
 }
 
 // IsNotRetriable returns true indicating that this is a terminal error.
@@ -110,8 +304,17 @@ func (e *CredentialUnavailableError) IsNotRetriable() bool {
 // TokenCredentialOptions are used to configure how requests are made to Azure Active Directory.
 type TokenCredentialOptions struct {
 	// The host of the Azure Active Directory authority. The default is https://login.microsoft.com
+	// The authority host must use the HTTPS protocol scheme.
 	AuthorityHost *url.URL
 
+	// AllowedAuthorityHosts restricts the authority host, including one set with the AZURE_AUTHORITY_HOST
+	// environment variable, to the specified hosts.  Leave this empty to allow any authority host.
+	AllowedAuthorityHosts []string
+
+	// AllowInsecureLocalhost allows an authority host that uses the HTTP protocol scheme when it refers to
+	// localhost or a loopback address.  This is intended for testing purposes only.
+	AllowInsecureLocalhost bool
+
 	// HTTPClient sets the transport for making HTTP requests
 	// Leave this as nil to use the default HTTP transport
 	HTTPClient azcore.Transport
@@ -119,92 +322,300 @@ type TokenCredentialOptions struct {
 	// LogOptions configures the built-in request logging policy behavior
 	LogOptions azcore.RequestLogOptions
 
-	// Retry configures the built-in retry policy behavior
+	// Retry configures the built-in retry policy behavior. By default, requests throttled by
+	// Azure Active Directory (status code 429) are retried in addition to azcore.StatusCodesForRetry.
 	Retry *azcore.RetryOptions
 
+	// TryTimeout limits how long each attempt of a token request, including reading the response, may take, for
+	// example to allow slow proxy handshakes. It overrides the TryTimeout of Retry. Defaults to 1 minute.
+	TryTimeout time.Duration
+
+	// ConnectTimeout limits how long establishing a connection to the authority host may take.
+	// It's ignored when HTTPClient is set. Defaults to the timeout of the default HTTP transport.
+	ConnectTimeout time.Duration
+
 	// Telemetry configures the built-in telemetry policy behavior
 	Telemetry azcore.TelemetryOptions
+
+	// ApplicationID identifies the application in the User-Agent of the credential's requests, so that Azure Active
+	// Directory sign-in logs and proxy logs can be attributed to it. It's prepended to the User-Agent, overriding
+	// Telemetry.ApplicationID, and must be at most 24 characters without spaces.
+	ApplicationID string
+
+	// PerCallPolicies are run once per token request, after the built-in telemetry and request ID policies,
+	// for example to add a header required by a corporate proxy.
+	PerCallPolicies []azcore.Policy
+
+	// PerRetryPolicies are run for each attempt of a token request, after the built-in retry policy.
+	PerRetryPolicies []azcore.Policy
+
+	// Pipeline replaces the credential's pipeline, for example to send token requests through an internal token proxy.
+	// When set, HTTPClient, LogOptions, Retry, TryTimeout, Telemetry, PerCallPolicies and PerRetryPolicies are ignored and
+	// the pipeline's policies must redact the secrets in token requests from any logs they write.
+	Pipeline *azcore.Pipeline
+
+	// UseV1Endpoint requests tokens from the Azure Active Directory v1.0 endpoint, which expects a resource
+	// instead of scopes. Set this for older services and Azure Stack. The resource is taken from the first
+	// requested scope with any "/.default" suffix removed.
+	UseV1Endpoint bool
+
+	// ClientCapabilities are the capabilities of the client sent to Azure Active Directory with each token request.
+	// Include ClientCapabilityCAE ("cp1") to receive tokens that support Continuous Access Evaluation.
+	// Leave this empty to disable capability-aware tokens for resources that don't handle them correctly.
+	ClientCapabilities []string
+
+	// TokenCache stores the tokens acquired by the credential. Set this to share a cache between credentials or to
+	// save and restore its contents. Leave this as nil to give the credential a cache of its own.
+	TokenCache *TokenCache
+
+	// Metrics receives a measurement of each token request, such as whether the token was cached and how long
+	// acquiring it took. Leave this as nil to record nothing.
+	Metrics TokenMetrics
+
+	// Tracer records each token request as a span in the caller's trace. Leave this as nil to record nothing.
+	Tracer azcore.Tracer
+
+	// OnTokenRefreshed is called after the credential acquires a new token, for example to record token lifetimes or to
+	// notify components that the identity's state changed. It receives the token's metadata, never the token itself,
+	// and is called on the request path, so it should return quickly. Leave this as nil to be notified of nothing.
+	OnTokenRefreshed func(TokenRefreshedEvent)
+
+	// AdditionallyAllowedTenants are the tenants, besides the credential's own, that tokens can be requested from by
+	// setting azcore.TokenRequestOptions.TenantID. Add "*" to allow any tenant. Defaults to the semicolon separated
+	// list in the AZURE_ADDITIONALLY_ALLOWED_TENANTS environment variable. Device code credentials always use their own tenant.
+	AdditionallyAllowedTenants []string
+
+	// SendCertificateChain makes certificate credentials send the certificates in their PEM file in the x5c header
+	// of their client assertions, which is required for subject name and issuer authentication.
+	// Defaults to the value of the AZURE_CLIENT_SEND_CERTIFICATE_CHAIN environment variable.
+	SendCertificateChain bool
+
+	// ClientCertificatePassword decrypts the private key of certificate credentials when it's encrypted.
+	// Defaults to the value of the AZURE_CLIENT_CERTIFICATE_PASSWORD environment variable.
+	ClientCertificatePassword string
 }
 
-// setDefaultValues initializes an instance of TokenCredentialOptions with default settings.
+// setDefaultValues returns a copy of the TokenCredentialOptions with default settings. The caller's options,
+// including the URL AuthorityHost points to, are not modified so they can be shared by several credentials.
 func (c *TokenCredentialOptions) setDefaultValues() (*TokenCredentialOptions, error) {
-	authorityHost := AzurePublicCloud
-	if envAuthorityHost := os.Getenv("AZURE_AUTHORITY_HOST"); envAuthorityHost != "" {
-		authorityHost = envAuthorityHost
+	o := TokenCredentialOptions{}
+	if c != nil {
+		o = *c
 	}
 
-	if c == nil {
-		defaultAuthorityHostURL, err := url.Parse(authorityHost)
+	var authorityHost url.URL
+	if o.AuthorityHost != nil {
+		authorityHost = *o.AuthorityHost
+	} else {
+		host := AzurePublicCloud
+		if envAuthorityHost := os.Getenv("AZURE_AUTHORITY_HOST"); envAuthorityHost != "" {
+			host = envAuthorityHost
+		}
+		defaultAuthorityHostURL, err := url.Parse(host)
 		if err != nil {
 			return nil, err
 		}
-		c = &TokenCredentialOptions{AuthorityHost: defaultAuthorityHostURL}
+		authorityHost = *defaultAuthorityHostURL
 	}
 
-	if c.AuthorityHost == nil {
-		defaultAuthorityHostURL, err := url.Parse(authorityHost)
+	if len(authorityHost.Path) == 0 || authorityHost.Path[len(authorityHost.Path)-1:] != "/" {
+		authorityHost.Path = authorityHost.Path + "/"
+	}
+	o.AuthorityHost = &authorityHost
+
+	if err := o.validateAuthorityHost(); err != nil {
+		return nil, err
+	}
+
+	if err := validateApplicationID(o.ApplicationID); err != nil {
+		return nil, err
+	}
+
+	if o.AdditionallyAllowedTenants == nil {
+		for _, tenant := range strings.Split(os.Getenv(additionallyAllowedTenantsEnvVar), ";") {
+			if tenant = strings.TrimSpace(tenant); tenant != "" {
+				o.AdditionallyAllowedTenants = append(o.AdditionallyAllowedTenants, tenant)
+			}
+		}
+	}
+	if !o.SendCertificateChain {
+		o.SendCertificateChain, _ = strconv.ParseBool(os.Getenv(sendCertificateChainEnvVar))
+	}
+	if o.ClientCertificatePassword == "" {
+		o.ClientCertificatePassword = os.Getenv(clientCertificatePasswordEnvVar)
+	}
+
+	return &o, nil
+}
+
+// validateAuthorityHost ensures that secrets and assertions can't be sent to an authority host over an insecure
+// connection or, when AllowedAuthorityHosts is set, to an authority host that hasn't been allowed.
+func (c *TokenCredentialOptions) validateAuthorityHost() error {
+	switch {
+	case strings.EqualFold(c.AuthorityHost.Scheme, "https"):
+	case strings.EqualFold(c.AuthorityHost.Scheme, "http") && c.AllowInsecureLocalhost && isLoopbackHost(c.AuthorityHost.Hostname()):
+	default:
+		return fmt.Errorf("authority host %s must use the HTTPS protocol scheme", c.AuthorityHost.String())
+	}
+	if len(c.AllowedAuthorityHosts) == 0 {
+		return nil
+	}
+	for _, allowed := range c.AllowedAuthorityHosts {
+		u, err := url.Parse(allowed)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if strings.EqualFold(u.Scheme, c.AuthorityHost.Scheme) && strings.EqualFold(u.Host, c.AuthorityHost.Host) {
+			return nil
 		}
-		c.AuthorityHost = defaultAuthorityHostURL
 	}
+	return fmt.Errorf("authority host %s is not one of the allowed authority hosts", c.AuthorityHost.String())
+}
+
+// isLoopbackHost returns true if host is localhost or a loopback IP address.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// maxApplicationIDLength is the longest ApplicationID allowed in the User-Agent.
+const maxApplicationIDLength = 24
+
+// validateApplicationID returns an error when the application ID can't be sent in the User-Agent.
+func validateApplicationID(applicationID string) error {
+	if len(applicationID) > maxApplicationIDLength || strings.ContainsAny(applicationID, " \t\r\n") {
+		return fmt.Errorf("the application ID %q must be at most %d characters without spaces", applicationID, maxApplicationIDLength)
+	}
+	return nil
+}
 
-	if len(c.AuthorityHost.Path) == 0 || c.AuthorityHost.Path[len(c.AuthorityHost.Path)-1:] != "/" {
-		c.AuthorityHost.Path = c.AuthorityHost.Path + "/"
+// telemetryOptions returns the options of the telemetry policy with the credential's application ID, when it has one.
+func telemetryOptions(o azcore.TelemetryOptions, applicationID string) azcore.TelemetryOptions {
+	if applicationID != "" {
+		o.ApplicationID = applicationID
 	}
+	return o
+}
+
+// secretHeaders are the request headers that carry secrets, such as the App Service managed identity secret.
+var secretHeaders = []string{"secret", "X-IDENTITY-HEADER"}
+
+// secretParameters are the request parameters that carry secrets or tokens. They're redacted from the form bodies of
+// token requests by the secret redaction policy, and wherever they appear in a query by the request logging policy.
+var secretParameters = []string{qpClientSecret, qpClientAssertion, qpAssertion, "code", qpDeviceCode, qpPassword, qpRefreshToken, "access_token"}
+
+// loggedHeaders and loggedParameters are the managed identity request headers and query parameters that identify
+// the token requested, whose values are logged.
+var (
+	loggedHeaders    = []string{azcore.HeaderMetadata}
+	loggedParameters = []string{qpClientID, qpResource}
+)
 
-	return c, nil
+// newSecretRedactionPolicy creates a policy that hides the secret headers and body fields of requests from the policies
+// that follow it, so that no logging policy can write them.
+func newSecretRedactionPolicy() azcore.Policy {
+	return azcore.NewRedactionPolicy(azcore.RedactionOptions{Headers: secretHeaders, Fields: secretParameters})
+}
+
+// redactedLogOptions returns a copy of o that logs the values identifying the token requested by credentials and
+// redacts the secrets they send.
+func redactedLogOptions(o azcore.RequestLogOptions) azcore.RequestLogOptions {
+	o.AllowedHeaders = append(append([]string{}, o.AllowedHeaders...), loggedHeaders...)
+	o.AllowedQueryParameters = append(append([]string{}, o.AllowedQueryParameters...), loggedParameters...)
+	o.RedactedHeaders = append(append([]string{}, o.RedactedHeaders...), secretHeaders...)
+	o.RedactedQueryParameters = append(append([]string{}, o.RedactedQueryParameters...), secretParameters...)
+	return o
 }
 
 // newDefaultPipeline creates a pipeline using the specified pipeline options.
 func newDefaultPipeline(o TokenCredentialOptions) azcore.Pipeline {
+	if o.Pipeline != nil {
+		return *o.Pipeline
+	}
 	if o.HTTPClient == nil {
-		o.HTTPClient = azcore.DefaultHTTPClientTransport()
+		o.HTTPClient = newHTTPClientTransport(o.ConnectTimeout)
+	}
+
+	retry := o.Retry
+	if retry == nil {
+		def := azcore.DefaultRetryOptions()
+		def.StatusCodes = append(append([]int{}, def.StatusCodes...), http.StatusTooManyRequests)
+		retry = &def
+	}
+	if o.TryTimeout > 0 {
+		withTimeout := *retry
+		withTimeout.TryTimeout = o.TryTimeout
+		retry = &withTimeout
 	}
 
-	return azcore.NewPipeline(
-		o.HTTPClient,
-		azcore.NewTelemetryPolicy(o.Telemetry),
-		azcore.NewUniqueRequestIDPolicy(),
-		azcore.NewRetryPolicy(o.Retry),
-		azcore.NewRequestLogPolicy(o.LogOptions))
+	return azcore.NewDefaultPipeline(&azcore.PipelineOptions{
+		HTTPClient:       o.HTTPClient,
+		Telemetry:        telemetryOptions(o.Telemetry, o.ApplicationID),
+		Retry:            retry,
+		Logging:          redactedLogOptions(o.LogOptions),
+		PerCallPolicies:  append([]azcore.Policy{newSecretRedactionPolicy()}, o.PerCallPolicies...),
+		PerRetryPolicies: append([]azcore.Policy{newThrottlingPolicy(), newAADErrorPolicy()}, o.PerRetryPolicies...),
+	})
+}
+
+// newHTTPClientTransport returns the default HTTP transport, or when connectTimeout is positive, a transport like it
+// whose connections must be established within connectTimeout.
+func newHTTPClientTransport(connectTimeout time.Duration) azcore.Transport {
+	if connectTimeout <= 0 {
+		return azcore.DefaultHTTPClientTransport()
+	}
+	return azcore.NewDefaultHTTPClientTransport(azcore.TransportOptions{DialTimeout: connectTimeout})
+}
+
+// msiRetryStatusCodes are the status codes of managed identity responses that are retried. The following status codes
+// are a subset of those found in azcore.StatusCodesForRetry, these are the only ones specifically needed for MSI scenarios.
+var msiRetryStatusCodes = []int{
+	http.StatusRequestTimeout,      // 408
+	http.StatusTooManyRequests,     // 429
+	http.StatusInternalServerError, // 500
+	http.StatusBadGateway,          // 502
+	http.StatusGatewayTimeout,      // 504
+	http.StatusNotFound,
+	http.StatusGone,
+	// all remaining 5xx
+	http.StatusNotImplemented,
+	http.StatusHTTPVersionNotSupported,
+	http.StatusVariantAlsoNegotiates,
+	http.StatusInsufficientStorage,
+	http.StatusLoopDetected,
+	http.StatusNotExtended,
+	http.StatusNetworkAuthenticationRequired,
 }
 
 // newDefaultMSIPipeline creates a pipeline using the specified pipeline options needed
 // for a Managed Identity, such as a MSI specific retry policy.
 func newDefaultMSIPipeline(o ManagedIdentityCredentialOptions) azcore.Pipeline {
+	if o.Pipeline != nil {
+		return *o.Pipeline
+	}
 	if o.HTTPClient == nil {
-		o.HTTPClient = azcore.DefaultHTTPClientTransport()
+		o.HTTPClient = newHTTPClientTransport(o.ConnectTimeout)
 	}
-	var statusCodes []int
-	// retry policy for MSI is not end-user configurable
+	// retry policy for MSI is not end-user configurable, except for the time each attempt may take
 	retryOpts := azcore.RetryOptions{
-		MaxRetries: 4,
-		RetryDelay: 2 * time.Second,
-		TryTimeout: 1 * time.Minute,
-		StatusCodes: append(statusCodes,
-			// The following status codes are a subset of those found in azcore.StatusCodesForRetry, these are the only ones specifically needed for MSI scenarios
-			http.StatusRequestTimeout,      // 408
-			http.StatusTooManyRequests,     // 429
-			http.StatusInternalServerError, // 500
-			http.StatusBadGateway,          // 502
-			http.StatusGatewayTimeout,      // 504
-			http.StatusNotFound,
-			http.StatusGone,
-			// all remaining 5xx
-			http.StatusNotImplemented,
-			http.StatusHTTPVersionNotSupported,
-			http.StatusVariantAlsoNegotiates,
-			http.StatusInsufficientStorage,
-			http.StatusLoopDetected,
-			http.StatusNotExtended,
-			http.StatusNetworkAuthenticationRequired),
-	}
-
-	return azcore.NewPipeline(
-		o.HTTPClient,
-		azcore.NewTelemetryPolicy(o.Telemetry),
-		azcore.NewUniqueRequestIDPolicy(),
-		azcore.NewRetryPolicy(&retryOpts),
-		azcore.NewRequestLogPolicy(o.LogOptions))
+		MaxRetries:  4,
+		RetryDelay:  2 * time.Second,
+		TryTimeout:  1 * time.Minute,
+		StatusCodes: msiRetryStatusCodes,
+	}
+	if o.TryTimeout > 0 {
+		retryOpts.TryTimeout = o.TryTimeout
+	}
+
+	return azcore.NewDefaultPipeline(&azcore.PipelineOptions{
+		HTTPClient:       o.HTTPClient,
+		Telemetry:        telemetryOptions(o.Telemetry, o.ApplicationID),
+		Retry:            &retryOpts,
+		Logging:          redactedLogOptions(o.LogOptions),
+		PerCallPolicies:  append([]azcore.Policy{newSecretRedactionPolicy()}, o.PerCallPolicies...),
+		PerRetryPolicies: o.PerRetryPolicies,
+	})
 }
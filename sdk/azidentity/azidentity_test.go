@@ -4,11 +4,19 @@
 package azidentity
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
 )
 
 const (
@@ -85,6 +93,31 @@ func Test_DefaultAuthorityHost(t *testing.T) {
 	}
 }
 
+func Test_SetDefaultValuesDoesNotModifyOptions(t *testing.T) {
+	u, err := url.Parse("https://login.microsoftonline.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &TokenCredentialOptions{AuthorityHost: u}
+	defaulted, err := opts.setDefaultValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defaulted.AuthorityHost.String() != "https://login.microsoftonline.com/" {
+		t.Fatalf("Unexpected AuthorityHost: %s", defaulted.AuthorityHost.String())
+	}
+	if opts.AuthorityHost != u || u.String() != "https://login.microsoftonline.com" {
+		t.Fatalf("Expected the caller's AuthorityHost to be unchanged. Received: %s", opts.AuthorityHost.String())
+	}
+	opts = &TokenCredentialOptions{}
+	if _, err = opts.setDefaultValues(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.AuthorityHost != nil {
+		t.Fatalf("Expected the caller's options not to receive a default AuthorityHost")
+	}
+}
+
 func Test_AzureGermanyAuthorityHost(t *testing.T) {
 	opts := &TokenCredentialOptions{}
 	opts, err := opts.setDefaultValues()
@@ -132,3 +165,336 @@ func Test_AzureGovernmentAuthorityHost(t *testing.T) {
 		t.Fatalf("Did not retrieve expected authority host string")
 	}
 }
+
+func Test_InsecureAuthorityHost(t *testing.T) {
+	u, err := url.Parse("http://login.microsoftonline.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &TokenCredentialOptions{AuthorityHost: u, AllowInsecureLocalhost: true}
+	if _, err = opts.setDefaultValues(); err == nil {
+		t.Fatalf("Expected an error for an authority host that doesn't use HTTPS")
+	}
+}
+
+func Test_InsecureLocalhostAuthorityHost(t *testing.T) {
+	u, err := url.Parse("http://127.0.0.1:8080/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &TokenCredentialOptions{AuthorityHost: u}
+	if _, err = opts.setDefaultValues(); err == nil {
+		t.Fatalf("Expected an error when AllowInsecureLocalhost isn't set")
+	}
+	opts = &TokenCredentialOptions{AuthorityHost: u, AllowInsecureLocalhost: true}
+	if _, err = opts.setDefaultValues(); err != nil {
+		t.Fatalf("Received an error: %v", err)
+	}
+}
+
+func Test_AllowedAuthorityHosts(t *testing.T) {
+	err := os.Setenv("AZURE_AUTHORITY_HOST", envHostString)
+	if err != nil {
+		t.Fatalf("Unexpected error when initializing environment variables: %v", err)
+	}
+	defer os.Unsetenv("AZURE_AUTHORITY_HOST")
+	opts := &TokenCredentialOptions{AllowedAuthorityHosts: KnownAuthorityHosts}
+	if _, err = opts.setDefaultValues(); err == nil {
+		t.Fatalf("Expected an error for an authority host that isn't allowed")
+	}
+	u, err := url.Parse(AzureGovernment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = &TokenCredentialOptions{AuthorityHost: u, AllowedAuthorityHosts: KnownAuthorityHosts}
+	if _, err = opts.setDefaultValues(); err != nil {
+		t.Fatalf("Received an error: %v", err)
+	}
+}
+
+func TestAuthenticationFailedError_Details(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(`{"error": "invalid_client", "error_description": "AADSTS7000222: The provided client secret keys are expired.", "error_codes": [7000222], "timestamp": "2020-01-01 00:00:00Z", "trace_id": "trace", "correlation_id": "correlation"}`)), mock.WithStatusCode(http.StatusUnauthorized))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	var authFailed *AuthenticationFailedError
+	if !errors.As(err, &authFailed) {
+		t.Fatalf("Expected an AuthenticationFailedError. Received: %v", err)
+	}
+	if authFailed.ErrorCode() != "AADSTS7000222" {
+		t.Fatalf("Unexpected error code: %s", authFailed.ErrorCode())
+	}
+	if authFailed.CorrelationID() != "correlation" || authFailed.Timestamp() != "2020-01-01 00:00:00Z" {
+		t.Fatalf("Unexpected correlation ID %q or timestamp %q", authFailed.CorrelationID(), authFailed.Timestamp())
+	}
+	if authFailed.TraceID() != "trace" || !authFailed.ServerTime().Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected trace ID %q or server time %v", authFailed.TraceID(), authFailed.ServerTime())
+	}
+	if resp := authFailed.RawResponse(); resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected the raw response. Received: %v", resp)
+	}
+}
+
+func TestAuthenticationFailedError_NoDetails(t *testing.T) {
+	err := &AuthenticationFailedError{msg: "failed", inner: errors.New("failed")}
+	if err.RawResponse() != nil || err.ErrorCode() != "" || err.CorrelationID() != "" || err.Timestamp() != "" {
+		t.Fatalf("Expected no details for an error that didn't come from Azure Active Directory")
+	}
+}
+
+func TestErrors_ReachableWithErrorsAs(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespError)), mock.WithStatusCode(http.StatusUnauthorized))
+	srvURL := srv.URL()
+	secCred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	chain, err := NewChainedTokenCredential(unavailableCredential{"Environment Credential", "not configured"}, secCred)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	_, err = chain.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	// the application wraps the error
+	err = fmt.Errorf("calling the service: %w", err)
+	var authFailed *AuthenticationFailedError
+	if !errors.As(err, &authFailed) {
+		t.Fatalf("Expected an AuthenticationFailedError. Received: %v", err)
+	}
+	if authFailed.ErrorCode() != "AADSTS0" {
+		t.Fatalf("Expected the AAD error to be reachable through the chain. Received code: %q", authFailed.ErrorCode())
+	}
+	var aadErr *AADAuthenticationFailedError
+	if !errors.As(err, &aadErr) {
+		t.Fatalf("Expected an AADAuthenticationFailedError. Received: %v", err)
+	}
+	var credentialFailed *AuthenticationFailedError
+	if !errors.As(authFailed.Unwrap(), &credentialFailed) || credentialFailed.CredentialType != "ClientSecretCredential" {
+		t.Fatalf("Expected the credential's error to name the credential. Received: %+v", credentialFailed)
+	}
+	if !strings.Contains(credentialFailed.Error(), troubleshootingURL+"#client-secret") {
+		t.Fatalf("Expected troubleshooting guidance. Received: %s", credentialFailed.Error())
+	}
+}
+
+func TestCredentialUnavailableError_Unwrap(t *testing.T) {
+	_, err := NewClientCertificateCredentialWithSelector(tenantID, clientID, "testdata/missing", ClientCertificateSelector{}, nil)
+	var credErr *CredentialUnavailableError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError. Received: %v", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the cause to be reachable. Received: %v", err)
+	}
+	if !strings.Contains(err.Error(), troubleshootingURL+"#client-cert") {
+		t.Fatalf("Expected troubleshooting guidance. Received: %s", err.Error())
+	}
+}
+
+func TestTroubleshootingLink(t *testing.T) {
+	if troubleshootingLink("Client Secret Credential") != troubleshootingLink("ClientSecretCredential") {
+		t.Fatalf("Expected credential types with and without spaces to share a link")
+	}
+	if troubleshootingLink("Chained Token Credential") != "" {
+		t.Fatalf("Expected no link for credentials without a section")
+	}
+}
+
+func Test_AdvancedSettingsFromEnvironment(t *testing.T) {
+	for k, v := range map[string]string{
+		additionallyAllowedTenantsEnvVar: "tenant1; tenant2;",
+		sendCertificateChainEnvVar:       "true",
+		clientCertificatePasswordEnvVar:  "password",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		_ = os.Setenv(k, v)
+	}
+	o, err := (&TokenCredentialOptions{}).setDefaultValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.AdditionallyAllowedTenants) != 2 || o.AdditionallyAllowedTenants[0] != "tenant1" || o.AdditionallyAllowedTenants[1] != "tenant2" {
+		t.Fatalf("Unexpected AdditionallyAllowedTenants: %v", o.AdditionallyAllowedTenants)
+	}
+	if !o.SendCertificateChain || o.ClientCertificatePassword != "password" {
+		t.Fatalf("Expected the settings in the environment. Received: %v, %q", o.SendCertificateChain, o.ClientCertificatePassword)
+	}
+	o, err = (&TokenCredentialOptions{AdditionallyAllowedTenants: []string{}, ClientCertificatePassword: "explicit"}).setDefaultValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.AdditionallyAllowedTenants) != 0 || o.ClientCertificatePassword != "explicit" {
+		t.Fatalf("Expected explicit options to take precedence. Received: %v, %q", o.AdditionallyAllowedTenants, o.ClientCertificatePassword)
+	}
+}
+
+// countingPolicy counts the requests that pass through it.
+type countingPolicy struct {
+	count int32
+}
+
+func (p *countingPolicy) Do(ctx context.Context, req *azcore.Request) (*azcore.Response, error) {
+	atomic.AddInt32(&p.count, 1)
+	return req.Next(ctx)
+}
+
+func Test_PerCallAndPerRetryPolicies(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusInternalServerError))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	perCall, perRetry := &countingPolicy{}, &countingPolicy{}
+	options := TokenCredentialOptions{
+		HTTPClient:             srv,
+		AuthorityHost:          &srvURL,
+		AllowInsecureLocalhost: true,
+		Retry:                  &azcore.RetryOptions{MaxRetries: 3, TryTimeout: time.Minute, RetryDelay: time.Millisecond, StatusCodes: []int{http.StatusInternalServerError}},
+		PerCallPolicies:        []azcore.Policy{perCall},
+		PerRetryPolicies:       []azcore.Policy{perRetry},
+	}
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &options)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if perCall.count != 1 || perRetry.count != 2 {
+		t.Fatalf("Expected 1 per call and 2 per retry requests. Received %d and %d", perCall.count, perRetry.count)
+	}
+}
+
+func Test_CustomPipeline(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	custom := &countingPolicy{}
+	pipeline := azcore.NewPipeline(srv, custom)
+	// the custom pipeline is used instead of the one built from the other options
+	unused := &countingPolicy{}
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{AuthorityHost: &srvURL, AllowInsecureLocalhost: true, Pipeline: &pipeline, PerCallPolicies: []azcore.Policy{unused}})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if custom.count != 1 || unused.count != 0 {
+		t.Fatalf("Expected the request to use only the custom pipeline. Received %d and %d", custom.count, unused.count)
+	}
+}
+
+func Test_ApplicationID(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	var userAgent string
+	recordUserAgent := azcore.PolicyFunc(func(ctx context.Context, req *azcore.Request) (*azcore.Response, error) {
+		userAgent = req.Request.Header.Get(azcore.HeaderUserAgent)
+		return req.Next(ctx)
+	})
+	options := TokenCredentialOptions{
+		HTTPClient:             srv,
+		AuthorityHost:          &srvURL,
+		AllowInsecureLocalhost: true,
+		Telemetry:              azcore.TelemetryOptions{Value: "sdk/1.0"},
+		ApplicationID:          "my-app",
+		PerCallPolicies:        []azcore.Policy{recordUserAgent},
+	}
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &options)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if !strings.HasPrefix(userAgent, "my-app sdk/1.0 ") {
+		t.Fatalf("Expected the application ID in the User-Agent. Received: %s", userAgent)
+	}
+	for _, id := range []string{"my app", strings.Repeat("a", maxApplicationIDLength+1)} {
+		if _, err = NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{ApplicationID: id}); err == nil {
+			t.Fatalf("Expected an error for the application ID %q", id)
+		}
+	}
+}
+
+func Test_ConnectTimeout(t *testing.T) {
+	transport := newHTTPClientTransport(50 * time.Millisecond)
+	req, err := http.NewRequest(http.MethodGet, "https://10.255.255.1/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	// 10.255.255.1 is a non-routable address, so the connection either fails or times out
+	if _, err = transport.Do(context.Background(), req); err == nil {
+		t.Fatalf("Expected an error connecting to a non-routable address")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected the connection attempt to time out quickly. Elapsed: %v", elapsed)
+	}
+}
+
+func Test_TryTimeout(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)), mock.WithSlowResponse(time.Second))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	options := TokenCredentialOptions{
+		HTTPClient:             srv,
+		AuthorityHost:          &srvURL,
+		AllowInsecureLocalhost: true,
+		Retry:                  &azcore.RetryOptions{MaxRetries: 1, TryTimeout: time.Minute, RetryDelay: time.Millisecond},
+		TryTimeout:             50 * time.Millisecond,
+	}
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &options)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	// the slow first attempt times out and is retried
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if srv.Requests() != 2 {
+		t.Fatalf("Expected 2 requests. Received: %d", srv.Requests())
+	}
+	if options.Retry.TryTimeout != time.Minute {
+		t.Fatalf("Expected the caller's retry options not to be modified")
+	}
+}
+
+func TestAuthenticationFailedError_DetailsFromHeaders(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(
+		mock.WithBody([]byte(`<html>Bad Gateway</html>`)),
+		mock.WithStatusCode(http.StatusBadRequest),
+		mock.WithHeader("client-request-id", "correlation"),
+		mock.WithHeader("x-ms-request-id", "trace"))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	var authFailed *AuthenticationFailedError
+	if !errors.As(err, &authFailed) {
+		t.Fatalf("Expected an AuthenticationFailedError. Received: %v", err)
+	}
+	if authFailed.CorrelationID() != "correlation" || authFailed.TraceID() != "trace" {
+		t.Fatalf("Unexpected correlation ID %q or trace ID %q", authFailed.CorrelationID(), authFailed.TraceID())
+	}
+	// the mock server sends a Date header
+	if authFailed.ServerTime().IsZero() {
+		t.Fatalf("Expected the server time from the Date header. Timestamp: %q", authFailed.Timestamp())
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -23,39 +25,112 @@ type AzureCLITokenProvider func(ctx context.Context, resource string) ([]byte, e
 
 // AzureCLICredentialOptions contains options used to configure the AzureCLICredential
 type AzureCLICredentialOptions struct {
+	// TokenProvider replaces the default provider which runs the Azure CLI.
+	// When set, TenantID and Subscription are ignored.
 	TokenProvider AzureCLITokenProvider
+
+	// TenantID identifies the tenant the token is requested for. Leave this empty to use the CLI's default tenant.
+	// Set this when the user is logged into multiple tenants.
+	TenantID string
+
+	// Subscription is the name or ID of the subscription whose tenant the token is requested for.
+	// Leave this empty to use the CLI's default subscription. TenantID takes precedence when both are set.
+	Subscription string
+
+	// TokenCache stores the tokens acquired by the credential. Leave this as nil to give the credential a cache of its own.
+	TokenCache *TokenCache
+
+	// Metrics receives a measurement of each token request. Leave this as nil to record nothing.
+	Metrics TokenMetrics
+
+	// Tracer records each token request as a span in the caller's trace. Leave this as nil to record nothing.
+	Tracer azcore.Tracer
+
+	// OnTokenRefreshed is called with the metadata of each token the credential acquires. Leave this as nil to be notified of nothing.
+	OnTokenRefreshed func(TokenRefreshedEvent)
 }
 
 // AzureCLICredential enables authentication to Azure Active Directory using the Azure CLI command "az account get-access-token".
 type AzureCLICredential struct {
 	tokenProvider AzureCLITokenProvider
+	// defaultProvider is true when the credential runs the Azure CLI rather than an alternate token provider
+	defaultProvider bool
+	tenantID        string // partitions the cache along with subscription, since the CLI returns tokens for different tenants
+	subscription    string
+	cache           *TokenCache
+	metrics         TokenMetrics
+	tracer          azcore.Tracer
+	onRefreshed     func(TokenRefreshedEvent)
 }
 
 // NewAzureCLICredential constructs a new AzureCLICredential with the details needed to authenticate against Azure Active Directory
 // options: configure the management of the requests sent to Azure Active Directory.
 func NewAzureCLICredential(options *AzureCLICredentialOptions) (*AzureCLICredential, error) {
 	if options == nil {
-		options = &AzureCLICredentialOptions{TokenProvider: defaultTokenProvider()}
+		options = &AzureCLICredentialOptions{}
 	}
-	return &AzureCLICredential{
-		tokenProvider: options.TokenProvider,
-	}, nil
+	tokenProvider := options.TokenProvider
+	defaultProvider := tokenProvider == nil
+	if defaultProvider {
+		tokenProvider = defaultTokenProvider(options.TenantID, options.Subscription)
+	}
+	cache := options.TokenCache
+	if cache == nil {
+		cache = NewTokenCache(nil)
+	}
+	cred := &AzureCLICredential{
+		tokenProvider:   tokenProvider,
+		defaultProvider: defaultProvider,
+		tenantID:        options.TenantID,
+		subscription:    options.Subscription,
+		cache:           cache,
+		metrics:         options.Metrics,
+		tracer:          options.Tracer,
+		onRefreshed:     options.OnTokenRefreshed,
+	}
+	logCredentialCreated(cred)
+	return cred, nil
 }
 
 // GetToken obtains a token from Azure Active Directory, using the Azure CLI command to authenticate.
 // ctx: Context used to control the request lifetime.
 // opts: TokenRequestOptions contains the list of scopes for which the token will have access.
 // Returns an AccessToken which can be used to authenticate service client calls.
-func (c *AzureCLICredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
-	// The following code will remove the /.default suffix from the scope passed into the method since AzureCLI expect a resource string instead of a scope string
-	opts.Scopes[0] = strings.TrimSuffix(opts.Scopes[0], defaultSuffix)
-	at, err := c.authenticate(ctx, opts.Scopes[0])
+func (c *AzureCLICredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("Azure CLI Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	// AzureCLI expects a resource string instead of a scope string, so the /.default suffix is removed from the scope.
+	// The caller's scopes are left as they are.
+	resource := ScopeToResource(opts.Scopes[0])
+	at, err := c.cache.getToken(ctx, tokenCacheKey("azure cli|"+c.subscription, c.tenantID, opts), tokenTelemetry{metrics: c.metrics, tracer: c.tracer, onTokenRefreshed: c.onRefreshed, credentialType: "AzureCLICredential", tenantID: c.tenantID, scopes: opts.Scopes}, func(ctx context.Context) (*azcore.AccessToken, error) {
+		return c.authenticate(ctx, resource)
+	})
 	if err != nil {
 		addGetTokenFailureLogs("Azure CLI Credential", err)
-		return nil, err
+		return azcore.AccessToken{}, err
 	}
 	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
-	return at, nil
+	return *at, nil
+}
+
+// Validate checks that the Azure CLI is installed without requesting a token. It doesn't check that a user is logged in.
+// Credentials with a TokenProvider option are always valid.
+func (c *AzureCLICredential) Validate(ctx context.Context) error {
+	if !c.defaultProvider {
+		return nil
+	}
+	if _, err := findAzureCLI(); err != nil {
+		return cliNotFoundError(err)
+	}
+	return nil
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *AzureCLICredential) ClearCache() {
+	c.cache.clear("azure cli|" + c.subscription + "|")
 }
 
 // AuthenticationPolicy implements the azcore.Credential interface on AzureCLICredential and calls the Bearer Token policy
@@ -79,17 +154,8 @@ func (c *AzureCLICredential) authenticate(ctx context.Context, resource string)
 	return c.createAccessToken(output)
 }
 
-func defaultTokenProvider() func(ctx context.Context, resource string) ([]byte, error) {
+func defaultTokenProvider(tenantID string, subscription string) func(ctx context.Context, resource string) ([]byte, error) {
 	return func(ctx context.Context, resource string) ([]byte, error) {
-		// This is the path that a developer can set to tell this class what the install path for Azure CLI is.
-		const azureCLIPath = "AZURE_CLI_PATH"
-
-		// The default install paths are used to find Azure CLI. This is for security, so that any path in the calling program's Path environment is not used to execute Azure CLI.
-		azureCLIDefaultPathWindows := fmt.Sprintf("%s\\Microsoft SDKs\\Azure\\CLI2\\wbin; %s\\Microsoft SDKs\\Azure\\CLI2\\wbin", os.Getenv("ProgramFiles(x86)"), os.Getenv("ProgramFiles"))
-
-		// Default path for non-Windows.
-		const azureCLIDefaultPath = "/bin:/sbin:/usr/bin:/usr/local/bin"
-
 		// Validate resource, since it gets sent as a command line argument to Azure CLI
 		const invalidResourceErrorTemplate = "Resource %s is not in expected format. Only alphanumeric characters, [dot], [colon], [hyphen], and [forward slash] are allowed."
 		match, err := regexp.MatchString("^[0-9a-zA-Z-.:/]+$", resource)
@@ -99,23 +165,26 @@ func defaultTokenProvider() func(ctx context.Context, resource string) ([]byte,
 		if !match {
 			return nil, fmt.Errorf(invalidResourceErrorTemplate, resource)
 		}
+		args, err := cliArgs(resource, tenantID, subscription)
+		if err != nil {
+			return nil, err
+		}
 
 		ctx, cancel := context.WithTimeout(ctx, timeoutCLIRequest)
 		defer cancel()
 
-		// Execute Azure CLI to get token
+		// Execute the Azure CLI found by findAzureCLI, the one Validate checks, by its full path
+		az, err := findAzureCLI()
+		if err != nil {
+			return nil, cliNotFoundError(err)
+		}
 		var cliCmd *exec.Cmd
 		if runtime.GOOS == "windows" {
-			cliCmd = exec.CommandContext(ctx, fmt.Sprintf("%s\\system32\\cmd.exe", os.Getenv("windir")))
-			cliCmd.Env = os.Environ()
-			cliCmd.Env = append(cliCmd.Env, fmt.Sprintf("PATH=%s;%s", os.Getenv(azureCLIPath), azureCLIDefaultPathWindows))
-			cliCmd.Args = append(cliCmd.Args, "/c", "az")
+			cliCmd = exec.CommandContext(ctx, fmt.Sprintf("%s\\system32\\cmd.exe", os.Getenv("windir")), "/c", az)
 		} else {
-			cliCmd = exec.CommandContext(ctx, "az")
-			cliCmd.Env = os.Environ()
-			cliCmd.Env = append(cliCmd.Env, fmt.Sprintf("PATH=%s:%s", os.Getenv(azureCLIPath), azureCLIDefaultPath))
+			cliCmd = exec.CommandContext(ctx, az)
 		}
-		cliCmd.Args = append(cliCmd.Args, "account", "get-access-token", "-o", "json", "--resource", resource)
+		cliCmd.Args = append(cliCmd.Args, args...)
 
 		var stderr bytes.Buffer
 		cliCmd.Stderr = &stderr
@@ -129,6 +198,60 @@ func defaultTokenProvider() func(ctx context.Context, resource string) ([]byte,
 	}
 }
 
+// azureCLISearchPath returns the directories searched for the Azure CLI: those in the AZURE_CLI_PATH environment variable,
+// which a developer can set to tell the credential where the CLI is installed, followed by the default install directories.
+// The credential executes the CLI it finds there by its full path, so the calling program's PATH can't make it execute
+// another program.
+func azureCLISearchPath() string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf("%s;%s\\Microsoft SDKs\\Azure\\CLI2\\wbin; %s\\Microsoft SDKs\\Azure\\CLI2\\wbin", os.Getenv("AZURE_CLI_PATH"), os.Getenv("ProgramFiles(x86)"), os.Getenv("ProgramFiles"))
+	}
+	return os.Getenv("AZURE_CLI_PATH") + ":/bin:/sbin:/usr/bin:/usr/local/bin"
+}
+
+// findAzureCLI returns the path of the Azure CLI in the directories of azureCLISearchPath.
+func findAzureCLI() (string, error) {
+	name := "az"
+	if runtime.GOOS == "windows" {
+		name = "az.cmd"
+	}
+	for _, dir := range filepath.SplitList(azureCLISearchPath()) {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		p := filepath.Join(dir, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() && (runtime.GOOS == "windows" || fi.Mode()&0111 != 0) {
+			return p, nil
+		}
+	}
+	return "", errors.New("Azure CLI not found on path")
+}
+
+// cliNotFoundError returns the error of a credential that can't find the Azure CLI.
+func cliNotFoundError(err error) error {
+	return &CredentialUnavailableError{CredentialType: "Azure CLI Credential", Message: err.Error() + ", set AZURE_CLI_PATH to the directory it's installed in", inner: err}
+}
+
+// cliArgs returns the arguments passed to the Azure CLI in order to get an access token for the specified resource.
+// The tenant and subscription are validated since they get sent as command line arguments to Azure CLI.
+func cliArgs(resource string, tenantID string, subscription string) ([]string, error) {
+	args := []string{"account", "get-access-token", "-o", "json", "--resource", resource}
+	if tenantID != "" {
+		if match, _ := regexp.MatchString("^[0-9a-zA-Z-.]+$", tenantID); !match {
+			return nil, fmt.Errorf("Tenant ID %s is not in expected format. Only alphanumeric characters, [dot] and [hyphen] are allowed.", tenantID)
+		}
+		return append(args, "--tenant", tenantID), nil
+	}
+	if subscription != "" {
+		if match, _ := regexp.MatchString("^[0-9a-zA-Z-._ ]+$", subscription); !match {
+			return nil, fmt.Errorf("Subscription %s is not in expected format. Only alphanumeric characters, [space], [dot], [underscore] and [hyphen] are allowed.", subscription)
+		}
+		args = append(args, "--subscription", subscription)
+	}
+	return args, nil
+}
+
 func (c *AzureCLICredential) createAccessToken(tk []byte) (*azcore.AccessToken, error) {
 	t := struct {
 		AccessToken      string `json:"accessToken"`
@@ -6,7 +6,12 @@ package azidentity
 import (
 	"context"
 	"errors"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -54,6 +59,46 @@ func TestAzureCLICredential_GetTokenInvalidToken(t *testing.T) {
 	}
 }
 
+func TestAzureCLICredential_NilTokenProvider(t *testing.T) {
+	cred, err := NewAzureCLICredential(&AzureCLICredentialOptions{TenantID: tenantID})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if cred.tokenProvider == nil {
+		t.Fatalf("Expected the default token provider to be set")
+	}
+}
+
+func TestAzureCLICredential_CLIArgs(t *testing.T) {
+	args, err := cliArgs("https://storage.azure.com", "", "")
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	if strings.Join(args, " ") != "account get-access-token -o json --resource https://storage.azure.com" {
+		t.Fatalf("Unexpected arguments: %v", args)
+	}
+	args, err = cliArgs("https://storage.azure.com", "my-tenant.onmicrosoft.com", "mysub")
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	if strings.Join(args, " ") != "account get-access-token -o json --resource https://storage.azure.com --tenant my-tenant.onmicrosoft.com" {
+		t.Fatalf("Unexpected arguments: %v", args)
+	}
+	args, err = cliArgs("https://storage.azure.com", "", "My Subscription")
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	if args[len(args)-2] != "--subscription" || args[len(args)-1] != "My Subscription" {
+		t.Fatalf("Unexpected arguments: %v", args)
+	}
+	if _, err = cliArgs("https://storage.azure.com", "tenant&calc", ""); err == nil {
+		t.Fatalf("Expected an error for an invalid tenant ID")
+	}
+	if _, err = cliArgs("https://storage.azure.com", "", "sub|calc"); err == nil {
+		t.Fatalf("Expected an error for an invalid subscription")
+	}
+}
+
 func TestBearerPolicy_AzureCLICredential(t *testing.T) {
 	srv, close := mock.NewTLSServer()
 	defer close()
@@ -74,3 +119,74 @@ func TestBearerPolicy_AzureCLICredential(t *testing.T) {
 		t.Fatal("Expected nil error but received one")
 	}
 }
+
+func TestAzureCLICredential_Validate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test installs a shell script")
+	}
+	cred, err := NewAzureCLICredential(&AzureCLICredentialOptions{TokenProvider: mockCLITokenProviderFailure})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if err = cred.Validate(context.Background()); err != nil {
+		t.Fatalf("Expected a credential with a token provider to be valid. Received: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "azcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "az"), []byte("#!/bin/sh\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("AZURE_CLI_PATH", os.Getenv("AZURE_CLI_PATH"))
+	os.Setenv("AZURE_CLI_PATH", dir)
+	cred, err = NewAzureCLICredential(nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if err = cred.Validate(context.Background()); err != nil {
+		t.Fatalf("Expected the Azure CLI to be found. Received: %v", err)
+	}
+	if p, _ := findAzureCLI(); p != filepath.Join(dir, "az") {
+		t.Fatalf("Expected the Azure CLI in AZURE_CLI_PATH to be found first. Received: %s", p)
+	}
+}
+
+func TestAzureCLICredential_RunsCLIFoundByValidate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test installs shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "azcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cliDir, pathDir := filepath.Join(dir, "cli"), filepath.Join(dir, "path")
+	for d, script := range map[string]string{
+		cliDir:  `#!/bin/sh` + "\n" + `echo '{"accessToken":"mocktoken","expiresOn":"2007-01-01 01:01:01.079627","tokenType":"Bearer"}'` + "\n",
+		pathDir: "#!/bin/sh\necho 'not the Azure CLI' >&2\nexit 1\n",
+	} {
+		if err = os.Mkdir(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(d, "az"), []byte(script), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer os.Setenv("AZURE_CLI_PATH", os.Getenv("AZURE_CLI_PATH"))
+	os.Setenv("AZURE_CLI_PATH", cliDir)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", pathDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cred, err := NewAzureCLICredential(nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	at, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Expected the Azure CLI in AZURE_CLI_PATH to be run. Received: %v", err)
+	}
+	if at.Token != "mocktoken" {
+		t.Fatalf("Unexpected token: %s", at.Token)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// azureCLICloudAuthorityHosts are the authority hosts of the clouds built into the Azure CLI, by the name it gives them.
+var azureCLICloudAuthorityHosts = map[string]string{
+	"AzureCloud":        AzurePublicCloud,
+	"AzureChinaCloud":   AzureChina,
+	"AzureUSGovernment": AzureGovernment,
+	"AzureGermanCloud":  AzureGermany,
+}
+
+// AzureCLIProfile is the account selected in the Azure CLI, read from its configuration directory.
+// Tools can use it to default to the tenant, subscription and cloud the user chose with "az login",
+// "az account set" and "az cloud set".
+type AzureCLIProfile struct {
+	// TenantID is the tenant of the default subscription.
+	TenantID string
+	// SubscriptionID is the ID of the default subscription.
+	SubscriptionID string
+	// SubscriptionName is the name of the default subscription.
+	SubscriptionName string
+	// User is the name of the user or service principal logged in to the default subscription.
+	User string
+	// Cloud is the name of the active cloud, for example "AzureCloud" or "AzureUSGovernment".
+	Cloud string
+	// AuthorityHost is the Azure Active Directory authority host of the active cloud, for example AzurePublicCloud.
+	// It's empty when the CLI's configuration doesn't say what it is for a custom cloud.
+	AuthorityHost string
+}
+
+// azureCLIProfileDocument is the format of azureProfile.json.
+type azureCLIProfileDocument struct {
+	Subscriptions []struct {
+		ID              string `json:"id"`
+		Name            string `json:"name"`
+		TenantID        string `json:"tenantId"`
+		IsDefault       bool   `json:"isDefault"`
+		EnvironmentName string `json:"environmentName"`
+		User            struct {
+			Name string `json:"name"`
+		} `json:"user"`
+	} `json:"subscriptions"`
+}
+
+// LoadAzureCLIProfile reads the default account of the Azure CLI from the directory in the AZURE_CONFIG_DIR
+// environment variable, or ~/.azure when it isn't set. It returns a *CredentialUnavailableError when the user
+// hasn't logged in to the CLI.
+func LoadAzureCLIProfile() (*AzureCLIProfile, error) {
+	dir, err := azureCLIConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return loadAzureCLIProfile(dir)
+}
+
+// azureCLIConfigDir returns the Azure CLI's configuration directory.
+func azureCLIConfigDir() (string, error) {
+	if dir := os.Getenv("AZURE_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".azure"), nil
+}
+
+func loadAzureCLIProfile(dir string) (*AzureCLIProfile, error) {
+	config, err := readINI(filepath.Join(dir, "config"))
+	if err != nil {
+		return nil, err
+	}
+	clouds, err := readINI(filepath.Join(dir, "clouds.config"))
+	if err != nil {
+		return nil, err
+	}
+	p := &AzureCLIProfile{Cloud: config["cloud"]["name"]}
+	if p.Cloud == "" {
+		p.Cloud = "AzureCloud"
+	}
+	p.AuthorityHost = azureCLICloudAuthorityHosts[p.Cloud]
+	if endpoint := clouds[p.Cloud]["endpoint_active_directory"]; endpoint != "" {
+		p.AuthorityHost = strings.TrimSuffix(endpoint, "/") + "/"
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "azureProfile.json"))
+	if os.IsNotExist(err) {
+		return nil, &CredentialUnavailableError{CredentialType: "Azure CLI Credential", Message: "Please run 'az login' to set up an account"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc azureCLIProfileDocument
+	// the CLI writes the file with a byte order mark
+	if err = json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &doc); err != nil {
+		return nil, fmt.Errorf("unmarshalling %s: %w", filepath.Join(dir, "azureProfile.json"), err)
+	}
+	// the subscription selected in clouds.config takes precedence over the one marked as default
+	selected := clouds[p.Cloud]["subscription"]
+	for _, s := range doc.Subscriptions {
+		if s.EnvironmentName != "" && s.EnvironmentName != p.Cloud {
+			continue
+		}
+		if (selected == "" && s.IsDefault) || (selected != "" && strings.EqualFold(s.ID, selected)) {
+			p.TenantID, p.SubscriptionID, p.SubscriptionName, p.User = s.TenantID, s.ID, s.Name, s.User.Name
+			return p, nil
+		}
+	}
+	return nil, &CredentialUnavailableError{CredentialType: "Azure CLI Credential", Message: "No default subscription found for cloud " + p.Cloud + ". Please run 'az login' or 'az account set'"}
+}
+
+// readINI returns the values of the INI file by section and key, or an empty map when the file doesn't exist.
+func readINI(path string) (map[string]map[string]string, error) {
+	sections := map[string]map[string]string{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return sections, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+		default:
+			i := strings.IndexAny(line, "=:")
+			if i < 0 {
+				return nil, errors.New("unexpected line in " + path + ": " + line)
+			}
+			if sections[section] == nil {
+				sections[section] = map[string]string{}
+			}
+			sections[section][strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return sections, scanner.Err()
+}
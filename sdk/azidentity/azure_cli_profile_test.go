@@ -0,0 +1,94 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testAzureProfile = "\xef\xbb\xbf" + `{"subscriptions": [
+	{"id": "sub1", "name": "First", "tenantId": "tenant1", "isDefault": false, "environmentName": "AzureCloud", "user": {"name": "user@contoso.com", "type": "user"}},
+	{"id": "sub2", "name": "Second", "tenantId": "tenant2", "isDefault": true, "environmentName": "AzureCloud", "user": {"name": "user@contoso.com", "type": "user"}},
+	{"id": "sub3", "name": "Government", "tenantId": "tenant3", "isDefault": true, "environmentName": "AzureUSGovernment", "user": {"name": "user@contoso.us", "type": "user"}}
+], "installationId": "id"}`
+
+// writeAzureCLIConfig creates an Azure CLI configuration directory with the files and points AZURE_CONFIG_DIR to it
+// until the returned func is called.
+func writeAzureCLIConfig(t *testing.T, files map[string]string) func() {
+	dir, err := ioutil.TempDir("", "azureconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	original := os.Getenv("AZURE_CONFIG_DIR")
+	_ = os.Setenv("AZURE_CONFIG_DIR", dir)
+	return func() {
+		_ = os.Setenv("AZURE_CONFIG_DIR", original)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLoadAzureCLIProfile_Default(t *testing.T) {
+	defer writeAzureCLIConfig(t, map[string]string{"azureProfile.json": testAzureProfile})()
+	p, err := LoadAzureCLIProfile()
+	if err != nil {
+		t.Fatalf("Unable to load the profile: %v", err)
+	}
+	expected := AzureCLIProfile{TenantID: "tenant2", SubscriptionID: "sub2", SubscriptionName: "Second", User: "user@contoso.com", Cloud: "AzureCloud", AuthorityHost: AzurePublicCloud}
+	if *p != expected {
+		t.Fatalf("Unexpected profile: %+v", *p)
+	}
+}
+
+func TestLoadAzureCLIProfile_Cloud(t *testing.T) {
+	defer writeAzureCLIConfig(t, map[string]string{
+		"azureProfile.json": testAzureProfile,
+		"config":            "[cloud]\nname = AzureUSGovernment\n\n[core]\noutput = json\n",
+	})()
+	p, err := LoadAzureCLIProfile()
+	if err != nil {
+		t.Fatalf("Unable to load the profile: %v", err)
+	}
+	if p.SubscriptionID != "sub3" || p.TenantID != "tenant3" || p.AuthorityHost != AzureGovernment {
+		t.Fatalf("Expected the default subscription of the active cloud. Received: %+v", *p)
+	}
+}
+
+func TestLoadAzureCLIProfile_CloudsConfig(t *testing.T) {
+	defer writeAzureCLIConfig(t, map[string]string{
+		"azureProfile.json": testAzureProfile,
+		"clouds.config":     "[AzureCloud]\nsubscription = SUB1\nendpoint_active_directory = https://login.contoso.com\n",
+	})()
+	p, err := LoadAzureCLIProfile()
+	if err != nil {
+		t.Fatalf("Unable to load the profile: %v", err)
+	}
+	if p.SubscriptionID != "sub1" || p.TenantID != "tenant1" || p.AuthorityHost != "https://login.contoso.com/" {
+		t.Fatalf("Expected the subscription and authority host in clouds.config. Received: %+v", *p)
+	}
+}
+
+func TestLoadAzureCLIProfile_NotLoggedIn(t *testing.T) {
+	defer writeAzureCLIConfig(t, map[string]string{"config": "[cloud]\nname = AzureCloud\n"})()
+	_, err := LoadAzureCLIProfile()
+	var credErr *CredentialUnavailableError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError. Received: %v", err)
+	}
+}
+
+func TestLoadAzureCLIProfile_Malformed(t *testing.T) {
+	defer writeAzureCLIConfig(t, map[string]string{"azureProfile.json": "{"})()
+	if _, err := LoadAzureCLIProfile(); err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// GetTokens requests an access token from the specified credential for each of the independent sets of scopes
+// in opts. The requests are made concurrently so that applications which need tokens for several resources at
+// startup (e.g. Storage, Key Vault and ARM) don't pay for each round trip to Azure Active Directory in sequence.
+// The returned slice contains the tokens in the same order as opts. If any of the requests fail then the first
+// error encountered is returned and no tokens are returned. Credentials that sign a user in, such as
+// DeviceCodeCredential, serialize their requests, so the user signs in once and the other tokens are acquired
+// with the refresh token of that sign in.
+// ctx: Context used to control the lifetime of all the requests.
+// cred: The TokenCredential used to acquire the tokens.
+// opts: One TokenRequestOptions for each token to acquire.
+func GetTokens(ctx context.Context, cred azcore.TokenCredential, opts ...azcore.TokenRequestOptions) ([]azcore.AccessToken, error) {
+	tokens := make([]azcore.AccessToken, len(opts))
+	errs := make([]error, len(opts))
+	// cancel any requests that are still in flight once one of them fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wg := sync.WaitGroup{}
+	for i := range opts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = cred.GetToken(ctx, opts[i])
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+	var firstErr error
+	for _, err := range errs {
+		// prefer the error that caused the cancellation over any context.Canceled errors that followed
+		if err != nil && (firstErr == nil || firstErr == context.Canceled) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return tokens, nil
+}
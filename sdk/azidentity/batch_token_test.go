@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// fakeCredential returns a token whose value is the requested scopes
+type fakeCredential struct {
+	fail string
+}
+
+func (f *fakeCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	tk := strings.Join(opts.Scopes, " ")
+	if tk == f.fail {
+		return azcore.AccessToken{}, errors.New("failed to get token for " + tk)
+	}
+	return azcore.AccessToken{Token: tk, ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func (f *fakeCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
+	return newBearerTokenPolicy(f, options)
+}
+
+func TestGetTokens_Success(t *testing.T) {
+	scopes := []string{"https://storage.azure.com/.default", "https://vault.azure.net/.default", "https://management.azure.com/.default"}
+	opts := []azcore.TokenRequestOptions{}
+	for _, s := range scopes {
+		opts = append(opts, azcore.TokenRequestOptions{Scopes: []string{s}})
+	}
+	tks, err := GetTokens(context.Background(), &fakeCredential{}, opts...)
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	if len(tks) != len(scopes) {
+		t.Fatalf("Expected %d tokens but received %d", len(scopes), len(tks))
+	}
+	for i, s := range scopes {
+		if tks[i].Token != s {
+			t.Fatalf("Expected token %s but received %s", s, tks[i].Token)
+		}
+	}
+}
+
+func TestGetTokens_Failure(t *testing.T) {
+	opts := []azcore.TokenRequestOptions{
+		{Scopes: []string{"https://storage.azure.com/.default"}},
+		{Scopes: []string{"https://vault.azure.net/.default"}},
+	}
+	tks, err := GetTokens(context.Background(), &fakeCredential{fail: "https://vault.azure.net/.default"}, opts...)
+	if err == nil {
+		t.Fatalf("Expected an error but did not receive one")
+	}
+	if tks != nil {
+		t.Fatalf("Expected nil tokens on failure")
+	}
+}
+
+func TestGetTokens_DeviceCodeSignsInOnce(t *testing.T) {
+	var mu sync.Mutex
+	deviceCodes, refreshes := 0, 0
+	transport := azcore.TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if err := req.ParseForm(); err != nil {
+			return nil, err
+		}
+		body := `{"access_token": "` + tokenValue + `", "refresh_token": "refresh", "expires_in": 3600}`
+		mu.Lock()
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/devicecode"):
+			deviceCodes++
+			body = deviceCodeResponse
+		case req.PostForm.Get(qpGrantType) == "refresh_token":
+			refreshes++
+		}
+		mu.Unlock()
+		// give the other requests time to start while the user signs in
+		time.Sleep(20 * time.Millisecond)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	prompts := 0
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, func(string) { prompts++ }, &DeviceCodeCredentialOptions{TokenCredentialOptions: TokenCredentialOptions{HTTPClient: transport}})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	opts := []azcore.TokenRequestOptions{
+		{Scopes: []string{"https://storage.azure.com/.default"}},
+		{Scopes: []string{"https://vault.azure.net/.default"}},
+		{Scopes: []string{"https://management.azure.com/.default"}},
+	}
+	if _, err = GetTokens(context.Background(), cred, opts...); err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	if prompts != 1 || deviceCodes != 1 {
+		t.Fatalf("Expected the user to sign in once. Prompts: %d, device codes: %d", prompts, deviceCodes)
+	}
+	if refreshes != len(opts)-1 {
+		t.Fatalf("Expected the other tokens to be acquired with the refresh token. Refreshes: %d", refreshes)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
@@ -41,7 +42,7 @@ func TestBearerPolicy_SuccessGetToken(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Expected nil error but received one")
 	}
-	const expectedToken = bearerTokenPrefix + tokenValue
+	const expectedToken = "Bearer " + tokenValue
 	if token := resp.Request.Header.Get(azcore.HeaderAuthorization); token != expectedToken {
 		t.Fatalf("expected token '%s', got '%s'", expectedToken, token)
 	}
@@ -107,6 +108,37 @@ func TestBearerTokenPolicy_TokenExpired(t *testing.T) {
 	}
 }
 
+// refreshOnCredential counts its calls and returns tokens the service recommends refreshing immediately
+type refreshOnCredential struct {
+	calls int
+}
+
+func (c *refreshOnCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.calls++
+	return azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(time.Hour), RefreshOn: time.Now().Add(-time.Second)}, nil
+}
+
+func (c *refreshOnCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
+	return newBearerTokenPolicy(c, options)
+}
+
+func TestBearerTokenPolicy_RefreshOn(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	cred := &refreshOnCredential{}
+	pipeline := azcore.NewPipeline(srv, cred.AuthenticationPolicy(azcore.AuthenticationPolicyOptions{Options: azcore.TokenRequestOptions{Scopes: []string{scope}}}))
+	for i := 0; i < 2; i++ {
+		if _, err := pipeline.Do(context.Background(), azcore.NewRequest(http.MethodGet, srv.URL())); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if cred.calls != 2 {
+		t.Fatalf("Expected a token past its recommended refresh time to be refreshed. Calls: %d", cred.calls)
+	}
+}
+
 // with https scheme enabled we get an auth failed error which let's us test the is not retriable error
 func TestRetryPolicy_IsNotRetriable(t *testing.T) {
 	srv, close := mock.NewTLSServer()
@@ -137,7 +169,7 @@ func TestRetryPolicy_HTTPRequest(t *testing.T) {
 	defer close()
 	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized))
 	srvURL := srv.URL()
-	cred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
@@ -154,3 +186,47 @@ func TestRetryPolicy_HTTPRequest(t *testing.T) {
 		t.Fatalf("unexpected error type %v", err)
 	}
 }
+
+// blockingCredential blocks GetToken until release is closed, then fails
+type blockingCredential struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	close(c.started)
+	<-c.release
+	return azcore.AccessToken{}, errors.New("token request failed")
+}
+
+func (c *blockingCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
+	return newBearerTokenPolicy(c, options)
+}
+
+func TestBearerTokenPolicy_WaitCanceled(t *testing.T) {
+	srv, closeSrv := mock.NewTLSServer()
+	defer closeSrv()
+	cred := &blockingCredential{started: make(chan struct{}), release: make(chan struct{})}
+	pipeline := azcore.NewPipeline(srv, cred.AuthenticationPolicy(azcore.AuthenticationPolicyOptions{Options: azcore.TokenRequestOptions{Scopes: []string{scope}}}))
+	first := make(chan error)
+	go func() {
+		_, err := pipeline.Do(context.Background(), azcore.NewRequest(http.MethodGet, srv.URL()))
+		first <- err
+	}()
+	<-cred.started
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pipeline.Do(ctx, azcore.NewRequest(http.MethodGet, srv.URL())); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected waiting for the token to stop when the context is done. Received: %v", err)
+	}
+	close(cred.release)
+	if err := <-first; err == nil {
+		t.Fatalf("Expected the failed token request's error")
+	}
+	// the failed refresh must not leave the policy waiting for a refresh that will never finish
+	cred.started, cred.release = make(chan struct{}), make(chan struct{})
+	close(cred.release)
+	if _, err := pipeline.Do(context.Background(), azcore.NewRequest(http.MethodGet, srv.URL())); err == nil {
+		t.Fatalf("Expected the next request to try to get a token again")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package cache provides persistent, encrypted storage for azidentity token caches so that applications such as
+// command line tools don't have to ask users to sign in every time they run.
+//
+// Cache contents are protected with DPAPI on Windows, stored in the login Keychain on macOS and stored with the
+// Secret Service (libsecret, for example GNOME Keyring or KWallet) on Linux.
+package cache
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// defaultName is the name of the cache when Options.Name is empty.
+const defaultName = "msal.cache"
+
+// ErrUnencryptedStorage is returned by New when encrypted storage isn't available on the platform
+// and Options.AllowUnencryptedStorage is false.
+var ErrUnencryptedStorage = errors.New("encrypted storage isn't available on this platform and unencrypted storage isn't allowed")
+
+// validName matches cache names that are safe to use as file names and secret labels.
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Storage persists the serialized contents of a token cache.
+type Storage interface {
+	// Read returns the persisted data, or nil when nothing has been persisted yet.
+	Read(ctx context.Context) ([]byte, error)
+	// Write replaces the persisted data.
+	Write(ctx context.Context, data []byte) error
+	// Delete removes the persisted data.
+	Delete(ctx context.Context) error
+}
+
+// Options configure the Storage returned by New.
+type Options struct {
+	// Name distinguishes the caches of different applications. Defaults to "msal.cache".
+	Name string
+	// Directory holds cache files on platforms that store the cache in a file. Defaults to the
+	// .IdentityService directory in the user's local application data or home directory.
+	Directory string
+	// AllowUnencryptedStorage allows the cache to be stored in a file readable only by the current user when
+	// encrypted storage isn't available, for example on a Linux host without a Secret Service.
+	AllowUnencryptedStorage bool
+}
+
+// New returns the encrypted Storage for the current platform. When it isn't available, a file Storage that
+// isn't encrypted is returned if o.AllowUnencryptedStorage is true, otherwise ErrUnencryptedStorage.
+func New(o *Options) (Storage, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	opts := *o
+	if opts.Name == "" {
+		opts.Name = defaultName
+	}
+	if !validName.MatchString(opts.Name) {
+		return nil, errors.New("cache name may only contain letters, digits, '.', '_' and '-'")
+	}
+	if opts.Directory == "" {
+		dir, err := defaultDirectory()
+		if err != nil {
+			return nil, err
+		}
+		opts.Directory = dir
+	}
+	s, err := newPlatformStorage(opts)
+	if err == nil {
+		return s, nil
+	}
+	if !opts.AllowUnencryptedStorage {
+		return nil, ErrUnencryptedStorage
+	}
+	return newFileStorage(filepath.Join(opts.Directory, opts.Name)), nil
+}
+
+// defaultDirectory returns the directory that holds cache files by default.
+func defaultDirectory() (string, error) {
+	if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+		return filepath.Join(dir, ".IdentityService"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".IdentityService"), nil
+}
+
+// fileStorage stores data in a file readable only by the current user, optionally transforming it
+// with protect before writing and with unprotect after reading.
+type fileStorage struct {
+	fileLock
+	path      string
+	protect   func([]byte) ([]byte, error)
+	unprotect func([]byte) ([]byte, error)
+}
+
+func newFileStorage(path string) *fileStorage {
+	return &fileStorage{fileLock: fileLock{path: path + lockFileSuffix}, path: path}
+}
+
+func (s *fileStorage) Read(ctx context.Context) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.unprotect != nil && len(data) > 0 {
+		return s.unprotect(data)
+	}
+	return data, nil
+}
+
+func (s *fileStorage) Write(ctx context.Context, data []byte) error {
+	if s.protect != nil {
+		var err error
+		if data, err = s.protect(data); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	// write to a temporary file and rename it so that readers never see a partially written cache
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *fileStorage) Delete(ctx context.Context) error {
+	err := os.Remove(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
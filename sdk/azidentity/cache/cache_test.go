@@ -0,0 +1,116 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := newFileStorage(filepath.Join(dir, "nested", "test.cache"))
+	data, err := s.Read(context.Background())
+	if err != nil || data != nil {
+		t.Fatalf("Expected no data and no error before writing. Received: %v, %v", data, err)
+	}
+	if err = s.Write(context.Background(), []byte("cached")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Mode().Perm()&0077 != 0 && os.PathSeparator == '/' {
+		t.Fatalf("Expected the cache file to be readable only by its owner. Mode: %v", info.Mode())
+	}
+	data, err = s.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "cached" {
+		t.Fatalf("Unexpected data: %s", data)
+	}
+	if err = s.Delete(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err = s.Delete(context.Background()); err != nil {
+		t.Fatalf("Expected deleting a missing cache to succeed. Received: %v", err)
+	}
+}
+
+func TestFileStorage_Protect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	reverse := func(b []byte) ([]byte, error) {
+		out := make([]byte, len(b))
+		for i := range b {
+			out[len(b)-1-i] = b[i]
+		}
+		return out, nil
+	}
+	s := newFileStorage(filepath.Join(dir, "test.cache"))
+	s.protect, s.unprotect = reverse, reverse
+	if err = s.Write(context.Background(), []byte("abc")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	raw, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(raw, []byte("cba")) {
+		t.Fatalf("Expected the data to be protected before it is written")
+	}
+	data, err := s.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "abc" {
+		t.Fatalf("Unexpected data: %s", data)
+	}
+}
+
+func TestNew_InvalidName(t *testing.T) {
+	if _, err := New(&Options{Name: "../escape", AllowUnencryptedStorage: true}); err == nil {
+		t.Fatalf("Expected an error for an invalid name")
+	}
+}
+
+func TestNew_UnencryptedStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	// an empty PATH prevents finding the commands that access encrypted storage on Linux and macOS
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	_ = os.Setenv("PATH", "")
+	if _, err = newPlatformStorage(Options{Name: defaultName, Directory: dir}); err == nil {
+		t.Skip("encrypted storage doesn't depend on PATH on this platform")
+	}
+	_, err = New(&Options{Directory: dir})
+	if !errors.Is(err, ErrUnencryptedStorage) {
+		t.Fatalf("Expected ErrUnencryptedStorage. Received: %v", err)
+	}
+	s, err := New(&Options{Directory: dir, AllowUnencryptedStorage: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fs, ok := s.(*fileStorage); !ok || fs.path != filepath.Join(dir, defaultName) {
+		t.Fatalf("Expected an unencrypted file storage in the directory. Received: %#v", s)
+	}
+}
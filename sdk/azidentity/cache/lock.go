@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// lockRetryDelay and lockRetries match the MSAL extensions, which wait up to six seconds for a lock.
+	lockRetryDelay = 100 * time.Millisecond
+	lockRetries    = 60
+	// lockFileSuffix is appended to a cache's path to get the path of its lock file, as the MSAL extensions do.
+	lockFileSuffix = ".lockfile"
+)
+
+// Locker is implemented by Storage that can be locked for a read followed by a write, so that
+// processes sharing the storage, including other MSAL based tools, don't overwrite each other's changes.
+type Locker interface {
+	// Lock blocks until the lock is acquired or ctx is done. Call the returned func to release the lock.
+	Lock(ctx context.Context) (unlock func() error, err error)
+}
+
+// fileLock is a cross-process lock held by locking a lock file with the operating system, as the MSAL extensions do.
+// The operating system releases the lock when its owner exits, so a lock file left behind by a process that crashed
+// doesn't block other processes.
+type fileLock struct {
+	path string
+}
+
+func (l fileLock) Lock(ctx context.Context) (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return nil, err
+	}
+	for i := 0; ; i++ {
+		f, err := l.tryLock()
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			return func() error {
+				// remove the file before unlocking it, so a process waiting for the lock finds it was removed
+				// and locks a new file rather than one that another process can also lock
+				os.Remove(l.path)
+				err := unlockFile(f)
+				if cerr := f.Close(); err == nil {
+					err = cerr
+				}
+				return err
+			}, nil
+		}
+		if i == lockRetries {
+			return nil, errors.New("timed out waiting for the lock file " + l.path)
+		}
+		select {
+		case <-time.After(lockRetryDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// tryLock returns the locked lock file, or nil when another process holds the lock.
+func (l fileLock) tryLock() (*os.File, error) {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := lockFile(f)
+	if err != nil || !locked {
+		f.Close()
+		return nil, err
+	}
+	// the previous owner may have removed the file after this process opened it
+	fi, err := f.Stat()
+	if err != nil {
+		unlockFile(f)
+		f.Close()
+		return nil, err
+	}
+	if current, err := os.Stat(l.path); err != nil || !os.SameFile(fi, current) {
+		unlockFile(f)
+		f.Close()
+		return nil, nil
+	}
+	// the MSAL extensions write the owner's process ID to the lock file for diagnostics
+	if err = f.Truncate(0); err == nil {
+		_, err = fmt.Fprintf(f, "%d", os.Getpid())
+	}
+	if err != nil {
+		unlockFile(f)
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,19 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package cache
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(f *os.File) (bool, error) {
+	return false, errors.New("locking files isn't supported on this platform")
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
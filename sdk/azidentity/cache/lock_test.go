@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	var s Storage = newFileStorage(filepath.Join(dir, "test.cache"))
+	locker, ok := s.(Locker)
+	if !ok {
+		t.Fatalf("Expected file storage to implement Locker")
+	}
+	unlock, err := locker.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "test.cache"+lockFileSuffix)); err != nil {
+		t.Fatalf("Expected the lock file to exist: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockRetryDelay)
+	defer cancel()
+	if _, err = locker.Lock(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the lock to be held. Received: %v", err)
+	}
+	released := make(chan error)
+	go func() {
+		unlock, err := locker.Lock(context.Background())
+		if err == nil {
+			err = unlock()
+		}
+		released <- err
+	}()
+	time.Sleep(lockRetryDelay)
+	if err = unlock(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err = <-released; err != nil {
+		t.Fatalf("Expected the lock to be acquired after it was released. Received: %v", err)
+	}
+}
+
+func TestFileLockLeftBehind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := newFileStorage(filepath.Join(dir, "test.cache"))
+	// a process that crashed while holding the lock leaves the lock file behind, but not the lock
+	if err = ioutil.WriteFile(s.fileLock.path, []byte("12345"), 0600); err != nil {
+		t.Fatalf("Unable to write lock file: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lockRetryDelay)
+	defer cancel()
+	unlock, err := s.Lock(ctx)
+	if err != nil {
+		t.Fatalf("Expected the lock to be acquired. Received: %v", err)
+	}
+	if err = unlock(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
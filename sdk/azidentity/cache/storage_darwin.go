@@ -0,0 +1,86 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// keychainService is the Keychain service of the caches of the MSAL extensions used by Azure CLI and other tools.
+	// The Keychain account is the cache's name, as it is for them.
+	keychainService = "Microsoft.Developer.IdentityService"
+	// errSecItemNotFound is the exit code of the security command when a Keychain item doesn't exist.
+	errSecItemNotFound = 44
+)
+
+// keychainStorage stores data as a generic password in the user's login Keychain through the security command.
+type keychainStorage struct {
+	fileLock
+	path    string // path of the security executable
+	account string
+}
+
+func newPlatformStorage(o Options) (Storage, error) {
+	path, err := exec.LookPath("security")
+	if err != nil {
+		return nil, err
+	}
+	return &keychainStorage{fileLock: fileLock{path: filepath.Join(o.Directory, o.Name+lockFileSuffix)}, path: path, account: o.Name}, nil
+}
+
+func (s *keychainStorage) run(ctx context.Context, stdin string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, s.path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("security: %s: %w", msg, err)
+		}
+		return nil, fmt.Errorf("security: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (s *keychainStorage) Read(ctx context.Context) ([]byte, error) {
+	out, err := s.run(ctx, "", "find-generic-password", "-s", keychainService, "-a", s.account, "-w")
+	if isItemNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the data is stored as it is, like the MSAL extensions do; the security command ends it with a newline
+	return bytes.TrimSuffix(out, []byte("\n")), nil
+}
+
+func (s *keychainStorage) Write(ctx context.Context, data []byte) error {
+	// the command is passed on stdin in interactive mode so the data doesn't appear in the process list,
+	// hex encoded so that it's stored as it is whatever characters it contains
+	cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", keychainService, s.account, hex.EncodeToString(data))
+	_, err := s.run(ctx, cmd, "-i")
+	return err
+}
+
+func (s *keychainStorage) Delete(ctx context.Context) error {
+	_, err := s.run(ctx, "", "delete-generic-password", "-s", keychainService, "-a", s.account)
+	if isItemNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func isItemNotFound(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound
+}
@@ -0,0 +1,99 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// msalClientID is the MsalClientID attribute of the caches of the MSAL extensions used by Azure CLI and other
+	// tools. Their schema attribute and label are the cache's name, as they are for them.
+	msalClientID = "Microsoft.Developer.IdentityService"
+	// lookupNotFound is the exit code of secret-tool lookup when the secret doesn't exist. lookup also exits
+	// with this code when it fails for another reason, but then explains why on stderr.
+	lookupNotFound = 1
+)
+
+// secretToolStorage stores data with the Secret Service through libsecret's secret-tool command.
+type secretToolStorage struct {
+	fileLock
+	path    string // path of the secret-tool executable
+	account string
+}
+
+func newPlatformStorage(o Options) (Storage, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, err
+	}
+	return &secretToolStorage{fileLock: fileLock{path: filepath.Join(o.Directory, o.Name+lockFileSuffix)}, path: path, account: o.Name}, nil
+}
+
+func (s *secretToolStorage) run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, s.path, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if args[0] == "lookup" && msg == "" && errors.As(err, &exitErr) && exitErr.ExitCode() == lookupNotFound {
+			return nil, &notFoundError{err: err}
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("secret-tool %s: %s: %w", args[0], msg, err)
+		}
+		return nil, fmt.Errorf("secret-tool %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// notFoundError is returned when secret-tool lookup exits with lookupNotFound without
+// explanation, which is how it reports that the secret doesn't exist.
+type notFoundError struct {
+	err error
+}
+
+func (e *notFoundError) Error() string {
+	return "secret-tool: " + e.err.Error()
+}
+
+func (s *secretToolStorage) Read(ctx context.Context) ([]byte, error) {
+	out, err := s.run(ctx, nil, append([]string{"lookup"}, s.attributes()...)...)
+	if _, ok := err.(*notFoundError); ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the data is stored as it is, like the MSAL extensions do
+	return out, nil
+}
+
+func (s *secretToolStorage) Write(ctx context.Context, data []byte) error {
+	_, err := s.run(ctx, data, append([]string{"store", "--label=" + s.account}, s.attributes()...)...)
+	return err
+}
+
+func (s *secretToolStorage) Delete(ctx context.Context) error {
+	_, err := s.run(ctx, nil, append([]string{"clear"}, s.attributes()...)...)
+	return err
+}
+
+// attributes returns the attributes identifying the secret, those the MSAL extensions look it up with.
+// The xdg:schema attribute is set explicitly because secret-tool doesn't take a schema.
+func (s *secretToolStorage) attributes() []string {
+	return []string{"xdg:schema", s.account, "MsalClientID", msalClientID}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSecretTool implements the secret-tool commands used by secretToolStorage with a file per secret.
+const fakeSecretTool = `#!/bin/sh
+cmd=$1
+shift
+[ "$cmd" = "store" ] && shift
+[ "$1" = "xdg:schema" ] && [ "$3" = "MsalClientID" ] || exit 2
+file="$FAKE_SECRET_DIR/$2-$4"
+case $cmd in
+store) cat > "$file" ;;
+lookup) [ -f "$file" ] || exit 1; cat "$file" ;;
+clear) rm -f "$file" ;;
+esac
+`
+
+// withFakeSecretTool puts script first on the PATH as secret-tool and returns a function restoring the environment.
+func withFakeSecretTool(t *testing.T, script string) func() {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "secret-tool"), []byte(script), 0700); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Unable to write fake secret-tool: %v", err)
+	}
+	path := os.Getenv("PATH")
+	_ = os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	_ = os.Setenv("FAKE_SECRET_DIR", dir)
+	return func() {
+		os.Setenv("PATH", path)
+		os.Unsetenv("FAKE_SECRET_DIR")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSecretToolStorage(t *testing.T) {
+	defer withFakeSecretTool(t, fakeSecretTool)()
+	s, err := New(&Options{Name: "test"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := s.(*secretToolStorage); !ok {
+		t.Fatalf("Expected secret-tool storage. Received: %T", s)
+	}
+	data, err := s.Read(context.Background())
+	if err != nil || data != nil {
+		t.Fatalf("Expected no data and no error before writing. Received: %v, %v", data, err)
+	}
+	if err = s.Write(context.Background(), []byte("cached\x00data")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stored, err := ioutil.ReadFile(filepath.Join(os.Getenv("FAKE_SECRET_DIR"), "test-"+msalClientID))
+	if err != nil || string(stored) != "cached\x00data" {
+		t.Fatalf("Expected the data to be stored as it is, like the MSAL extensions do. Received: %q, %v", stored, err)
+	}
+	data, err = s.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "cached\x00data" {
+		t.Fatalf("Unexpected data: %q", data)
+	}
+	if err = s.Delete(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, err = s.Read(context.Background()); err != nil || data != nil {
+		t.Fatalf("Expected no data after deleting. Received: %v, %v", data, err)
+	}
+}
+
+func TestSecretToolStorageLookupErrors(t *testing.T) {
+	// exits with lookupNotFound after explaining why, and with another code when explaining nothing
+	defer withFakeSecretTool(t, `#!/bin/sh
+[ -n "$FAKE_SECRET_STDERR" ] && echo "$FAKE_SECRET_STDERR" >&2 && exit 1
+exit 2
+`)()
+	s, err := New(&Options{Name: "test"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, err := s.Read(context.Background()); err == nil {
+		t.Fatalf("Expected an error for an unexpected exit code. Received: %v", data)
+	}
+	_ = os.Setenv("FAKE_SECRET_STDERR", "Cannot autolaunch D-Bus")
+	defer os.Unsetenv("FAKE_SECRET_STDERR")
+	if _, err := s.Read(context.Background()); err == nil || !strings.Contains(err.Error(), "Cannot autolaunch D-Bus") {
+		t.Fatalf("Expected the error explained on stderr. Received: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Read(ctx); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled. Received: %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+// +build !windows,!darwin,!linux
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package cache
+
+import "errors"
+
+func newPlatformStorage(o Options) (Storage, error) {
+	return nil, errors.New("encrypted storage isn't supported on this platform")
+}
@@ -6,6 +6,8 @@ package azidentity
 import (
 	"context"
 	"errors"
+	"strconv"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 )
@@ -30,26 +32,30 @@ func NewChainedTokenCredential(sources ...azcore.TokenCredential) (*ChainedToken
 			return nil, credErr
 		}
 	}
-	return &ChainedTokenCredential{sources: sources}, nil
+	cred := &ChainedTokenCredential{sources: sources}
+	logCredentialCreated(cred, "sources", strconv.Itoa(len(sources)))
+	return cred, nil
 }
 
 // GetToken sequentially calls TokenCredential.GetToken on all the specified sources, returning the token from the first successful call to GetToken().
-func (c *ChainedTokenCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (token *azcore.AccessToken, err error) {
-	var errList []*CredentialUnavailableError
+func (c *ChainedTokenCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (token azcore.AccessToken, err error) {
+	var errList []string
 	for _, cred := range c.sources { // loop through all of the credentials provided in sources
 		token, err = cred.GetToken(ctx, opts) // make a GetToken request for the current credential in the loop
+		logChainAttempt(cred, err)
 		var credErr *CredentialUnavailableError
 		if errors.As(err, &credErr) { // check if we received a CredentialUnavailableError
-			errList = append(errList, credErr) // if we did receive a CredentialUnavailableError then we append it to our error slice and continue looping for a good credential
+			errList = append(errList, credErr.Error()) // if we did receive a CredentialUnavailableError then we append it to our error slice and continue looping for a good credential
 		} else if err != nil { // if we receive some other type of error then we must stop looping and process the error accordingly
+			errList = append(errList, credentialTypeName(cred)+": "+err.Error())
 			var authenticationFailed *AuthenticationFailedError
 			if errors.As(err, &authenticationFailed) { // if the error is an AuthenticationFailedError we return the error related to the invalid credential and append all of the other error messages received prior to this point
 				authErr := &AuthenticationFailedError{msg: "Received an AuthenticationFailedError, there is an invalid credential in the chain. " + createChainedErrorMessage(errList), inner: err}
 				addGetTokenFailureLogs("Chained Token Credential", authErr)
-				return nil, authErr
+				return azcore.AccessToken{}, authErr
 			}
 			addGetTokenFailureLogs("Chained Token Credential", err)
-			return nil, err // if we receive some other error type this is unexpected and we simple return the unexpected error
+			return azcore.AccessToken{}, err // if we receive some other error type this is unexpected and we simple return the unexpected error
 		} else {
 			azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
 			return token, nil // if we did not receive an error then we return the token
@@ -58,7 +64,39 @@ func (c *ChainedTokenCredential) GetToken(ctx context.Context, opts azcore.Token
 	// if we reach this point it means that all of the credentials in the chain returned CredentialUnavailableErrors
 	credErr := &CredentialUnavailableError{CredentialType: "Chained Token Credential", Message: createChainedErrorMessage(errList)}
 	addGetTokenFailureLogs("Chained Token Credential", credErr)
-	return nil, credErr
+	return azcore.AccessToken{}, credErr
+}
+
+// Validate checks the sources in order, without requesting a token, and returns nil as soon as one of them is valid.
+// Sources that don't implement CredentialValidator are assumed to be valid.
+func (c *ChainedTokenCredential) Validate(ctx context.Context) error {
+	var errList []string
+	for _, cred := range c.sources {
+		v, ok := cred.(CredentialValidator)
+		if !ok {
+			return nil
+		}
+		err := v.Validate(ctx)
+		if err == nil {
+			return nil
+		}
+		var credErr *CredentialUnavailableError
+		if errors.As(err, &credErr) {
+			errList = append(errList, credErr.Error())
+		} else {
+			errList = append(errList, credentialTypeName(cred)+": "+err.Error())
+		}
+	}
+	return &CredentialUnavailableError{CredentialType: "Chained Token Credential", Message: createChainedErrorMessage(errList)}
+}
+
+// ClearCache clears the token cache of every source that has one, so that the next token request acquires a new token.
+func (c *ChainedTokenCredential) ClearCache() {
+	for _, cred := range c.sources {
+		if clearer, ok := cred.(interface{ ClearCache() }); ok {
+			clearer.ClearCache()
+		}
+	}
 }
 
 // AuthenticationPolicy implements the azcore.Credential interface on ChainedTokenCredential and sets the bearer token
@@ -66,12 +104,8 @@ func (c *ChainedTokenCredential) AuthenticationPolicy(options azcore.Authenticat
 	return newBearerTokenPolicy(c, options)
 }
 
-// helper function used to chain the error messages of the CredentialUnavailableError slice
-func createChainedErrorMessage(errList []*CredentialUnavailableError) string {
-	msg := ""
-	for _, err := range errList {
-		msg += err.Error()
-	}
-
-	return msg
+// createChainedErrorMessage describes the failure of each credential tried in the chain, for example
+// "Environment Credential: Missing environment variable AZURE_TENANT_ID; Managed Identity Credential: IMDS is unreachable"
+func createChainedErrorMessage(errList []string) string {
+	return strings.Join(errList, "; ")
 }
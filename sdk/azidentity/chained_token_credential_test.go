@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -68,11 +69,11 @@ func TestChainedTokenCredential_GetTokenSuccess(t *testing.T) {
 	defer close()
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
-	secCred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	secCred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	envCred, err := NewEnvironmentCredential(&TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	envCred, err := NewEnvironmentCredential(&TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Failed to create environment credential: %v", err)
 	}
@@ -97,7 +98,7 @@ func TestChainedTokenCredential_GetTokenFail(t *testing.T) {
 	defer close()
 	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized))
 	testURL := srv.URL()
-	secCred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &testURL})
+	secCred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &testURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
@@ -124,7 +125,7 @@ func TestChainedTokenCredential_GetTokenWithUnavailableCredentialInChain(t *test
 	srv.AppendError(&CredentialUnavailableError{CredentialType: "MockCredential", Message: "Mocking a credential unavailable error"})
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	testURL := srv.URL()
-	secCred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &testURL})
+	secCred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &testURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
@@ -179,3 +180,89 @@ func TestBearerPolicy_ChainedTokenCredential(t *testing.T) {
 		t.Fatalf("Expected an empty error but receive: %v", err)
 	}
 }
+
+// unavailableCredential is a credential that is never available.
+type unavailableCredential struct {
+	credentialType string
+	message        string
+}
+
+func (c unavailableCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{}, &CredentialUnavailableError{CredentialType: c.credentialType, Message: c.message}
+}
+
+func (c unavailableCredential) Validate(ctx context.Context) error {
+	return &CredentialUnavailableError{CredentialType: c.credentialType, Message: c.message}
+}
+
+func (c unavailableCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
+	return newBearerTokenPolicy(c, options)
+}
+
+func TestChainedTokenCredential_GetTokenAllUnavailable(t *testing.T) {
+	cred, err := NewChainedTokenCredential(
+		unavailableCredential{"Environment Credential", "AZURE_TENANT_ID is not set"},
+		unavailableCredential{"Managed Identity Credential", "IMDS is unreachable"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	var credErr *CredentialUnavailableError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError. Received: %v", err)
+	}
+	expected := "Chained Token Credential: Environment Credential: AZURE_TENANT_ID is not set" + troubleshootingLink("Environment Credential") +
+		"; Managed Identity Credential: IMDS is unreachable" + troubleshootingLink("Managed Identity Credential")
+	if err.Error() != expected {
+		t.Fatalf("Expected the error to enumerate each credential.\nExpected: %s\nReceived: %s", expected, err.Error())
+	}
+}
+
+func TestChainedTokenCredential_GetTokenFailAfterUnavailable(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized))
+	testURL := srv.URL()
+	secCred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &testURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	cred, err := NewChainedTokenCredential(unavailableCredential{"Environment Credential", "AZURE_TENANT_ID is not set"}, secCred)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	var authErr *AuthenticationFailedError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("Expected an AuthenticationFailedError. Received: %v", err)
+	}
+	if !strings.Contains(err.Error(), "Environment Credential: AZURE_TENANT_ID is not set (to troubleshoot, see https://aka.ms/azsdk/go/identity/troubleshoot#environment); ClientSecretCredential: ") {
+		t.Fatalf("Expected the error to enumerate each credential. Received: %s", err.Error())
+	}
+}
+
+func TestChainedTokenCredential_Validate(t *testing.T) {
+	cred, err := NewChainedTokenCredential(unavailableCredential{"Environment Credential", "AZURE_TENANT_ID is not set"}, &fakeCredential{})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if err = cred.Validate(context.Background()); err != nil {
+		t.Fatalf("Expected a source without validation to be assumed valid. Received: %v", err)
+	}
+	cred, err = NewChainedTokenCredential(
+		unavailableCredential{"Environment Credential", "AZURE_TENANT_ID is not set"},
+		unavailableCredential{"Azure CLI Credential", "Azure CLI not found on path"},
+	)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	err = cred.Validate(context.Background())
+	var credErr *CredentialUnavailableError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError. Received: %v", err)
+	}
+	if !strings.Contains(err.Error(), "AZURE_TENANT_ID is not set") || !strings.Contains(err.Error(), "Azure CLI not found on path") {
+		t.Fatalf("Expected the error to describe every source. Received: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// AssertionProvider returns a signed JWT that the App Registration accepts as a client assertion.  It is invoked
+// on each token request so that a rotated certificate or a freshly issued federated token is always used.
+type AssertionProvider func(ctx context.Context) (string, error)
+
+// ClientAssertionCredential enables authentication of a service principal to Azure Active Directory using a client assertion
+// supplied by the caller, such as a federated token issued by another identity provider.  More information on client assertions
+// can be found here:
+// https://docs.microsoft.com/en-us/azure/active-directory/develop/active-directory-certificate-credentials#assertion-format
+type ClientAssertionCredential struct {
+	client   *aadIdentityClient
+	tenantID string            // The Azure Active Directory tenant (directory) ID of the service principal
+	clientID string            // The client (application) ID of the service principal
+	provider AssertionProvider // Gets the client assertion on each token request
+}
+
+// NewClientAssertionCredential constructs a new ClientAssertionCredential with the details needed to authenticate against Azure Active Directory with a client assertion.
+// tenantID: The Azure Active Directory tenant (directory) ID of the service principal.
+// clientID: The client (application) ID of the service principal.
+// provider: Returns the client assertion used to authenticate the client.
+// options: allow to configure the management of the requests sent to Azure Active Directory.
+func NewClientAssertionCredential(tenantID string, clientID string, provider AssertionProvider, options *TokenCredentialOptions) (*ClientAssertionCredential, error) {
+	if provider == nil {
+		credErr := &CredentialUnavailableError{CredentialType: "Client Assertion Credential", Message: "Assertion provider cannot be nil"}
+		azcore.Log().Write(azcore.LogError, logCredentialError(credErr.CredentialType, credErr))
+		return nil, credErr
+	}
+	c, err := newAADIdentityClient(options)
+	if err != nil {
+		return nil, err
+	}
+	cred := &ClientAssertionCredential{tenantID: tenantID, clientID: clientID, provider: provider, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
+}
+
+// GetToken obtains a token from Azure Active Directory, using the assertion returned by the provider to authenticate.
+// ctx: Context used to control the request lifetime.
+// opts: TokenRequestOptions contains the list of scopes for which the token will have access.
+// Returns an AccessToken which can be used to authenticate service client calls.
+func (c *ClientAssertionCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("Client Assertion Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tenantID, err := c.client.resolveTenant(c.tenantID, opts)
+	if err != nil {
+		addGetTokenFailureLogs("Client Assertion Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tk, err := c.client.cache.getToken(ctx, c.client.cacheKey(c.clientID, tenantID, opts), c.client.telemetry("ClientAssertionCredential", tenantID, opts.Scopes), func(ctx context.Context) (*azcore.AccessToken, error) {
+		assertion, err := c.provider(ctx)
+		if err != nil {
+			return nil, &AuthenticationFailedError{msg: "Unable to get the client assertion from the provider: " + err.Error(), inner: err}
+		}
+		return c.client.authenticateAssertion(ctx, tenantID, c.clientID, assertion, opts)
+	})
+	if err != nil {
+		addGetTokenFailureLogs("Client Assertion Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
+	return *tk, nil
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *ClientAssertionCredential) ClearCache() {
+	c.client.clearCache(c.clientID)
+}
+
+// AuthenticationPolicy implements the azcore.Credential interface on ClientAssertionCredential.
+func (c *ClientAssertionCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
+	return newBearerTokenPolicy(c, options)
+}
+
+var _ azcore.TokenCredential = (*ClientAssertionCredential)(nil)
@@ -0,0 +1,69 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestClientAssertionCredential_GetTokenSuccess(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespShortLived)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespShortLived)))
+	srvURL := srv.URL()
+	calls := 0
+	provider := func(context.Context) (string, error) {
+		calls++
+		return "assertion", nil
+	}
+	cred, err := NewClientAssertionCredential(tenantID, clientID, provider, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+			t.Fatalf("Expected an empty error but received: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected the provider to be called for each token request but it was called %d times", calls)
+	}
+}
+
+func TestClientAssertionCredential_ProviderFailure(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	srvURL := srv.URL()
+	providerErr := errors.New("provider failed")
+	provider := func(context.Context) (string, error) {
+		return "", providerErr
+	}
+	cred, err := NewClientAssertionCredential(tenantID, clientID, provider, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if !errors.Is(err, providerErr) {
+		t.Fatalf("Expected the provider error but received: %v", err)
+	}
+	if srv.Requests() != 0 {
+		t.Fatalf("Did not expect a request to be sent")
+	}
+}
+
+func TestClientAssertionCredential_NilProvider(t *testing.T) {
+	_, err := NewClientAssertionCredential(tenantID, clientID, nil, nil)
+	var credErr *CredentialUnavailableError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError but received: %v", err)
+	}
+}
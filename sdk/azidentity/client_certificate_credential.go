@@ -18,6 +18,7 @@ type ClientCertificateCredential struct {
 	tenantID          string // The Azure Active Directory tenant (directory) ID of the service principal
 	clientID          string // The client (application) ID of the service principal
 	clientCertificate string // Path to the client certificate generated for the App Registration used to authenticate the client
+	selector          *ClientCertificateSelector
 }
 
 // NewClientCertificateCredential creates an instance of ClientCertificateCredential with the details needed to authenticate against Azure Active Directory with the specified certificate.
@@ -36,21 +37,99 @@ func NewClientCertificateCredential(tenantID string, clientID string, clientCert
 	if err != nil {
 		return nil, err
 	}
-	return &ClientCertificateCredential{tenantID: tenantID, clientID: clientID, clientCertificate: clientCertificate, client: c}, nil
+	cred := &ClientCertificateCredential{tenantID: tenantID, clientID: clientID, clientCertificate: clientCertificate, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
+}
+
+// NewClientCertificateCredentialWithSelector creates an instance of ClientCertificateCredential that authenticates with a certificate
+// chosen from a PEM bundle containing several certificates and private keys, or from a directory of PEM files. The certificates are
+// reloaded and the selection is made again on every token request, so a new certificate can be added before the old one is removed.
+// tenantID: The Azure Active Directory tenant (directory) ID of the service principal.
+// clientID: The client (application) ID of the service principal.
+// clientCertificate: The path to the PEM bundle or the directory that holds the certificates.
+// selector: Chooses the certificate to authenticate with.
+// options: configure the management of the requests sent to Azure Active Directory.
+func NewClientCertificateCredentialWithSelector(tenantID string, clientID string, clientCertificate string, selector ClientCertificateSelector, options *TokenCredentialOptions) (*ClientCertificateCredential, error) {
+	c, err := newAADIdentityClient(options)
+	if err != nil {
+		return nil, err
+	}
+	_, err = selector.selectCertificate(clientCertificate, c.options.ClientCertificatePassword)
+	if err != nil {
+		credErr := &CredentialUnavailableError{CredentialType: "Client Certificate Credential", Message: err.Error(), inner: err}
+		azcore.Log().Write(azcore.LogError, logCredentialError(credErr.CredentialType, credErr))
+		return nil, credErr
+	}
+	cred := &ClientCertificateCredential{tenantID: tenantID, clientID: clientID, clientCertificate: clientCertificate, selector: &selector, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
 }
 
 // GetToken obtains a token from Azure Active Directory, using the certificate in the file path.
 // scopes: The list of scopes for which the token will have access.
 // ctx: controlling the request lifetime.
 // Returns an AccessToken which can be used to authenticate service client calls.
-func (c *ClientCertificateCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
-	tk, err := c.client.authenticateCertificate(ctx, c.tenantID, c.clientID, c.clientCertificate, opts.Scopes)
+func (c *ClientCertificateCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("Client Certificate Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tenantID, err := c.client.resolveTenant(c.tenantID, opts)
 	if err != nil {
 		addGetTokenFailureLogs("Client Certificate Credential", err)
-		return nil, err
+		return azcore.AccessToken{}, err
+	}
+	tk, err := c.client.cache.getToken(ctx, c.client.cacheKey(c.clientID, tenantID, opts), c.client.telemetry("ClientCertificateCredential", tenantID, opts.Scopes), func(ctx context.Context) (*azcore.AccessToken, error) {
+		if c.selector != nil {
+			return c.authenticateSelected(ctx, tenantID, opts)
+		}
+		return c.client.authenticateCertificate(ctx, tenantID, c.clientID, c.clientCertificate, opts)
+	})
+	if err != nil {
+		addGetTokenFailureLogs("Client Certificate Credential", err)
+		return azcore.AccessToken{}, err
 	}
 	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
-	return tk, nil
+	return *tk, nil
+}
+
+// authenticateSelected authenticates with the certificate currently chosen by the credential's selector.
+func (c *ClientCertificateCredential) authenticateSelected(ctx context.Context, tenantID string, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
+	pair, err := c.selector.selectCertificate(c.clientCertificate, c.client.options.ClientCertificatePassword)
+	if err != nil {
+		return nil, &CredentialUnavailableError{CredentialType: "Client Certificate Credential", Message: err.Error(), inner: err}
+	}
+	u := c.client.tokenURL(tenantID)
+	var chain [][]byte
+	if c.client.options.SendCertificateChain {
+		chain = [][]byte{pair.cert.Raw}
+	}
+	assertion, err := signClientAssertionJWT(c.clientID, u.String(), pair.thumbprint, chain, pair.key)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.authenticateAssertion(ctx, tenantID, c.clientID, assertion, opts)
+}
+
+// Validate checks that the certificate and its private key can be loaded without requesting a token.
+func (c *ClientCertificateCredential) Validate(ctx context.Context) error {
+	var err error
+	if c.selector != nil {
+		_, err = c.selector.selectCertificate(c.clientCertificate, c.client.options.ClientCertificatePassword)
+	} else if _, err = spkiFingerprint(c.clientCertificate); err == nil {
+		_, err = getPrivateKey(c.clientCertificate, c.client.options.ClientCertificatePassword)
+	}
+	if err != nil {
+		return &CredentialUnavailableError{CredentialType: "Client Certificate Credential", Message: err.Error(), inner: err}
+	}
+	return nil
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *ClientCertificateCredential) ClearCache() {
+	c.client.clearCache(c.clientID)
 }
 
 // AuthenticationPolicy implements the azcore.Credential interface on ClientSecretCredential.
@@ -5,10 +5,16 @@ package azidentity
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -25,7 +31,7 @@ func TestClientCertificateCredential_CreateAuthRequestSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to instantiate credential")
 	}
-	req, err := cred.client.createClientCertificateAuthRequest(cred.tenantID, cred.clientID, cred.clientCertificate, []string{scope})
+	req, err := cred.client.createClientCertificateAuthRequest(cred.tenantID, cred.clientID, cred.clientCertificate, azcore.TokenRequestOptions{Scopes: []string{scope}})
 	if err != nil {
 		t.Fatalf("Unexpectedly received an error: %v", err)
 	}
@@ -69,7 +75,7 @@ func TestClientCertificateCredential_GetTokenSuccess(t *testing.T) {
 	defer close()
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
-	cred, err := NewClientCertificateCredential(tenantID, clientID, certificatePath, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientCertificateCredential(tenantID, clientID, certificatePath, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Expected an empty error but received: %s", err.Error())
 	}
@@ -84,7 +90,7 @@ func TestClientCertificateCredential_GetTokenInvalidCredentials(t *testing.T) {
 	defer close()
 	srv.SetResponse(mock.WithStatusCode(http.StatusUnauthorized))
 	srvURL := srv.URL()
-	cred, err := NewClientCertificateCredential(tenantID, clientID, certificatePath, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientCertificateCredential(tenantID, clientID, certificatePath, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Did not expect an error but received one: %v", err)
 	}
@@ -103,7 +109,7 @@ func TestClientCertificateCredential_WrongCertificatePath(t *testing.T) {
 	defer close()
 	srv.SetResponse(mock.WithStatusCode(http.StatusUnauthorized))
 	srvURL := srv.URL()
-	_, err := NewClientCertificateCredential(tenantID, clientID, wrongCertificatePath, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	_, err := NewClientCertificateCredential(tenantID, clientID, wrongCertificatePath, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err == nil {
 		t.Fatalf("Expected an error but did not receive one")
 	}
@@ -114,7 +120,7 @@ func TestClientCertificateCredential_GetTokenCheckPrivateKeyBlocks(t *testing.T)
 	defer close()
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
-	cred, err := NewClientCertificateCredential(tenantID, clientID, "testdata/certificate_formatB.pem", &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientCertificateCredential(tenantID, clientID, "testdata/certificate_formatB.pem", &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Expected an empty error but received: %s", err.Error())
 	}
@@ -129,7 +135,7 @@ func TestClientCertificateCredential_GetTokenCheckCertificateBlocks(t *testing.T
 	defer close()
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
-	cred, err := NewClientCertificateCredential(tenantID, clientID, "testdata/certificate_formatA.pem", &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientCertificateCredential(tenantID, clientID, "testdata/certificate_formatA.pem", &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Expected an empty error but received: %s", err.Error())
 	}
@@ -144,7 +150,7 @@ func TestClientCertificateCredential_GetTokenEmptyCertificate(t *testing.T) {
 	defer close()
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
-	cred, err := NewClientCertificateCredential(tenantID, clientID, "testdata/certificate_empty.pem", &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientCertificateCredential(tenantID, clientID, "testdata/certificate_empty.pem", &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Expected an empty error but received: %s", err.Error())
 	}
@@ -159,7 +165,7 @@ func TestClientCertificateCredential_GetTokenNoPrivateKey(t *testing.T) {
 	defer close()
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
-	cred, err := NewClientCertificateCredential(tenantID, clientID, "testdata/certificate_nokey.pem", &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientCertificateCredential(tenantID, clientID, "testdata/certificate_nokey.pem", &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Expected an empty error but received: %s", err.Error())
 	}
@@ -191,3 +197,103 @@ func TestBearerPolicy_ClientCertificateCredential(t *testing.T) {
 		t.Fatalf("Expected nil error but received one")
 	}
 }
+
+func TestClientCertificateCredential_Validate(t *testing.T) {
+	cred, err := NewClientCertificateCredential(tenantID, clientID, certificatePath, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if err = cred.Validate(context.Background()); err != nil {
+		t.Fatalf("Expected the certificate to be valid. Received: %v", err)
+	}
+	for _, path := range []string{"testdata/certificate_nokey.pem", "testdata/certificate_empty.pem"} {
+		cred, err = NewClientCertificateCredential(tenantID, clientID, path, nil)
+		if err != nil {
+			t.Fatalf("Unable to create credential. Received: %v", err)
+		}
+		var credErr *CredentialUnavailableError
+		if err = cred.Validate(context.Background()); !errors.As(err, &credErr) {
+			t.Fatalf("Expected a CredentialUnavailableError for %s. Received: %v", path, err)
+		}
+	}
+}
+
+func TestClientCertificateCredential_SendCertificateChain(t *testing.T) {
+	cred, err := NewClientCertificateCredential(tenantID, clientID, certificatePath, &TokenCredentialOptions{SendCertificateChain: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	req, err := cred.client.createClientCertificateAuthRequest(cred.tenantID, cred.clientID, cred.clientCertificate, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		t.Fatalf("Unable to read request body")
+	}
+	params, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Unable to parse query params in request")
+	}
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(params.Get(qpClientAssertion), ".")[0])
+	if err != nil {
+		t.Fatalf("Unable to decode the assertion's header: %v", err)
+	}
+	var jwtHeader struct {
+		X5c []string `json:"x5c"`
+	}
+	if err = json.Unmarshal(header, &jwtHeader); err != nil {
+		t.Fatalf("Unable to unmarshal the assertion's header: %v", err)
+	}
+	chain, err := certificateChain(certificatePath)
+	if err != nil {
+		t.Fatalf("Unable to load the certificate: %v", err)
+	}
+	if len(jwtHeader.X5c) != 1 || jwtHeader.X5c[0] != base64.StdEncoding.EncodeToString(chain[0]) {
+		t.Fatalf("Expected the certificate in the x5c header. Received: %v", jwtHeader.X5c)
+	}
+}
+
+func TestClientCertificateCredential_OtherTenant(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewClientCertificateCredential(tenantID, clientID, certificatePath, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}, TenantID: "other-tenant"}); err == nil {
+		t.Fatalf("Expected an error for a tenant that isn't allowed")
+	}
+	if srv.Requests() != 0 {
+		t.Fatalf("Expected no token request for a tenant that isn't allowed")
+	}
+}
+
+// writeEncryptedCertificatePEM writes the test certificate with its private key encrypted by the password in the
+// legacy OpenSSL format and returns the path of the file.
+func writeEncryptedCertificatePEM(t *testing.T, password string) string {
+	key, err := getPrivateKey(certificatePath, "")
+	if err != nil {
+		t.Fatalf("Unable to load the private key: %v", err)
+	}
+	chain, err := certificateChain(certificatePath)
+	if err != nil {
+		t.Fatalf("Unable to load the certificate: %v", err)
+	}
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), []byte(password), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("Unable to encrypt the private key: %v", err)
+	}
+	f, err := ioutil.TempFile("", "azidentity*.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b := append(pem.EncodeToMemory(block), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: chain[0]})...)
+	if _, err = f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
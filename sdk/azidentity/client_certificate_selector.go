@@ -0,0 +1,151 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ClientCertificateSelector chooses which certificate a ClientCertificateCredential authenticates with when its
+// certificate path is a PEM bundle holding several certificates and private keys, or a directory of PEM files.
+// This allows an old and a new certificate to coexist while a certificate is rotated.
+type ClientCertificateSelector struct {
+	// Thumbprint is the hex encoded SHA-1 thumbprint of the certificate to use, as shown in the Azure Portal.
+	// When the module is built with the fips tag it's the SHA-256 thumbprint instead.
+	// Colons and spaces are ignored and the comparison is case insensitive.
+	// When empty, the certificate with the most recent NotBefore date that is currently valid is used.
+	Thumbprint string
+}
+
+// certificatePair is a certificate together with its private key.
+type certificatePair struct {
+	cert       *x509.Certificate
+	key        *rsa.PrivateKey
+	thumbprint fingerprint
+}
+
+// selectCertificate loads the certificates at the path, which may be a PEM file or a directory of PEM files, and
+// returns the one chosen by the selector.
+func (s ClientCertificateSelector) selectCertificate(path string, password string) (certificatePair, error) {
+	pairs, err := loadCertificatePairs(path, password)
+	if err != nil {
+		return certificatePair{}, err
+	}
+	if s.Thumbprint != "" {
+		thumbprint := normalizeThumbprint(s.Thumbprint)
+		for _, p := range pairs {
+			if hex.EncodeToString(p.thumbprint) == thumbprint {
+				return p, nil
+			}
+		}
+		return certificatePair{}, fmt.Errorf("no certificate with thumbprint %s and a matching private key found in %s", s.Thumbprint, path)
+	}
+	now := time.Now()
+	var selected *certificatePair
+	for i, p := range pairs {
+		if now.Before(p.cert.NotBefore) || now.After(p.cert.NotAfter) {
+			continue
+		}
+		if selected == nil || p.cert.NotBefore.After(selected.cert.NotBefore) {
+			selected = &pairs[i]
+		}
+	}
+	if selected == nil {
+		return certificatePair{}, fmt.Errorf("no currently valid certificate with a matching private key found in %s", path)
+	}
+	return *selected, nil
+}
+
+// normalizeThumbprint returns the thumbprint in lower case without any separators.
+func normalizeThumbprint(thumbprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(thumbprint))
+}
+
+// loadCertificatePairs returns every certificate found at the path that has a matching RSA private key.
+// Encrypted private keys are decrypted with the password.
+func loadCertificatePairs(path string, password string) ([]certificatePair, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	var certs []*x509.Certificate
+	var keys []*rsa.PrivateKey
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			var block *pem.Block
+			block, b = pem.Decode(b)
+			if block == nil {
+				break
+			}
+			switch block.Type {
+			case "CERTIFICATE":
+				cert, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					return nil, fmt.Errorf("%s: ParseCertificate: %w", file, err)
+				}
+				certs = append(certs, cert)
+			case "PRIVATE KEY":
+				der, err := privateKeyBytes(block, password)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", file, err)
+				}
+				key, err := x509.ParsePKCS8PrivateKey(der)
+				if err != nil {
+					return nil, fmt.Errorf("%s: ParsePKCS8PrivateKey: %w", file, err)
+				}
+				if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+					keys = append(keys, rsaKey)
+				}
+			case "RSA PRIVATE KEY":
+				der, err := privateKeyBytes(block, password)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", file, err)
+				}
+				key, err := x509.ParsePKCS1PrivateKey(der)
+				if err != nil {
+					return nil, fmt.Errorf("%s: ParsePKCS1PrivateKey: %w", file, err)
+				}
+				keys = append(keys, key)
+			}
+		}
+	}
+	var pairs []certificatePair
+	for _, cert := range certs {
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		for _, key := range keys {
+			if key.N.Cmp(pub.N) == 0 && key.E == pub.E {
+				pairs = append(pairs, certificatePair{cert: cert, key: key, thumbprint: certificateThumbprint(cert.Raw)})
+				break
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, errors.New("Cannot find a CERTIFICATE with a matching PRIVATE KEY in " + path)
+	}
+	return pairs, nil
+}
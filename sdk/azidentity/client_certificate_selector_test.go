@@ -0,0 +1,164 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// createTestCertificatePEM returns a self-signed certificate valid in the specified period and its private key
+// in PEM format, along with the certificate's hex encoded thumbprint.
+func createTestCertificatePEM(t *testing.T, notBefore time.Time, notAfter time.Time) ([]byte, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(notBefore.Unix()),
+		Subject:      pkix.Name{CommonName: "azidentity test"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Unable to marshal key: %v", err)
+	}
+	b := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	return b, hex.EncodeToString(certificateThumbprint(der))
+}
+
+func TestClientCertificateSelector_Bundle(t *testing.T) {
+	now := time.Now()
+	oldCert, oldThumbprint := createTestCertificatePEM(t, now.Add(-48*time.Hour), now.Add(24*time.Hour))
+	newCert, newThumbprint := createTestCertificatePEM(t, now.Add(-time.Hour), now.Add(48*time.Hour))
+	futureCert, _ := createTestCertificatePEM(t, now.Add(time.Hour), now.Add(72*time.Hour))
+	expiredCert, _ := createTestCertificatePEM(t, now.Add(-72*time.Hour), now.Add(-time.Hour))
+	dir, err := ioutil.TempDir("", "azidentity")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	bundle := filepath.Join(dir, "bundle.pem")
+	var b []byte
+	for _, c := range [][]byte{expiredCert, oldCert, newCert, futureCert} {
+		b = append(b, c...)
+	}
+	if err = ioutil.WriteFile(bundle, b, 0600); err != nil {
+		t.Fatalf("Unable to write bundle: %v", err)
+	}
+	pair, err := ClientCertificateSelector{}.selectCertificate(bundle, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if hex.EncodeToString(pair.thumbprint) != newThumbprint {
+		t.Fatalf("Expected the latest valid certificate to be selected")
+	}
+	pair, err = ClientCertificateSelector{Thumbprint: strings.ToUpper(oldThumbprint)}.selectCertificate(bundle, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if hex.EncodeToString(pair.thumbprint) != oldThumbprint {
+		t.Fatalf("Expected the certificate with the thumbprint to be selected")
+	}
+	_, err = ClientCertificateSelector{Thumbprint: "00:11:22"}.selectCertificate(bundle, "")
+	if err == nil {
+		t.Fatalf("Expected an error for an unknown thumbprint")
+	}
+}
+
+func TestClientCertificateSelector_Directory(t *testing.T) {
+	now := time.Now()
+	oldCert, _ := createTestCertificatePEM(t, now.Add(-48*time.Hour), now.Add(24*time.Hour))
+	newCert, newThumbprint := createTestCertificatePEM(t, now.Add(-time.Hour), now.Add(48*time.Hour))
+	dir, err := ioutil.TempDir("", "azidentity")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "old.pem"), oldCert, 0600); err != nil {
+		t.Fatalf("Unable to write certificate: %v", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "new.pem"), newCert, 0600); err != nil {
+		t.Fatalf("Unable to write certificate: %v", err)
+	}
+	pair, err := ClientCertificateSelector{}.selectCertificate(dir, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if hex.EncodeToString(pair.thumbprint) != newThumbprint {
+		t.Fatalf("Expected the latest valid certificate to be selected")
+	}
+}
+
+func TestClientCertificateSelector_NoValidCertificate(t *testing.T) {
+	_, err := ClientCertificateSelector{}.selectCertificate("testdata/certificate_nokey.pem", "")
+	if err == nil {
+		t.Fatalf("Expected an error for a certificate without a private key")
+	}
+	now := time.Now()
+	expiredCert, _ := createTestCertificatePEM(t, now.Add(-72*time.Hour), now.Add(-time.Hour))
+	dir, err := ioutil.TempDir("", "azidentity")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "expired.pem"), expiredCert, 0600); err != nil {
+		t.Fatalf("Unable to write certificate: %v", err)
+	}
+	_, err = NewClientCertificateCredentialWithSelector(tenantID, clientID, dir, ClientCertificateSelector{}, nil)
+	var credErr *CredentialUnavailableError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError, received: %v", err)
+	}
+}
+
+func TestClientCertificateCredentialWithSelector_GetTokenSuccess(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	now := time.Now()
+	certPEM, thumbprint := createTestCertificatePEM(t, now.Add(-time.Hour), now.Add(time.Hour))
+	dir, err := ioutil.TempDir("", "azidentity")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0600); err != nil {
+		t.Fatalf("Unable to write certificate: %v", err)
+	}
+	cred, err := NewClientCertificateCredentialWithSelector(tenantID, clientID, dir, ClientCertificateSelector{Thumbprint: thumbprint}, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	tk, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if tk.Token != tokenValue {
+		t.Fatalf("Unexpected token: %s", tk.Token)
+	}
+}
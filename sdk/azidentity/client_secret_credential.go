@@ -9,14 +9,19 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 )
 
+// SecretProvider returns the current client secret.  It is invoked on each token request so that a secret
+// rotated in Key Vault or a configuration service takes effect without recreating the credential.
+type SecretProvider func(ctx context.Context) (string, error)
+
 // ClientSecretCredential enables authentication to Azure Active Directory using a client secret that was generated for an App Registration.  More information on how
 // to configure a client secret can be found here:
 // https://docs.microsoft.com/en-us/azure/active-directory/develop/quickstart-configure-app-access-web-apis#add-credentials-to-your-web-application
 type ClientSecretCredential struct {
 	client       *aadIdentityClient
-	tenantID     string // Gets the Azure Active Directory tenant (directory) ID of the service principal
-	clientID     string // Gets the client (application) ID of the service principal
-	clientSecret string // Gets the client secret that was generated for the App Registration used to authenticate the client.
+	tenantID     string         // Gets the Azure Active Directory tenant (directory) ID of the service principal
+	clientID     string         // Gets the client (application) ID of the service principal
+	clientSecret string         // Gets the client secret that was generated for the App Registration used to authenticate the client.
+	provider     SecretProvider // Gets the current client secret on each token request when not nil
 }
 
 // NewClientSecretCredential constructs a new ClientSecretCredential with the details needed to authenticate against Azure Active Directory with a client secret.
@@ -29,21 +34,68 @@ func NewClientSecretCredential(tenantID string, clientID string, clientSecret st
 	if err != nil {
 		return nil, err
 	}
-	return &ClientSecretCredential{tenantID: tenantID, clientID: clientID, clientSecret: clientSecret, client: c}, nil
+	cred := &ClientSecretCredential{tenantID: tenantID, clientID: clientID, clientSecret: clientSecret, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
+}
+
+// NewClientSecretCredentialFromProvider constructs a new ClientSecretCredential that gets the client secret from the specified provider
+// on each token request.
+// tenantID: The Azure Active Directory tenant (directory) ID of the service principal.
+// clientID: The client (application) ID of the service principal.
+// provider: Returns the client secret that was generated for the App Registration used to authenticate the client.
+// options: allow to configure the management of the requests sent to Azure Active Directory.
+func NewClientSecretCredentialFromProvider(tenantID string, clientID string, provider SecretProvider, options *TokenCredentialOptions) (*ClientSecretCredential, error) {
+	if provider == nil {
+		credErr := &CredentialUnavailableError{CredentialType: "Client Secret Credential", Message: "Secret provider cannot be nil"}
+		azcore.Log().Write(azcore.LogError, logCredentialError(credErr.CredentialType, credErr))
+		return nil, credErr
+	}
+	c, err := newAADIdentityClient(options)
+	if err != nil {
+		return nil, err
+	}
+	cred := &ClientSecretCredential{tenantID: tenantID, clientID: clientID, provider: provider, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
 }
 
 // GetToken obtains a token from Azure Active Directory, using the specified client secret to authenticate.
 // ctx: Context used to control the request lifetime.
 // opts: TokenRequestOptions contains the list of scopes for which the token will have access.
 // Returns an AccessToken which can be used to authenticate service client calls.
-func (c *ClientSecretCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
-	tk, err := c.client.authenticate(ctx, c.tenantID, c.clientID, c.clientSecret, opts.Scopes)
+func (c *ClientSecretCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("Client Secret Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tenantID, err := c.client.resolveTenant(c.tenantID, opts)
 	if err != nil {
 		addGetTokenFailureLogs("Client Secret Credential", err)
-		return nil, err
+		return azcore.AccessToken{}, err
+	}
+	tk, err := c.client.cache.getToken(ctx, c.client.cacheKey(c.clientID, tenantID, opts), c.client.telemetry("ClientSecretCredential", tenantID, opts.Scopes), func(ctx context.Context) (*azcore.AccessToken, error) {
+		clientSecret := c.clientSecret
+		if c.provider != nil {
+			var err error
+			if clientSecret, err = c.provider(ctx); err != nil {
+				return nil, &AuthenticationFailedError{msg: "Unable to get the client secret from the provider: " + err.Error(), inner: err}
+			}
+		}
+		return c.client.authenticate(ctx, tenantID, c.clientID, clientSecret, opts)
+	})
+	if err != nil {
+		addGetTokenFailureLogs("Client Secret Credential", err)
+		return azcore.AccessToken{}, err
 	}
 	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
-	return tk, nil
+	return *tk, nil
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *ClientSecretCredential) ClearCache() {
+	c.client.clearCache(c.clientID)
 }
 
 // AuthenticationPolicy implements the azcore.Credential interface on ClientSecretCredential and calls the Bearer Token policy
@@ -9,6 +9,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -30,7 +32,7 @@ func TestClientSecretCredential_CreateAuthRequestSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	req, err := cred.client.createClientSecretAuthRequest(cred.tenantID, cred.clientID, cred.clientSecret, []string{scope})
+	req, err := cred.client.createClientSecretAuthRequest(cred.tenantID, cred.clientID, cred.clientSecret, azcore.TokenRequestOptions{Scopes: []string{scope}})
 	if err != nil {
 		t.Fatalf("Unexpectedly received an error: %v", err)
 	}
@@ -68,7 +70,7 @@ func TestClientSecretCredential_GetTokenSuccess(t *testing.T) {
 	defer close()
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
-	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
@@ -83,7 +85,7 @@ func TestClientSecretCredential_GetTokenInvalidCredentials(t *testing.T) {
 	defer close()
 	srv.SetResponse(mock.WithBody([]byte(accessTokenRespError)), mock.WithStatusCode(http.StatusUnauthorized))
 	srvURL := srv.URL()
-	cred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientSecretCredential(tenantID, clientID, wrongSecret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
@@ -129,7 +131,7 @@ func TestClientSecretCredential_GetTokenUnexpectedJSON(t *testing.T) {
 	defer close()
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespMalformed)))
 	srvURL := srv.URL()
-	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
 	if err != nil {
 		t.Fatalf("Failed to create the credential")
 	}
@@ -138,3 +140,130 @@ func TestClientSecretCredential_GetTokenUnexpectedJSON(t *testing.T) {
 		t.Fatalf("Expected a JSON marshal error but received nil")
 	}
 }
+
+func TestClientSecretCredential_GetTokenFromProvider(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespShortLived)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespShortLived)))
+	srvURL := srv.URL()
+	secrets := []string{secret, "rotated_secret"}
+	calls := 0
+	provider := func(context.Context) (string, error) {
+		s := secrets[calls]
+		calls++
+		return s, nil
+	}
+	cred, err := NewClientSecretCredentialFromProvider(tenantID, clientID, provider, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+			t.Fatalf("Expected an empty error but received: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected the provider to be called for each token request but it was called %d times", calls)
+	}
+}
+
+func TestClientSecretCredential_ProviderFailure(t *testing.T) {
+	providerErr := errors.New("provider failed")
+	cred, err := NewClientSecretCredentialFromProvider(tenantID, clientID, func(context.Context) (string, error) { return "", providerErr }, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	var authFailed *AuthenticationFailedError
+	if !errors.As(err, &authFailed) {
+		t.Fatalf("Expected an AuthenticationFailedError but received: %v", err)
+	}
+	if !errors.Is(err, providerErr) {
+		t.Fatalf("Expected the provider error to be wrapped")
+	}
+}
+
+func TestClientSecretCredential_AdditionallyAllowedTenant(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true, AdditionallyAllowedTenants: []string{"other-tenant"}})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	req, err := cred.client.createClientSecretAuthRequest("other-tenant", clientID, secret, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	if !strings.HasPrefix(req.Request.URL.Path, "/other-tenant/") {
+		t.Fatalf("Expected a request to the other tenant. Received: %s", req.Request.URL.Path)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}, TenantID: "other-tenant"}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if srv.Requests() != 2 {
+		t.Fatalf("Expected tokens for each tenant to be cached separately. Received %d requests", srv.Requests())
+	}
+}
+
+func TestClientSecretCredential_SecretNotLogged(t *testing.T) {
+	const clientSecret = "client-secret-value"
+	var mu sync.Mutex
+	var logged []string
+	azcore.Log().SetListener(func(cls azcore.LogClassification, msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, msg)
+	})
+	defer azcore.Log().SetListener(nil)
+	var sent string
+	transport := azcore.TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if err := req.ParseForm(); err != nil {
+			return nil, err
+		}
+		sent = req.PostForm.Get(qpClientSecret)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(accessTokenRespSuccess)), Request: req}, nil
+	})
+	// logs request bodies, unlike the built-in request logging policy
+	bodyLogger := azcore.PolicyFunc(func(ctx context.Context, req *azcore.Request) (*azcore.Response, error) {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err = req.RewindBody(); err != nil {
+			return nil, err
+		}
+		azcore.Log().Write(azcore.LogRequest, string(b))
+		return req.Next(ctx)
+	})
+	cred, err := NewClientSecretCredential(tenantID, clientID, clientSecret, &TokenCredentialOptions{HTTPClient: transport, PerRetryPolicies: []azcore.Policy{bodyLogger}})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if sent != clientSecret {
+		t.Fatalf("Expected the secret in the request body, got %q", sent)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	bodyLogged := false
+	for _, msg := range logged {
+		bodyLogged = bodyLogged || strings.Contains(msg, qpClientSecret+"=REDACTED")
+	}
+	if !bodyLogged {
+		t.Fatalf("Expected the redacted request body to be logged")
+	}
+	for _, msg := range logged {
+		if strings.Contains(msg, clientSecret) {
+			t.Fatalf("The client secret was logged: %s", msg)
+		}
+	}
+}
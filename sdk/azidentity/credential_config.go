@@ -0,0 +1,150 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// The credential types that can be specified in CredentialConfig.Type.
+const (
+	CredentialTypeClientSecret      = "client_secret"
+	CredentialTypeCertificate       = "certificate"
+	CredentialTypeManagedIdentity   = "managed_identity"
+	CredentialTypeAzureCLI          = "cli"
+	CredentialTypeEnvironment       = "environment"
+	CredentialTypeUsernamePassword  = "username_password"
+	CredentialTypeDefaultCredential = "default"
+)
+
+// CredentialConfig describes a credential in an operator-provided JSON configuration document.
+type CredentialConfig struct {
+	// Type selects the credential to construct, e.g. "client_secret", "certificate", "managed_identity" or "cli".
+	Type string `json:"type"`
+
+	// TenantID is the Azure Active Directory tenant (directory) ID.
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// ClientID is the client (application) ID of the service principal or user assigned managed identity.
+	ClientID string `json:"client_id,omitempty"`
+
+	// ClientSecret is used by the client_secret credential type.
+	ClientSecret string `json:"client_secret,omitempty"`
+
+	// CertificatePath is the path to the PEM certificate used by the certificate credential type.
+	CertificatePath string `json:"certificate_path,omitempty"`
+
+	// Username and Password are used by the username_password credential type.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// AuthorityHost overrides the Azure Active Directory authority host.
+	AuthorityHost string `json:"authority_host,omitempty"`
+}
+
+// NewCredentialFromConfig constructs the credential described by config.
+// On failure the returned credential is nil and the error from the credential's constructor is returned.
+// config: describes the type of credential and the details needed to construct it.
+// options: configure the management of the requests sent to Azure Active Directory. The authority host in config takes precedence.
+// The cli credential type doesn't send requests itself, so it only uses the TokenCache, Metrics, Tracer and
+// OnTokenRefreshed of options.
+func NewCredentialFromConfig(config CredentialConfig, options *TokenCredentialOptions) (azcore.TokenCredential, error) {
+	if options == nil {
+		options = &TokenCredentialOptions{}
+	}
+	if config.AuthorityHost != "" {
+		u, err := url.Parse(config.AuthorityHost)
+		if err != nil {
+			return nil, err
+		}
+		o := *options
+		o.AuthorityHost = u
+		options = &o
+	}
+	switch strings.ToLower(config.Type) {
+	case CredentialTypeClientSecret:
+		c, err := NewClientSecretCredential(config.TenantID, config.ClientID, config.ClientSecret, options)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case CredentialTypeCertificate:
+		c, err := NewClientCertificateCredential(config.TenantID, config.ClientID, config.CertificatePath, options)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case CredentialTypeUsernamePassword:
+		c, err := NewUsernamePasswordCredential(config.TenantID, config.ClientID, config.Username, config.Password, options)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case CredentialTypeEnvironment:
+		c, err := NewEnvironmentCredential(options)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case CredentialTypeManagedIdentity:
+		c, err := NewManagedIdentityCredential(config.ClientID, managedIdentityOptions(options))
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case CredentialTypeAzureCLI:
+		c, err := NewAzureCLICredential(azureCLIOptions(config.TenantID, options))
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case CredentialTypeDefaultCredential:
+		c, err := NewDefaultAzureCredential(&DefaultAzureCredentialOptions{TokenCredentialOptions: options})
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	default:
+		err := fmt.Errorf("unknown credential type %q", config.Type)
+		azcore.Log().Write(azcore.LogError, logCredentialError("Credential Config", err))
+		return nil, err
+	}
+}
+
+// NewCredentialFromJSON constructs the credential described by a JSON document with the shape of CredentialConfig.
+// data: the JSON document, e.g. {"type": "client_secret", "tenant_id": "...", "client_id": "...", "client_secret": "..."}
+// options: configure the management of the requests sent to Azure Active Directory.
+func NewCredentialFromJSON(data []byte, options *TokenCredentialOptions) (azcore.TokenCredential, error) {
+	config := CredentialConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("unmarshalling credential config: %w", err)
+	}
+	return NewCredentialFromConfig(config, options)
+}
+
+// NewCredentialFromMap constructs the credential described by a map whose keys are the JSON field names of CredentialConfig.
+// To read the configuration from a YAML document, unmarshal it into a map[string]string with a YAML package and pass the map.
+// m: the credential configuration, e.g. map[string]string{"type": "managed_identity", "client_id": "..."}
+// options: configure the management of the requests sent to Azure Active Directory.
+func NewCredentialFromMap(m map[string]string, options *TokenCredentialOptions) (azcore.TokenCredential, error) {
+	// marshalling a map of strings can't fail
+	data, _ := json.Marshal(m)
+	return NewCredentialFromJSON(data, options)
+}
+
+// azureCLIOptions returns the AzureCLICredentialOptions for the tenant with the options that apply to the Azure CLI credential.
+func azureCLIOptions(tenantID string, o *TokenCredentialOptions) *AzureCLICredentialOptions {
+	return &AzureCLICredentialOptions{
+		TenantID:         tenantID,
+		TokenCache:       o.TokenCache,
+		Metrics:          o.Metrics,
+		Tracer:           o.Tracer,
+		OnTokenRefreshed: o.OnTokenRefreshed,
+	}
+}
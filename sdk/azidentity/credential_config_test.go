@@ -0,0 +1,112 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"testing"
+)
+
+func TestNewCredentialFromJSON_ClientSecret(t *testing.T) {
+	cred, err := NewCredentialFromJSON([]byte(`{"type": "client_secret", "tenant_id": "`+tenantID+`", "client_id": "`+clientID+`", "client_secret": "`+secret+`"}`), nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	secretCred, ok := cred.(*ClientSecretCredential)
+	if !ok {
+		t.Fatalf("Expected a ClientSecretCredential but received %T", cred)
+	}
+	if secretCred.tenantID != tenantID || secretCred.clientID != clientID || secretCred.clientSecret != secret {
+		t.Fatalf("Unexpected credential configuration")
+	}
+}
+
+func TestNewCredentialFromMap_Certificate(t *testing.T) {
+	cred, err := NewCredentialFromMap(map[string]string{
+		"type":             CredentialTypeCertificate,
+		"tenant_id":        tenantID,
+		"client_id":        clientID,
+		"certificate_path": certificatePath,
+		"authority_host":   AzureGovernment,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	certCred, ok := cred.(*ClientCertificateCredential)
+	if !ok {
+		t.Fatalf("Expected a ClientCertificateCredential but received %T", cred)
+	}
+	if certCred.client.options.AuthorityHost.String() != AzureGovernment {
+		t.Fatalf("Unexpected authority host: %s", certCred.client.options.AuthorityHost.String())
+	}
+}
+
+func TestNewCredentialFromConfig_CLI(t *testing.T) {
+	cred, err := NewCredentialFromConfig(CredentialConfig{Type: CredentialTypeAzureCLI}, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, ok := cred.(*AzureCLICredential); !ok {
+		t.Fatalf("Expected an AzureCLICredential but received %T", cred)
+	}
+}
+
+func TestNewCredentialFromConfig_CLIUsesOptions(t *testing.T) {
+	cache := NewTokenCache(nil)
+	cred, err := NewCredentialFromConfig(CredentialConfig{Type: CredentialTypeAzureCLI, TenantID: tenantID}, &TokenCredentialOptions{TokenCache: cache})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	cliCred, ok := cred.(*AzureCLICredential)
+	if !ok {
+		t.Fatalf("Expected an AzureCLICredential but received %T", cred)
+	}
+	if cliCred.tenantID != tenantID || cliCred.cache != cache {
+		t.Fatal("Expected the credential to use the tenant of the config and the token cache of the options")
+	}
+}
+
+func TestNewCredentialFromConfig_UnknownType(t *testing.T) {
+	if _, err := NewCredentialFromConfig(CredentialConfig{Type: "unknown"}, nil); err == nil {
+		t.Fatalf("Expected an error for an unknown credential type")
+	}
+}
+
+func TestNewCredentialFromJSON_Malformed(t *testing.T) {
+	if _, err := NewCredentialFromJSON([]byte(`{"type": `), nil); err == nil {
+		t.Fatalf("Expected an error for a malformed document")
+	}
+}
+
+func TestNewCredentialFromConfig_ConstructorError(t *testing.T) {
+	cred, err := NewCredentialFromConfig(CredentialConfig{Type: CredentialTypeCertificate, CertificatePath: wrongCertificatePath}, nil)
+	if err == nil {
+		t.Fatalf("Expected an error for a missing certificate")
+	}
+	if cred != nil {
+		t.Fatalf("Expected a nil credential but received %T", cred)
+	}
+}
+
+func TestNewCredentialFromConfig_DefaultUsesOptions(t *testing.T) {
+	err := initEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unable to set environment variables")
+	}
+	defer func() { _ = resetEnvironmentVarsForTest() }()
+	cred, err := NewCredentialFromConfig(CredentialConfig{Type: CredentialTypeDefaultCredential, AuthorityHost: AzureGovernment}, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	chain, ok := cred.(*ChainedTokenCredential)
+	if !ok {
+		t.Fatalf("Expected a ChainedTokenCredential but received %T", cred)
+	}
+	envCred, ok := chain.sources[0].(*ClientSecretCredential)
+	if !ok {
+		t.Fatalf("Expected the first source to be a ClientSecretCredential but received %T", chain.sources[0])
+	}
+	if envCred.client.options.AuthorityHost.String() != AzureGovernment {
+		t.Fatalf("Unexpected authority host: %s", envCred.client.options.AuthorityHost.String())
+	}
+}
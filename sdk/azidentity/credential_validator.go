@@ -0,0 +1,16 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import "context"
+
+// CredentialValidator is implemented by credentials that can check their configuration and environment without
+// requesting a token, for example that a certificate can be parsed or that the managed identity endpoint is reachable.
+// Services can call Validate from their readiness checks to fail at startup with an actionable error instead of at
+// their first token request.
+type CredentialValidator interface {
+	// Validate returns a *CredentialUnavailableError describing the problem when the credential can't authenticate.
+	// It doesn't check that Azure Active Directory accepts the credential.
+	Validate(ctx context.Context) error
+}
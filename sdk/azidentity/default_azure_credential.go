@@ -4,11 +4,16 @@
 package azidentity
 
 import (
+	"os"
+	"strconv"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 )
 
 const (
 	developerSignOnClientID = "04b07795-8ddb-461a-bbee-02f9e1bf7b46"
+	// productionModeEnvVar enables DefaultAzureCredentialOptions.ProductionMode when set to a true value.
+	productionModeEnvVar = "AZURE_IDENTITY_PRODUCTION_MODE"
 )
 
 // DefaultAzureCredentialOptions contains options for configuring how credentials are acquired.
@@ -19,6 +24,61 @@ type DefaultAzureCredentialOptions struct {
 	// set this field to true in order to exclude the ManagedIdentityCredential from the set of
 	// credentials that will be used to authenticate with
 	ExcludeMSICredential bool
+	// set this field to true, or set the AZURE_IDENTITY_PRODUCTION_MODE environment variable to "true", to restrict
+	// the chain to credentials that are suitable for production, those that neither prompt a user nor rely on a
+	// developer's sign in such as an Azure CLI session. Creating the credential fails when none of them are available.
+	ProductionMode bool
+	// configures the requests sent to Azure Active Directory by the EnvironmentCredential and ManagedIdentityCredential
+	TokenCredentialOptions *TokenCredentialOptions
+}
+
+// defaultCredentialSource is a credential that NewDefaultAzureCredential can add to its chain.
+type defaultCredentialSource struct {
+	exclude   bool
+	developer bool // true for credentials that rely on an interactive or developer sign in
+	create    func() (azcore.TokenCredential, error)
+}
+
+// managedIdentityOptions returns the ManagedIdentityCredentialOptions with the settings in o that apply to managed identity,
+// or nil when o is nil.
+func managedIdentityOptions(o *TokenCredentialOptions) *ManagedIdentityCredentialOptions {
+	if o == nil {
+		return nil
+	}
+	return &ManagedIdentityCredentialOptions{
+		HTTPClient:    o.HTTPClient,
+		LogOptions:    o.LogOptions,
+		Telemetry:     o.Telemetry,
+		ApplicationID: o.ApplicationID,
+	}
+}
+
+// productionMode returns true when the options or the environment enable production mode.
+func (o *DefaultAzureCredentialOptions) productionMode() bool {
+	if o.ProductionMode {
+		return true
+	}
+	enabled, err := strconv.ParseBool(os.Getenv(productionModeEnvVar))
+	return err == nil && enabled
+}
+
+// createDefaultCredentials creates the credentials of the sources that aren't excluded, leaving out those that rely on
+// an interactive or developer sign in in production mode. It returns the errors of the sources that failed.
+func createDefaultCredentials(sources []defaultCredentialSource, production bool) ([]azcore.TokenCredential, []string) {
+	var creds []azcore.TokenCredential
+	var errList []string
+	for _, source := range sources {
+		if source.exclude || (production && source.developer) {
+			continue
+		}
+		cred, err := source.create()
+		if err == nil {
+			creds = append(creds, cred)
+		} else {
+			errList = append(errList, err.Error())
+		}
+	}
+	return creds, errList
 }
 
 // NewDefaultAzureCredential provides a default ChainedTokenCredential configuration for applications that will be deployed to Azure.  The following credential
@@ -26,33 +86,42 @@ type DefaultAzureCredentialOptions struct {
 // - EnvironmentCredential
 // - ManagedIdentityCredential
 // Consult the documentation for these credential types for more information on how they attempt authentication.
+// In production mode credentials that rely on an interactive or developer sign in are never added to the chain.
 func NewDefaultAzureCredential(options *DefaultAzureCredentialOptions) (*ChainedTokenCredential, error) {
-	var creds []azcore.TokenCredential
-	errMsg := ""
-
 	if options == nil {
 		options = &DefaultAzureCredentialOptions{}
 	}
+	production := options.productionMode()
 
-	if !options.ExcludeEnvironmentCredential {
-		envCred, err := NewEnvironmentCredential(nil)
-		if err == nil {
-			creds = append(creds, envCred)
-		} else {
-			errMsg += err.Error()
-		}
-	}
-
-	if !options.ExcludeMSICredential {
-		msiCred, err := NewManagedIdentityCredential("", nil)
-		if err == nil {
-			creds = append(creds, msiCred)
-		} else {
-			errMsg += err.Error()
-		}
+	sources := []defaultCredentialSource{
+		{
+			exclude: options.ExcludeEnvironmentCredential,
+			create: func() (azcore.TokenCredential, error) {
+				envCred, err := NewEnvironmentCredential(options.TokenCredentialOptions)
+				if err != nil {
+					return nil, err
+				}
+				return envCred, nil
+			},
+		},
+		{
+			exclude: options.ExcludeMSICredential,
+			create: func() (azcore.TokenCredential, error) {
+				msiCred, err := NewManagedIdentityCredential("", managedIdentityOptions(options.TokenCredentialOptions))
+				if err != nil {
+					return nil, err
+				}
+				return msiCred, nil
+			},
+		},
 	}
+	creds, errList := createDefaultCredentials(sources, production)
 	// if no credentials are added to the slice of TokenCredentials then return a CredentialUnavailableError
 	if len(creds) == 0 {
+		errMsg := createChainedErrorMessage(errList)
+		if production {
+			errMsg = "no production credential is available in production mode: " + errMsg
+		}
 		err := &CredentialUnavailableError{CredentialType: "Default Azure Credential", Message: errMsg}
 		azcore.Log().Write(azcore.LogError, logCredentialError(err.CredentialType, err))
 		return nil, err
@@ -7,7 +7,10 @@ import (
 	"context"
 	"errors"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 )
 
 func TestDefaultAzureCredential_ExcludeEnvCredential(t *testing.T) {
@@ -88,3 +91,66 @@ func TestDefaultAzureCredential_NilOptions(t *testing.T) {
 		t.Fatalf("Received an error when trying to determine MSI type: %v", err)
 	}
 }
+
+func TestDefaultAzureCredential_ProductionModeEnvVar(t *testing.T) {
+	for _, v := range []string{"true", "1", "TRUE"} {
+		_ = os.Setenv(productionModeEnvVar, v)
+		if !(&DefaultAzureCredentialOptions{}).productionMode() {
+			t.Fatalf("Expected production mode for %s=%s", productionModeEnvVar, v)
+		}
+	}
+	for _, v := range []string{"", "false", "yes"} {
+		_ = os.Setenv(productionModeEnvVar, v)
+		if (&DefaultAzureCredentialOptions{}).productionMode() {
+			t.Fatalf("Unexpected production mode for %s=%s", productionModeEnvVar, v)
+		}
+	}
+	_ = os.Unsetenv(productionModeEnvVar)
+	if !(&DefaultAzureCredentialOptions{ProductionMode: true}).productionMode() {
+		t.Fatalf("Expected production mode when the option is set")
+	}
+}
+
+func TestDefaultAzureCredential_ProductionMode(t *testing.T) {
+	err := initEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unexpected error when initializing environment variables: %v", err)
+	}
+	cred, err := NewDefaultAzureCredential(&DefaultAzureCredentialOptions{ExcludeMSICredential: true, ProductionMode: true})
+	if err != nil {
+		t.Fatalf("Did not expect to receive an error in creating the credential: %v", err)
+	}
+	if len(cred.sources) != 1 {
+		t.Fatalf("Length of ChainedTokenCredential sources for DefaultAzureCredential. Expected: 1, Received: %d", len(cred.sources))
+	}
+	err = resetEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unexpected error when initializing environment variables: %v", err)
+	}
+	_, err = NewDefaultAzureCredential(&DefaultAzureCredentialOptions{ExcludeMSICredential: true, ProductionMode: true})
+	var credUnavailable *CredentialUnavailableError
+	if !errors.As(err, &credUnavailable) {
+		t.Fatalf("Expected: CredentialUnavailableError, Received: %T", err)
+	}
+	if !strings.Contains(err.Error(), "production mode") {
+		t.Fatalf("Expected the error to mention production mode: %v", err)
+	}
+}
+
+func TestDefaultAzureCredential_ProductionModeExcludesDeveloperCredentials(t *testing.T) {
+	developerCreated := false
+	sources := []defaultCredentialSource{
+		{create: func() (azcore.TokenCredential, error) { return &fakeCredential{}, nil }},
+		{developer: true, create: func() (azcore.TokenCredential, error) {
+			developerCreated = true
+			return &fakeCredential{}, nil
+		}},
+	}
+	if creds, _ := createDefaultCredentials(sources, false); len(creds) != 2 || !developerCreated {
+		t.Fatalf("Expected both credentials outside production mode. Received: %d", len(creds))
+	}
+	developerCreated = false
+	if creds, _ := createDefaultCredentials(sources, true); len(creds) != 1 || developerCreated {
+		t.Fatalf("Expected production mode to leave out the developer credential. Received: %d", len(creds))
+	}
+}
@@ -6,62 +6,142 @@ package azidentity
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity/cache"
 )
 
 const (
 	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
 )
 
+// DeviceCodeCredentialOptions contains parameters that can be used to configure a DeviceCodeCredential.
+type DeviceCodeCredentialOptions struct {
+	// TokenCredentialOptions configures the requests sent to Azure Active Directory.
+	TokenCredentialOptions
+
+	// PollingInterval is the amount of time to wait between polls of the token endpoint while the user signs in.
+	// The default is the interval returned by the device code endpoint.
+	PollingInterval time.Duration
+
+	// Timeout is the maximum amount of time GetToken will wait for the user to complete sign-in.
+	// If the user hasn't signed in before the timeout elapses a *DeviceCodeTimeoutError is returned.
+	// The default is to wait until the device code expires or the context is done.
+	Timeout time.Duration
+
+	// TokenCachePersistence persists the user's sign in to encrypted storage when not nil, so that it can be reused
+	// by other processes instead of running the device code flow again.
+	TokenCachePersistence *TokenCachePersistenceOptions
+}
+
+// DeviceCodeTimeoutError is returned when the user does not complete the device code sign-in before DeviceCodeCredentialOptions.Timeout elapses.
+type DeviceCodeTimeoutError struct {
+	// Timeout is the amount of time that was spent waiting for the user to sign in.
+	Timeout time.Duration
+}
+
+func (e *DeviceCodeTimeoutError) Error() string {
+	return fmt.Sprintf("Device Code Credential: the user did not complete sign-in within %v", e.Timeout)
+}
+
+// IsNotRetriable returns true indicating that this is a terminal error.
+func (e *DeviceCodeTimeoutError) IsNotRetriable() bool {
+	return true
+}
+
 // DeviceCodeCredential authenticates a user using the device code flow, and provides access tokens for that user account.
 // For more information on the device code authentication flow see: https://docs.microsoft.com/en-us/azure/active-directory/develop/v2-oauth2-device-code.
 type DeviceCodeCredential struct {
 	client       *aadIdentityClient
-	tenantID     string       // Gets the Azure Active Directory tenant (directory) ID of the service principal
-	clientID     string       // Gets the client (application) ID of the service principal
-	callback     func(string) // Sends the user a message with a verification URL and device code to sign in to the login server
-	refreshToken string       // Gets the refresh token sent from the service and will be used to retreive new access tokens after the initial request for a token. Thread safety for updates is handled in the AuthenticationPolicy since only one goroutine will be updating at a time
+	tenantID     string        // Gets the Azure Active Directory tenant (directory) ID of the service principal
+	clientID     string        // Gets the client (application) ID of the service principal
+	callback     func(string)  // Sends the user a message with a verification URL and device code to sign in to the login server
+	authMu       sync.Mutex    // Serializes sign ins and refresh token redemptions, so that concurrent token requests sign the user in once
+	refreshToken string        // Gets the refresh token sent from the service and will be used to retreive new access tokens after the initial request for a token. Guarded by authMu
+	interval     time.Duration // Overrides the polling interval returned by the service when not zero
+	timeout      time.Duration // The maximum amount of time to wait for the user to sign in when not zero
+	storage      cache.Storage // Persists the refresh token when not nil
 }
 
 // NewDeviceCodeCredential constructs a new DeviceCodeCredential used to authenticate against Azure Active Directory with a device code.
 // tenantID: The Azure Active Directory tenant (directory) ID of the service principal. If none is set then the default value ("organizations") will be used in place of the tenantID.
-// clientID: The client (application) ID of the service principal.
+// clientID: The client (application) ID of the service principal. If none is set then the Azure SDK developer sign-on client ID will be used.
+// Organizations that block third-party client IDs through conditional access can register their own public client application and pass its ID here.
 // callback: The callback function used to send the login message back to the user
-// options: Options used to configure the management of the requests sent to Azure Active Directory.
-func NewDeviceCodeCredential(tenantID string, clientID string, callback func(string), options *TokenCredentialOptions) (*DeviceCodeCredential, error) {
-	c, err := newAADIdentityClient(options)
+// options: Options used to configure polling and the management of the requests sent to Azure Active Directory.
+func NewDeviceCodeCredential(tenantID string, clientID string, callback func(string), options *DeviceCodeCredentialOptions) (*DeviceCodeCredential, error) {
+	if options == nil {
+		options = &DeviceCodeCredentialOptions{}
+	}
+	c, err := newAADIdentityClient(&options.TokenCredentialOptions)
 	if err != nil {
 		return nil, err
 	}
-	return &DeviceCodeCredential{tenantID: tenantID, clientID: clientID, callback: callback, client: c}, nil
+	if len(clientID) == 0 { // if the user did not pass in a clientID then the developer sign-on client ID is used
+		clientID = developerSignOnClientID
+	}
+	var storage cache.Storage
+	if options.TokenCachePersistence != nil {
+		storage, err = newPersistentStorage(options.TokenCachePersistence)
+		if err != nil {
+			credErr := &CredentialUnavailableError{CredentialType: "Device Code Credential", Message: "Unable to use the persistent token cache: " + err.Error()}
+			azcore.Log().Write(azcore.LogError, logCredentialError(credErr.CredentialType, credErr))
+			return nil, credErr
+		}
+	}
+	cred := &DeviceCodeCredential{tenantID: tenantID, clientID: clientID, callback: callback, client: c, interval: options.PollingInterval, timeout: options.Timeout, storage: storage}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
 }
 
 // GetToken obtains a token from Azure Active Directory, following the device code authentication
 // flow. This function first requests a device code and requests that the user login before continuing to authenticate the device.
-// This function will keep polling the service for a token until the user logs in.
+// This function will keep polling the service for a token until the user logs in, the context is done or the configured timeout elapses.
 // scopes: The list of scopes for which the token will have access. The "offline_access" scope is checked for and automatically added in case it isn't present to allow for silent token refresh.
 // ctx: The context for controlling the request lifetime.
 // Returns an AccessToken which can be used to authenticate service client calls.
-func (c *DeviceCodeCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
+func (c *DeviceCodeCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("Device Code Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tk, err := c.client.cache.getToken(ctx, c.client.cacheKey(c.clientID, c.tenantID, opts), c.client.telemetry("DeviceCodeCredential", c.tenantID, opts.Scopes), func(ctx context.Context) (*azcore.AccessToken, error) {
+		return c.authenticate(ctx, opts)
+	})
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
+	return *tk, nil
+}
+
+// authenticate redeems the refresh token from a previous sign in when there is one, otherwise it runs the device code flow.
+// Requests wait for each other, so a request made while the user signs in redeems the refresh token of that sign in.
+func (c *DeviceCodeCredential) authenticate(ctx context.Context, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
 	for i, scope := range opts.Scopes {
 		if scope == "offline_access" { // if we find that the opts.Scopes slice contains "offline_access" then we don't need to do anything and exit
 			break
 		}
-		if i == len(opts.Scopes)-1 && scope != "offline_access" { // if we haven't found "offline_access" when reaching the last element in the slice then we append it
-			opts.Scopes = append(opts.Scopes, "offline_access")
+		if i == len(opts.Scopes)-1 && scope != "offline_access" { // if we haven't found "offline_access" when reaching the last element in the slice then we append it to a copy of the caller's scopes
+			opts.Scopes = append(append([]string{}, opts.Scopes...), "offline_access")
 		}
 	}
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if len(c.refreshToken) == 0 && c.storage != nil {
+		c.refreshToken = c.persistentAccount().loadRefreshToken(ctx, c.storage)
+	}
 	if len(c.refreshToken) != 0 {
-		tk, err := c.client.refreshAccessToken(ctx, c.tenantID, c.clientID, "", c.refreshToken, opts.Scopes)
+		tk, err := c.client.refreshAccessToken(ctx, c.tenantID, c.clientID, "", c.refreshToken, opts)
 		if err != nil {
 			addGetTokenFailureLogs("Device Code Credential", err)
 			return nil, err
 		}
 		// assign new refresh token to the credential for future use
-		c.refreshToken = tk.refreshToken
-		azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
+		c.setRefreshToken(ctx, tk)
 		// passing the access token and/or error back up
 		return tk.token, nil
 	}
@@ -74,20 +154,40 @@ func (c *DeviceCodeCredential) GetToken(ctx context.Context, opts azcore.TokenRe
 	}
 	// send authentication flow instructions back to the user to log in and authorize the device
 	c.callback(dc.Message)
+	pollCtx := ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		pollCtx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	interval := time.Duration(dc.Interval) * time.Second
+	if c.interval > 0 {
+		interval = c.interval
+	}
 	// poll the token endpoint until a valid access token is received or until authentication fails
 	for {
-		tk, err := c.client.authenticateDeviceCode(ctx, c.tenantID, c.clientID, dc.DeviceCode, opts.Scopes)
+		tk, err := c.client.authenticateDeviceCode(pollCtx, c.tenantID, c.clientID, dc.DeviceCode, opts)
 		// if there is no error, save the refresh token and return the token credential
 		if err == nil {
-			c.refreshToken = tk.refreshToken
-			azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
+			c.setRefreshToken(ctx, tk)
 			return tk.token, err
 		}
+		if c.timedOut(ctx, pollCtx) {
+			return nil, c.timeoutError()
+		}
 		// if there is an error, check for an AADAuthenticationFailedError in order to check the status for token retrieval
 		// if the error is not an AADAuthenticationFailedError, then fail here since something unexpected occurred
 		if authRespErr := (*AADAuthenticationFailedError)(nil); errors.As(err, &authRespErr) && authRespErr.Message == "authorization_pending" {
-			// wait for the interval specified from the initial device code endpoint and then poll for the token again
-			time.Sleep(time.Duration(dc.Interval) * time.Second)
+			// wait for the polling interval and then poll for the token again
+			select {
+			case <-time.After(interval):
+			case <-pollCtx.Done():
+				if c.timedOut(ctx, pollCtx) {
+					return nil, c.timeoutError()
+				}
+				addGetTokenFailureLogs("Device Code Credential", ctx.Err())
+				return nil, ctx.Err()
+			}
 		} else {
 			addGetTokenFailureLogs("Device Code Credential", err)
 			// any other error should be returned
@@ -96,6 +196,36 @@ func (c *DeviceCodeCredential) GetToken(ctx context.Context, opts azcore.TokenRe
 	}
 }
 
+// timedOut returns true if polling stopped because the configured timeout elapsed rather than because the caller's context is done.
+func (c *DeviceCodeCredential) timedOut(ctx context.Context, pollCtx context.Context) bool {
+	return ctx.Err() == nil && pollCtx.Err() == context.DeadlineExceeded
+}
+
+func (c *DeviceCodeCredential) timeoutError() error {
+	err := &DeviceCodeTimeoutError{Timeout: c.timeout}
+	addGetTokenFailureLogs("Device Code Credential", err)
+	return err
+}
+
+// setRefreshToken stores the refresh token in tk for future use and persists it when the credential has storage.
+func (c *DeviceCodeCredential) setRefreshToken(ctx context.Context, tk *tokenResponse) {
+	c.refreshToken = tk.refreshToken
+	if c.storage != nil && len(tk.refreshToken) != 0 {
+		c.persistentAccount().saveRefreshToken(ctx, c.storage, tk)
+	}
+}
+
+// persistentAccount selects the sign ins the credential can use from its persistent cache.
+func (c *DeviceCodeCredential) persistentAccount() persistentAccount {
+	return persistentAccount{environment: c.client.options.AuthorityHost.Host, clientID: c.clientID, tenantID: c.tenantID}
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *DeviceCodeCredential) ClearCache() {
+	c.client.clearCache(c.clientID)
+}
+
 // AuthenticationPolicy implements the azcore.Credential interface on ClientSecretCredential.
 func (c *DeviceCodeCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
 	return newBearerTokenPolicy(c, options)
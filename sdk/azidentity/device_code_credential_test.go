@@ -9,7 +9,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
@@ -29,7 +31,7 @@ func TestDeviceCodeCredential_CreateAuthRequestSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	req, err := cred.client.createDeviceCodeAuthRequest(cred.tenantID, cred.clientID, deviceCode, []string{deviceCodeScopes})
+	req, err := cred.client.createDeviceCodeAuthRequest(cred.tenantID, cred.clientID, deviceCode, azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
 	if err != nil {
 		t.Fatalf("Unexpectedly received an error: %v", err)
 	}
@@ -71,7 +73,7 @@ func TestDeviceCodeCredential_CreateAuthRequestEmptyTenant(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	req, err := cred.client.createDeviceCodeAuthRequest(cred.tenantID, cred.clientID, deviceCode, []string{deviceCodeScopes})
+	req, err := cred.client.createDeviceCodeAuthRequest(cred.tenantID, cred.clientID, deviceCode, azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
 	if err != nil {
 		t.Fatalf("Unexpectedly received an error: %v", err)
 	}
@@ -116,7 +118,7 @@ func TestDeviceCodeCredential_RequestNewDeviceCodeEmptyTenant(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	req, err := cred.client.createDeviceCodeNumberRequest(cred.tenantID, cred.clientID, []string{deviceCodeScopes})
+	req, err := cred.client.createDeviceCodeNumberRequest(cred.tenantID, cred.clientID, strings.Fields(deviceCodeScopes))
 	if err != nil {
 		t.Fatalf("Unexpectedly received an error: %v", err)
 	}
@@ -149,6 +151,32 @@ func TestDeviceCodeCredential_RequestNewDeviceCodeEmptyTenant(t *testing.T) {
 	}
 }
 
+func TestDeviceCodeCredential_DefaultClientID(t *testing.T) {
+	handler := func(s string) {}
+	cred, err := NewDeviceCodeCredential(tenantID, "", handler, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if cred.clientID != developerSignOnClientID {
+		t.Fatalf("Expected the developer sign-on client ID but received: %s", cred.clientID)
+	}
+	req, err := cred.client.createDeviceCodeNumberRequest(cred.tenantID, cred.clientID, strings.Fields(deviceCodeScopes))
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		t.Fatalf("Unable to read request body")
+	}
+	reqQueryParams, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Unable to parse query params in request")
+	}
+	if reqQueryParams[qpClientID][0] != developerSignOnClientID {
+		t.Fatalf("Unexpected client ID in the client_id header")
+	}
+}
+
 func TestDeviceCodeCredential_GetTokenSuccess(t *testing.T) {
 	srv, close := mock.NewServer()
 	defer close()
@@ -157,11 +185,11 @@ func TestDeviceCodeCredential_GetTokenSuccess(t *testing.T) {
 	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
 	srvURL := srv.URL()
 	handler := func(string) {}
-	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true}})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	tk, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{deviceCodeScopes}})
+	tk, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
 	if err != nil {
 		t.Fatalf("Expected an empty error but received: %s", err.Error())
 	}
@@ -176,11 +204,11 @@ func TestDeviceCodeCredential_GetTokenInvalidCredentials(t *testing.T) {
 	srv.SetResponse(mock.WithStatusCode(http.StatusUnauthorized))
 	srvURL := srv.URL()
 	handler := func(string) {}
-	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true}})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{deviceCodeScopes}})
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
 	if err == nil {
 		t.Fatalf("Expected an error but did not receive one.")
 	}
@@ -195,11 +223,11 @@ func TestDeviceCodeCredential_GetTokenAuthorizationPending(t *testing.T) {
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
 	handler := func(string) {}
-	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true}})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{deviceCodeScopes}})
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
 	if err != nil {
 		t.Fatalf("Expected an empty error but received %v", err)
 	}
@@ -213,11 +241,11 @@ func TestDeviceCodeCredential_GetTokenExpiredToken(t *testing.T) {
 	srv.AppendResponse(mock.WithBody([]byte(expiredTokenResponse)), mock.WithStatusCode(http.StatusUnauthorized))
 	srvURL := srv.URL()
 	handler := func(string) {}
-	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true}})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
-	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{deviceCodeScopes}})
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
 	if err == nil {
 		t.Fatalf("Expected an error but received none")
 	}
@@ -229,12 +257,12 @@ func TestDeviceCodeCredential_GetTokenWithRefreshTokenFailure(t *testing.T) {
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespError)), mock.WithStatusCode(http.StatusUnauthorized))
 	srvURL := srv.URL()
 	handler := func(string) {}
-	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true}})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
 	cred.refreshToken = "refresh_token"
-	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{deviceCodeScopes}})
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
 	if err == nil {
 		t.Fatalf("Expected an error but did not receive one")
 	}
@@ -250,12 +278,12 @@ func TestDeviceCodeCredential_GetTokenWithRefreshTokenSuccess(t *testing.T) {
 	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
 	srvURL := srv.URL()
 	handler := func(string) {}
-	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true}})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
 	cred.refreshToken = "refresh_token"
-	tk, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{deviceCodeScopes}})
+	tk, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
 	if err != nil {
 		t.Fatalf("Received an unexpected error: %s", err.Error())
 	}
@@ -272,7 +300,7 @@ func TestBearerPolicy_DeviceCodeCredential(t *testing.T) {
 	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
 	srvURL := srv.URL()
 	handler := func(string) {}
-	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL})
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL}})
 	if err != nil {
 		t.Fatalf("Unable to create credential. Received: %v", err)
 	}
@@ -281,7 +309,7 @@ func TestBearerPolicy_DeviceCodeCredential(t *testing.T) {
 		azcore.NewTelemetryPolicy(azcore.TelemetryOptions{}),
 		azcore.NewUniqueRequestIDPolicy(),
 		azcore.NewRetryPolicy(nil),
-		cred.AuthenticationPolicy(azcore.AuthenticationPolicyOptions{Options: azcore.TokenRequestOptions{Scopes: []string{deviceCodeScopes}}}),
+		cred.AuthenticationPolicy(azcore.AuthenticationPolicyOptions{Options: azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)}}),
 		azcore.NewRequestLogPolicy(azcore.RequestLogOptions{}))
 	req := azcore.NewRequest(http.MethodGet, srv.URL())
 	_, err = pipeline.Do(context.Background(), req)
@@ -289,3 +317,52 @@ func TestBearerPolicy_DeviceCodeCredential(t *testing.T) {
 		t.Fatalf("Expected an empty error but receive: %v", err)
 	}
 }
+
+func TestDeviceCodeCredential_GetTokenPollingInterval(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(deviceCodeResponse)))
+	srv.AppendResponse(mock.WithBody([]byte(authorizationPendingResponse)), mock.WithStatusCode(http.StatusUnauthorized))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	handler := func(string) {}
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{
+		TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true},
+		PollingInterval:        10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	start := time.Now()
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
+	if err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("Expected the polling interval to override the interval returned by the service")
+	}
+}
+
+func TestDeviceCodeCredential_GetTokenTimeout(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(deviceCodeResponse)))
+	for i := 0; i < 100; i++ {
+		srv.AppendResponse(mock.WithBody([]byte(authorizationPendingResponse)), mock.WithStatusCode(http.StatusUnauthorized))
+	}
+	srvURL := srv.URL()
+	handler := func(string) {}
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, &DeviceCodeCredentialOptions{
+		TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true},
+		PollingInterval:        10 * time.Millisecond,
+		Timeout:                100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)})
+	var timeoutErr *DeviceCodeTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected a DeviceCodeTimeoutError but received: %v", err)
+	}
+}
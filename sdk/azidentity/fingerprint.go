@@ -6,14 +6,14 @@ package azidentity
 import (
 	"bufio"
 	"bytes"
-	"crypto/sha1"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
 )
 
-// fingerprint type wraps a byte slice that contains the corresponding SHA-1 fingerprint for the client's certificate
+// fingerprint type wraps a byte slice that contains the thumbprint of the client's certificate, its SHA-1 fingerprint
+// unless the module is built in FIPS mode
 type fingerprint []byte
 
 // String represents the fingerprint digest as a series of
@@ -29,8 +29,7 @@ func (f fingerprint) String() string {
 	return buf.String()
 }
 
-// spkiFingerprint calculates the fingerprint of the certificate based on it's Subject Public Key Info with the SHA-1
-// signing algorithm.
+// spkiFingerprint calculates the fingerprint of the first certificate in the PEM file with certificateThumbprint.
 func spkiFingerprint(cert string) (fingerprint, error) {
 	privateKeyFile, err := os.Open(cert)
 	if err != nil {
@@ -53,27 +52,11 @@ func spkiFingerprint(cert string) (fingerprint, error) {
 	// Get first block of PEM file
 	data, rest := pem.Decode([]byte(pemBytes))
 	const certificateBlock = "CERTIFICATE"
-	if data.Type != certificateBlock {
-		for len(rest) > 0 {
-			data, rest = pem.Decode(rest)
-			if data.Type == certificateBlock {
-				// Sign the CERTIFICATE block with SHA1
-				h := sha1.New()
-				_, err := h.Write(data.Bytes)
-				if err != nil {
-					return nil, err
-				}
-
-				return fingerprint(h.Sum(nil)), nil
-			}
-		}
-		return nil, errors.New("Cannot find CERTIFICATE in file")
+	for data != nil && data.Type != certificateBlock {
+		data, rest = pem.Decode(rest)
 	}
-	h := sha1.New()
-	_, err = h.Write(data.Bytes)
-	if err != nil {
-		return nil, err
+	if data == nil {
+		return nil, errors.New("Cannot find CERTIFICATE in file")
 	}
-
-	return fingerprint(h.Sum(nil)), nil
+	return certificateThumbprint(data.Bytes), nil
 }
@@ -0,0 +1,48 @@
+// +build fips
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/pem"
+	"errors"
+)
+
+// fipsMode is true when the module is built with the fips tag, for applications that must only use FIPS-approved
+// algorithms, such as those built with a FIPS-validated crypto module like boringcrypto. In this mode:
+//   - certificates are identified by their SHA-256 thumbprint instead of their SHA-1 thumbprint. Client assertions send
+//     it in the x5t#S256 header and ClientCertificateSelector.Thumbprint must be the SHA-256 thumbprint.
+//   - client assertions can only be signed with RSA keys of at least 2048 bits.
+//
+// Client assertions are always signed with RS256, and certificates are only read from PEM files, so no other algorithm
+// is used in either mode.
+const fipsMode = true
+
+// fipsMinRSAKeyBits is the size of the smallest RSA key allowed to sign client assertions in FIPS mode.
+const fipsMinRSAKeyBits = 2048
+
+// certificateThumbprint returns the thumbprint identifying a DER encoded certificate to Azure Active Directory.
+func certificateThumbprint(der []byte) fingerprint {
+	sum := sha256.Sum256(der)
+	return fingerprint(sum[:])
+}
+
+// decryptPEMBlock returns the decrypted bytes of an encrypted PEM block. The legacy OpenSSL encryption derives the key
+// with MD5, which isn't FIPS-approved.
+func decryptPEMBlock(block *pem.Block, password string) ([]byte, error) {
+	return nil, errors.New("encrypted PEM private keys aren't supported in FIPS mode")
+}
+
+// checkSigner returns an error when the signer's key can't sign client assertions in FIPS mode.
+func checkSigner(signer crypto.Signer) error {
+	pub, ok := signer.Public().(*rsa.PublicKey)
+	if !ok || pub.Size()*8 < fipsMinRSAKeyBits {
+		return errors.New("FIPS mode requires an RSA key of at least 2048 bits to sign client assertions")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+// +build !fips
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// fipsMode is true when the module is built with the fips tag. See fips.go.
+const fipsMode = false
+
+// certificateThumbprint returns the thumbprint identifying a DER encoded certificate to Azure Active Directory.
+func certificateThumbprint(der []byte) fingerprint {
+	sum := sha1.Sum(der)
+	return fingerprint(sum[:])
+}
+
+// decryptPEMBlock returns the decrypted bytes of an encrypted PEM block.
+func decryptPEMBlock(block *pem.Block, password string) ([]byte, error) {
+	return x509.DecryptPEMBlock(block, []byte(password))
+}
+
+// checkSigner returns an error when the signer's key can't sign client assertions. Any key can outside of FIPS mode.
+func checkSigner(signer crypto.Signer) error {
+	return nil
+}
@@ -0,0 +1,69 @@
+// +build !fips
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCertificateThumbprint_SHA1(t *testing.T) {
+	der := []byte("certificate")
+	sum := sha1.Sum(der)
+	if thumbprint := certificateThumbprint(der); string(thumbprint) != string(sum[:]) {
+		t.Fatalf("Expected the SHA-1 thumbprint. Received: %s", thumbprint)
+	}
+}
+
+func TestClientAssertionJWT_X5t(t *testing.T) {
+	key, err := getPrivateKey(certificatePath, "")
+	if err != nil {
+		t.Fatalf("Unable to load the private key: %v", err)
+	}
+	assertion, err := signClientAssertionJWT(clientID, "audience", fingerprint("thumbprint"), nil, key)
+	if err != nil {
+		t.Fatalf("Unable to sign the assertion: %v", err)
+	}
+	header := decodeJWTHeader(t, assertion)
+	if header["x5t"] != base64.RawURLEncoding.EncodeToString([]byte("thumbprint")) || header["x5t#S256"] != "" {
+		t.Fatalf("Expected the thumbprint in the x5t header. Received: %v", header)
+	}
+}
+
+// decodeJWTHeader returns the fields of the JWT's header.
+func decodeJWTHeader(t *testing.T, jwt string) map[string]string {
+	b, err := base64.RawURLEncoding.DecodeString(strings.Split(jwt, ".")[0])
+	if err != nil {
+		t.Fatalf("Unable to decode the header: %v", err)
+	}
+	var header map[string]string
+	if err = json.Unmarshal(b, &header); err != nil {
+		t.Fatalf("Unable to unmarshal the header: %v", err)
+	}
+	return header
+}
+
+func TestGetPrivateKey_Encrypted(t *testing.T) {
+	path := writeEncryptedCertificatePEM(t, "password")
+	defer os.Remove(path)
+	if _, err := getPrivateKey(path, "password"); err != nil {
+		t.Fatalf("Unable to decrypt the private key: %v", err)
+	}
+	if _, err := getPrivateKey(path, "wrong"); err == nil {
+		t.Fatalf("Expected an error for the wrong password")
+	}
+	if _, err := getPrivateKey(path, ""); err == nil || !strings.Contains(err.Error(), clientCertificatePasswordEnvVar) {
+		t.Fatalf("Expected an error naming the password environment variable. Received: %v", err)
+	}
+	pairs, err := loadCertificatePairs(path, "password")
+	if err != nil || len(pairs) != 1 {
+		t.Fatalf("Expected the selector to decrypt the private key. Received: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+// +build fips
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCertificateThumbprint_SHA256(t *testing.T) {
+	der := []byte("certificate")
+	sum := sha256.Sum256(der)
+	if thumbprint := certificateThumbprint(der); string(thumbprint) != string(sum[:]) {
+		t.Fatalf("Expected the SHA-256 thumbprint. Received: %s", thumbprint)
+	}
+}
+
+func TestClientAssertionJWT_X5tS256(t *testing.T) {
+	key, err := getPrivateKey(certificatePath, "")
+	if err != nil {
+		t.Fatalf("Unable to load the private key: %v", err)
+	}
+	assertion, err := signClientAssertionJWT(clientID, "audience", fingerprint("thumbprint"), nil, key)
+	if err != nil {
+		t.Fatalf("Unable to sign the assertion: %v", err)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.Split(assertion, ".")[0])
+	if err != nil {
+		t.Fatalf("Unable to decode the header: %v", err)
+	}
+	var header map[string]string
+	if err = json.Unmarshal(b, &header); err != nil {
+		t.Fatalf("Unable to unmarshal the header: %v", err)
+	}
+	if header["x5t#S256"] != base64.RawURLEncoding.EncodeToString([]byte("thumbprint")) || header["x5t"] != "" {
+		t.Fatalf("Expected the thumbprint in the x5t#S256 header. Received: %v", header)
+	}
+}
+
+func TestClientAssertionJWT_SmallKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Unable to generate key: %v", err)
+	}
+	if _, err = signClientAssertionJWT(clientID, "audience", fingerprint("thumbprint"), nil, key); err == nil {
+		t.Fatalf("Expected a 1024 bit key to be rejected")
+	}
+}
+
+func TestGetPrivateKey_EncryptedUnsupported(t *testing.T) {
+	path := writeEncryptedCertificatePEM(t, "password")
+	defer os.Remove(path)
+	if _, err := getPrivateKey(path, "password"); err == nil {
+		t.Fatalf("Expected legacy encrypted private keys to be rejected in FIPS mode")
+	}
+}
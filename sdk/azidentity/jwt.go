@@ -6,7 +6,6 @@ package azidentity
 import (
 	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -17,10 +16,13 @@ import (
 )
 
 // headerJWT type contains the fields necessary to create a JSON Web Token including the x5t field which must contain a x.509 certificate thumbprint
+// or, in FIPS mode, the x5t#S256 field which must contain its SHA-256 thumbprint
 type headerJWT struct {
-	Typ string `json:"typ"`
-	Alg string `json:"alg"`
-	X5t string `json:"x5t"`
+	Typ     string   `json:"typ"`
+	Alg     string   `json:"alg"`
+	X5t     string   `json:"x5t,omitempty"`
+	X5tS256 string   `json:"x5t#S256,omitempty"`
+	X5c     []string `json:"x5c,omitempty"`
 }
 
 // payloadJWT type contains all fields that are necessary when creating a JSON Web Token payload section
@@ -35,16 +37,47 @@ type payloadJWT struct {
 
 // createClientAssertionJWT build the JWT header, payload and signature,
 // then returns a string for the JWT assertion
-func createClientAssertionJWT(clientID string, audience string, clientCertificate string) (string, error) {
+// password: decrypts the private key when it's encrypted.
+// sendChain: includes the certificates in the file in the x5c header.
+func createClientAssertionJWT(clientID string, audience string, clientCertificate string, password string, sendChain bool) (string, error) {
 	fingerprint, err := spkiFingerprint(clientCertificate)
 	if err != nil {
 		return "", err
 	}
 
+	privateKey, err := getPrivateKey(clientCertificate, password)
+	if err != nil {
+		return "", err
+	}
+
+	var chain [][]byte
+	if sendChain {
+		if chain, err = certificateChain(clientCertificate); err != nil {
+			return "", err
+		}
+	}
+
+	return signClientAssertionJWT(clientID, audience, fingerprint, chain, privateKey)
+}
+
+// signClientAssertionJWT builds the JWT header and payload for the certificate with the specified thumbprint and
+// signs them with the signer, which must hold the certificate's RSA private key. The DER encoded certificates in
+// chain, if any, are sent in the x5c header.
+func signClientAssertionJWT(clientID string, audience string, thumbprint fingerprint, chain [][]byte, signer crypto.Signer) (string, error) {
+	if err := checkSigner(signer); err != nil {
+		return "", err
+	}
 	headerData := headerJWT{
 		Typ: "JWT",
 		Alg: "RS256",
-		X5t: base64.RawURLEncoding.EncodeToString(fingerprint),
+	}
+	if fipsMode {
+		headerData.X5tS256 = base64.RawURLEncoding.EncodeToString(thumbprint)
+	} else {
+		headerData.X5t = base64.RawURLEncoding.EncodeToString(thumbprint)
+	}
+	for _, der := range chain {
+		headerData.X5c = append(headerData.X5c, base64.StdEncoding.EncodeToString(der))
 	}
 
 	headerJSON, err := json.Marshal(headerData)
@@ -72,12 +105,8 @@ func createClientAssertionJWT(clientID string, audience string, clientCertificat
 	hashedSum := sha256.Sum256(hashed)
 	cryptoRand := rand.Reader
 
-	privateKey, err := getPrivateKey(clientCertificate)
-	if err != nil {
-		return "", err
-	}
-
-	signed, err := rsa.SignPKCS1v15(cryptoRand, privateKey, crypto.SHA256, hashedSum[:])
+	// for an RSA key this produces a PKCS #1 v1.5 signature as required by RS256
+	signed, err := signer.Sign(cryptoRand, hashedSum[:], crypto.SHA256)
 	if err != nil {
 		return "", err
 	}
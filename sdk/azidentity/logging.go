@@ -4,9 +4,14 @@
 package azidentity
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 )
@@ -14,6 +19,134 @@ import (
 // LogCredential is the log classification that can be used for logging Azure Identity related information
 const LogCredential azcore.LogClassification = "credential"
 
+// Events logged with the LogCredential classification. Each message names its event
+// followed by key=value fields, for example "Azure Identity => TokenAcquired credential=...".
+const (
+	logEventCredentialCreated  = "CredentialCreated"
+	logEventChainAttempt       = "ChainAttempt"
+	logEventTokenAcquired      = "TokenAcquired"
+	logEventTokenRequestFailed = "TokenRequestFailed"
+)
+
+// LogFormat is the format of the events logged with the LogCredential classification.
+type LogFormat int32
+
+const (
+	// LogFormatText writes each event as its name followed by key=value fields, for example
+	// "Azure Identity => TokenAcquired credential=ClientSecretCredential ...". This is the default.
+	LogFormatText LogFormat = iota
+	// LogFormatJSON writes each event as a JSON object whose "event" member is the name of the event and whose
+	// other members are its fields, for example {"event":"TokenAcquired","credential":"ClientSecretCredential",...}.
+	// All values are strings. Use it to send the events to log analytics or a SIEM without parsing them.
+	LogFormatJSON
+)
+
+// logFormat is the LogFormat set by SetLogFormat.
+var logFormat int32
+
+// SetLogFormat sets the format of the events logged with the LogCredential classification by every credential in
+// the process. Other messages logged by the module are unaffected.
+func SetLogFormat(format LogFormat) {
+	atomic.StoreInt32(&logFormat, int32(format))
+}
+
+// logEvent writes an event with fields given as alternating keys and values.
+// Values containing spaces or quotes are quoted so that the fields can be parsed.
+func logEvent(event string, fields ...string) {
+	if !azcore.Log().Should(LogCredential) {
+		return
+	}
+	if LogFormat(atomic.LoadInt32(&logFormat)) == LogFormatJSON {
+		azcore.Log().Write(LogCredential, jsonLogEvent(event, fields))
+		return
+	}
+	var b strings.Builder
+	b.WriteString("Azure Identity => ")
+	b.WriteString(event)
+	for i := 0; i+1 < len(fields); i += 2 {
+		value := fields[i+1]
+		if value == "" || strings.ContainsAny(value, " \t\n\"") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(" " + fields[i] + "=" + value)
+	}
+	azcore.Log().Write(LogCredential, b.String())
+}
+
+// jsonLogEvent formats an event as a JSON object, keeping the order of its fields.
+func jsonLogEvent(event string, fields []string) string {
+	var b strings.Builder
+	b.WriteString(`{"event":`)
+	b.Write(jsonString(event))
+	for i := 0; i+1 < len(fields); i += 2 {
+		b.WriteByte(',')
+		b.Write(jsonString(fields[i]))
+		b.WriteByte(':')
+		b.Write(jsonString(fields[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// jsonString returns s encoded as a JSON string.
+func jsonString(s string) []byte {
+	// marshaling a string can't fail
+	b, _ := json.Marshal(s)
+	return b
+}
+
+// credentialTypeName returns the name of the type of cred without its package, for example "ClientSecretCredential".
+func credentialTypeName(cred interface{}) string {
+	name := fmt.Sprintf("%T", cred)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// logCredentialCreated logs the construction of cred along with fields identifying it, which must not contain secrets.
+func logCredentialCreated(cred azcore.TokenCredential, fields ...string) {
+	logEvent(logEventCredentialCreated, append([]string{"credential", credentialTypeName(cred)}, fields...)...)
+}
+
+// logChainAttempt logs the result of a credential's attempt to get a token for a ChainedTokenCredential.
+func logChainAttempt(cred azcore.TokenCredential, err error) {
+	var unavailable *CredentialUnavailableError
+	switch {
+	case err == nil:
+		logEvent(logEventChainAttempt, "credential", credentialTypeName(cred), "result", "success")
+	case errors.As(err, &unavailable):
+		logEvent(logEventChainAttempt, "credential", credentialTypeName(cred), "result", "unavailable", "reason", err.Error())
+	default:
+		logEvent(logEventChainAttempt, "credential", credentialTypeName(cred), "result", "failed", "reason", err.Error())
+	}
+}
+
+// logTokenAcquired logs a token acquired from the identity provider.
+func logTokenAcquired(t tokenTelemetry, outcome TokenRequestOutcome, duration time.Duration, tk *azcore.AccessToken) {
+	fields := append(tokenRequestFields(t, outcome, duration), "expires_on", tk.ExpiresOn.UTC().Format(time.RFC3339))
+	logEvent(logEventTokenAcquired, fields...)
+}
+
+// logTokenRequestFailed logs a failure to acquire a token, including the AADSTS error code when Azure Active Directory returned one.
+func logTokenRequestFailed(t tokenTelemetry, duration time.Duration, err error) {
+	fields := tokenRequestFields(t, TokenRequestFailed, duration)
+	if code := aadstsCode(err); code != "" {
+		fields = append(fields, "code", code)
+	}
+	logEvent(logEventTokenRequestFailed, append(fields, "error", err.Error())...)
+}
+
+// tokenRequestFields returns the fields common to the events of a token request.
+func tokenRequestFields(t tokenTelemetry, outcome TokenRequestOutcome, duration time.Duration) []string {
+	fields := []string{"credential", t.credentialType}
+	if t.tenantID != "" {
+		fields = append(fields, "tenant", t.tenantID)
+	}
+	return append(fields,
+		"scopes", strings.Join(t.scopes, " "),
+		"scope_hash", scopeHash(t.scopes),
+		"outcome", string(outcome),
+		"duration_ms", strconv.FormatInt(duration.Milliseconds(), 10))
+}
+
 // log environment variables that can be used for credential types
 func logEnvVars() {
 	if !azcore.Log().Should(LogCredential) {
@@ -55,19 +188,12 @@ func logCredentialError(credName string, err error) string {
 	return fmt.Sprintf("Azure Identity => ERROR in %s: %s", credName, err.Error())
 }
 
-func logMSIEnv(msi msiType) string {
-	switch msi {
-	case 1:
-		return "Azure Identity => Managed Identity environment: IMDS"
-	case 2:
-		return "Azure Identity => Managed Identity environment: MSI_ENDPOINT"
-	case 3:
-		return "Azure Identity => Managed Identity environment: MSI_ENDPOINT"
-	case 4:
-		return "Azure Identity => Managed Identity environment: Unavailable"
-	default:
-		return "Azure Identity => Managed Identity environment: Unknown"
+func logMSIEnv(msi msiType, reason string) string {
+	msg := fmt.Sprintf("Azure Identity => Managed Identity environment: %s", msi.source())
+	if len(reason) > 0 {
+		msg += " (" + reason + ")"
 	}
+	return msg
 }
 
 func addGetTokenFailureLogs(credName string, err error) {
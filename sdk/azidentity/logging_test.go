@@ -0,0 +1,159 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// captureCredentialLogs records the messages logged with the LogCredential classification until the returned func is called.
+func captureCredentialLogs() (messages func() []string, stop func()) {
+	var mu sync.Mutex
+	var logged []string
+	azcore.Log().SetClassifications(LogCredential)
+	azcore.Log().SetListener(func(cls azcore.LogClassification, msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, msg)
+	})
+	messages = func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, logged...)
+	}
+	stop = func() {
+		azcore.Log().SetListener(nil)
+		azcore.Log().SetClassifications()
+	}
+	return messages, stop
+}
+
+// findLogEvent returns the first message for the event, or an empty string when there isn't one.
+func findLogEvent(messages []string, event string) string {
+	for _, msg := range messages {
+		if strings.HasPrefix(msg, "Azure Identity => "+event+" ") {
+			return msg
+		}
+	}
+	return ""
+}
+
+func TestLogEvents(t *testing.T) {
+	messages, stop := captureCredentialLogs()
+	defer stop()
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srv.AppendResponse(mock.WithBody([]byte(`{"error": "invalid_grant", "error_description": "AADSTS50126: Invalid username or password.", "error_codes": [50126]}`)), mock.WithStatusCode(http.StatusBadRequest))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	msg := findLogEvent(messages(), logEventCredentialCreated)
+	if !strings.Contains(msg, "credential=ClientSecretCredential") || !strings.Contains(msg, "client="+clientID) || strings.Contains(msg, secret) {
+		t.Fatalf("Unexpected credential created event: %q", msg)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	msg = findLogEvent(messages(), logEventTokenAcquired)
+	if !strings.Contains(msg, "scopes="+scope) || !strings.Contains(msg, "expires_on=") {
+		t.Fatalf("Unexpected token acquired event: %q", msg)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{"other"}}); err == nil {
+		t.Fatalf("Expected an error")
+	}
+	if msg = findLogEvent(messages(), logEventTokenRequestFailed); !strings.Contains(msg, "code=AADSTS50126") {
+		t.Fatalf("Expected the failure event to include the AADSTS code. Received: %q", msg)
+	}
+}
+
+func TestLogChainAttempt(t *testing.T) {
+	messages, stop := captureCredentialLogs()
+	defer stop()
+	cred, err := NewChainedTokenCredential(unavailableCredential{"fake", "not configured"}, &fakeCredential{})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	var attempts []string
+	for _, msg := range messages() {
+		if strings.HasPrefix(msg, "Azure Identity => "+logEventChainAttempt) {
+			attempts = append(attempts, msg)
+		}
+	}
+	if len(attempts) != 2 || !strings.Contains(attempts[0], `result=unavailable reason="fake: not configured"`) || !strings.Contains(attempts[1], "result=success") {
+		t.Fatalf("Unexpected chain attempt events: %v", attempts)
+	}
+}
+
+func TestLogEventQuoting(t *testing.T) {
+	messages, stop := captureCredentialLogs()
+	defer stop()
+	logEvent("Test", "plain", "value", "spaced", "a b", "empty", "")
+	if msgs := messages(); len(msgs) != 1 || msgs[0] != `Azure Identity => Test plain=value spaced="a b" empty=""` {
+		t.Fatalf("Unexpected message: %v", msgs)
+	}
+}
+
+func TestLogFormatJSON(t *testing.T) {
+	messages, stop := captureCredentialLogs()
+	defer stop()
+	SetLogFormat(LogFormatJSON)
+	defer SetLogFormat(LogFormatText)
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	var acquired map[string]string
+	for _, msg := range messages() {
+		if !strings.HasPrefix(msg, "{") {
+			// only events are formatted as JSON
+			continue
+		}
+		var record map[string]string
+		if err := json.Unmarshal([]byte(msg), &record); err != nil {
+			t.Fatalf("Expected a JSON record. Received: %q", msg)
+		}
+		if record["event"] == logEventTokenAcquired {
+			acquired = record
+		}
+	}
+	if acquired == nil {
+		t.Fatalf("Expected a token acquired record")
+	}
+	expected := map[string]string{"credential": "ClientSecretCredential", "tenant": tenantID, "scope_hash": scopeHash([]string{scope}), "outcome": string(TokenRequestAcquired)}
+	for k, v := range expected {
+		if acquired[k] != v {
+			t.Fatalf("Expected %s to be %q. Received: %v", k, v, acquired)
+		}
+	}
+	if acquired["duration_ms"] == "" || acquired["expires_on"] == "" {
+		t.Fatalf("Expected the duration and expiration to be logged. Received: %v", acquired)
+	}
+}
+
+func TestJSONLogEvent(t *testing.T) {
+	if msg := jsonLogEvent("Test", []string{"quoted", `a "b"`, "empty", ""}); msg != `{"event":"Test","quoted":"a \"b\"","empty":""}` {
+		t.Fatalf("Unexpected message: %s", msg)
+	}
+}
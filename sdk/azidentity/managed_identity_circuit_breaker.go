@@ -0,0 +1,123 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// msiCircuitBreakerThreshold is how many consecutive token requests must fail because a managed identity
+// endpoint is unavailable before requests to it fail fast.
+const msiCircuitBreakerThreshold = 3
+
+// msiCircuitBreakerCooldown is how long requests to an unavailable managed identity endpoint fail fast
+// before a single request is sent to find out whether it has recovered.
+const msiCircuitBreakerCooldown = 30 * time.Second
+
+// ManagedIdentityCircuitOpenError is returned by ManagedIdentityCredential without sending a request when the
+// managed identity endpoint failed several consecutive token requests. The requests of every credential in the
+// process using the endpoint fail fast until RetryAfter, instead of each waiting out the retries of a failing endpoint.
+type ManagedIdentityCircuitOpenError struct {
+	// Source is the hosting environment whose managed identity endpoint is failing.
+	Source ManagedIdentitySource
+	// RetryAfter is when a request will be sent to the endpoint again.
+	RetryAfter time.Time
+}
+
+func (e *ManagedIdentityCircuitOpenError) Error() string {
+	return fmt.Sprintf("Managed Identity Credential: the %s managed identity endpoint failed %d consecutive token requests, no requests will be sent to it until %s",
+		e.Source, msiCircuitBreakerThreshold, e.RetryAfter.Format(time.RFC3339))
+}
+
+// IsNotRetriable returns true indicating that this is a terminal error.
+func (e *ManagedIdentityCircuitOpenError) IsNotRetriable() bool {
+	return true
+}
+
+// msiCircuitBreakers are the circuit breakers of the managed identity endpoints the process requested tokens from, by endpoint.
+var msiCircuitBreakers = struct {
+	sync.Mutex
+	m map[string]*msiCircuitBreaker
+}{m: map[string]*msiCircuitBreaker{}}
+
+// msiCircuitBreakerFor returns the circuit breaker shared by the credentials requesting tokens from the endpoint.
+func msiCircuitBreakerFor(u url.URL) *msiCircuitBreaker {
+	endpoint := msiCircuitBreakerKey(u)
+	msiCircuitBreakers.Lock()
+	defer msiCircuitBreakers.Unlock()
+	b, ok := msiCircuitBreakers.m[endpoint]
+	if !ok {
+		b = newMSICircuitBreaker(msiCircuitBreakerCooldown)
+		msiCircuitBreakers.m[endpoint] = b
+	}
+	return b
+}
+
+// msiCircuitBreakerKey returns the key of the endpoint's circuit breaker in msiCircuitBreakers.
+// The query of the endpoint, which contains the requested resource, is ignored.
+func msiCircuitBreakerKey(u url.URL) string {
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// msiCircuitBreaker counts the consecutive token requests to a managed identity endpoint that failed because the
+// endpoint is unavailable, with an azcore.CircuitBreaker opening after msiCircuitBreakerThreshold of them.
+type msiCircuitBreaker struct {
+	*azcore.CircuitBreaker
+}
+
+// newMSICircuitBreaker creates a closed msiCircuitBreaker whose requests fail fast for cooldown once it opens.
+func newMSICircuitBreaker(cooldown time.Duration) *msiCircuitBreaker {
+	return &msiCircuitBreaker{azcore.NewCircuitBreaker(azcore.CircuitBreakerOptions{
+		FailureThreshold: msiCircuitBreakerThreshold,
+		Cooldown:         cooldown,
+	})}
+}
+
+// allow returns a *ManagedIdentityCircuitOpenError when a request mustn't be sent to the endpoint.
+// Requests that are allowed must be followed by a call to done.
+func (b *msiCircuitBreaker) allow(source ManagedIdentitySource) error {
+	if ok, retryAfter := b.Allow(); !ok {
+		return &ManagedIdentityCircuitOpenError{Source: source, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// done records the result of a request that was allowed.
+func (b *msiCircuitBreaker) done(ctx context.Context, err error) {
+	b.Done(ctx, msiEndpointFailed(err))
+}
+
+// msiEndpointFailed returns true when err means the managed identity endpoint is unavailable: it couldn't be reached,
+// it returned a server error or one of the other status codes that are retried. Other errors, such as requesting a token for an identity
+// that isn't assigned, show the endpoint is working.
+func msiEndpointFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	var authErr *AuthenticationFailedError
+	if !errors.As(err, &authErr) {
+		return true
+	}
+	resp := authErr.RawResponse()
+	if resp == nil {
+		return false
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+	for _, code := range msiRetryStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// openMSICircuitBreaker returns a breaker with the cooldown whose circuit is open
+func openMSICircuitBreaker(t *testing.T, cooldown time.Duration) *msiCircuitBreaker {
+	b := newMSICircuitBreaker(cooldown)
+	for i := 0; i < msiCircuitBreakerThreshold; i++ {
+		if err := b.allow(ManagedIdentitySourceIMDS); err != nil {
+			t.Fatalf("Expected request %d to be allowed. Received: %v", i, err)
+		}
+		b.done(context.Background(), errors.New("connection refused"))
+	}
+	return b
+}
+
+func TestMSICircuitBreaker(t *testing.T) {
+	b := openMSICircuitBreaker(t, msiCircuitBreakerCooldown)
+	err := b.allow(ManagedIdentitySourceIMDS)
+	var openErr *ManagedIdentityCircuitOpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("Expected a ManagedIdentityCircuitOpenError. Received: %v", err)
+	}
+	if openErr.Source != ManagedIdentitySourceIMDS || time.Until(openErr.RetryAfter) < msiCircuitBreakerCooldown-time.Second {
+		t.Fatalf("Unexpected error: %v", openErr)
+	}
+}
+
+func TestMSICircuitBreaker_AfterCooldown(t *testing.T) {
+	// the cooldown has elapsed by the time the breaker is checked, so a single request tests the endpoint
+	b := openMSICircuitBreaker(t, time.Nanosecond)
+	var openErr *ManagedIdentityCircuitOpenError
+	if err := b.allow(ManagedIdentitySourceIMDS); err != nil {
+		t.Fatalf("Expected a request to be allowed after the cooldown. Received: %v", err)
+	}
+	if err := b.allow(ManagedIdentitySourceIMDS); !errors.As(err, &openErr) {
+		t.Fatalf("Expected requests to fail fast while the endpoint is tested. Received: %v", err)
+	}
+	b.done(context.Background(), errors.New("connection refused"))
+	if err := b.allow(ManagedIdentitySourceIMDS); err != nil {
+		t.Fatalf("Expected a failed test to open the circuit until the next cooldown. Received: %v", err)
+	}
+	b.done(context.Background(), nil)
+	// a successful test closes the circuit, so it takes the threshold of failures to open it again
+	b.done(context.Background(), errors.New("connection refused"))
+	if err := b.allow(ManagedIdentitySourceIMDS); err != nil {
+		t.Fatalf("Expected a successful test to close the circuit. Received: %v", err)
+	}
+	if err := b.allow(ManagedIdentitySourceIMDS); err != nil {
+		t.Fatalf("Expected a closed circuit to allow concurrent requests. Received: %v", err)
+	}
+}
+
+func TestMSICircuitBreaker_IgnoresOtherFailures(t *testing.T) {
+	b := newMSICircuitBreaker(msiCircuitBreakerCooldown)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < msiCircuitBreakerThreshold; i++ {
+		b.done(ctx, context.Canceled)
+		b.done(context.Background(), &AuthenticationFailedError{msg: "identity not found"})
+	}
+	if err := b.allow(ManagedIdentitySourceIMDS); err != nil {
+		t.Fatalf("Expected cancelled requests and errors returned by a working endpoint not to open the circuit. Received: %v", err)
+	}
+}
+
+func TestManagedIdentityCredential_CircuitOpen(t *testing.T) {
+	err := resetEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unable to set environment variables")
+	}
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	testURL := srv.URL()
+	_ = os.Setenv("MSI_ENDPOINT", testURL.String())
+	defer os.Unsetenv("MSI_ENDPOINT")
+	key := msiCircuitBreakerKey(testURL)
+	msiCircuitBreakers.Lock()
+	msiCircuitBreakers.m[key] = openMSICircuitBreaker(t, msiCircuitBreakerCooldown)
+	msiCircuitBreakers.Unlock()
+	defer func() {
+		msiCircuitBreakers.Lock()
+		delete(msiCircuitBreakers.m, key)
+		msiCircuitBreakers.Unlock()
+	}()
+	cred, err := NewManagedIdentityCredential(clientID, &ManagedIdentityCredentialOptions{HTTPClient: srv})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{msiScope}})
+	var openErr *ManagedIdentityCircuitOpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("Expected a ManagedIdentityCircuitOpenError. Received: %v", err)
+	}
+	if openErr.Source != ManagedIdentitySourceCloudShell {
+		t.Fatalf("Unexpected source: %s", openErr.Source)
+	}
+	if srv.Requests() != 0 {
+		t.Fatalf("Expected no request to be sent while the circuit is open")
+	}
+}
+
+func TestMSIEndpointFailed(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusBadRequest))
+	for _, expected := range []bool{true, false} {
+		resp, err := azcore.NewPipeline(srv).Do(context.Background(), azcore.NewRequest(http.MethodGet, srv.URL()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if failed := msiEndpointFailed(&AuthenticationFailedError{inner: newAADAuthenticationFailedError(resp)}); failed != expected {
+			t.Fatalf("Expected msiEndpointFailed to return %v for status %d", expected, resp.StatusCode)
+		}
+	}
+}
@@ -38,6 +38,38 @@ const (
 	msiTypeUnavailable msiType = 4
 )
 
+// ManagedIdentitySource identifies the hosting environment that a ManagedIdentityCredential gets its tokens from.
+type ManagedIdentitySource string
+
+const (
+	// ManagedIdentitySourceUnknown is reported before the hosting environment has been detected.
+	ManagedIdentitySourceUnknown ManagedIdentitySource = "Unknown"
+	// ManagedIdentitySourceIMDS is the Azure Instance Metadata Service available on Azure VMs and scale sets.
+	ManagedIdentitySourceIMDS ManagedIdentitySource = "IMDS"
+	// ManagedIdentitySourceAppService is the managed identity endpoint of Azure App Service and Azure Functions.
+	ManagedIdentitySourceAppService ManagedIdentitySource = "AppService"
+	// ManagedIdentitySourceCloudShell is the managed identity endpoint of Azure Cloud Shell.
+	ManagedIdentitySourceCloudShell ManagedIdentitySource = "CloudShell"
+	// ManagedIdentitySourceUnavailable is reported when no managed identity environment was detected.
+	ManagedIdentitySourceUnavailable ManagedIdentitySource = "Unavailable"
+)
+
+// source returns the ManagedIdentitySource that corresponds to the msiType.
+func (m msiType) source() ManagedIdentitySource {
+	switch m {
+	case msiTypeIMDS:
+		return ManagedIdentitySourceIMDS
+	case msiTypeAppService:
+		return ManagedIdentitySourceAppService
+	case msiTypeCloudShell:
+		return ManagedIdentitySourceCloudShell
+	case msiTypeUnavailable:
+		return ManagedIdentitySourceUnavailable
+	default:
+		return ManagedIdentitySourceUnknown
+	}
+}
+
 // managedIdentityClient provides the base for authenticating in managed identity environments
 // This type includes an azcore.Pipeline and TokenCredentialOptions.
 type managedIdentityClient struct {
@@ -45,7 +77,13 @@ type managedIdentityClient struct {
 	imdsAPIVersion         string
 	imdsAvailableTimeoutMS time.Duration
 	msiType                msiType
+	msiReason              string // describes why msiType was selected
 	endpoint               *url.URL
+	cache                  *TokenCache
+	metrics                TokenMetrics
+	tracer                 azcore.Tracer
+	onTokenRefreshed       func(TokenRefreshedEvent)
+	refreshInterval        time.Duration // refresh tokens at least this often, when positive
 }
 
 type wrappedNumber json.Number
@@ -81,12 +119,25 @@ func newDefaultManagedIdentityOptions() *ManagedIdentityCredentialOptions {
 func newManagedIdentityClient(options *ManagedIdentityCredentialOptions) *managedIdentityClient {
 	logEnvVars()
 	options = options.setDefaultValues()
-	return &managedIdentityClient{
+	cache := options.TokenCache
+	if cache == nil {
+		cache = NewTokenCache(nil)
+	}
+	c := &managedIdentityClient{
 		pipeline:               newDefaultMSIPipeline(*options), // a pipeline that includes the specific requirements for MSI authentication, such as custom retry policy options
 		imdsAPIVersion:         imdsAPIVersion,                  // this field will be set to whatever value exists in the constant and is used when creating requests to IMDS
 		imdsAvailableTimeoutMS: 500,                             // we allow a timeout of 500 ms since the endpoint might be slow to respond
 		msiType:                msiTypeUnknown,                  // when creating a new managedIdentityClient, the current MSI type is unknown and will be tested for and replaced once authenticate() is called from GetToken on the credential side
+		cache:                  cache,
+		metrics:                options.Metrics,
+		tracer:                 options.Tracer,
+		onTokenRefreshed:       options.OnTokenRefreshed,
+		refreshInterval:        options.RefreshInterval,
+	}
+	if options.ProbeTimeout > 0 {
+		c.imdsAvailableTimeoutMS = options.ProbeTimeout / time.Millisecond
 	}
+	return c
 }
 
 // authenticate creates an authentication request for a Managed Identity and returns the resulting Access Token if successful.
@@ -111,24 +162,58 @@ func (c *managedIdentityClient) authenticate(ctx context.Context, clientID strin
 	return AT, nil
 }
 
+// sendAuthRequest requests a token from the managed identity endpoint unless the endpoint's circuit breaker is open.
 func (c *managedIdentityClient) sendAuthRequest(ctx context.Context, msiType msiType, clientID string, scopes []string) (*azcore.AccessToken, error) {
 	msg, err := c.createAuthRequest(msiType, clientID, scopes)
 	if err != nil {
 		return nil, err
 	}
+	breaker := msiCircuitBreakerFor(*msg.URL)
+	if err = breaker.allow(msiType.source()); err != nil {
+		return nil, err
+	}
+	tk, err := c.send(ctx, msg)
+	breaker.done(ctx, err)
+	return tk, err
+}
 
+func (c *managedIdentityClient) send(ctx context.Context, msg *azcore.Request) (*azcore.AccessToken, error) {
 	resp, err := c.pipeline.Do(ctx, msg)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.HasStatusCode(successStatusCodes[:]...) {
-		return c.createAccessToken(resp)
+		tk, err := c.createAccessToken(resp)
+		if err != nil {
+			return nil, err
+		}
+		c.setRefreshOn(tk, time.Now())
+		return tk, nil
 	}
 
 	return nil, &AuthenticationFailedError{inner: newAADAuthenticationFailedError(resp)}
 }
 
+// longLivedTokenLifetime is the shortest lifetime of a token that's refreshed halfway through its lifetime
+// when the service doesn't recommend when to refresh it.
+const longLivedTokenLifetime = 2 * time.Hour
+
+// setRefreshOn sets when the token acquired at the time should be refreshed. Long-lived tokens, which IMDS issues
+// for up to 24 hours, are refreshed halfway through their lifetime unless the service recommends another time, and
+// no token is used for longer than the credential's RefreshInterval so that changes to the identity, such as new
+// role assignments, take effect.
+func (c *managedIdentityClient) setRefreshOn(tk *azcore.AccessToken, acquired time.Time) {
+	if lifetime := tk.ExpiresOn.Sub(acquired); tk.RefreshOn.IsZero() && lifetime >= longLivedTokenLifetime {
+		tk.RefreshOn = acquired.Add(lifetime / 2).UTC()
+	}
+	if c.refreshInterval > 0 {
+		if next := acquired.Add(c.refreshInterval).UTC(); tk.RefreshOn.IsZero() || next.Before(tk.RefreshOn) {
+			tk.RefreshOn = next
+		}
+	}
+}
+
 func (c *managedIdentityClient) createAccessToken(res *azcore.Response) (*azcore.AccessToken, error) {
 	value := struct {
 		// these are the only fields that we use
@@ -136,6 +221,7 @@ func (c *managedIdentityClient) createAccessToken(res *azcore.Response) (*azcore
 		RefreshToken string        `json:"refresh_token,omitempty"`
 		ExpiresIn    wrappedNumber `json:"expires_in,omitempty"` // this field should always return the number of seconds for which a token is valid
 		ExpiresOn    string        `json:"expires_on,omitempty"` // the value returned in this field varies between a number and a date string
+		RefreshIn    wrappedNumber `json:"refresh_in,omitempty"` // the number of seconds after which the service recommends refreshing the token
 	}{}
 	if err := res.UnmarshalAsJSON(&value); err != nil {
 		return nil, fmt.Errorf("internal AccessToken: %w", err)
@@ -145,16 +231,16 @@ func (c *managedIdentityClient) createAccessToken(res *azcore.Response) (*azcore
 		if err != nil {
 			return nil, err
 		}
-		return &azcore.AccessToken{Token: value.Token, ExpiresOn: time.Now().Add(time.Second * time.Duration(expiresIn)).UTC()}, nil
+		return &azcore.AccessToken{Token: value.Token, ExpiresOn: time.Now().Add(time.Second * time.Duration(expiresIn)).UTC(), RefreshOn: refreshOn(json.Number(value.RefreshIn))}, nil
 	}
 	if expiresOn, err := strconv.Atoi(value.ExpiresOn); err == nil {
-		return &azcore.AccessToken{Token: value.Token, ExpiresOn: time.Now().Add(time.Second * time.Duration(expiresOn)).UTC()}, nil
+		return &azcore.AccessToken{Token: value.Token, ExpiresOn: time.Now().Add(time.Second * time.Duration(expiresOn)).UTC(), RefreshOn: refreshOn(json.Number(value.RefreshIn))}, nil
 	}
 	// this is the case when expires_on is a time string
 	// this is the format of the string coming from the service
 	if expiresOn, err := time.Parse("01/02/2006 15:04:05 PM +00:00", value.ExpiresOn); err == nil { // the date string specified in the layout param of time.Parse cannot be changed, Golang expects whatever layout to always signify January 2, 2006 at 3:04 PM
 		eo := expiresOn.UTC()
-		return &azcore.AccessToken{Token: value.Token, ExpiresOn: eo}, nil
+		return &azcore.AccessToken{Token: value.Token, ExpiresOn: eo, RefreshOn: refreshOn(json.Number(value.RefreshIn))}, nil
 	} else {
 		return nil, err
 	}
@@ -234,16 +320,22 @@ func (c *managedIdentityClient) getMSIType(ctx context.Context) (msiType, error)
 			c.endpoint = endpoint
 			if secretEnvVar := os.Getenv(msiSecretEnvironemntVariable); secretEnvVar != "" { // if BOTH the env vars MSI_ENDPOINT and MSI_SECRET are set the MsiType is AppService
 				c.msiType = msiTypeAppService
+				c.msiReason = "the MSI_ENDPOINT and MSI_SECRET environment variables are set"
 			} else { // if ONLY the env var MSI_ENDPOINT is set the MsiType is CloudShell
 				c.msiType = msiTypeCloudShell
+				c.msiReason = "the MSI_ENDPOINT environment variable is set and MSI_SECRET is not"
 			}
 		} else if c.imdsAvailable(ctx) { // if MSI_ENDPOINT is NOT set AND the IMDS endpoint is available the MsiType is Imds. This will timeout after 500 milliseconds
 			c.endpoint = imdsURL
 			c.msiType = msiTypeIMDS
+			c.msiReason = "the MSI_ENDPOINT environment variable is not set and the IMDS endpoint responded"
 		} else { // if MSI_ENDPOINT is NOT set and IMDS enpoint is not available ManagedIdentity is not available
 			c.msiType = msiTypeUnavailable
+			c.msiReason = fmt.Sprintf("the MSI_ENDPOINT environment variable is not set and the IMDS endpoint did not respond within %dms", c.imdsAvailableTimeoutMS)
+			azcore.Log().Write(LogCredential, logMSIEnv(c.msiType, c.msiReason))
 			return c.msiType, &CredentialUnavailableError{CredentialType: "Managed Identity Credential", Message: "Make sure you are running in a valid Managed Identity Environment"}
 		}
+		azcore.Log().Write(LogCredential, logMSIEnv(c.msiType, c.msiReason))
 	}
 	return c.msiType, nil
 }
@@ -6,6 +6,9 @@ package azidentity
 import (
 	"net/url"
 	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 )
 
 func TestIMDSEndpointParse(t *testing.T) {
@@ -18,3 +21,56 @@ func TestIMDSEndpointParse(t *testing.T) {
 // func TestNewDefaultMSIPipeline(t *testing.T) {
 // 	p := newDefaultMSIPipeline(ManagedIdentityCredentialOptions{})
 // }
+
+func TestMSITypeSource(t *testing.T) {
+	for msi, expected := range map[msiType]ManagedIdentitySource{
+		msiTypeUnknown:     ManagedIdentitySourceUnknown,
+		msiTypeIMDS:        ManagedIdentitySourceIMDS,
+		msiTypeAppService:  ManagedIdentitySourceAppService,
+		msiTypeCloudShell:  ManagedIdentitySourceCloudShell,
+		msiTypeUnavailable: ManagedIdentitySourceUnavailable,
+	} {
+		if s := msi.source(); s != expected {
+			t.Fatalf("expected %s for msiType %d, received %s", expected, msi, s)
+		}
+	}
+}
+
+func TestManagedIdentityClient_RefreshOn(t *testing.T) {
+	acquired := time.Now()
+	hint := acquired.Add(time.Hour)
+	for _, test := range []struct {
+		lifetime  time.Duration
+		refreshOn time.Time
+		interval  time.Duration
+		expected  time.Time
+	}{
+		// short-lived tokens without a hint are refreshed by the cache's RefreshOffset
+		{lifetime: time.Hour, expected: time.Time{}},
+		// long-lived tokens without a hint are refreshed halfway through their lifetime
+		{lifetime: 24 * time.Hour, expected: acquired.Add(12 * time.Hour)},
+		// the service's hint is honored
+		{lifetime: 24 * time.Hour, refreshOn: hint, expected: hint},
+		// RefreshInterval shortens the time until a refresh
+		{lifetime: 24 * time.Hour, interval: 30 * time.Minute, expected: acquired.Add(30 * time.Minute)},
+		{lifetime: 24 * time.Hour, refreshOn: hint, interval: 30 * time.Minute, expected: acquired.Add(30 * time.Minute)},
+		{lifetime: 24 * time.Hour, refreshOn: hint, interval: 2 * time.Hour, expected: hint},
+		{lifetime: time.Hour, interval: 30 * time.Minute, expected: acquired.Add(30 * time.Minute)},
+	} {
+		c := managedIdentityClient{refreshInterval: test.interval}
+		tk := &azcore.AccessToken{ExpiresOn: acquired.Add(test.lifetime), RefreshOn: test.refreshOn}
+		c.setRefreshOn(tk, acquired)
+		if !tk.RefreshOn.Equal(test.expected) {
+			t.Fatalf("Expected RefreshOn %v for %+v. Received: %v", test.expected, test, tk.RefreshOn)
+		}
+	}
+}
+
+func TestManagedIdentityClient_ProbeTimeout(t *testing.T) {
+	if c := newManagedIdentityClient(&ManagedIdentityCredentialOptions{}); c.imdsAvailableTimeoutMS != 500 {
+		t.Fatalf("Expected the default probe timeout. Received: %dms", c.imdsAvailableTimeoutMS)
+	}
+	if c := newManagedIdentityClient(&ManagedIdentityCredentialOptions{ProbeTimeout: 2 * time.Second}); c.imdsAvailableTimeoutMS != 2000 {
+		t.Fatalf("Expected a 2000ms probe timeout. Received: %dms", c.imdsAvailableTimeoutMS)
+	}
+}
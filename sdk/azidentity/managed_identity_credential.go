@@ -5,8 +5,8 @@ package azidentity
 
 import (
 	"context"
+	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -23,6 +23,49 @@ type ManagedIdentityCredentialOptions struct {
 
 	// Telemetry configures the built-in telemetry policy behavior.
 	Telemetry azcore.TelemetryOptions
+
+	// ApplicationID identifies the application at the start of the User-Agent of the credential's requests. It
+	// overrides Telemetry.ApplicationID and must be at most 24 characters without spaces.
+	ApplicationID string
+
+	// ConnectTimeout limits how long establishing a connection to the managed identity endpoint may take.
+	// It's ignored when HTTPClient is set. Defaults to the timeout of the default HTTP transport.
+	ConnectTimeout time.Duration
+
+	// TryTimeout limits how long each attempt of a token request, including reading the response, may take.
+	// Defaults to 1 minute.
+	TryTimeout time.Duration
+
+	// ProbeTimeout is how long the credential waits for the IMDS endpoint to respond when detecting whether it's
+	// available, which determines how quickly DefaultAzureCredential moves on outside Azure. Defaults to 500 milliseconds.
+	ProbeTimeout time.Duration
+
+	// RefreshInterval is the longest a token is used before it's refreshed, so that changes to the managed identity,
+	// such as new role assignments, reach long-running services sooner. By default, tokens are refreshed when the
+	// service recommends, or halfway through the lifetime of tokens lasting 2 hours or more.
+	RefreshInterval time.Duration
+
+	// PerCallPolicies are run once per token request, after the built-in telemetry and request ID policies.
+	PerCallPolicies []azcore.Policy
+
+	// PerRetryPolicies are run for each attempt of a token request, after the built-in retry policy.
+	PerRetryPolicies []azcore.Policy
+
+	// Pipeline replaces the credential's pipeline. When set, HTTPClient, LogOptions, Telemetry, PerCallPolicies and
+	// PerRetryPolicies are ignored.
+	Pipeline *azcore.Pipeline
+
+	// TokenCache stores the tokens acquired by the credential. Leave this as nil to give the credential a cache of its own.
+	TokenCache *TokenCache
+
+	// Metrics receives a measurement of each token request. Leave this as nil to record nothing.
+	Metrics TokenMetrics
+
+	// Tracer records each token request as a span in the caller's trace. Leave this as nil to record nothing.
+	Tracer azcore.Tracer
+
+	// OnTokenRefreshed is called with the metadata of each token the credential acquires. Leave this as nil to be notified of nothing.
+	OnTokenRefreshed func(TokenRefreshedEvent)
 }
 
 func (m *ManagedIdentityCredentialOptions) setDefaultValues() *ManagedIdentityCredentialOptions {
@@ -46,6 +89,11 @@ type ManagedIdentityCredential struct {
 // More information on user assigned managed identities cam be found here:
 // https://docs.microsoft.com/en-us/azure/active-directory/managed-identities-azure-resources/overview#how-a-user-assigned-managed-identity-works-with-an-azure-vm
 func NewManagedIdentityCredential(clientID string, options *ManagedIdentityCredentialOptions) (*ManagedIdentityCredential, error) {
+	if options != nil {
+		if err := validateApplicationID(options.ApplicationID); err != nil {
+			return nil, err
+		}
+	}
 	// Create a new Managed Identity Client with default options
 	client := newManagedIdentityClient(options)
 	// Create a context that will timeout after 500 milliseconds (that is the amount of time designated to find out if the IMDS endpoint is available)
@@ -64,29 +112,61 @@ func NewManagedIdentityCredential(clientID string, options *ManagedIdentityCrede
 	if len(clientID) == 0 {
 		clientID = os.Getenv("AZURE_CLIENT_ID")
 	}
-	return &ManagedIdentityCredential{clientID: clientID, client: client}, nil
+	cred := &ManagedIdentityCredential{clientID: clientID, client: client}
+	logCredentialCreated(cred, "client", clientID)
+	return cred, nil
+}
+
+// Source returns the managed identity hosting environment that was detected when the credential was created and
+// a description of why it was selected, to help diagnose managed identity problems.
+func (c *ManagedIdentityCredential) Source() (ManagedIdentitySource, string) {
+	return c.client.msiType.source(), c.client.msiReason
 }
 
 // GetToken obtains an AccessToken from the Managed Identity service if available.
 // scopes: The list of scopes for which the token will have access.
 // Returns an AccessToken which can be used to authenticate service client calls.
-func (c *ManagedIdentityCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
-	tk, err := c.client.authenticate(ctx, c.clientID, opts.Scopes)
+func (c *ManagedIdentityCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("Managed Identity Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tk, err := c.client.cache.getToken(ctx, tokenCacheKey("managed identity|"+c.clientID, "", opts), tokenTelemetry{metrics: c.client.metrics, tracer: c.client.tracer, onTokenRefreshed: c.client.onTokenRefreshed, credentialType: "ManagedIdentityCredential", scopes: opts.Scopes}, func(ctx context.Context) (*azcore.AccessToken, error) {
+		return c.client.authenticate(ctx, c.clientID, opts.Scopes)
+	})
 	if err != nil {
 		addGetTokenFailureLogs("Managed Identity Credential", err)
-		return nil, err
+		return azcore.AccessToken{}, err
 	}
 	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
-	azcore.Log().Write(LogCredential, logMSIEnv(c.client.msiType))
-	return tk, err
+	azcore.Log().Write(LogCredential, logMSIEnv(c.client.msiType, c.client.msiReason))
+	return *tk, nil
+}
+
+// Validate checks that the IMDS endpoint still responds, when the credential uses it, without requesting a token.
+// The endpoints of other hosting environments are found by the environment variables checked when the credential was created.
+func (c *ManagedIdentityCredential) Validate(ctx context.Context) error {
+	if c.client.msiType == msiTypeIMDS && !c.client.imdsAvailable(ctx) {
+		return &CredentialUnavailableError{CredentialType: "Managed Identity Credential", Message: fmt.Sprintf("the IMDS endpoint did not respond within %dms", c.client.imdsAvailableTimeoutMS)}
+	}
+	return nil
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *ManagedIdentityCredential) ClearCache() {
+	c.client.cache.clear("managed identity|" + c.clientID + "|")
 }
 
 // AuthenticationPolicy implements the azcore.Credential interface on ManagedIdentityCredential.
 // Please note: the TokenRequestOptions included in AuthenticationPolicyOptions must be a slice of resources in this case and not scopes
 func (c *ManagedIdentityCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
-	// The following code will remove the /.default suffix from any scopes passed into the method since ManagedIdentityCredentials expect a resource string instead of a scope string
-	for i := range options.Options.Scopes {
-		options.Options.Scopes[i] = strings.TrimSuffix(options.Options.Scopes[i], defaultSuffix)
+	// The following code will remove the /.default suffix from any scopes passed into the method since ManagedIdentityCredentials expect a resource string instead of a scope string.
+	// The resources are written to a new slice so that the caller's scopes are left as they are.
+	resources := make([]string, len(options.Options.Scopes))
+	for i, scope := range options.Options.Scopes {
+		resources[i] = ScopeToResource(scope)
 	}
+	options.Options.Scopes = resources
 	return newBearerTokenPolicy(c, options)
 }
@@ -5,9 +5,13 @@ package azidentity
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -34,6 +38,41 @@ func TestManagedIdentityCredential_GetTokenInCloudShellLive(t *testing.T) {
 	}
 }
 
+func TestManagedIdentityCredential_AuthenticationPolicyDoesNotModifyScopes(t *testing.T) {
+	err := resetEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unable to set environment variables")
+	}
+	_ = os.Setenv("MSI_ENDPOINT", "https://localhost/token")
+	defer os.Unsetenv("MSI_ENDPOINT")
+	var resource string
+	msiTransport := azcore.TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if err := req.ParseForm(); err != nil {
+			return nil, err
+		}
+		resource = req.PostForm.Get("resource")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(accessTokenRespSuccess)), Request: req}, nil
+	})
+	cred, err := NewManagedIdentityCredential("", &ManagedIdentityCredentialOptions{HTTPClient: msiTransport})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse()
+	scopes := []string{msiScope + "/.default"}
+	pipeline := azcore.NewPipeline(srv, cred.AuthenticationPolicy(azcore.AuthenticationPolicyOptions{Options: azcore.TokenRequestOptions{Scopes: scopes}}))
+	if _, err = pipeline.Do(context.Background(), azcore.NewRequest(http.MethodGet, srv.URL())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resource != msiScope {
+		t.Fatalf("Expected the policy to request the resource. Received: %s", resource)
+	}
+	if scopes[0] != msiScope+"/.default" {
+		t.Fatalf("Expected the caller's scopes to be unchanged. Received: %s", scopes[0])
+	}
+}
+
 func TestManagedIdentityCredential_GetTokenInCloudShellMock(t *testing.T) {
 	err := resetEnvironmentVarsForTest()
 	if err != nil {
@@ -95,6 +134,50 @@ func TestManagedIdentityCredential_GetTokenInAppServiceMock(t *testing.T) {
 	}
 }
 
+func TestManagedIdentityCredential_AppServiceSecretRedacted(t *testing.T) {
+	err := resetEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unable to set environment variables")
+	}
+	var mu sync.Mutex
+	var logged []string
+	azcore.Log().SetListener(func(cls azcore.LogClassification, msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, msg)
+	})
+	defer azcore.Log().SetListener(nil)
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(appServiceTokenSuccessResp)))
+	testURL := srv.URL()
+	_ = os.Setenv("MSI_ENDPOINT", testURL.String())
+	_ = os.Setenv("MSI_SECRET", "app-service-secret")
+	defer os.Unsetenv("MSI_SECRET")
+	msiCred, err := NewManagedIdentityCredential(clientID, &ManagedIdentityCredentialOptions{HTTPClient: srv})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = msiCred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{msiScope}}); err != nil {
+		t.Fatalf("Received an error when attempting to retrieve a token")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logged) == 0 {
+		t.Fatalf("Expected the request to be logged")
+	}
+	clientIDLogged := false
+	for _, msg := range logged {
+		if strings.Contains(msg, "app-service-secret") {
+			t.Fatalf("The managed identity secret was logged: %s", msg)
+		}
+		clientIDLogged = clientIDLogged || strings.Contains(msg, qpClientID+"="+clientID)
+	}
+	if !clientIDLogged {
+		t.Fatalf("Expected the client ID to be logged to identify the managed identity")
+	}
+}
+
 func TestManagedIdentityCredential_CreateAccessTokenExpiresOnInt(t *testing.T) {
 	err := resetEnvironmentVarsForTest()
 	if err != nil {
@@ -277,3 +360,72 @@ func TestManagedIdentityCredential_GetTokenEnvVar(t *testing.T) {
 		t.Fatalf("Did not receive the correct access token")
 	}
 }
+
+func TestManagedIdentityCredential_Source(t *testing.T) {
+	err := resetEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unable to set environment variables")
+	}
+	srv, close := mock.NewServer()
+	defer close()
+	_ = os.Unsetenv("MSI_SECRET")
+	testURL := srv.URL()
+	_ = os.Setenv("MSI_ENDPOINT", testURL.String())
+	msiCred, err := NewManagedIdentityCredential(clientID, &ManagedIdentityCredentialOptions{HTTPClient: srv})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	source, reason := msiCred.Source()
+	if source != ManagedIdentitySourceCloudShell {
+		t.Fatalf("expected source %s, received %s", ManagedIdentitySourceCloudShell, source)
+	}
+	if !strings.Contains(reason, "MSI_SECRET is not") {
+		t.Fatalf("unexpected reason: %s", reason)
+	}
+	_ = os.Setenv("MSI_SECRET", "secret")
+	defer os.Unsetenv("MSI_SECRET")
+	msiCred, err = NewManagedIdentityCredential(clientID, &ManagedIdentityCredentialOptions{HTTPClient: srv})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	source, reason = msiCred.Source()
+	if source != ManagedIdentitySourceAppService {
+		t.Fatalf("expected source %s, received %s", ManagedIdentitySourceAppService, source)
+	}
+	if !strings.Contains(reason, "MSI_SECRET environment variables are set") {
+		t.Fatalf("unexpected reason: %s", reason)
+	}
+}
+
+func TestManagedIdentityCredential_Validate(t *testing.T) {
+	err := resetEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unable to set environment variables")
+	}
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse()
+	testURL := srv.URL()
+	_ = os.Setenv("MSI_ENDPOINT", testURL.String())
+	defer os.Unsetenv("MSI_ENDPOINT")
+	msiCred, err := NewManagedIdentityCredential(clientID, &ManagedIdentityCredentialOptions{HTTPClient: srv})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = msiCred.Validate(context.Background()); err != nil {
+		t.Fatalf("Expected the Cloud Shell credential to be valid. Received: %v", err)
+	}
+	// the credential found IMDS when it was created, but it no longer responds
+	msiCred.client.msiType = msiTypeIMDS
+	srv.SetError(errors.New("IMDS is unreachable"))
+	var credErr *CredentialUnavailableError
+	if err = msiCred.Validate(context.Background()); !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError. Received: %v", err)
+	}
+}
+
+func TestManagedIdentityCredential_InvalidApplicationID(t *testing.T) {
+	if _, err := NewManagedIdentityCredential("", &ManagedIdentityCredentialOptions{ApplicationID: "my app"}); err == nil {
+		t.Fatalf("Expected an error for an application ID with a space")
+	}
+}
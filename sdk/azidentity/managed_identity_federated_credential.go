@@ -0,0 +1,101 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// tokenExchangeResource is the audience of the managed identity token that is presented as a client assertion.
+const tokenExchangeResource = "api://AzureADTokenExchange"
+
+// ManagedIdentityFederatedCredentialOptions contains parameters that can be used to configure a ManagedIdentityFederatedCredential.
+type ManagedIdentityFederatedCredentialOptions struct {
+	// TokenCredentialOptions configures the requests sent to Azure Active Directory for the App Registration.
+	TokenCredentialOptions
+
+	// ManagedIdentityClientID is the client ID of a user assigned managed identity.
+	// Leave this empty to use the system assigned managed identity.
+	ManagedIdentityClientID string
+
+	// ManagedIdentityOptions configures the pipeline for requests sent to the managed identity endpoint.
+	ManagedIdentityOptions *ManagedIdentityCredentialOptions
+}
+
+// ManagedIdentityFederatedCredential authenticates an App Registration, possibly in another tenant, using a token
+// obtained from a managed identity as the client assertion. The App Registration must have a federated identity credential
+// that trusts the managed identity. More information about federated identity credentials can be found here:
+// https://docs.microsoft.com/en-us/azure/active-directory/develop/workload-identity-federation
+type ManagedIdentityFederatedCredential struct {
+	client   *aadIdentityClient
+	msiCred  *ManagedIdentityCredential
+	tenantID string // The Azure Active Directory tenant (directory) ID of the App Registration
+	clientID string // The client (application) ID of the App Registration
+}
+
+// NewManagedIdentityFederatedCredential creates an instance of ManagedIdentityFederatedCredential.
+// tenantID: The Azure Active Directory tenant (directory) ID of the App Registration.
+// clientID: The client (application) ID of the App Registration.
+// options: configure the managed identity used for the assertion and the requests sent to Azure Active Directory.
+func NewManagedIdentityFederatedCredential(tenantID string, clientID string, options *ManagedIdentityFederatedCredentialOptions) (*ManagedIdentityFederatedCredential, error) {
+	if options == nil {
+		options = &ManagedIdentityFederatedCredentialOptions{}
+	}
+	msiCred, err := NewManagedIdentityCredential(options.ManagedIdentityClientID, options.ManagedIdentityOptions)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newAADIdentityClient(&options.TokenCredentialOptions)
+	if err != nil {
+		return nil, err
+	}
+	cred := &ManagedIdentityFederatedCredential{tenantID: tenantID, clientID: clientID, msiCred: msiCred, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
+}
+
+// GetToken obtains a token from Azure Active Directory, first acquiring a managed identity token for the token exchange
+// audience and then presenting it as the client assertion for the App Registration.
+// ctx: Context used to control the request lifetime.
+// opts: TokenRequestOptions contains the list of scopes for which the token will have access.
+// Returns an AccessToken which can be used to authenticate service client calls.
+func (c *ManagedIdentityFederatedCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("Managed Identity Federated Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tenantID, err := c.client.resolveTenant(c.tenantID, opts)
+	if err != nil {
+		addGetTokenFailureLogs("Managed Identity Federated Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tk, err := c.client.cache.getToken(ctx, c.client.cacheKey(c.clientID, tenantID, opts), c.client.telemetry("ManagedIdentityFederatedCredential", tenantID, opts.Scopes), func(ctx context.Context) (*azcore.AccessToken, error) {
+		assertion, err := c.msiCred.GetToken(ctx, azcore.TokenRequestOptions{Scopes: []string{tokenExchangeResource}})
+		if err != nil {
+			return nil, err
+		}
+		return c.client.authenticateAssertion(ctx, tenantID, c.clientID, assertion.Token, opts)
+	})
+	if err != nil {
+		addGetTokenFailureLogs("Managed Identity Federated Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
+	return *tk, nil
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *ManagedIdentityFederatedCredential) ClearCache() {
+	c.client.clearCache(c.clientID)
+}
+
+// AuthenticationPolicy implements the azcore.Credential interface on ManagedIdentityFederatedCredential.
+func (c *ManagedIdentityFederatedCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
+	return newBearerTokenPolicy(c, options)
+}
+
+var _ azcore.TokenCredential = (*ManagedIdentityFederatedCredential)(nil)
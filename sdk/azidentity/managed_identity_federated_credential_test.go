@@ -0,0 +1,77 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+const assertionTokenResp = `{"access_token": "mi_token", "expires_in": 3600}`
+
+func TestManagedIdentityFederatedCredential_GetTokenSuccess(t *testing.T) {
+	err := resetEnvironmentVarsForTest()
+	if err != nil {
+		t.Fatalf("Unable to set environment variables")
+	}
+	msiSrv, msiClose := mock.NewServer()
+	defer msiClose()
+	msiSrv.AppendResponse(mock.WithBody([]byte(assertionTokenResp)))
+	msiURL := msiSrv.URL()
+	_ = os.Setenv("MSI_ENDPOINT", msiURL.String())
+	defer os.Unsetenv("MSI_ENDPOINT")
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewManagedIdentityFederatedCredential(tenantID, clientID, &ManagedIdentityFederatedCredentialOptions{
+		TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true},
+		ManagedIdentityOptions: &ManagedIdentityCredentialOptions{HTTPClient: msiSrv},
+	})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	tk, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if tk.Token != tokenValue {
+		t.Fatalf("Received an unexpected value in azcore.AccessToken.Token")
+	}
+}
+
+func TestManagedIdentityFederatedCredential_CreateAuthRequest(t *testing.T) {
+	c, err := newAADIdentityClient(nil)
+	if err != nil {
+		t.Fatalf("Unable to create client. Received: %v", err)
+	}
+	req, err := c.createClientAssertionAuthRequest(tenantID, clientID, "mi_token", azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		t.Fatalf("Unable to read request body")
+	}
+	reqQueryParams, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Unable to parse query params in request")
+	}
+	if reqQueryParams.Get(qpClientAssertion) != "mi_token" {
+		t.Fatalf("Unexpected client assertion")
+	}
+	if reqQueryParams.Get(qpClientAssertionType) != clientAssertionType {
+		t.Fatalf("Unexpected client assertion type")
+	}
+	if req.Request.Method != http.MethodPost {
+		t.Fatalf("Unexpected request method")
+	}
+}
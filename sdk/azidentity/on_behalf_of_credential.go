@@ -0,0 +1,162 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// OnBehalfOfCredential enables a middle-tier application to authenticate to Azure Active Directory on behalf of the user whose access token
+// it received, using the on-behalf-of flow.  The middle-tier application authenticates itself with either a client secret or a certificate.
+// More information on the on-behalf-of flow can be found here:
+// https://docs.microsoft.com/en-us/azure/active-directory/develop/v2-oauth2-on-behalf-of-flow
+type OnBehalfOfCredential struct {
+	client            *aadIdentityClient
+	tenantID          string        // The Azure Active Directory tenant (directory) ID of the middle-tier application
+	clientID          string        // The client (application) ID of the middle-tier application
+	userAssertion     string        // The access token the middle-tier application received from its caller
+	clientSecret      string        // The client secret of the middle-tier application, empty when using a certificate
+	clientCertificate string        // Path to the client certificate of the middle-tier application, empty when using a secret or signer
+	thumbprint        fingerprint   // The SHA-1 thumbprint of the certificate whose private key is held by signer
+	certificate       []byte        // The DER encoding of the certificate whose private key is held by signer
+	signer            crypto.Signer // Signs client assertions when not nil
+}
+
+// NewOnBehalfOfCredentialWithSecret constructs a new OnBehalfOfCredential that authenticates the middle-tier application with a client secret.
+// tenantID: The Azure Active Directory tenant (directory) ID of the middle-tier application.
+// clientID: The client (application) ID of the middle-tier application.
+// userAssertion: The access token the middle-tier application received from its caller.
+// clientSecret: A client secret that was generated for the middle-tier application's App Registration.
+// options: configure the management of the requests sent to Azure Active Directory.
+func NewOnBehalfOfCredentialWithSecret(tenantID string, clientID string, userAssertion string, clientSecret string, options *TokenCredentialOptions) (*OnBehalfOfCredential, error) {
+	c, err := newAADIdentityClient(options)
+	if err != nil {
+		return nil, err
+	}
+	cred := &OnBehalfOfCredential{tenantID: tenantID, clientID: clientID, userAssertion: userAssertion, clientSecret: clientSecret, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
+}
+
+// NewOnBehalfOfCredentialWithCertificate constructs a new OnBehalfOfCredential that authenticates the middle-tier application with a certificate.
+// tenantID: The Azure Active Directory tenant (directory) ID of the middle-tier application.
+// clientID: The client (application) ID of the middle-tier application.
+// userAssertion: The access token the middle-tier application received from its caller.
+// clientCertificate: The path to the PEM file containing the certificate and private key assigned to the middle-tier application's App Registration.
+// options: configure the management of the requests sent to Azure Active Directory.
+func NewOnBehalfOfCredentialWithCertificate(tenantID string, clientID string, userAssertion string, clientCertificate string, options *TokenCredentialOptions) (*OnBehalfOfCredential, error) {
+	_, err := os.Stat(clientCertificate)
+	if err != nil {
+		credErr := &CredentialUnavailableError{CredentialType: "On Behalf Of Credential", Message: "Certificate file not found in path: " + clientCertificate}
+		azcore.Log().Write(azcore.LogError, logCredentialError(credErr.CredentialType, credErr))
+		return nil, credErr
+	}
+	c, err := newAADIdentityClient(options)
+	if err != nil {
+		return nil, err
+	}
+	cred := &OnBehalfOfCredential{tenantID: tenantID, clientID: clientID, userAssertion: userAssertion, clientCertificate: clientCertificate, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
+}
+
+// NewOnBehalfOfCredentialWithSigner constructs a new OnBehalfOfCredential that authenticates the middle-tier application with a certificate
+// whose private key is held by a crypto.Signer, such as a key stored in an HSM.
+// tenantID: The Azure Active Directory tenant (directory) ID of the middle-tier application.
+// clientID: The client (application) ID of the middle-tier application.
+// userAssertion: The access token the middle-tier application received from its caller.
+// certificate: The certificate assigned to the middle-tier application's App Registration.
+// signer: Holds the certificate's RSA private key and is used to sign client assertions.
+// options: configure the management of the requests sent to Azure Active Directory.
+func NewOnBehalfOfCredentialWithSigner(tenantID string, clientID string, userAssertion string, certificate *x509.Certificate, signer crypto.Signer, options *TokenCredentialOptions) (*OnBehalfOfCredential, error) {
+	if certificate == nil || signer == nil {
+		credErr := &CredentialUnavailableError{CredentialType: "On Behalf Of Credential", Message: "Certificate and signer cannot be nil"}
+		azcore.Log().Write(azcore.LogError, logCredentialError(credErr.CredentialType, credErr))
+		return nil, credErr
+	}
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		credErr := &CredentialUnavailableError{CredentialType: "On Behalf Of Credential", Message: "Signer must hold an RSA private key"}
+		azcore.Log().Write(azcore.LogError, logCredentialError(credErr.CredentialType, credErr))
+		return nil, credErr
+	}
+	c, err := newAADIdentityClient(options)
+	if err != nil {
+		return nil, err
+	}
+	cred := &OnBehalfOfCredential{tenantID: tenantID, clientID: clientID, userAssertion: userAssertion, thumbprint: certificateThumbprint(certificate.Raw), certificate: certificate.Raw, signer: signer, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
+}
+
+// GetToken obtains a token from Azure Active Directory on behalf of the user, using the middle-tier application's secret or certificate to authenticate.
+// ctx: Context used to control the request lifetime.
+// opts: TokenRequestOptions contains the list of scopes for which the token will have access.
+// Returns an AccessToken which can be used to authenticate service client calls.
+func (c *OnBehalfOfCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("On Behalf Of Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tenantID, err := c.client.resolveTenant(c.tenantID, opts)
+	if err != nil {
+		addGetTokenFailureLogs("On Behalf Of Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tk, err := c.client.cache.getToken(ctx, c.client.cacheKey(c.account(), tenantID, opts), c.client.telemetry("OnBehalfOfCredential", tenantID, opts.Scopes), func(ctx context.Context) (*azcore.AccessToken, error) {
+		clientAssertion, err := c.clientAssertion(tenantID)
+		if err != nil {
+			return nil, err
+		}
+		return c.client.authenticateOnBehalfOf(ctx, tenantID, c.clientID, c.userAssertion, c.clientSecret, clientAssertion, opts)
+	})
+	if err != nil {
+		addGetTokenFailureLogs("On Behalf Of Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
+	return *tk, nil
+}
+
+// account identifies the application and user in the token cache without storing the user's access token in the key.
+func (c *OnBehalfOfCredential) account() string {
+	sum := sha256.Sum256([]byte(c.userAssertion))
+	return c.clientID + "|" + hex.EncodeToString(sum[:])
+}
+
+// clientAssertion returns the signed JWT that authenticates the middle-tier application to the tenant, or an empty string when using a client secret.
+func (c *OnBehalfOfCredential) clientAssertion(tenantID string) (string, error) {
+	u := c.client.tokenURL(tenantID)
+	if c.signer != nil {
+		var chain [][]byte
+		if c.client.options.SendCertificateChain {
+			chain = [][]byte{c.certificate}
+		}
+		return signClientAssertionJWT(c.clientID, u.String(), c.thumbprint, chain, c.signer)
+	}
+	if len(c.clientCertificate) != 0 {
+		return createClientAssertionJWT(c.clientID, u.String(), c.clientCertificate, c.client.options.ClientCertificatePassword, c.client.options.SendCertificateChain)
+	}
+	return "", nil
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *OnBehalfOfCredential) ClearCache() {
+	c.client.clearCache(c.account())
+}
+
+// AuthenticationPolicy implements the azcore.Credential interface on OnBehalfOfCredential.
+func (c *OnBehalfOfCredential) AuthenticationPolicy(options azcore.AuthenticationPolicyOptions) azcore.Policy {
+	return newBearerTokenPolicy(c, options)
+}
+
+var _ azcore.TokenCredential = (*OnBehalfOfCredential)(nil)
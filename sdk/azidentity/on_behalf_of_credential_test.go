@@ -0,0 +1,164 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+const userAssertion = "user_assertion"
+
+func loadTestCertificate(t *testing.T) *x509.Certificate {
+	b, err := ioutil.ReadFile(certificatePath)
+	if err != nil {
+		t.Fatalf("Unable to read certificate: %v", err)
+	}
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			t.Fatalf("Certificate not found in %s", certificatePath)
+		}
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatalf("Unable to parse certificate: %v", err)
+			}
+			return cert
+		}
+	}
+}
+
+func readRequestParams(t *testing.T, req *azcore.Request) url.Values {
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		t.Fatalf("Unable to read request body")
+	}
+	reqQueryParams, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Unable to parse query params in request")
+	}
+	return reqQueryParams
+}
+
+func TestOnBehalfOfCredential_CreateAuthRequestSecret(t *testing.T) {
+	cred, err := NewOnBehalfOfCredentialWithSecret(tenantID, clientID, userAssertion, secret, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	req, err := cred.client.createOnBehalfOfAuthRequest(cred.tenantID, cred.clientID, cred.userAssertion, cred.clientSecret, "", azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	params := readRequestParams(t, req)
+	if params.Get(qpGrantType) != onBehalfOfGrantType {
+		t.Fatalf("Unexpected grant type")
+	}
+	if params.Get(qpRequestedTokenUse) != "on_behalf_of" {
+		t.Fatalf("Unexpected requested token use")
+	}
+	if params.Get(qpAssertion) != userAssertion {
+		t.Fatalf("Unexpected user assertion")
+	}
+	if params.Get(qpClientSecret) != secret {
+		t.Fatalf("Unexpected client secret")
+	}
+	if _, ok := params[qpClientAssertion]; ok {
+		t.Fatalf("Did not expect a client assertion")
+	}
+}
+
+func TestOnBehalfOfCredential_CreateAuthRequestCertificate(t *testing.T) {
+	cred, err := NewOnBehalfOfCredentialWithCertificate(tenantID, clientID, userAssertion, certificatePath, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	assertion, err := cred.clientAssertion(tenantID)
+	if err != nil {
+		t.Fatalf("Unable to create client assertion: %v", err)
+	}
+	req, err := cred.client.createOnBehalfOfAuthRequest(cred.tenantID, cred.clientID, cred.userAssertion, cred.clientSecret, assertion, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Unexpectedly received an error: %v", err)
+	}
+	params := readRequestParams(t, req)
+	if params.Get(qpClientAssertionType) != clientAssertionType {
+		t.Fatalf("Wrong client assertion type assigned to request")
+	}
+	if len(params.Get(qpClientAssertion)) == 0 {
+		t.Fatalf("Client assertion is not present on the request")
+	}
+	if _, ok := params[qpClientSecret]; ok {
+		t.Fatalf("Did not expect a client secret")
+	}
+}
+
+func TestOnBehalfOfCredential_SignerAssertionMatchesCertificate(t *testing.T) {
+	key, err := getPrivateKey(certificatePath, "")
+	if err != nil {
+		t.Fatalf("Unable to load private key: %v", err)
+	}
+	signerCred, err := NewOnBehalfOfCredentialWithSigner(tenantID, clientID, userAssertion, loadTestCertificate(t), key, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	fp, err := spkiFingerprint(certificatePath)
+	if err != nil {
+		t.Fatalf("Unable to compute fingerprint: %v", err)
+	}
+	if signerCred.thumbprint.String() != fp.String() {
+		t.Fatalf("Expected thumbprint %s but received %s", fp, signerCred.thumbprint)
+	}
+	if _, err = signerCred.clientAssertion(tenantID); err != nil {
+		t.Fatalf("Unable to create client assertion: %v", err)
+	}
+}
+
+func TestOnBehalfOfCredential_GetTokenSuccess(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewOnBehalfOfCredentialWithCertificate(tenantID, clientID, userAssertion, certificatePath, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	tk, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if tk.Token != tokenValue {
+		t.Fatalf("Received an unexpected value in azcore.AccessToken.Token")
+	}
+}
+
+func TestOnBehalfOfCredential_NilSigner(t *testing.T) {
+	if _, err := NewOnBehalfOfCredentialWithSigner(tenantID, clientID, userAssertion, nil, nil, nil); err == nil {
+		t.Fatalf("Expected an error for a nil certificate and signer")
+	}
+}
+
+func TestOnBehalfOfCredential_NonRSASigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewOnBehalfOfCredentialWithSigner(tenantID, clientID, userAssertion, loadTestCertificate(t), key, nil)
+	var credErr *CredentialUnavailableError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError for a non-RSA signer, got %v", err)
+	}
+}
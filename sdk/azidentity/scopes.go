@@ -0,0 +1,104 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// AzureService identifies a well-known Azure service whose scope depends on the cloud.
+type AzureService string
+
+// The services whose scopes are known to ServiceScope.
+const (
+	ServiceResourceManager AzureService = "ResourceManager"
+	ServiceStorage         AzureService = "Storage"
+	ServiceKeyVault        AzureService = "KeyVault"
+	ServiceGraph           AzureService = "Graph"
+)
+
+// serviceResources are the resource URIs of the well-known services, by the host of the cloud's authority.
+var serviceResources = map[string]map[AzureService]string{
+	"login.microsoftonline.com": {
+		ServiceResourceManager: "https://management.azure.com",
+		ServiceStorage:         "https://storage.azure.com",
+		ServiceKeyVault:        "https://vault.azure.net",
+		ServiceGraph:           "https://graph.microsoft.com",
+	},
+	"login.chinacloudapi.cn": {
+		ServiceResourceManager: "https://management.chinacloudapi.cn",
+		ServiceStorage:         "https://storage.azure.com",
+		ServiceKeyVault:        "https://vault.azure.cn",
+		ServiceGraph:           "https://microsoftgraph.chinacloudapi.cn",
+	},
+	"login.microsoftonline.us": {
+		ServiceResourceManager: "https://management.usgovcloudapi.net",
+		ServiceStorage:         "https://storage.azure.com",
+		ServiceKeyVault:        "https://vault.usgovcloudapi.net",
+		ServiceGraph:           "https://graph.microsoft.us",
+	},
+	"login.microsoftonline.de": {
+		ServiceResourceManager: "https://management.microsoftazure.de",
+		ServiceStorage:         "https://storage.azure.com",
+		ServiceKeyVault:        "https://vault.microsoftazure.de",
+		ServiceGraph:           "https://graph.microsoft.de",
+	},
+}
+
+// ServiceScope returns the ".default" scope of a well-known service in the cloud whose authority host is given,
+// for example "https://management.usgovcloudapi.net/.default" for ServiceResourceManager in AzureGovernment.
+// Use it instead of hard-coding public cloud scopes, which Azure Active Directory rejects in the other clouds.
+// authorityHost: one of the KnownAuthorityHosts, such as the AuthorityHost of a credential's options.
+// service: the service the token is requested for.
+func ServiceScope(authorityHost string, service AzureService) (string, error) {
+	u, err := url.Parse(authorityHost)
+	if err != nil {
+		return "", err
+	}
+	resources, ok := serviceResources[strings.ToLower(u.Host)]
+	if !ok {
+		return "", fmt.Errorf("the scopes of the cloud with authority host %s aren't known", authorityHost)
+	}
+	resource, ok := resources[service]
+	if !ok {
+		return "", fmt.Errorf("the scope of service %q isn't known", service)
+	}
+	return ResourceToScope(resource), nil
+}
+
+// ResourceToScope returns the ".default" scope for a resource URI, such as "https://vault.azure.net/.default"
+// for "https://vault.azure.net". The scope requests all of the permissions the application has for the resource.
+// A resource URI that is already a ".default" scope is returned unchanged.
+func ResourceToScope(resource string) string {
+	if strings.HasSuffix(resource, defaultSuffix) {
+		return resource
+	}
+	return resource + defaultSuffix
+}
+
+// ScopeToResource returns the resource URI of a ".default" scope, such as "https://vault.azure.net" for
+// "https://vault.azure.net/.default". Other scopes are returned unchanged.
+func ScopeToResource(scope string) string {
+	return strings.TrimSuffix(scope, defaultSuffix)
+}
+
+// validateScopes returns a descriptive error for scopes Azure Active Directory would reject with an invalid_scope error.
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required in TokenRequestOptions.Scopes")
+	}
+	for _, scope := range scopes {
+		if strings.TrimSpace(scope) == "" {
+			return errors.New("TokenRequestOptions.Scopes contains an empty scope")
+		}
+		if strings.IndexFunc(scope, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("the scope %q contains whitespace, pass each scope as a separate element of TokenRequestOptions.Scopes", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+func TestResourceToScope(t *testing.T) {
+	if s := ResourceToScope("https://vault.azure.net"); s != "https://vault.azure.net/.default" {
+		t.Fatalf("Unexpected scope: %s", s)
+	}
+	if s := ResourceToScope("https://management.azure.com/"); s != "https://management.azure.com//.default" {
+		t.Fatalf("Unexpected scope: %s", s)
+	}
+	if s := ResourceToScope("https://vault.azure.net/.default"); s != "https://vault.azure.net/.default" {
+		t.Fatalf("Expected a scope to be returned unchanged. Received: %s", s)
+	}
+}
+
+func TestScopeToResource(t *testing.T) {
+	if r := ScopeToResource("https://vault.azure.net/.default"); r != "https://vault.azure.net" {
+		t.Fatalf("Unexpected resource: %s", r)
+	}
+	if r := ScopeToResource(ResourceToScope("https://management.azure.com/")); r != "https://management.azure.com/" {
+		t.Fatalf("Expected the resource to round trip. Received: %s", r)
+	}
+	if r := ScopeToResource("User.Read"); r != "User.Read" {
+		t.Fatalf("Expected a scope without the .default suffix to be returned unchanged. Received: %s", r)
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	if err := validateScopes([]string{scope, "offline_access"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, scopes := range [][]string{nil, {""}, {" "}, {scope, ""}, {scope + " offline_access"}, {"https://vault.azure.net\t/.default"}} {
+		if err := validateScopes(scopes); err == nil {
+			t.Fatalf("Expected an error for scopes %q", scopes)
+		}
+	}
+}
+
+func TestClientSecretCredential_InvalidScope(t *testing.T) {
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	// the credential has no HTTP client, so a request to AAD would fail differently
+	_, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{"https://vault.azure.net/.default offline_access"}})
+	if err == nil || err.Error() != `the scope "https://vault.azure.net/.default offline_access" contains whitespace, pass each scope as a separate element of TokenRequestOptions.Scopes` {
+		t.Fatalf("Expected a descriptive error for the invalid scope. Received: %v", err)
+	}
+}
+
+func TestAzureCLICredential_NoScopes(t *testing.T) {
+	cred, err := NewAzureCLICredential(nil)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{}); err == nil {
+		t.Fatalf("Expected an error when no scopes are requested")
+	}
+}
+
+func TestServiceScope(t *testing.T) {
+	for _, c := range []struct {
+		authorityHost string
+		service       AzureService
+		scope         string
+	}{
+		{AzurePublicCloud, ServiceResourceManager, "https://management.azure.com/.default"},
+		{AzureChina, ServiceKeyVault, "https://vault.azure.cn/.default"},
+		{AzureGovernment, ServiceGraph, "https://graph.microsoft.us/.default"},
+		{AzureGermany, ServiceStorage, "https://storage.azure.com/.default"},
+		{"https://LOGIN.microsoftonline.us", ServiceResourceManager, "https://management.usgovcloudapi.net/.default"},
+	} {
+		scope, err := ServiceScope(c.authorityHost, c.service)
+		if err != nil || scope != c.scope {
+			t.Fatalf("Expected %s for %s in %s. Received: %s, %v", c.scope, c.service, c.authorityHost, scope, err)
+		}
+	}
+	if _, err := ServiceScope("https://login.contoso.com/", ServiceGraph); err == nil {
+		t.Fatalf("Expected an error for an unknown cloud")
+	}
+	if _, err := ServiceScope(AzurePublicCloud, AzureService("Unknown")); err == nil {
+		t.Fatalf("Expected an error for an unknown service")
+	}
+}
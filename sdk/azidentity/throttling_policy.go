@@ -0,0 +1,68 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// defaultThrottlingBackoff is how long requests to an authority host are held back after it throttles a request
+// without saying when to try again.
+const defaultThrottlingBackoff = 5 * time.Second
+
+// authorityBackoffs are the times until which token requests to each authority host are held back because the
+// host throttled a request, shared by every credential in the process.
+var authorityBackoffs = struct {
+	sync.Mutex
+	until map[string]time.Time
+}{until: map[string]time.Time{}}
+
+// backoffUntil returns when requests to the authority host may be sent again, the zero time when they needn't wait.
+func backoffUntil(host string) time.Time {
+	authorityBackoffs.Lock()
+	defer authorityBackoffs.Unlock()
+	return authorityBackoffs.until[strings.ToLower(host)]
+}
+
+// backOff holds back requests to the authority host until the time, unless they're already held back for longer.
+func backOff(host string, until time.Time) {
+	authorityBackoffs.Lock()
+	defer authorityBackoffs.Unlock()
+	host = strings.ToLower(host)
+	if until.After(authorityBackoffs.until[host]) {
+		authorityBackoffs.until[host] = until
+	}
+}
+
+// newThrottlingPolicy returns a policy that holds back token requests while the authority host is throttling
+// requests, so that a burst of requests from several credentials doesn't prolong the throttling. The backoff
+// lasts for the Retry-After duration of the last 429 response, or defaultThrottlingBackoff when it has none.
+// The policy follows the retry policy so that it applies to each try.
+func newThrottlingPolicy() azcore.Policy {
+	return azcore.PolicyFunc(func(ctx context.Context, req *azcore.Request) (*azcore.Response, error) {
+		if wait := time.Until(backoffUntil(req.URL.Host)); wait > 0 {
+			azcore.Log().Write(LogCredential, "Azure Identity => "+req.URL.Host+" is throttling token requests, waiting "+wait.String())
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		resp, err := req.Next(ctx)
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			delay := azcore.RetryAfter(resp.Response)
+			if delay <= 0 {
+				delay = defaultThrottlingBackoff
+			}
+			backOff(req.URL.Host, time.Now().Add(delay))
+		}
+		return resp, err
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestThrottlingPolicy_RetryAfter(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusTooManyRequests), mock.WithHeader(azcore.HeaderRetryAfter, "1"))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	start := time.Now()
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected the throttled request to be retried. Received: %v", err)
+	}
+	if time.Since(start) < time.Second {
+		t.Fatalf("Expected the retry to wait for the Retry-After duration")
+	}
+	if time.Until(backoffUntil(srvURL.Host)) > 0 {
+		t.Fatalf("Expected the backoff to have elapsed")
+	}
+	// the backoff is shared with other credentials using the authority host
+	backOff(srvURL.Host, time.Now().Add(200*time.Millisecond))
+	other, err := NewClientSecretCredential(tenantID, "other-client", secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	start = time.Now()
+	if _, err = other.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if time.Since(start) < 150*time.Millisecond {
+		t.Fatalf("Expected the request to wait for the authority host's backoff")
+	}
+}
+
+func TestThrottlingPolicy_ContextDone(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	backOff(srvURL.Host, time.Now().Add(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := azcore.NewPipeline(srv, newThrottlingPolicy()).Do(ctx, azcore.NewRequest(http.MethodPost, srvURL))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected waiting for the backoff to stop when the context is done. Received: %v", err)
+	}
+	if srv.Requests() != 0 {
+		t.Fatalf("Expected no request to be sent during the backoff")
+	}
+}
+
+func TestThrottlingPolicy_DefaultBackoff(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusTooManyRequests))
+	srvURL := srv.URL()
+	if _, err := azcore.NewPipeline(srv, newThrottlingPolicy()).Do(context.Background(), azcore.NewRequest(http.MethodPost, srvURL)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if wait := time.Until(backoffUntil(srvURL.Host)); wait <= defaultThrottlingBackoff-time.Second || wait > defaultThrottlingBackoff {
+		t.Fatalf("Expected the default backoff without a Retry-After header. Received: %v", wait)
+	}
+}
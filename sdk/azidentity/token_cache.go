@@ -0,0 +1,590 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const (
+	// tokenRefreshOffset is how long before its expiration a cached token is refreshed by default.
+	tokenRefreshOffset = 5 * time.Minute
+	// tokenCacheVersion is the version of the format written by TokenCache.Export.
+	tokenCacheVersion = 1
+	// backgroundRefreshJitter is the most a background refresh is moved ahead of the refresh offset so that
+	// instances of an application which acquired tokens together don't refresh them together.
+	backgroundRefreshJitter = time.Minute
+	// backgroundRefreshRetry is how long a failed background refresh waits before trying again.
+	backgroundRefreshRetry = 30 * time.Second
+	// backgroundRefreshTimeout bounds a single background refresh.
+	backgroundRefreshTimeout = time.Minute
+)
+
+// refreshJitter returns a random duration in [0, max); tests replace it to make refreshes predictable.
+var refreshJitter = func(max time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// TokenCacheOptions configure a TokenCache.
+type TokenCacheOptions struct {
+	// OnChange is called with the exported contents of the cache after a token is added to it, so that
+	// the application can save them in its own secure store. Errors it returns are logged.
+	OnChange func(ctx context.Context, data []byte) error
+
+	// Distributed shares tokens between the instances of an application, such as the servers of a web farm.
+	// Tokens missing from memory are looked up in it before they are requested, and acquired tokens are added to it.
+	Distributed DistributedCache
+
+	// BackgroundRefresh renews cached tokens in the background shortly before they're refreshed on demand, so that
+	// GetToken doesn't block on a request to Azure Active Directory or IMDS while a token is in use.
+	// Call Close to stop refreshing when the cache is no longer needed.
+	BackgroundRefresh bool
+
+	// RefreshOffset is how long before it expires a cached token is refreshed. Defaults to 5 minutes.
+	// Tokens whose RefreshOn is set are refreshed at that time instead, as recommended by the service.
+	// Set a negative value to use cached tokens until they expire, for example when tokens are very short-lived.
+	RefreshOffset time.Duration
+
+	// ClockSkew is how far the host's clock may be behind the clock of the token issuer. Tokens are treated as
+	// expiring this much earlier than their expiration time says. Defaults to zero.
+	ClockSkew time.Duration
+
+	// MaxEntries is the most tokens the cache holds. When it's full, the least recently used token is evicted to
+	// make room for a new one. Size it from the counters returned by Stats. Defaults to zero, which means no limit.
+	MaxEntries int
+}
+
+// TokenCacheStats are counters describing the use of a TokenCache, for sizing it.
+type TokenCacheStats struct {
+	// Entries is the number of tokens in the cache.
+	Entries int
+	// Hits is the number of requests for a token served from memory.
+	Hits uint64
+	// Misses is the number of requests for a token which wasn't in memory or was due for refresh.
+	Misses uint64
+	// Evictions is the number of tokens evicted to make room for others because the cache was full.
+	Evictions uint64
+}
+
+// DistributedCache is a cache shared by the instances of an application, for example one backed by Redis or memcached.
+// Values contain access tokens, so the cache must be secured accordingly. Errors are logged and otherwise ignored.
+type DistributedCache interface {
+	// Get returns the value for the key in the partition, or nil when there isn't one.
+	Get(ctx context.Context, partition string, key string) ([]byte, error)
+	// Set stores the value for the key in the partition. The value can be evicted once ttl elapses.
+	Set(ctx context.Context, partition string, key string, value []byte, ttl time.Duration) error
+}
+
+// TokenCache is a thread-safe in-memory cache of the access tokens acquired by credentials.
+// Each credential has its own cache unless one is set in its options. Tokens are partitioned by the credential's
+// client ID and tenant, and by user for credentials that know the user up front, so share a cache between device
+// code credentials for the same client only when they sign in the same user. A nil *TokenCache is valid and caches nothing.
+//
+// Concurrent requests for the same token are served by a single request to Azure Active Directory or IMDS. While a
+// cached token that's due to be refreshed hasn't expired, exactly one request refreshes it and the others use the
+// cached token without waiting. When the cached token has expired, or there isn't one, the others wait for the
+// refresh and share its result. A request whose context is done stops waiting, and when the refreshing request's
+// context is done, a waiting request refreshes the token in its place.
+type TokenCache struct {
+	mu          sync.Mutex
+	tokens      map[string]azcore.AccessToken
+	onChange    func(ctx context.Context, data []byte) error
+	distributed DistributedCache
+	background  bool
+	refreshers  map[string]*time.Timer
+	closed      bool
+	inflight    map[string]*inflight
+	// refreshOffset is how long before its expiration, adjusted for clock skew, a token is refreshed
+	refreshOffset time.Duration
+	clockSkew     time.Duration
+	// maxEntries is the most tokens the cache holds when greater than zero
+	maxEntries int
+	// lru orders the keys of tokens from most to least recently used
+	lru     *list.List
+	lruKeys map[string]*list.Element
+	stats   TokenCacheStats
+	// cleared holds when the tokens whose keys begin with each prefix were cleared, so that older tokens
+	// in the distributed cache are ignored
+	cleared map[string]time.Time
+}
+
+// NewTokenCache creates an empty TokenCache. Use Import to hydrate it with the contents of another cache.
+// options: Configures the cache, pass nil to accept the default values.
+func NewTokenCache(options *TokenCacheOptions) *TokenCache {
+	c := &TokenCache{tokens: map[string]azcore.AccessToken{}, refreshers: map[string]*time.Timer{}, inflight: map[string]*inflight{}, refreshOffset: tokenRefreshOffset,
+		lru: list.New(), lruKeys: map[string]*list.Element{}, cleared: map[string]time.Time{}}
+	if options != nil {
+		c.onChange = options.OnChange
+		c.distributed = options.Distributed
+		c.background = options.BackgroundRefresh
+		if options.RefreshOffset < 0 {
+			c.refreshOffset = 0
+		} else if options.RefreshOffset > 0 {
+			c.refreshOffset = options.RefreshOffset
+		}
+		if options.ClockSkew > 0 {
+			c.clockSkew = options.ClockSkew
+		}
+		if options.MaxEntries > 0 {
+			c.maxEntries = options.MaxEntries
+		}
+	}
+	return c
+}
+
+// refreshAt returns when the token should be refreshed, preferring the time recommended by the service.
+func (c *TokenCache) refreshAt(tk azcore.AccessToken) time.Time {
+	if !tk.RefreshOn.IsZero() && tk.RefreshOn.Before(c.expiresAt(tk)) {
+		return tk.RefreshOn
+	}
+	return tk.ExpiresOn.Add(-c.refreshOffset - c.clockSkew)
+}
+
+// expiresAt returns when the token expires, allowing for clock skew.
+func (c *TokenCache) expiresAt(tk azcore.AccessToken) time.Time {
+	return tk.ExpiresOn.Add(-c.clockSkew)
+}
+
+// Stats returns the counters of the cache.
+func (c *TokenCache) Stats() TokenCacheStats {
+	if c == nil {
+		return TokenCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.tokens)
+	return stats
+}
+
+// get returns the token for the key and marks it as the most recently used. c.mu must be held.
+func (c *TokenCache) get(key string) (azcore.AccessToken, bool) {
+	tk, ok := c.tokens[key]
+	if ok {
+		c.touch(key)
+	}
+	return tk, ok
+}
+
+// set stores the token for the key, evicting the least recently used tokens when the cache is full. c.mu must be held.
+func (c *TokenCache) set(key string, tk azcore.AccessToken) {
+	c.tokens[key] = tk
+	c.touch(key)
+	c.evict()
+}
+
+// touch marks the key as the most recently used. c.mu must be held.
+func (c *TokenCache) touch(key string) {
+	if e, ok := c.lruKeys[key]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.lruKeys[key] = c.lru.PushFront(key)
+}
+
+// evict removes the least recently used tokens, and stops refreshing them, until the cache isn't over its
+// maximum size. c.mu must be held.
+func (c *TokenCache) evict() {
+	if c.maxEntries <= 0 {
+		return
+	}
+	for len(c.tokens) > c.maxEntries {
+		e := c.lru.Back()
+		if e == nil {
+			return
+		}
+		key := c.lru.Remove(e).(string)
+		delete(c.lruKeys, key)
+		if _, ok := c.tokens[key]; !ok {
+			continue
+		}
+		delete(c.tokens, key)
+		if t, ok := c.refreshers[key]; ok {
+			t.Stop()
+			delete(c.refreshers, key)
+		}
+		c.stats.Evictions++
+	}
+}
+
+// Close stops refreshing tokens in the background. Tokens already in the cache remain available.
+func (c *TokenCache) Close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for k, t := range c.refreshers {
+		t.Stop()
+		delete(c.refreshers, k)
+	}
+}
+
+// Clear removes every token from the cache and stops refreshing them, so that they're acquired again, for example
+// after the application learns that tokens were revoked or an identity's role assignments changed. Tokens added to
+// the Distributed cache before Clear are ignored from then on, but other processes keep the tokens in their memory.
+func (c *TokenCache) Clear() {
+	c.clear("")
+}
+
+// clear removes the tokens whose keys begin with prefix and stops refreshing them.
+func (c *TokenCache) clear(prefix string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.tokens {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		delete(c.tokens, key)
+		if e, ok := c.lruKeys[key]; ok {
+			c.lru.Remove(e)
+			delete(c.lruKeys, key)
+		}
+		if t, ok := c.refreshers[key]; ok {
+			t.Stop()
+			delete(c.refreshers, key)
+		}
+	}
+	c.cleared[prefix] = time.Now()
+}
+
+// clearedAt returns when the token for the key was last cleared, or the zero time when it never was. c.mu must be held.
+func (c *TokenCache) clearedAt(key string) time.Time {
+	var t time.Time
+	for prefix, cleared := range c.cleared {
+		if strings.HasPrefix(key, prefix) && cleared.After(t) {
+			t = cleared
+		}
+	}
+	return t
+}
+
+// serializedTokenCache is the format written by TokenCache.Export.
+type serializedTokenCache struct {
+	Version      int                              `json:"version"`
+	AccessTokens map[string]serializedAccessToken `json:"access_tokens"`
+}
+
+type serializedAccessToken struct {
+	Token     string `json:"token"`
+	ExpiresOn int64  `json:"expires_on"`
+	RefreshOn int64  `json:"refresh_on,omitempty"`
+	// AcquiredOn is when the token was added to the distributed cache
+	AcquiredOn int64 `json:"acquired_on,omitempty"`
+}
+
+func newSerializedAccessToken(tk azcore.AccessToken) serializedAccessToken {
+	s := serializedAccessToken{Token: tk.Token, ExpiresOn: tk.ExpiresOn.Unix()}
+	if !tk.RefreshOn.IsZero() {
+		s.RefreshOn = tk.RefreshOn.Unix()
+	}
+	return s
+}
+
+func (s serializedAccessToken) accessToken() azcore.AccessToken {
+	tk := azcore.AccessToken{Token: s.Token, ExpiresOn: time.Unix(s.ExpiresOn, 0)}
+	if s.RefreshOn != 0 {
+		tk.RefreshOn = time.Unix(s.RefreshOn, 0)
+	}
+	return tk
+}
+
+// Export returns the unexpired tokens in the cache. The data contains access tokens and must be stored securely.
+func (c *TokenCache) Export() ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("the token cache is nil")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.export()
+}
+
+// export returns the unexpired tokens in the cache. c.mu must be held.
+func (c *TokenCache) export() ([]byte, error) {
+	s := serializedTokenCache{Version: tokenCacheVersion, AccessTokens: map[string]serializedAccessToken{}}
+	now := time.Now()
+	for k, tk := range c.tokens {
+		if tk.ExpiresOn.After(now) {
+			s.AccessTokens[k] = newSerializedAccessToken(tk)
+		}
+	}
+	return json.Marshal(s)
+}
+
+// Import replaces the contents of the cache with data returned by Export, which may come from another process or host.
+func (c *TokenCache) Import(data []byte) error {
+	if c == nil {
+		return errors.New("the token cache is nil")
+	}
+	var s serializedTokenCache
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s.Version != tokenCacheVersion {
+		return errors.New("unsupported token cache version")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = make(map[string]azcore.AccessToken, len(s.AccessTokens))
+	c.lru.Init()
+	c.lruKeys = make(map[string]*list.Element, len(s.AccessTokens))
+	for k, tk := range s.AccessTokens {
+		c.set(k, tk.accessToken())
+	}
+	return nil
+}
+
+// cacheKey identifies a token in a TokenCache.
+type cacheKey struct {
+	partition string // the account and tenant the token was issued for
+	id        string // the claims, CAE support and scopes of the token
+}
+
+func (k cacheKey) String() string {
+	return k.partition + "|" + k.id
+}
+
+// tokenCacheKey returns the key that identifies tokens for the account, tenant and token request.
+// The account distinguishes credentials sharing a cache. The order of the scopes doesn't affect the key.
+func tokenCacheKey(account string, tenantID string, opts azcore.TokenRequestOptions) cacheKey {
+	sorted := make([]string, len(opts.Scopes))
+	copy(sorted, opts.Scopes)
+	sort.Strings(sorted)
+	id := opts.Claims + "|" + strings.Join(sorted, " ")
+	if opts.EnableCAE {
+		id = "cae|" + id
+	}
+	return cacheKey{partition: account + "|" + tenantID, id: id}
+}
+
+// getToken returns the cached token for the key unless it's due for refresh, in which case acquire is called
+// and its token is cached. When acquire fails the cached token is returned if it hasn't expired yet.
+func (c *TokenCache) getToken(ctx context.Context, key cacheKey, t tokenTelemetry, acquire func(context.Context) (*azcore.AccessToken, error)) (*azcore.AccessToken, error) {
+	ctx, r := t.start(ctx, false)
+	now := time.Now()
+	if c == nil {
+		tk, err := acquire(ctx)
+		if err != nil {
+			err = withCredentialType(err, t.credentialType)
+			r.end(TokenRequestFailed, false, nil, err)
+			return nil, err
+		}
+		r.end(TokenRequestAcquired, false, tk, nil)
+		return tk, nil
+	}
+	c.mu.Lock()
+	cached, ok := c.get(key.String())
+	// while another request refreshes a token that hasn't expired, use it rather than wait for the refresh
+	fresh := ok && (c.refreshAt(cached).After(now) || (c.inflight[key.String()] != nil && c.expiresAt(cached).After(now)))
+	if fresh {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	c.mu.Unlock()
+	if fresh {
+		r.end(TokenRequestCacheHit, false, nil, nil)
+		return &cached, nil
+	}
+	if tk := c.getDistributed(ctx, key); tk != nil && c.refreshAt(*tk).After(now) {
+		c.mu.Lock()
+		c.set(key.String(), *tk)
+		c.mu.Unlock()
+		r.end(TokenRequestCacheHit, false, nil, nil)
+		return tk, nil
+	}
+	tk, shared, err := c.acquireOnce(ctx, key, t, acquire)
+	if err != nil {
+		r.end(TokenRequestFailed, shared, nil, err)
+		if ok && c.expiresAt(cached).After(now) {
+			azcore.Log().Write(LogCredential, "Azure Identity => Token refresh failed, using the cached token until it expires: "+err.Error())
+			return &cached, nil
+		}
+		return nil, err
+	}
+	if ok {
+		r.end(TokenRequestRefreshed, shared, tk, nil)
+	} else {
+		r.end(TokenRequestAcquired, shared, tk, nil)
+	}
+	return tk, nil
+}
+
+// inflight is a token acquisition shared by concurrent requests for the same token.
+type inflight struct {
+	done chan struct{}
+	tk   *azcore.AccessToken
+	err  error
+	// canceled is true when the acquisition failed because the context of the request that started it was done
+	canceled bool
+}
+
+// acquireOnce calls acquire and caches its token, unless another request for the key is already doing so, in which
+// case it waits for and shares that request's result. This prevents a burst of requests for a token that isn't
+// cached from sending a burst of requests to Azure Active Directory. When the context of the request acquiring the
+// token is done, the requests waiting for it acquire the token again. shared is true when the result came from another request.
+func (c *TokenCache) acquireOnce(ctx context.Context, key cacheKey, t tokenTelemetry, acquire func(context.Context) (*azcore.AccessToken, error)) (tk *azcore.AccessToken, shared bool, err error) {
+	c.mu.Lock()
+	if f, ok := c.inflight[key.String()]; ok {
+		// another request started refreshing the token after this one checked the cache
+		if cached, ok := c.get(key.String()); ok && c.expiresAt(cached).After(time.Now()) {
+			c.mu.Unlock()
+			return &cached, true, nil
+		}
+		c.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		if f.canceled {
+			return c.acquireOnce(ctx, key, t, acquire)
+		}
+		if f.err != nil {
+			return nil, true, f.err
+		}
+		shared := *f.tk
+		return &shared, true, nil
+	}
+	// a request which acquired the token may have finished after this one checked the cache
+	if cached, ok := c.get(key.String()); ok && c.refreshAt(cached).After(time.Now()) {
+		c.mu.Unlock()
+		return &cached, false, nil
+	}
+	f := &inflight{done: make(chan struct{})}
+	c.inflight[key.String()] = f
+	c.mu.Unlock()
+
+	f.tk, f.err = acquire(ctx)
+	// the error isn't shared with other requests until f.done is closed
+	f.err = withCredentialType(f.err, t.credentialType)
+	f.canceled = f.err != nil && ctx.Err() != nil
+	if f.err == nil {
+		c.add(ctx, key, f.tk, t, acquire)
+	}
+	c.mu.Lock()
+	delete(c.inflight, key.String())
+	c.mu.Unlock()
+	close(f.done)
+	return f.tk, false, f.err
+}
+
+// add caches the token acquired by acquire and, when background refresh is enabled, schedules its renewal.
+func (c *TokenCache) add(ctx context.Context, key cacheKey, tk *azcore.AccessToken, t tokenTelemetry, acquire func(context.Context) (*azcore.AccessToken, error)) {
+	c.setDistributed(ctx, key, tk)
+	c.mu.Lock()
+	c.set(key.String(), *tk)
+	if c.background {
+		c.scheduleRefresh(key, time.Until(c.refreshAt(*tk))-refreshJitter(backgroundRefreshJitter), t, acquire)
+	}
+	var data []byte
+	var err error
+	if c.onChange != nil {
+		data, err = c.export()
+	}
+	c.mu.Unlock()
+	if c.onChange != nil {
+		if err == nil {
+			err = c.onChange(ctx, data)
+		}
+		if err != nil {
+			azcore.Log().Write(LogCredential, "Azure Identity => Unable to save the token cache: "+err.Error())
+		}
+	}
+}
+
+// scheduleRefresh replaces the background refresh of the key with one that runs after delay. Tokens that are
+// already due aren't scheduled because the next call to getToken refreshes them. c.mu must be held.
+func (c *TokenCache) scheduleRefresh(key cacheKey, delay time.Duration, t tokenTelemetry, acquire func(context.Context) (*azcore.AccessToken, error)) {
+	if t, ok := c.refreshers[key.String()]; ok {
+		t.Stop()
+		delete(c.refreshers, key.String())
+	}
+	if c.closed || delay <= 0 {
+		return
+	}
+	c.refreshers[key.String()] = time.AfterFunc(delay, func() { c.refresh(key, t, acquire) })
+}
+
+// refresh acquires a new token for the key in the background. Failures are retried until the cached token is due
+// for refresh on demand, after which getToken takes over.
+func (c *TokenCache) refresh(key cacheKey, t tokenTelemetry, acquire func(context.Context) (*azcore.AccessToken, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundRefreshTimeout)
+	defer cancel()
+	ctx, r := t.start(ctx, true)
+	tk, err := acquire(ctx)
+	if err == nil {
+		r.end(TokenRequestRefreshed, false, tk, nil)
+		c.add(ctx, key, tk, t, acquire)
+		return
+	}
+	r.end(TokenRequestFailed, false, nil, err)
+	azcore.Log().Write(LogCredential, "Azure Identity => Background token refresh failed: "+err.Error())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.tokens[key.String()]; ok && time.Until(c.refreshAt(cached)) > backgroundRefreshRetry {
+		c.scheduleRefresh(key, backgroundRefreshRetry, t, acquire)
+	}
+}
+
+// getDistributed returns the token for the key from the distributed cache, or nil when there isn't one.
+func (c *TokenCache) getDistributed(ctx context.Context, key cacheKey) *azcore.AccessToken {
+	if c.distributed == nil {
+		return nil
+	}
+	value, err := c.distributed.Get(ctx, key.partition, key.id)
+	if err != nil {
+		azcore.Log().Write(LogCredential, "Azure Identity => Unable to read the distributed token cache: "+err.Error())
+		return nil
+	}
+	if value == nil {
+		return nil
+	}
+	var tk serializedAccessToken
+	if err = json.Unmarshal(value, &tk); err != nil {
+		azcore.Log().Write(LogCredential, "Azure Identity => Ignoring an unreadable distributed token cache entry: "+err.Error())
+		return nil
+	}
+	c.mu.Lock()
+	cleared := c.clearedAt(key.String())
+	c.mu.Unlock()
+	if !cleared.IsZero() && !time.Unix(tk.AcquiredOn, 0).After(cleared) {
+		// the token was added before the cache was cleared
+		return nil
+	}
+	at := tk.accessToken()
+	return &at
+}
+
+// setDistributed adds the token to the distributed cache until it expires.
+func (c *TokenCache) setDistributed(ctx context.Context, key cacheKey, tk *azcore.AccessToken) {
+	if c.distributed == nil {
+		return
+	}
+	ttl := time.Until(tk.ExpiresOn)
+	if ttl <= 0 {
+		return
+	}
+	s := newSerializedAccessToken(*tk)
+	s.AcquiredOn = time.Now().Unix()
+	// marshalling a struct of a string and integers can't fail
+	value, _ := json.Marshal(s)
+	if err := c.distributed.Set(ctx, key.partition, key.id, value, ttl); err != nil {
+		azcore.Log().Write(LogCredential, "Azure Identity => Unable to write the distributed token cache: "+err.Error())
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity/cache"
+)
+
+// TokenCachePersistenceOptions enable a public client credential to persist its sign in to encrypted storage, so that
+// a new process using a credential with the same options, tenant and client doesn't require the user to sign in again.
+// The cache uses the MSAL unified cache format and the MSAL extensions file locking, so with the default Name it is
+// shared with other MSAL based tools such as Azure PowerShell. See the cache package for the storage used on each platform.
+type TokenCachePersistenceOptions struct {
+	// Name distinguishes the persisted caches of different applications. Defaults to "msal.cache".
+	Name string
+	// AllowUnencryptedStorage allows the cache to be stored in a file readable only by the current user
+	// when encrypted storage isn't available.
+	AllowUnencryptedStorage bool
+}
+
+// newPersistentStorage returns the storage for the options; tests replace it to avoid the platform storage.
+var newPersistentStorage = func(o *TokenCachePersistenceOptions) (cache.Storage, error) {
+	return cache.New(&cache.Options{Name: o.Name, AllowUnencryptedStorage: o.AllowUnencryptedStorage})
+}
+
+const (
+	msalAccountSection      = "Account"
+	msalRefreshTokenSection = "RefreshToken"
+	msalRefreshTokenType    = "RefreshToken"
+	msalAuthorityType       = "MSSTS"
+)
+
+// msalCacheDocument is the MSAL unified token cache. Entries are kept as raw JSON so that
+// the sections and fields written by other tools are preserved.
+type msalCacheDocument map[string]map[string]json.RawMessage
+
+// msalCredential is a refresh token entry of the MSAL unified token cache.
+type msalCredential struct {
+	HomeAccountID  string `json:"home_account_id"`
+	Environment    string `json:"environment"`
+	CredentialType string `json:"credential_type"`
+	ClientID       string `json:"client_id"`
+	Secret         string `json:"secret"`
+	FamilyID       string `json:"family_id,omitempty"`
+}
+
+// msalAccount is an account entry of the MSAL unified token cache.
+type msalAccount struct {
+	HomeAccountID  string `json:"home_account_id"`
+	Environment    string `json:"environment"`
+	Realm          string `json:"realm"`
+	LocalAccountID string `json:"local_account_id"`
+	Username       string `json:"username"`
+	AuthorityType  string `json:"authority_type"`
+}
+
+// persistentAccount selects the sign ins a credential can use from the persistent cache.
+type persistentAccount struct {
+	environment string // the authority host
+	clientID    string
+	tenantID    string
+}
+
+// loadRefreshToken returns a persisted refresh token issued to the client by the authority, or an empty string
+// when there isn't one. Refresh tokens of accounts in the credential's tenant are preferred.
+func (a persistentAccount) loadRefreshToken(ctx context.Context, s cache.Storage) string {
+	doc := readMSALCache(ctx, s)
+	keys := make([]string, 0, len(doc[msalRefreshTokenSection]))
+	for k := range doc[msalRefreshTokenSection] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	refreshToken := ""
+	for _, k := range keys {
+		var rt msalCredential
+		if err := json.Unmarshal(doc[msalRefreshTokenSection][k], &rt); err != nil {
+			continue
+		}
+		if rt.CredentialType != msalRefreshTokenType || rt.Secret == "" || !strings.EqualFold(rt.Environment, a.environment) || !strings.EqualFold(rt.ClientID, a.clientID) {
+			continue
+		}
+		if a.tenantID != "" && strings.HasSuffix(strings.ToLower(rt.HomeAccountID), "."+strings.ToLower(a.tenantID)) {
+			return rt.Secret
+		}
+		if refreshToken == "" {
+			refreshToken = rt.Secret
+		}
+	}
+	return refreshToken
+}
+
+// saveRefreshToken persists the refresh token in tk along with the account it identifies. Failures are
+// logged and otherwise ignored because the token that was acquired is still valid.
+func (a persistentAccount) saveRefreshToken(ctx context.Context, s cache.Storage, tk *tokenResponse) {
+	if locker, ok := s.(cache.Locker); ok {
+		unlock, err := locker.Lock(ctx)
+		if err != nil {
+			azcore.Log().Write(LogCredential, "Azure Identity => Unable to lock the persistent token cache: "+err.Error())
+			return
+		}
+		defer unlock()
+	}
+	doc := readMSALCache(ctx, s)
+	homeAccountID, uid, utid := parseClientInfo(tk.clientInfo)
+	rt := msalCredential{
+		HomeAccountID:  homeAccountID,
+		Environment:    a.environment,
+		CredentialType: msalRefreshTokenType,
+		ClientID:       a.clientID,
+		Secret:         tk.refreshToken,
+	}
+	doc.set(msalRefreshTokenSection, strings.Join([]string{homeAccountID, a.environment, "refreshtoken", a.clientID, "", ""}, "-"), rt)
+	if homeAccountID != "" {
+		account := msalAccount{
+			HomeAccountID:  homeAccountID,
+			Environment:    a.environment,
+			Realm:          utid,
+			LocalAccountID: uid,
+			AuthorityType:  msalAuthorityType,
+		}
+		doc.set(msalAccountSection, strings.Join([]string{homeAccountID, a.environment, utid}, "-"), account)
+	}
+	// marshalling raw JSON messages can't fail
+	data, _ := json.Marshal(doc)
+	if err := s.Write(ctx, data); err != nil {
+		azcore.Log().Write(LogCredential, "Azure Identity => Unable to write the persistent token cache: "+err.Error())
+	}
+}
+
+// set adds or replaces the entry with the key, which MSAL stores in lower case, in the section.
+func (d msalCacheDocument) set(section string, key string, entry interface{}) {
+	if d[section] == nil {
+		d[section] = map[string]json.RawMessage{}
+	}
+	// marshalling structs containing strings can't fail
+	d[section][strings.ToLower(key)], _ = json.Marshal(entry)
+}
+
+// readMSALCache returns the contents of the storage. Unreadable contents are ignored
+// since they only cost the user another sign in.
+func readMSALCache(ctx context.Context, s cache.Storage) msalCacheDocument {
+	doc := msalCacheDocument{}
+	data, err := s.Read(ctx)
+	if err != nil {
+		azcore.Log().Write(LogCredential, "Azure Identity => Unable to read the persistent token cache: "+err.Error())
+		return doc
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &doc); err != nil {
+			azcore.Log().Write(LogCredential, "Azure Identity => Ignoring the unreadable persistent token cache: "+err.Error())
+			return msalCacheDocument{}
+		}
+	}
+	return doc
+}
+
+// parseClientInfo returns the MSAL home account ID, "<uid>.<utid>", and its parts from the client_info
+// returned by Azure Active Directory. The results are empty when the client info is missing or invalid.
+func parseClientInfo(clientInfo string) (homeAccountID string, uid string, utid string) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(clientInfo, "="))
+	if err != nil {
+		return "", "", ""
+	}
+	var info struct {
+		UID  string `json:"uid"`
+		UTID string `json:"utid"`
+	}
+	if err = json.Unmarshal(b, &info); err != nil || info.UID == "" || info.UTID == "" {
+		return "", "", ""
+	}
+	return info.UID + "." + info.UTID, info.UID, info.UTID
+}
@@ -0,0 +1,184 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity/cache"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// testClientInfo is the client_info of an account with uid "user" in the tenant "tenant"
+var testClientInfo = base64.RawURLEncoding.EncodeToString([]byte(`{"uid":"user","utid":"tenant"}`))
+
+var accessTokenRespWithRefreshToken = `{"access_token": "` + tokenValue + `", "refresh_token": "persisted_refresh_token", "expires_in": 3600, "client_info": "` + testClientInfo + `"}`
+
+// memoryStorage is a cache.Storage that keeps its data in memory.
+type memoryStorage struct {
+	data []byte
+	err  error
+}
+
+func (s *memoryStorage) Read(ctx context.Context) ([]byte, error) {
+	return s.data, s.err
+}
+
+func (s *memoryStorage) Write(ctx context.Context, data []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.data = data
+	return nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context) error {
+	s.data = nil
+	return s.err
+}
+
+// usePersistentStorage makes credentials with TokenCachePersistenceOptions use s until the returned func is called.
+func usePersistentStorage(s cache.Storage, err error) func() {
+	original := newPersistentStorage
+	newPersistentStorage = func(*TokenCachePersistenceOptions) (cache.Storage, error) {
+		return s, err
+	}
+	return func() { newPersistentStorage = original }
+}
+
+func TestDeviceCodeCredential_TokenCachePersistence(t *testing.T) {
+	storage := &memoryStorage{}
+	defer usePersistentStorage(storage, nil)()
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(deviceCodeResponse)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespWithRefreshToken)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespWithRefreshToken)))
+	srvURL := srv.URL()
+	options := &DeviceCodeCredentialOptions{
+		TokenCredentialOptions: TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true},
+		TokenCachePersistence:  &TokenCachePersistenceOptions{},
+	}
+	prompts := 0
+	handler := func(string) { prompts++ }
+	cred, err := NewDeviceCodeCredential(tenantID, clientID, handler, options)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)}); err != nil {
+		t.Fatalf("Received an unexpected error: %v", err)
+	}
+	var doc map[string]map[string]map[string]string
+	if err = json.Unmarshal(storage.data, &doc); err != nil {
+		t.Fatalf("Unable to unmarshal the persisted cache: %v", err)
+	}
+	rtKey := "user.tenant-" + srvURL.Host + "-refreshtoken-" + clientID + "--"
+	if rt := doc["RefreshToken"][rtKey]; rt["secret"] != "persisted_refresh_token" || rt["home_account_id"] != "user.tenant" || rt["credential_type"] != "RefreshToken" {
+		t.Fatalf("Expected an MSAL refresh token entry with key %s. Received: %v", rtKey, doc)
+	}
+	if account := doc["Account"]["user.tenant-"+srvURL.Host+"-tenant"]; account["local_account_id"] != "user" || account["realm"] != "tenant" {
+		t.Fatalf("Expected an MSAL account entry. Received: %v", doc)
+	}
+	// a new credential, as in another process, signs in silently with the persisted refresh token
+	cred, err = NewDeviceCodeCredential(tenantID, clientID, handler, options)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: strings.Fields(deviceCodeScopes)}); err != nil {
+		t.Fatalf("Received an unexpected error: %v", err)
+	}
+	if prompts != 1 {
+		t.Fatalf("Expected the user to be prompted once but was prompted %d times", prompts)
+	}
+	if cred.refreshToken != "persisted_refresh_token" {
+		t.Fatalf("Expected the persisted refresh token to be used")
+	}
+}
+
+func TestDeviceCodeCredential_TokenCachePersistenceUnavailable(t *testing.T) {
+	defer usePersistentStorage(nil, cache.ErrUnencryptedStorage)()
+	_, err := NewDeviceCodeCredential(tenantID, clientID, func(string) {}, &DeviceCodeCredentialOptions{TokenCachePersistence: &TokenCachePersistenceOptions{}})
+	var credErr *CredentialUnavailableError
+	if !errors.As(err, &credErr) {
+		t.Fatalf("Expected a CredentialUnavailableError. Received: %v", err)
+	}
+}
+
+func TestPersistentAccount_SharedCache(t *testing.T) {
+	// a cache written by another MSAL based tool
+	storage := &memoryStorage{data: []byte(`{
+		"AccessToken": {"other-at": {"secret": "at"}},
+		"AppMetadata": {"appmetadata-login.microsoftonline.com-client": {"client_id": "client", "family_id": "1"}},
+		"RefreshToken": {
+			"a.other-login.microsoftonline.com-refreshtoken-client--": {"home_account_id": "a.other", "environment": "login.microsoftonline.com", "credential_type": "RefreshToken", "client_id": "client", "secret": "other_tenant", "last_modification_time": "1"},
+			"b.home-login.microsoftonline.com-refreshtoken-client--": {"home_account_id": "b.home", "environment": "login.microsoftonline.com", "credential_type": "RefreshToken", "client_id": "client", "secret": "home_tenant"},
+			"c.home-login.microsoftonline.us-refreshtoken-client--": {"home_account_id": "c.home", "environment": "login.microsoftonline.us", "credential_type": "RefreshToken", "client_id": "client", "secret": "other_cloud"},
+			"d.home-login.microsoftonline.com-refreshtoken-other--": {"home_account_id": "d.home", "environment": "login.microsoftonline.com", "credential_type": "RefreshToken", "client_id": "other", "secret": "other_client"}
+		}
+	}`)}
+	account := persistentAccount{environment: "login.microsoftonline.com", clientID: "client", tenantID: "home"}
+	if rt := account.loadRefreshToken(context.Background(), storage); rt != "home_tenant" {
+		t.Fatalf("Expected the refresh token of the account in the tenant. Received: %q", rt)
+	}
+	account.tenantID = "organizations"
+	if rt := account.loadRefreshToken(context.Background(), storage); rt != "other_tenant" {
+		t.Fatalf("Expected the first refresh token for the client. Received: %q", rt)
+	}
+	account.environment = "login.chinacloudapi.cn"
+	if rt := account.loadRefreshToken(context.Background(), storage); rt != "" {
+		t.Fatalf("Expected no refresh token for another cloud. Received: %q", rt)
+	}
+	account.environment = "login.microsoftonline.com"
+	account.saveRefreshToken(context.Background(), storage, &tokenResponse{refreshToken: "new", clientInfo: testClientInfo})
+	var doc map[string]map[string]map[string]string
+	if err := json.Unmarshal(storage.data, &doc); err != nil {
+		t.Fatalf("Unable to unmarshal the persisted cache: %v", err)
+	}
+	if doc["AccessToken"]["other-at"]["secret"] != "at" || doc["AppMetadata"]["appmetadata-login.microsoftonline.com-client"]["family_id"] != "1" {
+		t.Fatalf("Expected the entries of other tools to be preserved. Received: %v", doc)
+	}
+	if doc["RefreshToken"]["a.other-login.microsoftonline.com-refreshtoken-client--"]["last_modification_time"] != "1" {
+		t.Fatalf("Expected unknown fields of other entries to be preserved. Received: %v", doc)
+	}
+	if doc["RefreshToken"]["user.tenant-login.microsoftonline.com-refreshtoken-client--"]["secret"] != "new" {
+		t.Fatalf("Expected the new refresh token to be saved. Received: %v", doc)
+	}
+}
+
+func TestPersistentAccount_UnreadableStorage(t *testing.T) {
+	account := persistentAccount{environment: "login.microsoftonline.com", clientID: "client"}
+	storage := &memoryStorage{data: []byte("not json")}
+	if rt := account.loadRefreshToken(context.Background(), storage); rt != "" {
+		t.Fatalf("Expected no refresh token from unreadable storage")
+	}
+	account.saveRefreshToken(context.Background(), storage, &tokenResponse{refreshToken: "token"})
+	if rt := account.loadRefreshToken(context.Background(), storage); rt != "token" {
+		t.Fatalf("Expected unreadable storage to be replaced. Received: %q", rt)
+	}
+	storage.err = errors.New("storage failed")
+	if rt := account.loadRefreshToken(context.Background(), storage); rt != "" {
+		t.Fatalf("Expected no refresh token when the storage fails")
+	}
+}
+
+func TestParseClientInfo(t *testing.T) {
+	if home, uid, utid := parseClientInfo(testClientInfo); home != "user.tenant" || uid != "user" || utid != "tenant" {
+		t.Fatalf("Unexpected client info: %s, %s, %s", home, uid, utid)
+	}
+	padded := base64.URLEncoding.EncodeToString([]byte(`{"uid":"u","utid":"t"}`))
+	if home, _, _ := parseClientInfo(padded); home != "u.t" {
+		t.Fatalf("Expected padded client info to be parsed. Received: %s", home)
+	}
+	for _, invalid := range []string{"", "!!!", base64.RawURLEncoding.EncodeToString([]byte(`{"uid":"u"}`))} {
+		if home, _, _ := parseClientInfo(invalid); home != "" {
+			t.Fatalf("Expected no home account ID for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,840 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// tokenAcquirer returns an acquire func that counts its calls and returns a token expiring after lifetime.
+func tokenAcquirer(calls *int, lifetime time.Duration) func(context.Context) (*azcore.AccessToken, error) {
+	return func(context.Context) (*azcore.AccessToken, error) {
+		*calls++
+		return &azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(lifetime)}, nil
+	}
+}
+
+func TestTokenCacheKey(t *testing.T) {
+	if tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{"a", "b"}}) != tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{"b", "a"}}) {
+		t.Fatalf("Expected the order of scopes not to affect the key")
+	}
+	if tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{"a"}}) == tokenCacheKey("other", tenantID, azcore.TokenRequestOptions{Scopes: []string{"a"}}) {
+		t.Fatalf("Expected the account to be part of the key")
+	}
+	if tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{"a"}}) == tokenCacheKey(clientID, "other", azcore.TokenRequestOptions{Scopes: []string{"a"}}) {
+		t.Fatalf("Expected the tenant to be part of the key")
+	}
+	if tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{"a"}}) == tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{"a"}, Claims: "claims"}) {
+		t.Fatalf("Expected the claims to be part of the key")
+	}
+	if tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{"a"}}) == tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{"a"}, EnableCAE: true}) {
+		t.Fatalf("Expected CAE support to be part of the key")
+	}
+}
+
+func TestTokenCache_Reuse(t *testing.T) {
+	cache := NewTokenCache(nil)
+	calls := 0
+	for i := 0; i < 3; i++ {
+		tk, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if tk.Token != tokenValue {
+			t.Fatalf("Unexpected token: %s", tk.Token)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected a single token acquisition but received %d", calls)
+	}
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "other"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected a token acquisition for a different key")
+	}
+}
+
+func TestTokenCache_RefreshAheadOfExpiry(t *testing.T) {
+	cache := NewTokenCache(nil)
+	calls := 0
+	for i := 0; i < 2; i++ {
+		if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, tokenRefreshOffset/2)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected a token expiring within the refresh offset to be refreshed")
+	}
+}
+
+func TestTokenCache_RefreshOffset(t *testing.T) {
+	calls := 0
+	cache := NewTokenCache(&TokenCacheOptions{RefreshOffset: time.Minute})
+	for i := 0; i < 2; i++ {
+		if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, 2*time.Minute)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected a token expiring after the configured offset to be reused. Calls: %d", calls)
+	}
+	calls = 0
+	cache = NewTokenCache(&TokenCacheOptions{RefreshOffset: -1})
+	for i := 0; i < 2; i++ {
+		if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Minute)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected a negative offset to reuse tokens until they expire. Calls: %d", calls)
+	}
+}
+
+func TestTokenCache_ClockSkew(t *testing.T) {
+	calls := 0
+	cache := NewTokenCache(&TokenCacheOptions{RefreshOffset: -1, ClockSkew: 2 * time.Minute})
+	for i := 0; i < 2; i++ {
+		if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Minute)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected a token expiring within the clock skew to be refreshed. Calls: %d", calls)
+	}
+	fail := func(context.Context) (*azcore.AccessToken, error) {
+		return nil, errors.New("refresh failed")
+	}
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, fail); err == nil {
+		t.Fatalf("Expected a token expiring within the clock skew to be treated as expired")
+	}
+}
+
+func TestTokenCache_RefreshFailure(t *testing.T) {
+	cache := NewTokenCache(nil)
+	calls := 0
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, tokenRefreshOffset/2)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fail := func(context.Context) (*azcore.AccessToken, error) {
+		return nil, errors.New("refresh failed")
+	}
+	tk, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, fail)
+	if err != nil {
+		t.Fatalf("Expected the unexpired cached token when refreshing fails but received: %v", err)
+	}
+	if tk.Token != tokenValue {
+		t.Fatalf("Unexpected token: %s", tk.Token)
+	}
+	if _, err = cache.getToken(context.Background(), cacheKey{id: "expired"}, tokenTelemetry{}, tokenAcquirer(&calls, -time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err = cache.getToken(context.Background(), cacheKey{id: "expired"}, tokenTelemetry{}, fail); err == nil {
+		t.Fatalf("Expected an error when the cached token has expired")
+	}
+}
+
+func TestTokenCache_Nil(t *testing.T) {
+	var cache *TokenCache
+	calls := 0
+	for i := 0; i < 2; i++ {
+		if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected a nil cache not to cache tokens")
+	}
+}
+
+func TestClientSecretCredential_CachedToken(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+			t.Fatalf("Expected an empty error but received: %v", err)
+		}
+	}
+	if srv.Requests() != 1 {
+		t.Fatalf("Expected a single request to AAD but received %d", srv.Requests())
+	}
+}
+
+func TestTokenCache_ExportImport(t *testing.T) {
+	var saved []byte
+	changes := 0
+	cache := NewTokenCache(&TokenCacheOptions{OnChange: func(ctx context.Context, data []byte) error {
+		changes++
+		saved = data
+		return nil
+	}})
+	calls := 0
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "expired"}, tokenTelemetry{}, tokenAcquirer(&calls, -time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if changes != 2 {
+		t.Fatalf("Expected OnChange to be called for each token added, called %d times", changes)
+	}
+	exported, err := cache.Export()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(exported) != string(saved) {
+		t.Fatalf("Expected OnChange to receive the exported cache")
+	}
+	hydrated := NewTokenCache(nil)
+	if err = hydrated.Import(exported); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(hydrated.tokens) != 1 {
+		t.Fatalf("Expected only the unexpired token to be exported, found %d tokens", len(hydrated.tokens))
+	}
+	tk, err := hydrated.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 || tk.Token != tokenValue {
+		t.Fatalf("Expected the imported token to be used")
+	}
+	if err = hydrated.Import([]byte(`{"version": 99}`)); err == nil {
+		t.Fatalf("Expected an error for an unsupported version")
+	}
+	if err = hydrated.Import([]byte("not json")); err == nil {
+		t.Fatalf("Expected an error for invalid data")
+	}
+	var nilCache *TokenCache
+	if _, err = nilCache.Export(); err == nil {
+		t.Fatalf("Expected an error exporting a nil cache")
+	}
+}
+
+func TestTokenCache_OnChangeError(t *testing.T) {
+	cache := NewTokenCache(&TokenCacheOptions{OnChange: func(ctx context.Context, data []byte) error {
+		return errors.New("store unavailable")
+	}})
+	calls := 0
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+		t.Fatalf("Expected OnChange errors not to fail token acquisition. Received: %v", err)
+	}
+}
+
+func TestClientSecretCredential_SharedTokenCache(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cache := NewTokenCache(nil)
+	options := &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true, TokenCache: cache}
+	for _, id := range []string{clientID, "other_client", clientID} {
+		cred, err := NewClientSecretCredential(tenantID, id, secret, options)
+		if err != nil {
+			t.Fatalf("Unable to create credential. Received: %v", err)
+		}
+		if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+			t.Fatalf("Expected an empty error but received: %v", err)
+		}
+	}
+	if srv.Requests() != 2 {
+		t.Fatalf("Expected a request for each client ID but received %d", srv.Requests())
+	}
+}
+
+func TestTokenCache_PartitionIsolation(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	otherCloud, closeOtherCloud := mock.NewServer()
+	defer closeOtherCloud()
+	for i := 0; i < 10; i++ {
+		srv.AppendResponse(mock.WithBody([]byte(fmt.Sprintf(`{"access_token": "token%d", "expires_in": 3600}`, i))))
+	}
+	otherCloud.AppendResponse(mock.WithBody([]byte(`{"access_token": "other cloud", "expires_in": 3600}`)))
+	srvURL, otherCloudURL := srv.URL(), otherCloud.URL()
+	cache := NewTokenCache(nil)
+	options := &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true, TokenCache: cache}
+	otherCloudOptions := &TokenCredentialOptions{HTTPClient: otherCloud, AuthorityHost: &otherCloudURL, AllowInsecureLocalhost: true, TokenCache: cache}
+	secretCred := func(tenant string, options *TokenCredentialOptions) azcore.TokenCredential {
+		cred, err := NewClientSecretCredential(tenant, clientID, secret, options)
+		if err != nil {
+			t.Fatalf("Unable to create credential. Received: %v", err)
+		}
+		return cred
+	}
+	oboCred := func(assertion string) azcore.TokenCredential {
+		cred, err := NewOnBehalfOfCredentialWithSecret(tenantID, clientID, assertion, secret, options)
+		if err != nil {
+			t.Fatalf("Unable to create credential. Received: %v", err)
+		}
+		return cred
+	}
+	base := azcore.TokenRequestOptions{Scopes: []string{scope}}
+	for _, test := range []struct {
+		name string
+		cred azcore.TokenCredential
+		opts azcore.TokenRequestOptions
+	}{
+		{"tenant", secretCred("other-tenant", options), base},
+		{"scopes", secretCred(tenantID, options), azcore.TokenRequestOptions{Scopes: []string{"https://vault.azure.net/.default"}}},
+		{"claims", secretCred(tenantID, options), azcore.TokenRequestOptions{Scopes: []string{scope}, Claims: `{"access_token":{"nbf":{"essential":true,"value":"1"}}}`}},
+		{"CAE", secretCred(tenantID, options), azcore.TokenRequestOptions{Scopes: []string{scope}, EnableCAE: true}},
+		{"user assertion", oboCred("user-a"), base},
+		{"another user assertion", oboCred("user-b"), base},
+		{"authority host", secretCred(tenantID, otherCloudOptions), base},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			first, err := secretCred(tenantID, options).GetToken(context.Background(), base)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tk, err := test.cred.GetToken(context.Background(), test.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tk.Token == first.Token {
+				t.Fatalf("Received the token of another partition")
+			}
+			again, err := test.cred.GetToken(context.Background(), test.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if again.Token != tk.Token {
+				t.Fatalf("Expected the partition's token to be reused")
+			}
+		})
+	}
+	if srv.Requests() != 7 || otherCloud.Requests() != 1 {
+		t.Fatalf("Expected a single request for each partition. Received %d and %d", srv.Requests(), otherCloud.Requests())
+	}
+}
+
+func TestAzureCLICredential_TenantPartition(t *testing.T) {
+	cache := NewTokenCache(nil)
+	tokens := map[string]string{}
+	for _, tenant := range []string{"tenant-a", "tenant-b", "tenant-a"} {
+		tenant := tenant
+		provider := func(ctx context.Context, resource string) ([]byte, error) {
+			return []byte(`{"accessToken": "` + tenant + `", "expiresOn": "` + time.Now().Add(time.Hour).Format("2006-01-02 15:04:05.999999") + `"}`), nil
+		}
+		cred, err := NewAzureCLICredential(&AzureCLICredentialOptions{TokenProvider: provider, TenantID: tenant, TokenCache: cache})
+		if err != nil {
+			t.Fatalf("Unable to create credential. Received: %v", err)
+		}
+		tk, err := cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		tokens[tenant] = tk.Token
+	}
+	if tokens["tenant-a"] != "tenant-a" || tokens["tenant-b"] != "tenant-b" {
+		t.Fatalf("Expected each tenant to receive its own token. Received: %v", tokens)
+	}
+}
+
+// mapDistributedCache is a DistributedCache backed by a map.
+type mapDistributedCache struct {
+	values map[string][]byte
+	ttls   map[string]time.Duration
+	err    error
+}
+
+func newMapDistributedCache() *mapDistributedCache {
+	return &mapDistributedCache{values: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (m *mapDistributedCache) Get(ctx context.Context, partition string, key string) ([]byte, error) {
+	return m.values[partition+"/"+key], m.err
+}
+
+func (m *mapDistributedCache) Set(ctx context.Context, partition string, key string, value []byte, ttl time.Duration) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.values[partition+"/"+key] = value
+	m.ttls[partition+"/"+key] = ttl
+	return nil
+}
+
+func TestTokenCache_Distributed(t *testing.T) {
+	distributed := newMapDistributedCache()
+	key := tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	calls := 0
+	instance1 := NewTokenCache(&TokenCacheOptions{Distributed: distributed})
+	if _, err := instance1.getToken(context.Background(), key, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entry := key.partition + "/" + key.id
+	if distributed.values[entry] == nil {
+		t.Fatalf("Expected the token to be added to the distributed cache in the credential's partition")
+	}
+	if ttl := distributed.ttls[entry]; ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Fatalf("Expected the TTL to match the token's lifetime. Received: %v", ttl)
+	}
+	// another instance of the application finds the token in the distributed cache
+	instance2 := NewTokenCache(&TokenCacheOptions{Distributed: distributed})
+	tk, err := instance2.getToken(context.Background(), key, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 1 || tk.Token != tokenValue {
+		t.Fatalf("Expected the token from the distributed cache to be used")
+	}
+	if len(instance2.tokens) != 1 {
+		t.Fatalf("Expected the token from the distributed cache to be cached in memory")
+	}
+}
+
+func TestTokenCache_DistributedFailure(t *testing.T) {
+	distributed := newMapDistributedCache()
+	distributed.err = errors.New("cache unavailable")
+	cache := NewTokenCache(&TokenCacheOptions{Distributed: distributed})
+	calls := 0
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+		t.Fatalf("Expected distributed cache errors not to fail token acquisition. Received: %v", err)
+	}
+	distributed.err = nil
+	distributed.values["/expiring"] = []byte(`{"token": "old", "expires_on": 1}`)
+	distributed.values["/invalid"] = []byte("not json")
+	for _, id := range []string{"expiring", "invalid"} {
+		tk, err := cache.getToken(context.Background(), cacheKey{id: id}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if tk.Token != tokenValue {
+			t.Fatalf("Expected a new token instead of the %s distributed cache entry", id)
+		}
+	}
+}
+
+func TestTokenCache_BackgroundRefresh(t *testing.T) {
+	defer func(original func(time.Duration) time.Duration) { refreshJitter = original }(refreshJitter)
+	refreshJitter = func(time.Duration) time.Duration { return 0 }
+	c := NewTokenCache(&TokenCacheOptions{BackgroundRefresh: true})
+	defer c.Close()
+	acquired := make(chan string, 3)
+	calls := 0
+	acquire := func(context.Context) (*azcore.AccessToken, error) {
+		calls++
+		token := fmt.Sprintf("token%d", calls)
+		acquired <- token
+		// the first token is due for background refresh shortly, later ones aren't
+		lifetime := time.Hour
+		if calls == 1 {
+			lifetime = tokenRefreshOffset + 50*time.Millisecond
+		}
+		return &azcore.AccessToken{Token: token, ExpiresOn: time.Now().Add(lifetime)}, nil
+	}
+	key := cacheKey{id: "key"}
+	tk, err := c.getToken(context.Background(), key, tokenTelemetry{}, acquire)
+	if err != nil || tk.Token != "token1" {
+		t.Fatalf("Unexpected result: %v, %v", tk, err)
+	}
+	<-acquired
+	select {
+	case token := <-acquired:
+		if token != "token2" {
+			t.Fatalf("Unexpected token %s", token)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the token to be refreshed in the background")
+	}
+	// wait for the refreshed token to be cached
+	for i := 0; i < 100; i++ {
+		if tk, _ = c.getToken(context.Background(), key, tokenTelemetry{}, func(context.Context) (*azcore.AccessToken, error) {
+			return nil, errors.New("expected the cached token")
+		}); tk != nil && tk.Token == "token2" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tk == nil || tk.Token != "token2" {
+		t.Fatalf("Expected the token refreshed in the background to be cached. Received: %v", tk)
+	}
+}
+
+func TestTokenCache_BackgroundRefreshClose(t *testing.T) {
+	defer func(original func(time.Duration) time.Duration) { refreshJitter = original }(refreshJitter)
+	refreshJitter = func(time.Duration) time.Duration { return 0 }
+	c := NewTokenCache(&TokenCacheOptions{BackgroundRefresh: true})
+	refreshed := make(chan struct{}, 2)
+	acquire := func(context.Context) (*azcore.AccessToken, error) {
+		refreshed <- struct{}{}
+		return &azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(tokenRefreshOffset + 50*time.Millisecond)}, nil
+	}
+	if _, err := c.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, acquire); err != nil {
+		t.Fatalf("Received an unexpected error: %v", err)
+	}
+	<-refreshed
+	c.Close()
+	select {
+	case <-refreshed:
+		t.Fatalf("Expected no background refresh after Close")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestTokenCache_ConcurrentRequests(t *testing.T) {
+	c := NewTokenCache(nil)
+	var calls int32
+	release := make(chan struct{})
+	acquire := func(context.Context) (*azcore.AccessToken, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tk, err := c.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, acquire)
+			if err == nil && tk.Token != tokenValue {
+				err = fmt.Errorf("unexpected token %s", tk.Token)
+			}
+			errs <- err
+		}()
+	}
+	// let the requests pile up behind the first acquisition
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected concurrent requests to share one acquisition. Calls: %d", calls)
+	}
+}
+
+func TestTokenCache_ConcurrentRequestCanceled(t *testing.T) {
+	c := NewTokenCache(nil)
+	var calls int32
+	started := make(chan struct{})
+	acquire := func(ctx context.Context) (*azcore.AccessToken, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := c.getToken(ctx, cacheKey{id: "key"}, tokenTelemetry{}, acquire)
+		first <- err
+	}()
+	<-started
+	second := make(chan error)
+	go func() {
+		_, err := c.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, acquire)
+		second <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the canceled request to fail. Received: %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("Expected the waiting request to acquire the token itself. Received: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected two acquisitions. Calls: %d", calls)
+	}
+}
+
+func TestTokenCache_RefreshOn(t *testing.T) {
+	calls := 0
+	recommended := func(context.Context) (*azcore.AccessToken, error) {
+		calls++
+		return &azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(time.Hour), RefreshOn: time.Now().Add(-time.Second)}, nil
+	}
+	cache := NewTokenCache(nil)
+	for i := 0; i < 2; i++ {
+		if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, recommended); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected a token past its recommended refresh time to be refreshed. Calls: %d", calls)
+	}
+	refreshOn := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+	cache.tokens[cacheKey{id: "key"}.String()] = azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(time.Hour), RefreshOn: refreshOn}
+	exported, err := cache.Export()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hydrated := NewTokenCache(nil)
+	if err = hydrated.Import(exported); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !hydrated.tokens[cacheKey{id: "key"}.String()].RefreshOn.Equal(refreshOn) {
+		t.Fatalf("Expected RefreshOn to survive export. Received: %v", hydrated.tokens[cacheKey{id: "key"}.String()].RefreshOn)
+	}
+	tk, err := hydrated.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, recommended)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 || !tk.RefreshOn.Equal(refreshOn) {
+		t.Fatalf("Expected the token to be reused until its recommended refresh time")
+	}
+}
+
+func TestTokenCache_MaxEntries(t *testing.T) {
+	c := NewTokenCache(&TokenCacheOptions{MaxEntries: 2})
+	calls := 0
+	get := func(id string) {
+		if _, err := c.getToken(context.Background(), cacheKey{id: id}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+			t.Fatalf("Received an unexpected error: %v", err)
+		}
+	}
+	get("a")
+	get("b")
+	// using "a" makes "b" the least recently used token
+	get("a")
+	get("c")
+	if calls != 3 {
+		t.Fatalf("Expected 3 tokens to be acquired. Acquired: %d", calls)
+	}
+	get("a")
+	if calls != 3 {
+		t.Fatalf("Expected the recently used token to remain cached")
+	}
+	get("b")
+	if calls != 4 {
+		t.Fatalf("Expected the least recently used token to be evicted")
+	}
+	stats := c.Stats()
+	if stats.Entries != 2 || stats.Hits != 2 || stats.Misses != 4 || stats.Evictions != 2 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+	if (*TokenCache)(nil).Stats() != (TokenCacheStats{}) {
+		t.Fatalf("Expected a nil cache to have no stats")
+	}
+}
+
+func TestTokenCache_MaxEntriesImport(t *testing.T) {
+	source := NewTokenCache(nil)
+	calls := 0
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := source.getToken(context.Background(), cacheKey{id: id}, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+			t.Fatalf("Received an unexpected error: %v", err)
+		}
+	}
+	data, err := source.Export()
+	if err != nil {
+		t.Fatalf("Unable to export the cache: %v", err)
+	}
+	c := NewTokenCache(&TokenCacheOptions{MaxEntries: 2})
+	if err = c.Import(data); err != nil {
+		t.Fatalf("Unable to import the cache: %v", err)
+	}
+	if stats := c.Stats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Fatalf("Expected the imported tokens to be limited to MaxEntries. Received: %+v", stats)
+	}
+}
+
+func TestTokenCache_MaxEntriesStopsBackgroundRefresh(t *testing.T) {
+	defer func(original func(time.Duration) time.Duration) { refreshJitter = original }(refreshJitter)
+	refreshJitter = func(time.Duration) time.Duration { return 0 }
+	c := NewTokenCache(&TokenCacheOptions{BackgroundRefresh: true, MaxEntries: 1})
+	defer c.Close()
+	refreshed := make(chan string, 2)
+	acquire := func(id string) func(context.Context) (*azcore.AccessToken, error) {
+		return func(context.Context) (*azcore.AccessToken, error) {
+			refreshed <- id
+			return &azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(tokenRefreshOffset + 100*time.Millisecond)}, nil
+		}
+	}
+	for _, id := range []string{"evicted", "cached"} {
+		if _, err := c.getToken(context.Background(), cacheKey{id: id}, tokenTelemetry{}, acquire(id)); err != nil {
+			t.Fatalf("Received an unexpected error: %v", err)
+		}
+		<-refreshed
+	}
+	select {
+	case id := <-refreshed:
+		if id != "cached" {
+			t.Fatalf("Expected no background refresh of an evicted token")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the cached token to be refreshed in the background")
+	}
+}
+
+func TestTokenCache_StaleWhileRevalidate(t *testing.T) {
+	c := NewTokenCache(nil)
+	calls := 0
+	// cache a token that's due to be refreshed but hasn't expired
+	if _, err := c.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, tokenAcquirer(&calls, tokenRefreshOffset/2)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var refreshes int32
+	started, release := make(chan struct{}), make(chan struct{})
+	refresh := func(context.Context) (*azcore.AccessToken, error) {
+		if atomic.AddInt32(&refreshes, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return &azcore.AccessToken{Token: "refreshed", ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}
+	refreshed := make(chan error)
+	go func() {
+		tk, err := c.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, refresh)
+		if err == nil && tk.Token != "refreshed" {
+			err = fmt.Errorf("unexpected token %s", tk.Token)
+		}
+		refreshed <- err
+	}()
+	<-started
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// these requests would block until release is closed if they waited for the refresh
+			tk, err := c.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, refresh)
+			if err == nil && tk.Token != tokenValue {
+				err = fmt.Errorf("expected the cached token while it's refreshed, received %s", tk.Token)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	if err := <-refreshed; err != nil {
+		t.Fatal(err)
+	}
+	tk, err := c.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, refresh)
+	if err != nil || tk.Token != "refreshed" {
+		t.Fatalf("Expected the refreshed token. Received: %v, %v", tk, err)
+	}
+	if refreshes != 1 {
+		t.Fatalf("Expected exactly one refresh. Refreshes: %d", refreshes)
+	}
+}
+
+func TestTokenCache_ExpiredTokenWaitsForRefresh(t *testing.T) {
+	c := NewTokenCache(nil)
+	c.set("key", azcore.AccessToken{Token: "expired", ExpiresOn: time.Now().Add(-time.Minute)})
+	var refreshes int32
+	release := make(chan struct{})
+	refresh := func(context.Context) (*azcore.AccessToken, error) {
+		atomic.AddInt32(&refreshes, 1)
+		<-release
+		return &azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tk, err := c.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{}, refresh)
+			if err == nil && tk.Token != tokenValue {
+				err = fmt.Errorf("expected the refreshed token, received %s", tk.Token)
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if refreshes != 1 {
+		t.Fatalf("Expected exactly one refresh. Refreshes: %d", refreshes)
+	}
+}
+
+func TestTokenCache_Clear(t *testing.T) {
+	distributed := newMapDistributedCache()
+	cache := NewTokenCache(&TokenCacheOptions{Distributed: distributed})
+	cleared := tokenCacheKey(clientID, tenantID, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	kept := tokenCacheKey("other", tenantID, azcore.TokenRequestOptions{Scopes: []string{scope}})
+	calls := 0
+	for _, key := range []cacheKey{cleared, kept} {
+		if _, err := cache.getToken(context.Background(), key, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	cache.clear(clientID + "|")
+	if len(cache.tokens) != 1 {
+		t.Fatalf("Expected only the tokens matching the prefix to be removed. Remaining: %d", len(cache.tokens))
+	}
+	// the token in the distributed cache was acquired before the cache was cleared
+	for _, key := range []cacheKey{cleared, kept} {
+		if _, err := cache.getToken(context.Background(), key, tokenTelemetry{}, tokenAcquirer(&calls, time.Hour)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("Expected only the cleared token to be acquired again. Acquisitions: %d", calls)
+	}
+	cache.Clear()
+	if len(cache.tokens) != 0 {
+		t.Fatalf("Expected Clear to remove every token")
+	}
+	var nilCache *TokenCache
+	nilCache.Clear()
+}
+
+func TestClientSecretCredential_ClearCache(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	chain, err := NewChainedTokenCredential(cred)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	for _, clear := range []func(){cred.ClearCache, chain.ClearCache} {
+		if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+			t.Fatalf("Expected an empty error but received: %v", err)
+		}
+		clear()
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if srv.Requests() != 3 {
+		t.Fatalf("Expected a request to AAD after each ClearCache. Requests: %d", srv.Requests())
+	}
+}
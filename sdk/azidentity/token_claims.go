@@ -0,0 +1,148 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// TokenClaims are claims from the payload of an access token issued by Azure Active Directory, for logging and
+// diagnostics, for example to confirm which managed identity a token was issued to.
+// They're parsed without validating the token's signature, so they must not be used to make authorization decisions.
+type TokenClaims struct {
+	// ObjectID is the object ID of the user, service principal or managed identity the token was issued to (oid).
+	ObjectID string `json:"oid"`
+	// TenantID is the tenant that issued the token (tid).
+	TenantID string `json:"tid"`
+	// AppID is the client ID of the application that requested the token (appid, or azp in v2.0 tokens).
+	AppID string `json:"appid"`
+	// ManagedIdentityResourceID is the Azure resource ID of the managed identity the token was issued to (xms_mirid).
+	// It's empty for tokens that weren't issued to a managed identity.
+	ManagedIdentityResourceID string `json:"xms_mirid"`
+	// Audience is the resource the token is intended for (aud), or the first of them when there are several.
+	Audience string `json:"-"`
+	// Issuer is the security token service that issued the token (iss).
+	Issuer string `json:"iss"`
+	// ExpiresOn is when the token expires (exp).
+	ExpiresOn time.Time `json:"-"`
+	// Raw contains every claim in the token, including those without a field.
+	Raw map[string]interface{} `json:"-"`
+}
+
+// ParseTokenClaims returns the claims of an access token, such as the Token of an azcore.AccessToken, WITHOUT
+// validating it. Some tokens, such as those for Microsoft Graph, are opaque to their clients and can't be parsed.
+func ParseTokenClaims(token string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("the token isn't a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("decoding the token's payload: %w", err)
+	}
+	claims := &TokenClaims{}
+	if err = json.Unmarshal(payload, claims); err != nil {
+		return nil, fmt.Errorf("unmarshalling the token's claims: %w", err)
+	}
+	if err = json.Unmarshal(payload, &claims.Raw); err != nil {
+		return nil, fmt.Errorf("unmarshalling the token's claims: %w", err)
+	}
+	if azp, ok := claims.Raw["azp"].(string); ok && claims.AppID == "" {
+		claims.AppID = azp
+	}
+	switch aud := claims.Raw["aud"].(type) {
+	case string:
+		claims.Audience = aud
+	case []interface{}:
+		if len(aud) > 0 {
+			claims.Audience, _ = aud[0].(string)
+		}
+	}
+	if exp, ok := claims.Raw["exp"].(float64); ok {
+		claims.ExpiresOn = time.Unix(int64(exp), 0)
+	}
+	return claims, nil
+}
+
+// ExpectedTokenClaims describes the token an application requested, for CheckTokenClaims. Empty fields aren't checked.
+type ExpectedTokenClaims struct {
+	// Scope is the scope the token was requested for, for example "https://storage.azure.com/.default".
+	Scope string
+	// AuthorityHost is the authority host the token was requested from, for example AzureGovernment.
+	AuthorityHost string
+	// TenantID is the tenant the token was requested from.
+	TenantID string
+}
+
+// stsHosts are the hosts of the security token services that issue v1.0 tokens, by authority host.
+// v2.0 tokens are issued by the authority host itself.
+var stsHosts = map[string]string{
+	AzurePublicCloud: "sts.windows.net",
+	AzureGovernment:  "sts.windows.net",
+	AzureChina:       "sts.chinacloudapi.cn",
+	AzureGermany:     "sts.microsoftazure.de",
+}
+
+// CheckTokenClaims verifies that the audience, issuer and tenant of an access token match what was requested,
+// WITHOUT validating the token, to catch misconfigured sovereign cloud or B2C endpoints early. It returns an error
+// describing every mismatch, which is also logged as an error. Opaque tokens, such as those for Microsoft Graph,
+// can't be checked. Resources must still validate the tokens they receive.
+func CheckTokenClaims(token string, expected ExpectedTokenClaims) error {
+	claims, err := ParseTokenClaims(token)
+	if err != nil {
+		return err
+	}
+	var mismatches []string
+	if expected.Scope != "" && !audienceMatches(claims.Audience, expected.Scope) {
+		mismatches = append(mismatches, fmt.Sprintf("the audience %q doesn't match the scope %q", claims.Audience, expected.Scope))
+	}
+	if expected.AuthorityHost != "" && !issuerMatches(claims.Issuer, expected.AuthorityHost) {
+		mismatches = append(mismatches, fmt.Sprintf("the issuer %q isn't the authority host %q", claims.Issuer, expected.AuthorityHost))
+	}
+	if expected.TenantID != "" && !strings.EqualFold(claims.TenantID, expected.TenantID) {
+		mismatches = append(mismatches, fmt.Sprintf("the tenant %q isn't the tenant %q", claims.TenantID, expected.TenantID))
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	err = errors.New("the token doesn't match the request: " + strings.Join(mismatches, "; "))
+	azcore.Log().Write(azcore.LogError, logCredentialError("CheckTokenClaims", err))
+	return err
+}
+
+// audienceMatches returns true when the audience is the resource of the scope, which may identify an application
+// by its client ID or its "api://" application ID URI.
+func audienceMatches(audience string, scope string) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.TrimSuffix(s, "/"))
+	}
+	resource := normalize(strings.TrimSuffix(scope, defaultSuffix))
+	audience = normalize(audience)
+	return audience != "" && (audience == resource || "api://"+audience == resource)
+}
+
+// issuerMatches returns true when the issuer is the v2.0 endpoint of the authority host or its cloud's v1.0 STS.
+func issuerMatches(issuer string, authorityHost string) bool {
+	iss, err := url.Parse(issuer)
+	if err != nil {
+		return false
+	}
+	authority, err := url.Parse(authorityHost)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(iss.Host, authority.Host) {
+		return true
+	}
+	sts, ok := stsHosts[strings.TrimSuffix(strings.ToLower(authorityHost), "/")+"/"]
+	return ok && strings.EqualFold(iss.Host, sts)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// testJWT returns an unsigned JWT with the payload.
+func testJWT(payload string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestParseTokenClaims(t *testing.T) {
+	claims, err := ParseTokenClaims(testJWT(`{"oid":"object","tid":"tenant","appid":"app","xms_mirid":"/subscriptions/sub/resourcegroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/id","exp":1600000000,"roles":["reader"]}`))
+	if err != nil {
+		t.Fatalf("Unable to parse the claims: %v", err)
+	}
+	if claims.ObjectID != "object" || claims.TenantID != "tenant" || claims.AppID != "app" || claims.ManagedIdentityResourceID == "" {
+		t.Fatalf("Unexpected claims: %+v", claims)
+	}
+	if !claims.ExpiresOn.Equal(time.Unix(1600000000, 0)) {
+		t.Fatalf("Unexpected expiration: %v", claims.ExpiresOn)
+	}
+	if roles, ok := claims.Raw["roles"].([]interface{}); !ok || len(roles) != 1 {
+		t.Fatalf("Expected the raw claims to include roles. Received: %v", claims.Raw)
+	}
+}
+
+func TestParseTokenClaims_V2(t *testing.T) {
+	claims, err := ParseTokenClaims(testJWT(`{"azp":"app","ver":"2.0"}`))
+	if err != nil {
+		t.Fatalf("Unable to parse the claims: %v", err)
+	}
+	if claims.AppID != "app" {
+		t.Fatalf("Expected the azp claim to be used as the app ID. Received: %q", claims.AppID)
+	}
+}
+
+func TestParseTokenClaims_Invalid(t *testing.T) {
+	for _, token := range []string{"", "opaque", "a.!!!.c", testJWT("not json")} {
+		if _, err := ParseTokenClaims(token); err == nil {
+			t.Fatalf("Expected an error for %q", token)
+		}
+	}
+}
+
+func TestParseTokenClaims_Audience(t *testing.T) {
+	for _, payload := range []string{`{"aud":"https://storage.azure.com"}`, `{"aud":["https://storage.azure.com","other"]}`} {
+		claims, err := ParseTokenClaims(testJWT(payload))
+		if err != nil {
+			t.Fatalf("Unable to parse the claims: %v", err)
+		}
+		if claims.Audience != "https://storage.azure.com" {
+			t.Fatalf("Unexpected audience for %s: %q", payload, claims.Audience)
+		}
+	}
+}
+
+func TestCheckTokenClaims(t *testing.T) {
+	v1 := testJWT(`{"aud":"https://storage.azure.com/","iss":"https://sts.windows.net/tenant/","tid":"tenant"}`)
+	v2 := testJWT(`{"aud":"00000000-0000-0000-0000-000000000001","iss":"https://login.microsoftonline.com/tenant/v2.0","tid":"tenant"}`)
+	for token, expected := range map[string]ExpectedTokenClaims{
+		v1: {Scope: "https://storage.azure.com/.default", AuthorityHost: AzurePublicCloud, TenantID: "tenant"},
+		v2: {Scope: "api://00000000-0000-0000-0000-000000000001/.default", AuthorityHost: AzurePublicCloud, TenantID: "TENANT"},
+	} {
+		if err := CheckTokenClaims(token, expected); err != nil {
+			t.Fatalf("Expected the claims to match. Received: %v", err)
+		}
+	}
+	for _, expected := range []ExpectedTokenClaims{
+		{Scope: "https://vault.azure.net/.default"},
+		{AuthorityHost: AzureChina},
+		{AuthorityHost: "https://contoso.b2clogin.com/"},
+		{TenantID: "other"},
+	} {
+		if err := CheckTokenClaims(v1, expected); err == nil {
+			t.Fatalf("Expected a mismatch for %+v", expected)
+		}
+	}
+	if err := CheckTokenClaims("opaque", ExpectedTokenClaims{}); err == nil {
+		t.Fatalf("Expected an error for an opaque token")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"time"
+)
+
+// TokenMetrics receives a measurement of every token request handled by a credential, so that applications can
+// monitor authentication with their own metrics system. Implementations must be safe for concurrent use and should
+// return quickly because they're called on the request path.
+type TokenMetrics interface {
+	// RecordTokenRequest is called after each token request completes.
+	RecordTokenRequest(m TokenRequestMetric)
+}
+
+// TokenRequestOutcome describes how a token request was satisfied.
+type TokenRequestOutcome string
+
+const (
+	// TokenRequestCacheHit means the requested token was cached.
+	TokenRequestCacheHit TokenRequestOutcome = "CacheHit"
+	// TokenRequestAcquired means the requested token wasn't cached, so a new one was acquired.
+	TokenRequestAcquired TokenRequestOutcome = "Acquired"
+	// TokenRequestRefreshed means the cached token was due for refresh, so a new one was acquired.
+	TokenRequestRefreshed TokenRequestOutcome = "Refreshed"
+	// TokenRequestFailed means acquiring a token failed. The request may still have succeeded with a cached token
+	// that hadn't expired yet.
+	TokenRequestFailed TokenRequestOutcome = "Failed"
+)
+
+// TokenRefreshedEvent describes a token a credential acquired, without the token itself.
+type TokenRefreshedEvent struct {
+	// CredentialType is the type of the credential that acquired the token, for example "ClientSecretCredential".
+	CredentialType string
+	// TenantID is the tenant the token was acquired from, when the credential knows it.
+	TenantID string
+	// Scopes are the scopes the token was requested for.
+	Scopes []string
+	// ExpiresOn is when the token expires.
+	ExpiresOn time.Time
+	// RefreshOn is when the service recommends refreshing the token, or the zero time when it made no recommendation.
+	RefreshOn time.Time
+	// Replaced is true when the token replaced a cached token that was due for refresh.
+	Replaced bool
+	// Background is true when the token was acquired by a background refresh rather than a call to GetToken.
+	Background bool
+}
+
+// TokenRequestMetric describes a completed token request.
+type TokenRequestMetric struct {
+	// CredentialType is the type of the credential handling the request, for example "ClientSecretCredential".
+	CredentialType string
+	// Outcome describes how the request was satisfied.
+	Outcome TokenRequestOutcome
+	// Duration is how long the request took.
+	Duration time.Duration
+	// Shared is true when the token was acquired by a concurrent request for the same token,
+	// so no additional request was sent to the identity provider.
+	Shared bool
+	// Background is true when the request is a background refresh rather than a call to GetToken.
+	Background bool
+	// Err is the error returned by the identity provider when Outcome is TokenRequestFailed.
+	Err error
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// recordingMetrics is a TokenMetrics that keeps the metrics it receives.
+type recordingMetrics struct {
+	mu      sync.Mutex
+	metrics []TokenRequestMetric
+}
+
+func (r *recordingMetrics) RecordTokenRequest(m TokenRequestMetric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+func (r *recordingMetrics) outcomes() []TokenRequestOutcome {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	outcomes := make([]TokenRequestOutcome, len(r.metrics))
+	for i, m := range r.metrics {
+		outcomes[i] = m.Outcome
+	}
+	return outcomes
+}
+
+func TestClientSecretCredential_Metrics(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespError)), mock.WithStatusCode(http.StatusUnauthorized))
+	srvURL := srv.URL()
+	metrics := &recordingMetrics{}
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true, Metrics: metrics})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+			t.Fatalf("Expected an empty error but received: %v", err)
+		}
+	}
+	if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{"other"}}); err == nil {
+		t.Fatalf("Expected an error")
+	}
+	outcomes := metrics.outcomes()
+	expected := []TokenRequestOutcome{TokenRequestAcquired, TokenRequestCacheHit, TokenRequestFailed}
+	if len(outcomes) != len(expected) {
+		t.Fatalf("Expected outcomes %v. Received: %v", expected, outcomes)
+	}
+	for i := range expected {
+		if outcomes[i] != expected[i] {
+			t.Fatalf("Expected outcomes %v. Received: %v", expected, outcomes)
+		}
+	}
+	for _, m := range metrics.metrics {
+		if m.CredentialType != "ClientSecretCredential" || m.Shared || m.Background {
+			t.Fatalf("Unexpected metric: %+v", m)
+		}
+	}
+	if metrics.metrics[2].Err == nil {
+		t.Fatalf("Expected the failure to include its error")
+	}
+}
+
+func TestTokenCache_MetricsRefresh(t *testing.T) {
+	metrics := &recordingMetrics{}
+	m := tokenTelemetry{metrics: metrics, credentialType: "TestCredential"}
+	cache := NewTokenCache(nil)
+	calls := 0
+	for i := 0; i < 2; i++ {
+		if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, m, tokenAcquirer(&calls, tokenRefreshOffset/2)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	fail := func(context.Context) (*azcore.AccessToken, error) {
+		return nil, errors.New("refresh failed")
+	}
+	// the failure is recorded although the cached token is returned
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, m, fail); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	outcomes := metrics.outcomes()
+	if len(outcomes) != 3 || outcomes[0] != TokenRequestAcquired || outcomes[1] != TokenRequestRefreshed || outcomes[2] != TokenRequestFailed {
+		t.Fatalf("Unexpected outcomes: %v", outcomes)
+	}
+}
+
+func TestTokenCache_MetricsBackgroundRefresh(t *testing.T) {
+	defer func(original func(time.Duration) time.Duration) { refreshJitter = original }(refreshJitter)
+	refreshJitter = func(time.Duration) time.Duration { return 0 }
+	metrics := &recordingMetrics{}
+	cache := NewTokenCache(&TokenCacheOptions{BackgroundRefresh: true})
+	defer cache.Close()
+	acquire := func(context.Context) (*azcore.AccessToken, error) {
+		return &azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(tokenRefreshOffset + 20*time.Millisecond)}, nil
+	}
+	if _, err := cache.getToken(context.Background(), cacheKey{id: "key"}, tokenTelemetry{metrics: metrics, credentialType: "TestCredential"}, acquire); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 100 && len(metrics.outcomes()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if len(metrics.metrics) < 2 || metrics.metrics[1].Outcome != TokenRequestRefreshed || !metrics.metrics[1].Background {
+		t.Fatalf("Expected a background refresh to be recorded. Received: %+v", metrics.metrics)
+	}
+}
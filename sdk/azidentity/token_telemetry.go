@@ -0,0 +1,112 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const (
+	spanGetToken     = "azidentity.GetToken"
+	spanRefreshToken = "azidentity.RefreshToken"
+
+	attrCredential = "az.identity.credential"
+	attrAuthority  = "az.identity.authority"
+	attrScopeHash  = "az.identity.scope_hash"
+	attrOutcome    = "az.identity.outcome"
+	attrShared     = "az.identity.shared"
+	attrBackground = "az.identity.background"
+)
+
+// tokenTelemetry reports the token requests of a credential to the application's TokenMetrics and Tracer, if any.
+type tokenTelemetry struct {
+	metrics          TokenMetrics
+	tracer           azcore.Tracer
+	onTokenRefreshed func(TokenRefreshedEvent)
+	credentialType   string
+	authority        string
+	tenantID         string
+	scopes           []string
+}
+
+// tokenRequest is a token request being reported.
+type tokenRequest struct {
+	telemetry  tokenTelemetry
+	span       azcore.Span
+	start      time.Time
+	background bool
+}
+
+// start begins reporting a token request. The returned context contains the request's span,
+// so the requests sent to acquire the token join the caller's trace.
+func (t tokenTelemetry) start(ctx context.Context, background bool) (context.Context, *tokenRequest) {
+	name := spanGetToken
+	if background {
+		name = spanRefreshToken
+	}
+	ctx, span := azcore.StartSpan(ctx, t.tracer, name)
+	if t.tracer != nil {
+		span.SetAttribute(attrCredential, t.credentialType)
+		if t.authority != "" {
+			span.SetAttribute(attrAuthority, t.authority)
+		}
+		span.SetAttribute(attrScopeHash, scopeHash(t.scopes))
+	}
+	return ctx, &tokenRequest{telemetry: t, span: span, start: time.Now(), background: background}
+}
+
+// end reports the outcome of the request. tk is the token acquired for the request, if any.
+func (r *tokenRequest) end(outcome TokenRequestOutcome, shared bool, tk *azcore.AccessToken, err error) {
+	duration := time.Since(r.start)
+	if err != nil {
+		logTokenRequestFailed(r.telemetry, duration, err)
+	} else if tk != nil && !shared {
+		logTokenAcquired(r.telemetry, outcome, duration, tk)
+		if r.telemetry.onTokenRefreshed != nil {
+			r.telemetry.onTokenRefreshed(TokenRefreshedEvent{
+				CredentialType: r.telemetry.credentialType,
+				TenantID:       r.telemetry.tenantID,
+				Scopes:         append([]string{}, r.telemetry.scopes...),
+				ExpiresOn:      tk.ExpiresOn,
+				RefreshOn:      tk.RefreshOn,
+				Replaced:       outcome == TokenRequestRefreshed,
+				Background:     r.background,
+			})
+		}
+	}
+	r.span.SetAttribute(attrOutcome, string(outcome))
+	r.span.SetAttribute(attrShared, shared)
+	r.span.SetAttribute(attrBackground, r.background)
+	if err != nil {
+		r.span.RecordError(err)
+	}
+	r.span.End()
+	if r.telemetry.metrics == nil {
+		return
+	}
+	r.telemetry.metrics.RecordTokenRequest(TokenRequestMetric{
+		CredentialType: r.telemetry.credentialType,
+		Outcome:        outcome,
+		Duration:       duration,
+		Shared:         shared,
+		Background:     r.background,
+		Err:            err,
+	})
+}
+
+// scopeHash identifies the scopes of a request in traces without recording them.
+func scopeHash(scopes []string) string {
+	sorted := make([]string, len(scopes))
+	copy(sorted, scopes)
+	sort.Strings(sorted)
+	h := sha256.Sum256([]byte(strings.Join(sorted, " ")))
+	return hex.EncodeToString(h[:8])
+}
@@ -0,0 +1,137 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+type spanKey struct{}
+
+// recordingTracer is an azcore.Tracer that keeps the spans it starts.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, azcore.Span) {
+	s := &recordingSpan{name: name, attributes: map[string]interface{}{}}
+	s.parent, _ = ctx.Value(spanKey{}).(*recordingSpan)
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+type recordingSpan struct {
+	name       string
+	parent     *recordingSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+
+func (s *recordingSpan) RecordError(err error) { s.err = err }
+
+func (s *recordingSpan) End() { s.ended = true }
+
+func TestClientSecretCredential_Tracing(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srv.AppendResponse(mock.WithBody([]byte(accessTokenRespError)), mock.WithStatusCode(http.StatusUnauthorized))
+	srvURL := srv.URL()
+	tracer := &recordingTracer{}
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &TokenCredentialOptions{HTTPClient: srv, AuthorityHost: &srvURL, AllowInsecureLocalhost: true, Tracer: tracer})
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	parent := &recordingSpan{name: "parent"}
+	ctx := context.WithValue(context.Background(), spanKey{}, parent)
+	if _, err = cred.GetToken(ctx, azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatalf("Expected an empty error but received: %v", err)
+	}
+	if _, err = cred.GetToken(ctx, azcore.TokenRequestOptions{Scopes: []string{"other"}}); err == nil {
+		t.Fatalf("Expected an error")
+	}
+	if len(tracer.spans) != 2 {
+		t.Fatalf("Expected a span for each request. Received: %d", len(tracer.spans))
+	}
+	s := tracer.spans[0]
+	if s.name != spanGetToken || s.parent != parent || !s.ended {
+		t.Fatalf("Expected an ended span joining the caller's trace. Received: %+v", s)
+	}
+	if s.attributes[attrCredential] != "ClientSecretCredential" || s.attributes[attrAuthority] != srvURL.Host || s.attributes[attrOutcome] != string(TokenRequestAcquired) {
+		t.Fatalf("Unexpected attributes: %v", s.attributes)
+	}
+	if s.attributes[attrScopeHash] != scopeHash([]string{scope}) || s.attributes[attrScopeHash] == scope {
+		t.Fatalf("Expected the scopes to be hashed. Received: %v", s.attributes[attrScopeHash])
+	}
+	if s = tracer.spans[1]; s.err == nil || s.attributes[attrOutcome] != string(TokenRequestFailed) {
+		t.Fatalf("Expected the failure to be recorded. Received: %+v", s)
+	}
+}
+
+func TestScopeHash(t *testing.T) {
+	if scopeHash([]string{"a", "b"}) != scopeHash([]string{"b", "a"}) {
+		t.Fatalf("Expected the order of scopes not to affect the hash")
+	}
+	if scopeHash([]string{"a"}) == scopeHash([]string{"b"}) {
+		t.Fatalf("Expected different scopes to have different hashes")
+	}
+}
+
+func TestClientSecretCredential_OnTokenRefreshed(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithBody([]byte(accessTokenRespSuccess)))
+	srvURL := srv.URL()
+	var events []TokenRefreshedEvent
+	options := TokenCredentialOptions{
+		HTTPClient:             srv,
+		AuthorityHost:          &srvURL,
+		AllowInsecureLocalhost: true,
+		OnTokenRefreshed:       func(e TokenRefreshedEvent) { events = append(events, e) },
+	}
+	cred, err := NewClientSecretCredential(tenantID, clientID, secret, &options)
+	if err != nil {
+		t.Fatalf("Unable to create credential. Received: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = cred.GetToken(context.Background(), azcore.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+			t.Fatalf("Expected an empty error but received: %v", err)
+		}
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected one event for the acquired token and none for the cached one. Received: %d", len(events))
+	}
+	e := events[0]
+	if e.CredentialType != "ClientSecretCredential" || e.TenantID != tenantID || len(e.Scopes) != 1 || e.Scopes[0] != scope || e.ExpiresOn.IsZero() || e.Replaced || e.Background {
+		t.Fatalf("Unexpected event: %+v", e)
+	}
+}
+
+func TestTokenCache_OnTokenRefreshedReplaced(t *testing.T) {
+	c := NewTokenCache(nil)
+	calls := 0
+	var events []TokenRefreshedEvent
+	telemetry := tokenTelemetry{credentialType: "test", onTokenRefreshed: func(e TokenRefreshedEvent) { events = append(events, e) }}
+	for i := 0; i < 2; i++ {
+		// the token expires within the refresh offset so it's replaced by the second request
+		if _, err := c.getToken(context.Background(), cacheKey{id: "key"}, telemetry, tokenAcquirer(&calls, tokenRefreshOffset/2)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(events) != 2 || events[0].Replaced || !events[1].Replaced {
+		t.Fatalf("Expected an event for the acquired token and one for its replacement. Received: %+v", events)
+	}
+}
@@ -33,21 +33,40 @@ func NewUsernamePasswordCredential(tenantID string, clientID string, username st
 	if err != nil {
 		return nil, err
 	}
-	return &UsernamePasswordCredential{tenantID: tenantID, clientID: clientID, username: username, password: password, client: c}, nil
+	cred := &UsernamePasswordCredential{tenantID: tenantID, clientID: clientID, username: username, password: password, client: c}
+	logCredentialCreated(cred, "tenant", tenantID, "client", clientID)
+	return cred, nil
 }
 
 // GetToken obtains a token from Azure Active Directory using the specified username and password.
 // scopes: The list of scopes for which the token will have access.
 // ctx: The context used to control the request lifetime.
 // Returns an AccessToken which can be used to authenticate service client calls.
-func (c *UsernamePasswordCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (*azcore.AccessToken, error) {
-	tk, err := c.client.authenticateUsernamePassword(ctx, c.tenantID, c.clientID, c.username, c.password, opts.Scopes)
+func (c *UsernamePasswordCredential) GetToken(ctx context.Context, opts azcore.TokenRequestOptions) (azcore.AccessToken, error) {
+	if err := validateScopes(opts.Scopes); err != nil {
+		addGetTokenFailureLogs("Username Password Credential", err)
+		return azcore.AccessToken{}, err
+	}
+	tenantID, err := c.client.resolveTenant(c.tenantID, opts)
 	if err != nil {
 		addGetTokenFailureLogs("Username Password Credential", err)
-		return nil, err
+		return azcore.AccessToken{}, err
+	}
+	tk, err := c.client.cache.getToken(ctx, c.client.cacheKey(c.clientID+"|"+c.username, tenantID, opts), c.client.telemetry("UsernamePasswordCredential", tenantID, opts.Scopes), func(ctx context.Context) (*azcore.AccessToken, error) {
+		return c.client.authenticateUsernamePassword(ctx, tenantID, c.clientID, c.username, c.password, opts)
+	})
+	if err != nil {
+		addGetTokenFailureLogs("Username Password Credential", err)
+		return azcore.AccessToken{}, err
 	}
 	azcore.Log().Write(LogCredential, logGetTokenSuccess(c, opts))
-	return tk, err
+	return *tk, nil
+}
+
+// ClearCache removes the credential's tokens from its cache, so that the next token request acquires a new token,
+// for example after the application learns that tokens were revoked or the identity's role assignments changed.
+func (c *UsernamePasswordCredential) ClearCache() {
+	c.client.clearCache(c.clientID + "|" + c.username)
 }
 
 // AuthenticationPolicy implements the azcore.Credential interface on UsernamePasswordCredential.